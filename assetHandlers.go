@@ -22,6 +22,86 @@ func DividendCreate(c context.Context, w http.ResponseWriter, r *http.Request) *
 	return handle(c, w, r)
 }
 
+// Issues additional supply of an existing asset
+func AssetReissue(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetReissue")
+
+	return handle(c, w, r)
+}
+
+// Locks the supply of an asset
+func AssetLock(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetLock")
+
+	return handle(c, w, r)
+}
+
+// Transfers ownership of an asset to another address
+func AssetTransfer(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetTransfer")
+
+	return handle(c, w, r)
+}
+
+// Changes the description of an asset
+func AssetDescription(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetDescription")
+
+	return handle(c, w, r)
+}
+
+// Requires the issuer to authorize trust lines for its assets
+func AssetRequireAuth(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetRequireAuth")
+
+	return handle(c, w, r)
+}
+
+// Authorizes a trust line for an asset of the issuer
+func AssetAuthorize(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetAuthorize")
+
+	return handle(c, w, r)
+}
+
+// Freezes or unfreezes a trust line for an asset of the issuer
+func AssetFreeze(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetFreeze")
+
+	return handle(c, w, r)
+}
+
+// Freezes or unfreezes all assets of the issuer
+func AssetGlobalFreeze(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetGlobalFreeze")
+
+	return handle(c, w, r)
+}
+
+// Permanently gives up the ability of the issuer to freeze its assets
+func AssetNoFreeze(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetNoFreeze")
+
+	return handle(c, w, r)
+}
+
+// Sets the fee charged on transfers of the assets of the issuer
+func AssetTransferRate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetTransferRate")
+
+	return handle(c, w, r)
+}
+
 func AssetIssuances(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
 	// Add to the context the RequestType
 	c = context.WithValue(c, consts.RequestTypeKey, "issuances") //new
@@ -37,6 +117,30 @@ func AssetLedger(c context.Context, w http.ResponseWriter, r *http.Request) *enu
 	return handle(c, w, r)
 }
 
+// Returns the changes to the holders of an asset between two block heights
+func AssetRegistryDiff(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetRegistryDiff")
+
+	return handle(c, w, r)
+}
+
+// Returns the snapshots taken of the holders of an asset
+func AssetRegistrySnapshots(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetRegistrySnapshots")
+
+	return handle(c, w, r)
+}
+
+// Schedules snapshots of the holders of an asset
+func AssetRegistrySchedule(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "assetRegistrySchedule")
+
+	return handle(c, w, r)
+}
+
 func GetDividend(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
 	// Add to the context the RequestType
 	c = context.WithValue(c, consts.RequestTypeKey, "getdividend") //new
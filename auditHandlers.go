@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Audit entries of the changes made by the access key
+func AuditEntries(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "auditEntries")
+
+	return handle(c, w, r)
+}
+
+// Verifies the hash chain of the audit trail
+func AuditVerify(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "auditVerify")
+
+	return handle(c, w, r)
+}
@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/whoisjeremylam/enu/consts"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/nodepool"
 
 	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcjson"
 	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
@@ -20,7 +23,7 @@ import (
 )
 
 // Globals
-var config btcrpcclient.ConnConfig
+var btcNodes *nodepool.Pool
 var isInit bool = false // set to true only after the init sequence is complete
 
 // Initialises global variables and database connection for all handlers
@@ -78,11 +81,16 @@ func InitWithConfigPath(configFilePath string) {
 	m := configuration.(map[string]interface{})
 
 	// Bitcoin API parameters
-	config.Host = m["btchost"].(string)     // Hostname:port for Bitcoin Core or BTCD
-	config.User = m["btcuser"].(string)     // Basic authentication user name
-	config.Pass = m["btcpassword"].(string) // Basic authentication password
-	config.HTTPPostMode = true              // Bitcoin core only supports HTTP POST mode
-	config.DisableTLS = true                // Bitcoin core does not provide TLS by default
+	btcHost := m["btchost"].(string)         // Hostname:port for Bitcoin Core or BTCD
+	btcUser := m["btcuser"].(string)         // Basic authentication user name
+	btcPassword := m["btcpassword"].(string) // Basic authentication password
+
+	// Optional. Further bitcoind nodes which requests are balanced across, the node above is tried first when they are equally caught up
+	nodes := []nodepool.Node{{Host: btcHost, User: btcUser, Password: btcPassword}}
+	if m["btcnodes"] != nil {
+		nodes = append(nodes, nodepool.ConfigNodes(m["btcnodes"])...)
+	}
+	btcNodes = nodepool.NewPool("bitcoind", nodes)
 
 	isInit = true
 }
@@ -93,17 +101,13 @@ func GetBlockCount() (int64, error) {
 		Init()
 	}
 
-	// Notice the notification parameter is nil since notifications are
-	// not supported in HTTP POST mode.
-	client, err := btcrpcclient.New(&config, nil)
-	if err != nil {
-		log.Println(err.Error())
-		return 0, err
-	}
-	defer client.Shutdown()
-
 	// Get the current block count.
-	blockCount, err := client.GetBlockCount()
+	var blockCount int64
+	err := withClient(context.TODO(), "", func(client *btcrpcclient.Client) error {
+		var err error
+		blockCount, err = client.GetBlockCount()
+		return err
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return 0, err
@@ -117,16 +121,13 @@ func GetNewAddress() (string, error) {
 		Init()
 	}
 
-	// Notice the notification parameter is nil since notifications are
-	// not supported in HTTP POST mode.
-	client, err := btcrpcclient.New(&config, nil)
-	if err != nil {
-		return "", err
-	}
-	defer client.Shutdown()
-
-	// Get a new BTC address.
-	address, err := client.GetNewAddress("")
+	// Get a new BTC address. Addresses are held by the wallet of the node, so they always come from the same node while it is available
+	var address btcutil.Address
+	err := withClient(context.TODO(), "wallet", func(client *btcrpcclient.Client) error {
+		var err error
+		address, err = client.GetNewAddress("")
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -165,17 +166,13 @@ func SendRawTransaction(c context.Context, txHexString string) (string, error) {
 
 	msgTx := tx.MsgTx()
 
-	// Notice the notification parameter is nil since notifications are
-	// not supported in HTTP POST mode.
-	client, err := btcrpcclient.New(&config, nil)
-	if err != nil {
-		log.Println(err.Error())
-		return "", err
-	}
-	defer client.Shutdown()
-
-	// Send the tx
-	result, err := client.SendRawTransaction(msgTx, true)
+	// Send the tx to the node the spender is pinned to, so that a transaction spending the change of the last one finds it in the mempool
+	var result *wire.ShaHash
+	err = withClient(c, spenderKey(msgTx), func(client *btcrpcclient.Client) error {
+		var err error
+		result, err = client.SendRawTransaction(msgTx, true)
+		return err
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return "", err
@@ -220,6 +217,159 @@ func GetBalance(c context.Context, address string) (uint64, error) {
 	return uint64(balanceFloat), nil
 }
 
+// Returns the number of transactions which have sent to or spent from the address
+func GetTransactionCount(c context.Context, address string) (uint64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	result, status, err := httpGet(c, "http://btc.blockr.io/api/v1/address/info/"+address+"?confirmations=0")
+
+	if status != 200 {
+		log.FluentfContext(consts.LOGERROR, c, "%s", string(result))
+	}
+
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+
+		return 0, err
+	}
+
+	var r interface{}
+
+	if err := json.Unmarshal(result, &r); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		return 0, err
+	}
+
+	m, ok := r.(map[string]interface{})
+	if ok == false || m["status"] != "success" {
+		return 0, errors.New("Blockr.io unavailable")
+	}
+
+	data, ok := m["data"].(map[string]interface{})
+	if ok == false {
+		log.FluentfContext(consts.LOGERROR, c, "%s", string(result))
+		return 0, errUnexpectedReply
+	}
+	if data["nb_txs"] == nil {
+		return 0, nil
+	}
+
+	count, ok := data["nb_txs"].(float64)
+	if ok == false {
+		log.FluentfContext(consts.LOGERROR, c, "%s", string(result))
+		return 0, errUnexpectedReply
+	}
+
+	return uint64(count), nil
+}
+
+var errUnexpectedReply = errors.New("Unexpected reply from blockr.io")
+
+// An unspent transaction output
+type Unspent struct {
+	TxId          string `json:"txId"`
+	Vout          uint32 `json:"vout"`
+	Amount        uint64 `json:"amount"` // in satoshis
+	Confirmations uint64 `json:"confirmations"`
+	Script        string `json:"script"`
+}
+
+// The address paid by a transaction output
+type OutputAddress struct {
+	Address string
+	Value   uint64 // in satoshis
+}
+
+// Returns the unspent outputs of the address, including unconfirmed outputs
+func GetUnspent(c context.Context, address string) ([]Unspent, error) {
+	var unspent []Unspent
+
+	if isInit == false {
+		Init()
+	}
+
+	result, status, err := httpGet(c, "http://btc.blockr.io/api/v1/address/unspent/"+address+"?unconfirmed=1")
+
+	if status != 200 {
+		log.FluentfContext(consts.LOGERROR, c, "%s", string(result))
+	}
+
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+
+		return unspent, err
+	}
+
+	unspent, err = parseUnspent(result)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+	}
+
+	return unspent, err
+}
+
+// Maps the reply of blockr.io to the unspent outputs it lists. Returns an error if the reply isn't in the expected format
+func parseUnspent(result []byte) ([]Unspent, error) {
+	var unspent []Unspent
+	var r interface{}
+
+	if err := json.Unmarshal(result, &r); err != nil {
+		return unspent, err
+	}
+
+	m, ok := r.(map[string]interface{})
+	if ok == false || m["status"] != "success" {
+		return unspent, errors.New("Blockr.io unavailable")
+	}
+
+	data, ok := m["data"].(map[string]interface{})
+	if ok == false {
+		return unspent, errUnexpectedReply
+	}
+	if data["unspent"] == nil {
+		return unspent, nil
+	}
+
+	outputs, ok := data["unspent"].([]interface{})
+	if ok == false {
+		return unspent, errUnexpectedReply
+	}
+
+	for _, u := range outputs {
+		output, ok := u.(map[string]interface{})
+		if ok == false {
+			return nil, errUnexpectedReply
+		}
+
+		txId, ok1 := output["tx"].(string)
+		n, ok2 := output["n"].(float64)
+		amountString, ok3 := output["amount"].(string)
+		confirmations, ok4 := output["confirmations"].(float64)
+		script, ok5 := output["script"].(string)
+		if ok1 == false || ok2 == false || ok3 == false || ok4 == false || ok5 == false {
+			return nil, errUnexpectedReply
+		}
+
+		// Amounts are returned as strings in BTC
+		amount, err := strconv.ParseFloat(amountString, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		unspent = append(unspent, Unspent{
+			TxId:          txId,
+			Vout:          uint32(n),
+			Amount:        uint64(math.Floor(amount*consts.Satoshi + 0.5)),
+			Confirmations: uint64(confirmations),
+			Script:        script,
+		})
+	}
+
+	return unspent, nil
+}
+
 func httpGet(c context.Context, url string) ([]byte, int64, error) {
 	// Set headers
 	req, err := http.NewRequest("GET", url, nil)
@@ -262,22 +412,18 @@ func GetRawTransaction(txid string) (*btcjson.TxRawResult, error) {
 		Init()
 	}
 
-	// Notice the notification parameter is nil since notifications are
-	// not supported in HTTP POST mode.
-	client, err := btcrpcclient.New(&config, nil)
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-	defer client.Shutdown()
-
 	txHash, err := wire.NewShaHashFromStr(txid)
 	if err != nil {
 		log.Fluentf(consts.LOGERROR, err.Error())
 		return nil, err
 	}
 
-	txVerbose, err := client.GetRawTransactionVerbose(txHash)
+	var txVerbose *btcjson.TxRawResult
+	err = withClient(context.TODO(), "", func(client *btcrpcclient.Client) error {
+		var err error
+		txVerbose, err = client.GetRawTransactionVerbose(txHash)
+		return err
+	})
 	if err != nil {
 		log.Fluentf(consts.LOGERROR, err.Error())
 		return nil, err
@@ -304,3 +450,69 @@ func GetConfirmations(txid string) (uint64, error) {
 
 	return rawtx.Confirmations, nil
 }
+
+// Returns the hash of the block at the height in the chain bitcoind currently follows
+func GetBlockHash(c context.Context, blockHeight int64) (string, error) {
+	if isInit == false {
+		Init()
+	}
+
+	var blockHash *wire.ShaHash
+	err := withClient(c, "", func(client *btcrpcclient.Client) error {
+		var err error
+		blockHash, err = client.GetBlockHash(blockHeight)
+		return err
+	})
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		return "", err
+	}
+
+	return blockHash.String(), nil
+}
+
+// Returns the hash of the block at the height and the ids of the transactions in the block
+func GetBlockTransactions(c context.Context, blockHeight int64) (string, []string, error) {
+	if isInit == false {
+		Init()
+	}
+
+	// The hash and the block are read from the same node so that they are of the same chain
+	var block *btcjson.GetBlockVerboseResult
+	err := withClient(c, "", func(client *btcrpcclient.Client) error {
+		blockHash, err := client.GetBlockHash(blockHeight)
+		if err != nil {
+			return err
+		}
+
+		block, err = client.GetBlockVerbose(blockHash, false)
+		return err
+	})
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		return "", nil, err
+	}
+
+	return block.Hash, block.Tx, nil
+}
+
+// Returns the addresses paid by each output of the transaction along with the value in satoshis, keyed by the output index.
+// Outputs which don't pay a standard address are excluded
+func GetOutputAddresses(tx *btcjson.TxRawResult) map[uint32]OutputAddress {
+	result := make(map[uint32]OutputAddress)
+
+	for _, vout := range tx.Vout {
+		if len(vout.ScriptPubKey.Addresses) != 1 {
+			continue
+		}
+
+		amount, err := btcutil.NewAmount(vout.Value)
+		if err != nil {
+			continue
+		}
+
+		result[vout.N] = OutputAddress{Address: vout.ScriptPubKey.Addresses[0], Value: uint64(amount)}
+	}
+
+	return result
+}
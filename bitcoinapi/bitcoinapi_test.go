@@ -1,6 +1,7 @@
 package bitcoinapi
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/whoisjeremylam/enu/consts"
@@ -88,3 +89,31 @@ func TestGetConfirmations(t *testing.T) {
 		t.Errorf("Expected err2 != nil, got: %s\n", err2.Error())
 	}
 }
+
+func TestParseUnspent(t *testing.T) {
+	var testData = []struct {
+		Reply           string
+		Expected        []Unspent
+		ExpectError     bool
+		CaseDescription string
+	}{
+		{`{"status":"success","data":{"unspent":[{"tx":"abc","n":1,"amount":"0.00010860","confirmations":3,"script":"76a9"}]}}`, []Unspent{{TxId: "abc", Vout: 1, Amount: 10860, Confirmations: 3, Script: "76a9"}}, false, "One output"},
+		{`{"status":"success","data":{"unspent":null}}`, nil, false, "No outputs"},
+		{`{"status":"fail","data":null}`, nil, true, "Blockr.io unavailable"},
+		{`[]`, nil, true, "Not an object"},
+		{`{"status":"success","data":"none"}`, nil, true, "Data isn't an object"},
+		{`{"status":"success","data":{"unspent":{}}}`, nil, true, "Unspent isn't a list"},
+		{`{"status":"success","data":{"unspent":[1]}}`, nil, true, "Output isn't an object"},
+		{`{"status":"success","data":{"unspent":[{"tx":"abc","n":1,"amount":0.0001086,"confirmations":3,"script":"76a9"}]}}`, nil, true, "Amount isn't a string"},
+		{`{"status":"success","data":{"unspent":[{"tx":"abc","amount":"0.00010860","confirmations":3,"script":"76a9"}]}}`, nil, true, "Missing output index"},
+		{`{"status":"success","data":{"unspent":[{"tx":"abc","n":1,"amount":"0.00010860","script":"76a9"}]}}`, nil, true, "Missing confirmations"},
+	}
+
+	for _, s := range testData {
+		result, err := parseUnspent([]byte(s.Reply))
+
+		if (err != nil) != s.ExpectError || reflect.DeepEqual(result, s.Expected) == false {
+			t.Errorf("Expected: %+v, error: %t, Got: %+v, error: %v\nCase: %s\n", s.Expected, s.ExpectError, result, err, s.CaseDescription)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package bitcoinapi
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcjson"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcrpcclient"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/nodepool"
+)
+
+// Returned by bitcoind while it is starting up and loading the block index
+var rpcInWarmup btcjson.RPCErrorCode = -28
+
+var errNoNode = errors.New("No bitcoind node was available")
+
+// Runs the request with a client of each of the bitcoind nodes in turn, best first, until one answers. An error returned by bitcoind itself,
+// eg an invalid transaction, is an answer and is returned. Any other error means the node couldn't be reached and the next node is tried.
+// Requests made on behalf of an account go to the node the account is pinned to, requests without an account are reads
+func withClient(c context.Context, account string, request func(client *btcrpcclient.Client) error) error {
+	var err error = errNoNode
+
+	nodes := btcNodes.ReadNodes()
+	if account != "" {
+		nodes = btcNodes.WriteNodes(account)
+	}
+
+	answered := btcNodes.Try(c, nodes, func(node nodepool.Node) (bool, error) {
+		err = withNode(node, request)
+		if rpcErr, ok := err.(*btcjson.RPCError); ok {
+			return rpcErr.Code != rpcInWarmup, err
+		}
+
+		return err == nil, err
+	})
+
+	if answered != -1 && account != "" {
+		btcNodes.Pin(account, answered)
+	}
+
+	return err
+}
+
+// Runs the request with a client of the node
+func withNode(node nodepool.Node, request func(client *btcrpcclient.Client) error) error {
+	config := btcrpcclient.ConnConfig{
+		Host:         node.Host,
+		User:         node.User,
+		Pass:         node.Password,
+		HTTPPostMode: true, // Bitcoin core only supports HTTP POST mode
+		DisableTLS:   true, // Bitcoin core does not provide TLS by default
+	}
+
+	// Notice the notification parameter is nil since notifications are
+	// not supported in HTTP POST mode.
+	client, err := btcrpcclient.New(&config, nil)
+	if err != nil {
+		return err
+	}
+	defer client.Shutdown()
+
+	return request(client)
+}
+
+// Returns the public key which signed the first input of the transaction, which identifies who is spending so that their transactions
+// go to the same node. Returns "" if the input wasn't signed with a public key
+func spenderKey(tx *wire.MsgTx) string {
+	if len(tx.TxIn) == 0 {
+		return ""
+	}
+
+	pushes, err := txscript.PushedData(tx.TxIn[0].SignatureScript)
+	if err != nil || len(pushes) == 0 {
+		return ""
+	}
+
+	return hex.EncodeToString(pushes[len(pushes)-1])
+}
+
+// Checks the health and block count of each bitcoind node so that reads go to the node which is most caught up. Runs until Enu is stopped
+func MonitorNodes(c context.Context) {
+	if isInit == false {
+		Init()
+	}
+
+	btcNodes.Monitor(c, func(c context.Context, node nodepool.Node) (int64, error) {
+		var blockCount int64
+
+		err := withNode(node, func(client *btcrpcclient.Client) error {
+			var err error
+			blockCount, err = client.GetBlockCount()
+			return err
+		})
+
+		return blockCount, err
+	})
+}
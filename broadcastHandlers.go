@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func BroadcastCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "broadcastCreate")
+
+	return handle(c, w, r)
+}
+
+func GetBroadcast(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "getBroadcast")
+
+	return handle(c, w, r)
+}
+
+// Broadcasts published on chain by an address
+func BroadcastsByAddress(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "broadcastsByAddress")
+
+	return handle(c, w, r)
+}
@@ -10,10 +10,12 @@ const NonceIntKey key = 2
 const BlockchainIdKey key = 3
 const RequestTypeKey key = 4
 const EnvKey key = 5
+const StaleReadKey key = 6
 
 const CounterpartyBlockchainId string = "counterparty"
 const RippleBlockchainId string = "ripple"
 const ColoredCoinsBlockchainId string = "coloredcoins"
+const BitcoinBlockchainId string = "bitcoin" // blocks of bitcoind are tracked, but bitcoin isn't offered as a blockchain of the API
 
 var SupportedBlockchains = []string{CounterpartyBlockchainId, RippleBlockchainId, ColoredCoinsBlockchainId}
 
@@ -23,6 +25,10 @@ const AccessKeyDisabledStatus = "disabled" // the access key has been disabled -
 
 var AccessKeyStatuses = []string{AccessKeyValidStatus, AccessKeyInvalidStatus, AccessKeyDisabledStatus}
 
+const BlockProcessedStatus = "processed" // the block has been processed, eg the credits and debits of monitored addresses in a Counterparty block have been recorded
+const BlockErrorStatus = "error"         // the block couldn't be processed and will be retried
+const BlockReorgedStatus = "reorged"     // the block was processed but is no longer in the chain of the backend. The block at its height will be processed again
+
 const LOGINFO = "INFO"
 const LOGERROR = "ERROR"
 const LOGDEBUG = "DEBUG"
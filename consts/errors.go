@@ -28,6 +28,12 @@ type CounterpartyStruct struct {
 	MalformedAddress          ErrCodes
 	OnlyIssuerCanPayDividends ErrCodes
 	NoSuchAsset               ErrCodes
+	VerificationFailed        ErrCodes
+	OnlyIssuerCanModifyAsset  ErrCodes
+	AssetLocked               ErrCodes
+	InvalidOrder              ErrCodes
+	DepositWalletUnavailable  ErrCodes
+	BlockNotParsed            ErrCodes
 }
 
 var CounterpartyErrors = CounterpartyStruct{
@@ -43,6 +49,12 @@ var CounterpartyErrors = CounterpartyStruct{
 	MalformedAddress:          ErrCodes{1010, "One of the addresses provided was not correct. Please check the addresses involved in the transaction."},
 	OnlyIssuerCanPayDividends: ErrCodes{1011, "Only the issuer may pay dividends."},
 	NoSuchAsset:               ErrCodes{1012, "The asset specified is incorrect or doesn't exist."},
+	VerificationFailed:        ErrCodes{1013, "The transaction composed by Counterparty did not match the request and was not signed. Please contact Vennd.io support."},
+	OnlyIssuerCanModifyAsset:  ErrCodes{1014, "Only the issuer may reissue, lock, transfer or change the description of an asset."},
+	AssetLocked:               ErrCodes{1015, "The asset is locked and no further units may be issued."},
+	InvalidOrder:              ErrCodes{1016, "The order or order match specified is incorrect or doesn't exist."},
+	DepositWalletUnavailable:  ErrCodes{1017, "Deposit addresses are not available. Please contact Vennd.io support."},
+	BlockNotParsed:            ErrCodes{1018, "The block has not been parsed by Counterparty yet. Please try again later."},
 }
 
 type GenericStruct struct {
@@ -61,6 +73,23 @@ type GenericStruct struct {
 	InvalidAddress        ErrCodes
 	InvalidAsset          ErrCodes
 	ApiKeyDisabled        ErrCodes
+	InvalidDerivationPath ErrCodes
+	InvalidExtendedKey    ErrCodes
+	InvalidWatchWalletId  ErrCodes
+	InvalidOrderId        ErrCodes
+	InvalidBroadcastId    ErrCodes
+	InvalidTrustId        ErrCodes
+	InvalidBlockId        ErrCodes
+	InvalidCustomerRef    ErrCodes
+	InvalidLedgerAccount  ErrCodes
+	InsufficientBalance   ErrCodes
+	DuplicateJournal      ErrCodes
+	GatewayUnavailable    ErrCodes
+	DuplicateAddressMap   ErrCodes
+	InvalidAuditQuery     ErrCodes
+	InvalidStatementMonth ErrCodes
+	InvalidBlockQuery     ErrCodes
+	UnverifiedDeposit     ErrCodes
 
 	GeneralError ErrCodes
 }
@@ -82,6 +111,23 @@ var GenericErrors = GenericStruct{
 	InvalidAddress:        ErrCodes{14, "The specified address is invalid. Please correct the address and resubmit."},
 	InvalidAsset:          ErrCodes{15, "The specified asset is invalid. Please correct the asset and resubmit."},
 	ApiKeyDisabled:        ErrCodes{16, "The specified API is valid. However it has been disabled by an administrator."},
+	InvalidDerivationPath: ErrCodes{17, "The specified account or derivation path is invalid. Please correct the account and resubmit."},
+	InvalidExtendedKey:    ErrCodes{18, "The extended public key is invalid. Please provide the xpub of a bitcoin account."},
+	InvalidWatchWalletId:  ErrCodes{19, "The specified watch wallet id is invalid."},
+	InvalidOrderId:        ErrCodes{20, "The specified order id is invalid."},
+	InvalidBroadcastId:    ErrCodes{21, "The specified broadcast id is invalid."},
+	InvalidTrustId:        ErrCodes{22, "The specified trust id is invalid."},
+	InvalidBlockId:        ErrCodes{23, "The specified block must be a block height of 0 or greater."},
+	InvalidCustomerRef:    ErrCodes{24, "The specified customer reference has no deposit address."},
+	InvalidLedgerAccount:  ErrCodes{25, "The specified ledger account doesn't exist or is reserved."},
+	InsufficientBalance:   ErrCodes{26, "Insufficient balance in the ledger account."},
+	DuplicateJournal:      ErrCodes{27, "A journal with the same reference has already been posted."},
+	GatewayUnavailable:    ErrCodes{28, "The gateway between Counterparty and Ripple is not configured."},
+	DuplicateAddressMap:   ErrCodes{29, "The external address is already mapped."},
+	InvalidAuditQuery:     ErrCodes{30, "The fromAuditId must be 0 or greater and the limit must be between 1 and 1000."},
+	InvalidStatementMonth: ErrCodes{31, "The statement month must be given as YYYY-MM."},
+	InvalidBlockQuery:     ErrCodes{32, "The chain must be bitcoin, counterparty or ripple, the offset must be 0 or greater and the limit must be between 1 and 1000."},
+	UnverifiedDeposit:     ErrCodes{33, "The transaction didn't credit an address of the access key with the asset and quantity, or hasn't been received yet."},
 }
 
 type RippleStruct struct {
@@ -99,6 +145,16 @@ type RippleStruct struct {
 	DistributionPassphraseMissing ErrCodes
 	DistributionInsufficientFunds ErrCodes
 	InsufficientXRP               ErrCodes
+	InvalidOffer                  ErrCodes
+	NoPathFound                   ErrCodes
+	DestinationTagRequired        ErrCodes
+	InvalidTransferRate           ErrCodes
+	FreezeDisabled                ErrCodes
+	RequireAuthNotSet             ErrCodes
+	TrustLineNotFound             ErrCodes
+	TrustLineNotEmpty             ErrCodes
+	DeliveredAmountUnavailable    ErrCodes
+	Unavailable                   ErrCodes
 }
 
 var RippleErrors = RippleStruct{
@@ -116,4 +172,14 @@ var RippleErrors = RippleStruct{
 	DistributionPassphraseMissing: ErrCodes{2011, "If a distribution address is specified the passphrase for the distribution address must be given."},
 	DistributionInsufficientFunds: ErrCodes{2012, "The specified distribution address does not contain sufficient funds. Please activate the address and try again."},
 	InsufficientXRP:               ErrCodes{2013, "There was insufficient XRP in the address to perform the payment. Please activate the address and try again."},
+	InvalidOffer:                  ErrCodes{2014, "The specified offer does not exist or is not open. Only open offers placed by the source address may be cancelled."},
+	NoPathFound:                   ErrCodes{2015, "No path was found to deliver the payment spending at most the maximum amount of the source asset."},
+	DestinationTagRequired:        ErrCodes{2016, "The destination address requires a destination tag. Please specify the destinationTag given by the recipient and try again."},
+	InvalidTransferRate:           ErrCodes{2017, "The transfer rate must be between 1000000000 and 2000000000, or 0 to remove the transfer fee."},
+	FreezeDisabled:                ErrCodes{2018, "The issuer has permanently given up the ability to freeze trust lines and to end a global freeze."},
+	RequireAuthNotSet:             ErrCodes{2019, "Trust lines can only be authorized once the issuer requires authorization."},
+	TrustLineNotFound:             ErrCodes{2020, "The source address does not have a trust line to the issuer for the specified asset."},
+	TrustLineNotEmpty:             ErrCodes{2021, "A trust line can only be removed once its balance is zero. Please send the balance back to the issuer and try again."},
+	DeliveredAmountUnavailable:    ErrCodes{2022, "The payment is a partial payment which was validated before the delivered amount was recorded. The amount received can't be determined."},
+	Unavailable:                   ErrCodes{2023, "No Ripple node is available or in sync with the network. Please try again later."},
 }
@@ -5,17 +5,80 @@ type Validations map[string]string
 // cf http://spacetelescope.github.io/understanding-json-schema/
 var ParameterValidations = map[string]Validations{
 	"counterparty": {
-		"asset":           `{"properties":{"blockchainId":{"type":"string"},"passphrase":{"type":"string"},"distributionAddress":{"type":"string","maxLength":34,"minLength":34},"distributionPassphrase":{"type":"string"},"description":{"type":"string"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"divisible":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","quantity","divisible"]}`,
-		"dividend":        `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","maxLength":34,"minLength":34},"asset":{"type":"string","minLength":4},"dividendAsset":{"type":"string"},"quantityPerUnit":{"type":"integer"},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","dividendAsset","quantityPerUnit"]}`,
-		"walletCreate":    `{"properties":{"blockchainId":{"type":"string"},"numberOfAddresses":{"type":"number","minimum":1,"maximum":100,"exclusiveMaximum":false},"nonce":{"type":"integer"}}}`,
-		"walletPayment":   `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","maxLength":34,"minLength":34},"destinationAddress":{"type":"string","maxLength":34,"minLength":34},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","quantity","destinationAddress"]}`,
-		"simplePayment":   `{"properties":{"sourceAddress":{"type":"string", "maxLength":34, "minLength":34},"destinationAddress":{"type":"string", "maxLength":34, "minLength":34},"asset":{"type":"string","minLength":4},"amount":{"type":"integer"},"txFee":{"type":"integer"}},"required":["sourceAddress","destinationAddress","asset","amount"]}`,
-		"activateaddress": `{"properties":{"blockchainId":{"type":"string"},"address":{"type":"string","maxLength":34,"minLength":34},"amount":{"type":"integer"},"nonce":{"type":"integer"}},"required":["address","amount"]}`,
+		"asset":           `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"distributionAddress":{"type":"string","format":"bitcoinAddress"},"distributionPassphrase":{"type":"string"},"description":{"type":"string"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"divisible":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","quantity","divisible"]}`,
+		"dividend":        `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"asset":{"type":"string","minLength":4},"dividendAsset":{"type":"string"},"quantityPerUnit":{"type":"integer"},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","dividendAsset","quantityPerUnit"]}`,
+		"walletCreate":    `{"properties":{"blockchainId":{"type":"string"},"numberOfAddresses":{"type":"number","minimum":1,"maximum":100,"exclusiveMaximum":false},"account":{"type":"integer","minimum":0},"accountPath":{"type":"string","pattern":"^m(/[0-9]+['h]?)*$"},"nonce":{"type":"integer"}}}`,
+		"walletAddresses": `{"properties":{"blockchainId":{"type":"string"},"passphrase":{"type":"string"},"numberOfAddresses":{"type":"number","minimum":1,"maximum":100,"exclusiveMaximum":false},"account":{"type":"integer","minimum":0},"accountPath":{"type":"string","pattern":"^m(/[0-9]+['h]?)*$"},"nonce":{"type":"integer"}},"required":["passphrase"]}`,
+		"walletXpub":      `{"properties":{"blockchainId":{"type":"string"},"passphrase":{"type":"string"},"account":{"type":"integer","minimum":0},"accountPath":{"type":"string","pattern":"^m(/[0-9]+['h]?)*$"},"nonce":{"type":"integer"}},"required":["passphrase"]}`,
+		"walletPayment":   `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"destinationAddress":{"type":"string","format":"bitcoinAddress"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","quantity","destinationAddress"]}`,
+		"simplePayment":   `{"properties":{"sourceAddress":{"type":"string", "format":"bitcoinAddress"},"destinationAddress":{"type":"string", "format":"bitcoinAddress"},"asset":{"type":"string","minLength":4},"amount":{"type":"integer"},"txFee":{"type":"integer"}},"required":["sourceAddress","destinationAddress","asset","amount"]}`,
+		"activateaddress": `{"properties":{"blockchainId":{"type":"string"},"address":{"type":"string","format":"bitcoinAddress"},"amount":{"type":"integer"},"nonce":{"type":"integer"}},"required":["address","amount"]}`,
+
+		// Watch-only wallets
+		"watchWalletCreate": `{"properties":{"blockchainId":{"type":"string"},"xpub":{"type":"string","minLength":111,"maxLength":112},"gapLimit":{"type":"integer","minimum":1,"maximum":100},"nonce":{"type":"integer"}},"required":["xpub"]}`,
+
+		// Multisig
+		"multisigAddressCreate": `{"properties":{"blockchainId":{"type":"string"},"requiredSignatures":{"type":"integer","minimum":1,"maximum":15},"publicKeys":{"type":"array","minItems":1,"maxItems":15,"items":{"type":"string","pattern":"^([0-9a-fA-F]{66}|[0-9a-fA-F]{130})$"}},"nonce":{"type":"integer"}},"required":["requiredSignatures","publicKeys"]}`,
+		"multisigSign":          `{"properties":{"blockchainId":{"type":"string"},"passphrase":{"type":"string"},"unsignedTx":{"type":"string","pattern":"^[0-9a-fA-F]+$"},"partialTx":{"type":"string","pattern":"^[0-9a-fA-F]+$"},"redeemScripts":{"type":"array","items":{"type":"string","pattern":"^[0-9a-fA-F]+$"}},"broadcast":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["passphrase","unsignedTx"]}`,
+		"multisigCombine":       `{"properties":{"blockchainId":{"type":"string"},"unsignedTx":{"type":"string","pattern":"^[0-9a-fA-F]+$"},"partialTxs":{"type":"array","minItems":1,"items":{"type":"string","pattern":"^[0-9a-fA-F]+$"}},"redeemScripts":{"type":"array","items":{"type":"string","pattern":"^[0-9a-fA-F]+$"}},"broadcast":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["unsignedTx","partialTxs"]}`,
+
+		// Asset lifecycle
+		"assetReissue":     `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer","minimum":1},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","quantity"]}`,
+		"assetLock":        `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":4},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset"]}`,
+		"assetTransfer":    `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":4},"destinationAddress":{"type":"string","format":"bitcoinAddress"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","destinationAddress"]}`,
+		"assetDescription": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":4},"description":{"type":"string","maxLength":52},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","description"]}`,
+
+		// Asset registry
+		"assetRegistrySchedule": `{"properties":{"blockchainId":{"type":"string"},"asset":{"type":"string","minLength":4},"blockIds":{"type":"array","items":{"type":"integer","minimum":0}},"onIssuance":{"type":"boolean"},"onDividend":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["asset"]}`,
+
+		// DEX
+		"orderCreate": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"giveAsset":{"type":"string","minLength":3},"giveQuantity":{"type":"integer","minimum":1},"getAsset":{"type":"string","minLength":3},"getQuantity":{"type":"integer","minimum":1},"expiration":{"type":"integer","minimum":1,"maximum":8064},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","giveAsset","giveQuantity","getAsset","getQuantity"]}`,
+		"orderCancel": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"offerHash":{"type":"string","pattern":"^[0-9a-f]{64}$"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","offerHash"]}`,
+		"orderBtcPay": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"orderMatchId":{"type":"string","pattern":"^[0-9a-f]{64}_[0-9a-f]{64}$"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","orderMatchId"]}`,
+
+		// Broadcasts
+		"broadcastCreate": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"passphrase":{"type":"string"},"text":{"type":"string"},"value":{"type":"number"},"feeFraction":{"type":"number","minimum":0,"maximum":1,"exclusiveMaximum":true},"timestamp":{"type":"integer","minimum":0,"maximum":4294967295},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","text"]}`,
+
+		// Deposit addresses
+		"depositAddressCreate": `{"properties":{"blockchainId":{"type":"string"},"customerReference":{"type":"string","minLength":1,"maxLength":100},"nonce":{"type":"integer"}},"required":["customerReference"]}`,
+		// Ledger
+		"ledgerAccountCreate": `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"nonce":{"type":"integer"}},"required":["accountId"]}`,
+		"ledgerTransfer":      `{"properties":{"blockchainId":{"type":"string"},"sourceAccountId":{"type":"string","minLength":1,"maxLength":100},"destinationAccountId":{"type":"string","minLength":1,"maxLength":100},"asset":{"type":"string","minLength":3},"quantity":{"type":"integer","minimum":1},"reference":{"type":"string","maxLength":100},"nonce":{"type":"integer"}},"required":["sourceAccountId","destinationAccountId","asset","quantity"]}`,
+		"ledgerDeposit":       `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"asset":{"type":"string","minLength":3},"quantity":{"type":"integer","minimum":1},"txId":{"type":"string","minLength":1,"maxLength":100},"nonce":{"type":"integer"}},"required":["accountId","asset","quantity","txId"]}`,
+		"ledgerWithdrawal":    `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"passphrase":{"type":"string"},"sourceAddress":{"type":"string","format":"bitcoinAddress"},"destinationAddress":{"type":"string","format":"bitcoinAddress"},"asset":{"type":"string","minLength":3},"quantity":{"type":"integer","minimum":1},"nonce":{"type":"integer"}},"required":["accountId","passphrase","sourceAddress","destinationAddress","asset","quantity"]}`,
+
+		// Gateway
+		"addressMapCreate": `{"properties":{"blockchainId":{"type":"string"},"externalAddress":{"type":"string","format":"rippleAddress"},"counterpartyAddress":{"type":"string","format":"bitcoinAddress"},"counterpartyAssetName":{"type":"string","minLength":4},"nativeAssetName":{"type":"string","minLength":3},"nonce":{"type":"integer"}},"required":["externalAddress","counterpartyAddress","counterpartyAssetName","nativeAssetName"]}`,
 	},
 	"ripple": {
-		"asset":           `{"properties":{"blockchainId":{"type":"string"},"passphrase":{"type":"string"},"distributionAddress":{"type":"string"},"distributionPassphrase":{"type":"string"},"description":{"type":"string"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"divisible":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","quantity","divisible"]}`,
+		"asset":           `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"distributionAddress":{"type":"string","format":"rippleAddress"},"distributionPassphrase":{"type":"string"},"description":{"type":"string"},"asset":{"type":"string","minLength":4},"quantity":{"type":"integer"},"divisible":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","quantity","divisible"]}`,
 		"walletCreate":    `{"properties":{"blockchainId":{"type":"string"},"nonce":{"type":"integer"}}}`,
-		"walletPayment":   `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string"},"destinationAddress":{"type":"string"},"asset":{"type":"string","minLength":3},"quantity":{"type":"integer"},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","quantity","destinationAddress"]}`,
-		"activateaddress": `{"properties":{"blockchainId":{"type":"string"},"address":{"type":"string"},"passphrase":{"type":"string"},"amount":{"type":"integer"},"assets":{"type":"array", "items": [{"type":"object","properties":{"currency":{"type":"string"},"issuer":{"type":"string"}}}]},"nonce":{"type":"integer"}},"required":["address","amount"]}`,
+		"walletPayment":   `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"destinationAddress":{"type":"string","format":"rippleAddress"},"asset":{"type":"string","minLength":3},"quantity":{"type":"integer"},"sourceAsset":{"type":"string","minLength":3},"sourceIssuer":{"type":"string","format":"rippleAddress"},"sendMax":{"type":"integer","minimum":1},"destinationTag":{"type":"integer","minimum":0,"maximum":4294967295},"sourceTag":{"type":"integer","minimum":0,"maximum":4294967295},"invoiceId":{"type":"string","pattern":"^[0-9A-Fa-f]{64}$"},"memos":{"type":"array","items":{"type":"object","properties":{"type":{"type":"string"},"data":{"type":"string"},"format":{"type":"string"}},"required":["data"]}},"nonce":{"type":"integer"}},"required":["sourceAddress","asset","quantity","destinationAddress"],"dependencies":{"sourceAsset":["sendMax"],"sendMax":["sourceAsset"]}}`,
+		"activateaddress": `{"properties":{"blockchainId":{"type":"string"},"address":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"amount":{"type":"integer"},"assets":{"type":"array", "items": [{"type":"object","properties":{"currency":{"type":"string"},"issuer":{"type":"string","format":"rippleAddress"}}}]},"nonce":{"type":"integer"}},"required":["address","amount"]}`,
+
+		// DEX
+		"orderCreate": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"giveAsset":{"type":"string","minLength":3},"giveIssuer":{"type":"string","format":"rippleAddress"},"giveQuantity":{"type":"integer","minimum":1},"getAsset":{"type":"string","minLength":3},"getIssuer":{"type":"string","format":"rippleAddress"},"getQuantity":{"type":"integer","minimum":1},"expiration":{"type":"integer","minimum":1},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","giveAsset","giveQuantity","getAsset","getQuantity"]}`,
+		"orderCancel": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"offerSequence":{"type":"integer","minimum":1},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","offerSequence"]}`,
+
+		// Issuer controls
+		"assetRequireAuth":  `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"requireAuth":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","requireAuth"]}`,
+		"assetAuthorize":    `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":3},"holderAddress":{"type":"string","format":"rippleAddress"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","holderAddress"]}`,
+		"assetFreeze":       `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":3},"holderAddress":{"type":"string","format":"rippleAddress"},"freeze":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","holderAddress","freeze"]}`,
+		"assetGlobalFreeze": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"freeze":{"type":"boolean"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","freeze"]}`,
+		"assetNoFreeze":     `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase"]}`,
+		"assetTransferRate": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"transferRate":{"type":"integer","minimum":0,"maximum":2000000000},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","transferRate"]}`,
+
+		// Trust lines
+		"trustLineCreate": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":3},"issuer":{"type":"string","format":"rippleAddress"},"limit":{"type":"integer","minimum":1},"qualityIn":{"type":"integer","minimum":0,"maximum":4294967295},"qualityOut":{"type":"integer","minimum":0,"maximum":4294967295},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","issuer","limit"]}`,
+		"trustLineRemove": `{"properties":{"blockchainId":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"passphrase":{"type":"string"},"asset":{"type":"string","minLength":3},"issuer":{"type":"string","format":"rippleAddress"},"nonce":{"type":"integer"}},"required":["sourceAddress","passphrase","asset","issuer"]}`,
+
+		// Ledger
+		"ledgerAccountCreate": `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"nonce":{"type":"integer"}},"required":["accountId"]}`,
+		"ledgerTransfer":      `{"properties":{"blockchainId":{"type":"string"},"sourceAccountId":{"type":"string","minLength":1,"maxLength":100},"destinationAccountId":{"type":"string","minLength":1,"maxLength":100},"asset":{"type":"string","minLength":3},"issuer":{"type":"string","format":"rippleAddress"},"quantity":{"type":"integer","minimum":1},"reference":{"type":"string","maxLength":100},"nonce":{"type":"integer"}},"required":["sourceAccountId","destinationAccountId","asset","quantity"]}`,
+		"ledgerDeposit":       `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"asset":{"type":"string","minLength":3},"issuer":{"type":"string","format":"rippleAddress"},"quantity":{"type":"integer","minimum":1},"txId":{"type":"string","minLength":1,"maxLength":100},"nonce":{"type":"integer"}},"required":["accountId","asset","quantity","txId"]}`,
+		"ledgerWithdrawal":    `{"properties":{"blockchainId":{"type":"string"},"accountId":{"type":"string","minLength":1,"maxLength":100},"passphrase":{"type":"string"},"sourceAddress":{"type":"string","format":"rippleAddress"},"destinationAddress":{"type":"string","format":"rippleAddress"},"asset":{"type":"string","minLength":3},"issuer":{"type":"string","format":"rippleAddress"},"quantity":{"type":"integer","minimum":1},"nonce":{"type":"integer"}},"required":["accountId","passphrase","sourceAddress","destinationAddress","asset","quantity"]}`,
+
+		// Gateway
+		"addressMapCreate": `{"properties":{"blockchainId":{"type":"string"},"externalAddress":{"type":"string","format":"rippleAddress"},"counterpartyAddress":{"type":"string","format":"bitcoinAddress"},"counterpartyAssetName":{"type":"string","minLength":4},"nativeAssetName":{"type":"string","minLength":3},"nonce":{"type":"integer"}},"required":["externalAddress","counterpartyAddress","counterpartyAssetName","nativeAssetName"]}`,
 	},
 }
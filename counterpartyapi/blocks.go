@@ -0,0 +1,120 @@
+// Counterparty credits and debits
+// Every change to a balance is recorded by counterpartyd as a credit or debit of the address, whatever the action which caused it.
+
+package counterpartyapi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// A credit as returned by get_credits. The event is the hash of the transaction which caused the credit
+type Credit struct {
+	BlockIndex      uint64 `json:"block_index"`
+	Address         string `json:"address"`
+	Asset           string `json:"asset"`
+	Quantity        uint64 `json:"quantity"`
+	CallingFunction string `json:"calling_function"`
+	Event           string `json:"event"`
+}
+
+// A debit as returned by get_debits. The event is the hash of the transaction which caused the debit
+type Debit struct {
+	BlockIndex uint64 `json:"block_index"`
+	Address    string `json:"address"`
+	Asset      string `json:"asset"`
+	Quantity   uint64 `json:"quantity"`
+	Action     string `json:"action"`
+	Event      string `json:"event"`
+}
+
+// A block as returned by get_block_info
+type BlockInfo struct {
+	BlockIndex uint64 `json:"block_index"`
+	BlockHash  string `json:"block_hash"`
+	BlockTime  uint64 `json:"block_time"`
+}
+
+type payloadGetBlockInfo struct {
+	Method  string                    `json:"method"`
+	Params  payloadGetBlockInfoParams `json:"params"`
+	Jsonrpc string                    `json:"jsonrpc"`
+	Id      uint32                    `json:"id"`
+}
+
+type payloadGetBlockInfoParams struct {
+	BlockIndex uint64 `json:"block_index"`
+}
+
+// Returns the block which counterpartyd parsed at the height
+func GetBlockInfo(c context.Context, blockIndex uint64) (BlockInfo, int64, error) {
+	var payload payloadGetBlockInfo
+	var result BlockInfo
+
+	if isInit == false {
+		Init()
+	}
+
+	payload.Method = "get_block_info"
+	payload.Params.BlockIndex = blockIndex
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postAPI(): %s", err.Error())
+		return result, errorCode, err
+	}
+
+	// Round trip the block through json rather than asserting each field
+	block, err := json.Marshal(responseData["result"])
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	if err := json.Unmarshal(block, &result); err != nil || result.BlockHash == "" {
+		log.FluentfContext(consts.LOGERROR, c, "Block %d wasn't returned by get_block_info", blockIndex)
+		return result, consts.CounterpartyErrors.BlockNotParsed.Code, errors.New(consts.CounterpartyErrors.BlockNotParsed.Description)
+	}
+
+	return result, 0, nil
+}
+
+// Returns every credit made in the block, however many there are, eg a dividend paid to thousands of holders
+func GetCreditsByBlock(c context.Context, blockIndex uint64) ([]Credit, int64, error) {
+	var result []Credit
+
+	errorCode, err := getTableByBlocks(c, "get_credits", "block_index", nil, blockIndex, blockIndex, &result)
+
+	return result, errorCode, err
+}
+
+// Returns every debit made in the block
+func GetDebitsByBlock(c context.Context, blockIndex uint64) ([]Debit, int64, error) {
+	var result []Debit
+
+	errorCode, err := getTableByBlocks(c, "get_debits", "block_index", nil, blockIndex, blockIndex, &result)
+
+	return result, errorCode, err
+}
+
+// Returns every valid send made in the block
+func GetSendsByBlock(c context.Context, blockIndex uint64) ([]ResultGetSends, int64, error) {
+	var result []ResultGetSends
+
+	filterList := filters{filter{Field: "status", Op: "==", Value: "valid"}}
+	errorCode, err := getTableByBlocks(c, "get_sends", "tx_index", filterList, blockIndex, blockIndex, &result)
+
+	return result, errorCode, err
+}
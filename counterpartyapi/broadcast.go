@@ -0,0 +1,127 @@
+// Counterparty broadcasts
+// A broadcast publishes a text and value from the source address, eg an oracle price feed or an announcement by an issuer.
+
+package counterpartyapi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+type payloadCreateBroadcast_Counterparty struct {
+	Method  string                                    `json:"method"`
+	Params  payloadCreateBroadcastParams_Counterparty `json:"params"`
+	Jsonrpc string                                    `json:"jsonrpc"`
+	Id      uint32                                    `json:"id"`
+}
+
+type payloadCreateBroadcastParams_Counterparty struct {
+	Source                 string  `json:"source"`
+	Text                   string  `json:"text"`
+	Value                  float64 `json:"value"`
+	FeeFraction            float64 `json:"fee_fraction"`
+	Timestamp              uint32  `json:"timestamp"`
+	AllowUnconfirmedInputs string  `json:"allow_unconfirmed_inputs"`
+	Encoding               string  `json:"encoding"`
+	PubKey                 string  `json:"pubkey"`
+	Fee                    uint64  `json:"fee"`
+	DustSize               uint64  `json:"regular_dust_size"`
+}
+
+// A broadcast as returned by get_broadcasts
+type Broadcast struct {
+	TxIndex        uint64  `json:"tx_index"`
+	TxHash         string  `json:"tx_hash"`
+	BlockIndex     uint64  `json:"block_index"`
+	Source         string  `json:"source"`
+	Timestamp      uint64  `json:"timestamp"`
+	Value          float64 `json:"value"`
+	FeeFractionInt uint64  `json:"fee_fraction_int"`
+	Text           string  `json:"text"`
+	Locked         uint64  `json:"locked"`
+	Status         string  `json:"status"`
+}
+
+// Counterparty stores the fee fraction as an integer in units of 1e-8
+func FeeFractionInt(feeFraction float64) uint32 {
+	return uint32(feeFraction * 1e8)
+}
+
+// Generates unsigned hex encoded transaction to broadcast the text and value from the source address.
+// The fee fraction is the fraction of bets on the feed paid to the source address and must be less than 1.
+func CreateBroadcast(c context.Context, sourceAddress string, text string, value float64, feeFraction float64, timestamp uint32, pubKeyHexString string) (string, int64, error) {
+	var payload payloadCreateBroadcast_Counterparty
+	var result string
+
+	if isInit == false {
+		Init()
+	}
+
+	if counterpartyComposer == ComposerLocal {
+		return ComposeBroadcast(c, sourceAddress, text, value, feeFraction, timestamp, pubKeyHexString)
+	}
+
+	payload.Method = "create_broadcast"
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+	payload.Params.Source = sourceAddress
+	payload.Params.Text = text
+	payload.Params.Value = value
+	payload.Params.FeeFraction = feeFraction
+	payload.Params.Timestamp = timestamp
+	payload.Params.AllowUnconfirmedInputs = "true"
+	payload.Params.Encoding = counterpartyTransactionEncoding
+	payload.Params.PubKey = pubKeyHexString
+	payload.Params.Fee = Counterparty_DefaultTxFee
+	payload.Params.DustSize = Counterparty_DefaultDustSize
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		// Counterparty is reparsing or timed out, compose the transaction ourselves
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing broadcast locally")
+			return ComposeBroadcast(c, sourceAddress, text, value, feeFraction, timestamp, pubKeyHexString)
+		}
+
+		return "", errorCode, err
+	}
+
+	if responseData["result"] != nil {
+		result = responseData["result"].(string)
+	}
+
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeBroadcast(timestamp, value, FeeFractionInt(feeFraction), text)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeBroadcast(): %s", err.Error())
+			return "", consts.CounterpartyErrors.ComposeError.Code, errors.New(consts.CounterpartyErrors.ComposeError.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
+	return result, 0, nil
+}
+
+// Returns the valid broadcasts made by the address, oldest first
+func GetBroadcastsByAddress(c context.Context, address string) ([]Broadcast, int64, error) {
+	var result []Broadcast
+
+	filterList := filters{filter{Field: "source", Op: "==", Value: address}, filter{Field: "status", Op: "==", Value: "valid"}}
+	errorCode, err := getTable(c, "get_broadcasts", "tx_index", filterList, &result)
+
+	return result, errorCode, err
+}
@@ -0,0 +1,379 @@
+// Local composition of Counterparty transactions
+// Used so that transactions can still be created while counterpartyd is reparsing or catching up with the blockchain.
+// counterpartyd checks the message against the Counterparty ledger (balances, asset ownership) which isn't possible locally.
+// A locally composed transaction which is invalid under the Counterparty protocol is still mined but has no effect other
+// than paying the miners fee.
+
+package counterpartyapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcec"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Values for counterpartycomposer in enuapi.json
+const (
+	ComposerRemote   = "counterpartyd" // Compose with counterpartyd only
+	ComposerFallback = "fallback"      // Compose with counterpartyd and cross-check the message. Compose locally if counterpartyd is unavailable
+	ComposerLocal    = "local"         // Always compose locally
+)
+
+var Counterparty_DefaultMultisigDustSize uint64 = 7800
+var Counterparty_MinimumChange uint64 = 546 // Smaller outputs are treated as dust by bitcoind and won't be relayed
+var Counterparty_MaxOpReturnSize = 80
+
+// Each multisig data output holds 2 fake public keys of 33 bytes, less the sign and nonce bytes, the length byte and the prefix
+const multisigChunkSize = 33*2 - 1 - 8 - 2 - 2
+
+// Generates unsigned hex encoded transaction to send an asset without using counterpartyd
+func ComposeSend(c context.Context, sourceAddress string, destinationAddress string, asset string, quantity uint64, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeSend(asset, quantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeSend(): %s", err.Error())
+		return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, destinationAddress, message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to issue an asset without using counterpartyd
+func ComposeIssuance(c context.Context, sourceAddress string, asset string, description string, quantity uint64, divisible bool, pubKeyHexString string) (string, int64, error) {
+	return composeIssuance(c, sourceAddress, asset, description, quantity, divisible, "", pubKeyHexString)
+}
+
+// A transfer of ownership is an issuance with the dust output paid to the new owner
+func composeIssuance(c context.Context, sourceAddress string, asset string, description string, quantity uint64, divisible bool, transferDestination string, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeIssuance(asset, quantity, divisible, description)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeIssuance(): %s", err.Error())
+		return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, transferDestination, message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to pay a dividend without using counterpartyd
+func ComposeDividend(c context.Context, sourceAddress string, asset string, dividendAsset string, quantityPerUnit uint64, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeDividend(quantityPerUnit, asset, dividendAsset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeDividend(): %s", err.Error())
+		return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, "", message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to place an order on the DEX without using counterpartyd
+func ComposeOrder(c context.Context, sourceAddress string, giveAsset string, giveQuantity uint64, getAsset string, getQuantity uint64, expiration uint16, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeOrder(giveAsset, giveQuantity, getAsset, getQuantity, expiration, 0)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeOrder(): %s", err.Error())
+		return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, "", message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to cancel an order without using counterpartyd
+func ComposeCancel(c context.Context, sourceAddress string, offerHash string, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeCancel(offerHash)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeCancel(): %s", err.Error())
+		return "", consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, "", message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to pay the BTC owed for an order match without using counterpartyd
+func ComposeBtcPay(c context.Context, sourceAddress string, orderMatchId string, destinationAddress string, btcQuantity uint64, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeBtcPay(orderMatchId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeBtcPay(): %s", err.Error())
+		return "", consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+	}
+
+	return composeTransactionWithAmount(c, sourceAddress, destinationAddress, btcQuantity, message, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to broadcast a message without using counterpartyd
+func ComposeBroadcast(c context.Context, sourceAddress string, text string, value float64, feeFraction float64, timestamp uint32, pubKeyHexString string) (string, int64, error) {
+	message, err := EncodeBroadcast(timestamp, value, FeeFractionInt(feeFraction), text)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in EncodeBroadcast(): %s", err.Error())
+		return "", consts.CounterpartyErrors.ComposeError.Code, errors.New(consts.CounterpartyErrors.ComposeError.Description)
+	}
+
+	return composeTransaction(c, sourceAddress, "", message, pubKeyHexString)
+}
+
+// Returns true if the transaction should be composed locally after counterpartyd returned the given error
+func composeLocally(errorCode int64) bool {
+	if counterpartyComposer != ComposerFallback {
+		return false
+	}
+
+	return errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code
+}
+
+// Checks the message in the transaction composed by counterpartyd is the same as the message encoded locally
+func crossCheckMessage(c context.Context, rawTxHexString string, message []byte) (int64, error) {
+	tx, err := ParseTransaction(rawTxHexString)
+	if err != nil {
+		return verificationFailed(c, "cross-check: "+err.Error())
+	}
+
+	expected := encodeMessage(tx.MessageTypeId, tx.Message)
+	if string(expected) != string(message) {
+		return verificationFailed(c, "cross-check: message from counterpartyd differs from the locally encoded message")
+	}
+
+	return 0, nil
+}
+
+// Generates unsigned hex encoded transaction which pays BTC to the destination without a Counterparty message.
+// Where the quantity plus the fee is the whole balance of the source address no change is returned
+func ComposeBtcSend(c context.Context, sourceAddress string, destinationAddress string, quantity uint64, pubKeyHexString string) (string, int64, error) {
+	return composeTransactionWithAmount(c, sourceAddress, destinationAddress, quantity, nil, pubKeyHexString)
+}
+
+// Builds the unsigned transaction in the same form as counterpartyd so it can be passed to SignRawTransaction(), ie with the
+// script of the output being spent in the signature script of each input.
+// Outputs are ordered destination (if any), data, change.
+func composeTransaction(c context.Context, sourceAddress string, destinationAddress string, message []byte, pubKeyHexString string) (string, int64, error) {
+	return composeTransactionWithAmount(c, sourceAddress, destinationAddress, Counterparty_DefaultDustSize, message, pubKeyHexString)
+}
+
+// As composeTransaction() where the destination is paid the given amount rather than dust, ie a BTCpay
+func composeTransactionWithAmount(c context.Context, sourceAddress string, destinationAddress string, destinationAmount uint64, message []byte, pubKeyHexString string) (string, int64, error) {
+	var outputTotal uint64
+
+	source, err := btcutil.DecodeAddress(sourceAddress, &chaincfg.MainNetParams)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DecodeAddress(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MalformedAddress.Code, errors.New(consts.CounterpartyErrors.MalformedAddress.Description)
+	}
+
+	sourceScript, err := txscript.PayToAddrScript(source)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in PayToAddrScript(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MalformedAddress.Code, errors.New(consts.CounterpartyErrors.MalformedAddress.Description)
+	}
+
+	tx := wire.NewMsgTx()
+
+	if destinationAddress != "" {
+		destination, err := btcutil.DecodeAddress(destinationAddress, &chaincfg.MainNetParams)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in DecodeAddress(): %s", err.Error())
+			return "", consts.CounterpartyErrors.MalformedAddress.Code, errors.New(consts.CounterpartyErrors.MalformedAddress.Description)
+		}
+
+		destinationScript, err := txscript.PayToAddrScript(destination)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in PayToAddrScript(): %s", err.Error())
+			return "", consts.CounterpartyErrors.MalformedAddress.Code, errors.New(consts.CounterpartyErrors.MalformedAddress.Description)
+		}
+
+		tx.AddTxOut(wire.NewTxOut(int64(destinationAmount), destinationScript))
+		outputTotal += destinationAmount
+	}
+
+	// The number and value of the data outputs is known before the inputs are selected, but not their contents
+	useOpReturn := counterpartyTransactionEncoding == "opreturn" || (counterpartyTransactionEncoding != "multisig" && len(Counterparty_Prefix)+len(message) <= Counterparty_MaxOpReturnSize)
+	var dataOutputValue uint64
+	if useOpReturn == false && message != nil {
+		numberOfChunks := (len(message) + multisigChunkSize - 1) / multisigChunkSize
+		dataOutputValue = Counterparty_DefaultMultisigDustSize
+		outputTotal += uint64(numberOfChunks) * dataOutputValue
+	}
+
+	// Select the coins
+	unspent, err := bitcoinapi.GetUnspent(c, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in GetUnspent(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	inputs, change, ok := selectCoins(unspent, outputTotal+Counterparty_DefaultTxFee)
+	if ok == false {
+		log.FluentfContext(consts.LOGERROR, c, "Insufficient BTC at %s to compose transaction requiring %d satoshis", sourceAddress, outputTotal+Counterparty_DefaultTxFee)
+		return "", consts.CounterpartyErrors.InsufficientFees.Code, errors.New(consts.CounterpartyErrors.InsufficientFees.Description)
+	}
+
+	for _, input := range inputs {
+		hash, err := wire.NewShaHashFromStr(input.TxId)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in NewShaHashFromStr(): %s", err.Error())
+			return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+		}
+
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, input.Vout), sourceScript))
+	}
+
+	// The data is obfuscated with the txid of the first input so can only be built after the coins are selected
+	key, err := arc4Key(tx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in arc4Key(): %s", err.Error())
+		return "", consts.CounterpartyErrors.ComposeError.Code, errors.New(consts.CounterpartyErrors.ComposeError.Description)
+	}
+
+	// Plain BTC sends have no data outputs
+	var dataScripts [][]byte
+	if message != nil {
+		if useOpReturn {
+			dataScripts, err = opReturnDataScripts(key, message)
+		} else {
+			dataScripts, err = multisigDataScripts(key, message, pubKeyHexString)
+		}
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error building data outputs: %s", err.Error())
+			return "", consts.CounterpartyErrors.ComposeError.Code, errors.New(consts.CounterpartyErrors.ComposeError.Description)
+		}
+	}
+
+	for _, dataScript := range dataScripts {
+		tx.AddTxOut(wire.NewTxOut(int64(dataOutputValue), dataScript))
+	}
+
+	if change > 0 {
+		tx.AddTxOut(wire.NewTxOut(int64(change), sourceScript))
+	}
+
+	result, err := encodeRawTransaction(c, tx)
+	if err != nil {
+		return "", consts.CounterpartyErrors.ComposeError.Code, errors.New(consts.CounterpartyErrors.ComposeError.Description)
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Composed transaction locally: %s", result)
+
+	return result, 0, nil
+}
+
+type byAmountDescending []bitcoinapi.Unspent
+
+func (u byAmountDescending) Len() int           { return len(u) }
+func (u byAmountDescending) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u byAmountDescending) Less(i, j int) bool { return u[i].Amount > u[j].Amount }
+
+// Selects the largest unspent outputs first until the amount is covered.
+// Returns the selected outputs and the change, which is either zero or large enough to not be dust.
+func selectCoins(unspent []bitcoinapi.Unspent, amount uint64) ([]bitcoinapi.Unspent, uint64, bool) {
+	var selected []bitcoinapi.Unspent
+	var total uint64
+
+	sorted := make([]bitcoinapi.Unspent, len(unspent))
+	copy(sorted, unspent)
+	sort.Sort(byAmountDescending(sorted))
+
+	for _, u := range sorted {
+		selected = append(selected, u)
+		total += u.Amount
+
+		if total == amount || total >= amount+Counterparty_MinimumChange {
+			return selected, total - amount, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+func opReturnDataScripts(key []byte, message []byte) ([][]byte, error) {
+	data, err := arc4Crypt(key, append([]byte(Counterparty_Prefix), message...))
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(data).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{script}, nil
+}
+
+// Splits the message into 1-of-3 multisig outputs. The first 2 public keys hold the data and the last is the public key of the source
+// so the output can be spent later.
+func multisigDataScripts(key []byte, message []byte, pubKeyHexString string) ([][]byte, error) {
+	var scripts [][]byte
+
+	sourcePubKeyBytes, err := hex.DecodeString(pubKeyHexString)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcePubKey, err := btcutil.NewAddressPubKey(sourcePubKeyBytes, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(message); start += multisigChunkSize {
+		end := start + multisigChunkSize
+		if end > len(message) {
+			end = len(message)
+		}
+
+		// Length byte, prefix, data then padding to fill both public keys
+		chunk := append([]byte(Counterparty_Prefix), message[start:end]...)
+		chunk = append([]byte{byte(len(chunk))}, chunk...)
+		chunk = append(chunk, make([]byte, 62-len(chunk))...)
+
+		encrypted, err := arc4Crypt(key, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		var pubKeys []*btcutil.AddressPubKey
+		for _, part := range [][]byte{encrypted[:31], encrypted[31:]} {
+			pubKey, err := validPubKey(part)
+			if err != nil {
+				return nil, err
+			}
+
+			pubKeys = append(pubKeys, pubKey)
+		}
+		pubKeys = append(pubKeys, sourcePubKey)
+
+		script, err := txscript.MultiSigScript(pubKeys, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		scripts = append(scripts, script)
+	}
+
+	return scripts, nil
+}
+
+// Adds a sign byte and a nonce byte to the 31 bytes of data so that the result is a valid compressed public key
+func validPubKey(data []byte) (*btcutil.AddressPubKey, error) {
+	hash := sha256.Sum256(data)
+
+	for i := 0; i < 256; i++ {
+		for _, sign := range []byte{0x02, 0x03} {
+			candidate := append([]byte{sign}, data...)
+			candidate = append(candidate, hash[0]+byte(i))
+
+			if _, err := btcec.ParsePubKey(candidate, btcec.S256()); err != nil {
+				continue
+			}
+
+			return btcutil.NewAddressPubKey(candidate, &chaincfg.MainNetParams)
+		}
+	}
+
+	return nil, errors.New("Unable to create a valid public key for the data")
+}
@@ -25,12 +25,8 @@ import (
 	"github.com/whoisjeremylam/enu/consts"
 	"github.com/whoisjeremylam/enu/counterpartycrypto"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/nodepool"
 
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcec"
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil"
 	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/securecookie"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 )
@@ -38,6 +34,7 @@ import (
 var Counterparty_DefaultDustSize uint64 = 5430
 var Counterparty_DefaultTxFee uint64 = 10000       // in satoshis
 var Counterparty_DefaultTestingTxFee uint64 = 1500 // in satoshis
+var Counterparty_LockDescription = "LOCK"          // an issuance of zero quantity with this description locks the asset
 var numericAssetIdMinString = "95428956661682176"
 var numericAssetIdMaxString = "18446744073709551616"
 
@@ -108,12 +105,12 @@ type payloadCreateIssuance_Counterparty struct {
 }
 
 type payloadCreateIssuanceParams_Counterparty struct {
-	Source      string `json:"source"`
-	Quantity    uint64 `json:"quantity"`
-	Asset       string `json:"asset"`
-	Divisible   bool   `json:"divisible"`
-	Description string `json:"description"`
-	//	TransferDestination    string `json:"transfer_destination"`
+	Source                 string `json:"source"`
+	Quantity               uint64 `json:"quantity"`
+	Asset                  string `json:"asset"`
+	Divisible              bool   `json:"divisible"`
+	Description            string `json:"description"`
+	TransferDestination    string `json:"transfer_destination,omitempty"`
 	Encoding               string `json:"encoding"`
 	PubKey                 string `json:"pubkey"`
 	AllowUnconfirmedInputs string `json:"allow_unconfirmed_inputs"`
@@ -247,11 +244,27 @@ type ResultGetSends struct {
 
 // Globals
 var isInit bool = false // set to true only after the init sequence is complete
-var counterpartyHost string
-var counterpartyUser string
-var counterpartyPassword string
+var counterpartyNodes *nodepool.Pool
 var counterpartyTransactionEncoding string
 var counterpartyDBLocation string
+var counterpartyComposer string
+var depositWallet DepositWallet
+var gatewayWallet GatewayWallet
+
+// The server held HD wallet from which customer deposit addresses are derived, and where their deposits are swept to
+type DepositWallet struct {
+	Passphrase      string
+	AccountPath     string
+	TreasuryAddress string
+	SweepInterval   int // minutes
+}
+
+// The server held HD wallet from which the gateway payment addresses are derived. Each payment address holds the Counterparty asset
+// deposited to it and pays out the asset when the mirrored Ripple IOU is sent back to the issuer
+type GatewayWallet struct {
+	Passphrase  string
+	AccountPath string
+}
 
 // Initialises global variables and database connection for all handlers
 func Init() {
@@ -310,18 +323,74 @@ func InitWithConfigPath(configFilePath string) {
 	m := configuration.(map[string]interface{})
 
 	// Counterparty API parameters
-	counterpartyHost = m["counterpartyhost"].(string)                               // End point for JSON RPC server
-	counterpartyUser = m["counterpartyuser"].(string)                               // Basic authentication user name
-	counterpartyPassword = m["counterpartypassword"].(string)                       // Basic authentication password
+	counterpartyHost := m["counterpartyhost"].(string)                              // End point for JSON RPC server
+	counterpartyUser := m["counterpartyuser"].(string)                              // Basic authentication user name
+	counterpartyPassword := m["counterpartypassword"].(string)                      // Basic authentication password
 	counterpartyTransactionEncoding = m["counterpartytransactionencoding"].(string) // The encoding that should be used for Counterparty transactions "auto" will let Counterparty select, valid values "multisig", "opreturn"
 	counterpartyDBLocation = m["counterpartydblocation"].(string)                   // Direct location of counterpartydb if we can't reach the API
 
+	// Optional. Further counterpartyd nodes which requests are balanced across, the node above is tried first when they are equally caught up
+	nodes := []nodepool.Node{{Host: counterpartyHost, User: counterpartyUser, Password: counterpartyPassword}}
+	if m["counterpartynodes"] != nil {
+		nodes = append(nodes, nodepool.ConfigNodes(m["counterpartynodes"])...)
+	}
+	counterpartyNodes = nodepool.NewPool("counterpartyd", nodes)
+
+	// Optional. Whether transactions are composed by counterpartyd, locally or locally when counterpartyd is unavailable
+	counterpartyComposer = ComposerRemote
+	if m["counterpartycomposer"] != nil {
+		counterpartyComposer = m["counterpartycomposer"].(string)
+	}
+
+	// Optional. Deposit addresses are only available when the deposit wallet and treasury address are configured
+	if m["depositpassphrase"] != nil && m["treasuryaddress"] != nil {
+		depositWallet.Passphrase = m["depositpassphrase"].(string)
+		depositWallet.TreasuryAddress = m["treasuryaddress"].(string)
+		depositWallet.AccountPath = counterpartycrypto.AccountPath(0)
+		depositWallet.SweepInterval = 60
+
+		if m["depositaccountpath"] != nil {
+			depositWallet.AccountPath = m["depositaccountpath"].(string)
+		}
+		if m["depositsweepinterval"] != nil {
+			depositWallet.SweepInterval = int(m["depositsweepinterval"].(float64))
+		}
+	}
+
+	// Optional. The gateway between Counterparty and Ripple is only available when the gateway wallet is configured
+	if m["gatewaypassphrase"] != nil {
+		gatewayWallet.Passphrase = m["gatewaypassphrase"].(string)
+		gatewayWallet.AccountPath = counterpartycrypto.AccountPath(1)
+
+		if m["gatewayaccountpath"] != nil {
+			gatewayWallet.AccountPath = m["gatewayaccountpath"].(string)
+		}
+	}
+
 	isInit = true
 }
 
-// Posts to the given counterparty JSON RPC call. Returns a map[string]interface{} which has already unmarshalled the JSON result
+// Returns the deposit wallet and whether one is configured
+func GetDepositWallet() (DepositWallet, bool) {
+	if isInit == false {
+		Init()
+	}
+
+	return depositWallet, depositWallet.Passphrase != ""
+}
+
+// Returns the gateway wallet and whether one is configured
+func GetGatewayWallet() (GatewayWallet, bool) {
+	if isInit == false {
+		Init()
+	}
+
+	return gatewayWallet, gatewayWallet.Passphrase != ""
+}
+
+// Posts to the given counterparty JSON RPC call on the node. Returns a map[string]interface{} which has already unmarshalled the JSON result
 // Attempts to interpret the counterparty errors such that the caller doesn't need to work out what is going on
-func postAPI(c context.Context, postData []byte) (map[string]interface{}, int64, error) {
+func postAPINode(c context.Context, node nodepool.Node, postData []byte) (map[string]interface{}, int64, error) {
 	var result map[string]interface{}
 	var apiResp ApiResult
 
@@ -329,8 +398,8 @@ func postAPI(c context.Context, postData []byte) (map[string]interface{}, int64,
 	//		log.FluentfContext(consts.LOGDEBUG, c, "counterpartyapi postAPI() posting: %s", postDataJson)
 
 	// Set headers
-	req, err := http.NewRequest("POST", counterpartyHost, bytes.NewBufferString(postDataJson))
-	req.SetBasicAuth(counterpartyUser, counterpartyPassword)
+	req, err := http.NewRequest("POST", node.Host, bytes.NewBufferString(postDataJson))
+	req.SetBasicAuth(node.User, node.Password)
 	req.Header.Set("Content-Type", "application/json")
 
 	clientPointer := &http.Client{}
@@ -353,9 +422,10 @@ func postAPI(c context.Context, postData []byte) (map[string]interface{}, int64,
 		return result, consts.CounterpartyErrors.Timeout.Code, errors.New(consts.CounterpartyErrors.Timeout.Description)
 	}
 
+	// The node couldn't be reached
 	if apiResp.err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in Do(req): %s", apiResp.err.Error())
-		return result, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+		return result, consts.CounterpartyErrors.ReparsingOrUnavailable.Code, errors.New(consts.CounterpartyErrors.ReparsingOrUnavailable.Description)
 	}
 
 	// Unsuccessful - ie didn't return HTTP status 200
@@ -483,6 +553,10 @@ func GetBalancesByAddress(c context.Context, address string) ([]Balance, int64,
 		Init()
 	}
 
+	if readFromDB(c) {
+		return GetBalancesByAddressDB(c, address)
+	}
+
 	filterCondition := filter{Field: "address", Op: "==", Value: address}
 
 	payload.Method = "get_balances"
@@ -499,7 +573,7 @@ func GetBalancesByAddress(c context.Context, address string) ([]Balance, int64,
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
 		// Counterparty DB is behind backend / reparsing or timed out, read directly from DB
-		if errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code {
+		if isFailover(errorCode) {
 			return GetBalancesByAddressDB(c, address)
 		}
 
@@ -523,6 +597,8 @@ func GetBalancesByAddress(c context.Context, address string) ([]Balance, int64,
 func GetBalancesByAddressDB(c context.Context, address string) ([]Balance, int64, error) {
 	var result []Balance
 
+	markStale(c)
+
 	// sqlite drivers are not concurrency safe, so must create a connection each time
 	db, err := sql.Open("sqlite3", counterpartyDBLocation)
 	if err != nil {
@@ -582,6 +658,10 @@ func GetBalancesByAsset(c context.Context, asset string) ([]Balance, int64, erro
 		Init()
 	}
 
+	if readFromDB(c) {
+		return GetBalancesByAssetDB(c, asset)
+	}
+
 	filterCondition := filter{Field: "asset", Op: "==", Value: asset}
 
 	payload.Method = "get_balances"
@@ -598,7 +678,7 @@ func GetBalancesByAsset(c context.Context, asset string) ([]Balance, int64, erro
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
 		// Counterparty DB is behind backend / reparsing or timed out, read directly from DB
-		if errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code {
+		if isFailover(errorCode) {
 			return GetBalancesByAssetDB(c, asset)
 		}
 
@@ -622,6 +702,8 @@ func GetBalancesByAsset(c context.Context, asset string) ([]Balance, int64, erro
 func GetBalancesByAssetDB(c context.Context, asset string) ([]Balance, int64, error) {
 	var result []Balance
 
+	markStale(c)
+
 	// sqlite drivers are not concurrency safe, so must create a connection each time
 	db, err := sql.Open("sqlite3", counterpartyDBLocation)
 	if err != nil {
@@ -682,6 +764,10 @@ func GetSendsByAddress(c context.Context, address string) ([]ResultGetSends, int
 		Init()
 	}
 
+	if readFromDB(c) {
+		return GetSendsByAddressDB(c, address)
+	}
+
 	var filterArray filters
 	filterCondition := filter{Field: "destination", Op: "==", Value: address}
 	filterArray = append(filterArray, filterCondition)
@@ -703,8 +789,8 @@ func GetSendsByAddress(c context.Context, address string) ([]ResultGetSends, int
 
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
-		//		 Counterparty DB is behind backend / reparsing or timed out, read directly from DB
-		if errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code {
+		// Counterparty DB is behind backend / reparsing or timed out, read directly from DB
+		if isFailover(errorCode) {
 			return GetSendsByAddressDB(c, address)
 		}
 
@@ -732,6 +818,8 @@ func GetSendsByAddress(c context.Context, address string) ([]ResultGetSends, int
 func GetSendsByAddressDB(c context.Context, address string) ([]ResultGetSends, int64, error) {
 	var result []ResultGetSends
 
+	markStale(c)
+
 	// sqlite drivers are not concurrency safe, so must create a connection each time
 	db, err := sql.Open("sqlite3", counterpartyDBLocation)
 	if err != nil {
@@ -795,6 +883,11 @@ func GetIssuances(c context.Context, asset string) ([]Issuance, int64, error) {
 	if isInit == false {
 		Init()
 	}
+
+	if readFromDB(c) {
+		return GetIssuancesDB(c, asset)
+	}
+
 	filterCondition := filter{Field: "asset", Op: "==", Value: asset}
 	filterCondition2 := filter{Field: "status", Op: "==", Value: "valid"}
 
@@ -815,7 +908,7 @@ func GetIssuances(c context.Context, asset string) ([]Issuance, int64, error) {
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
 		// Counterparty DB is behind backend / reparsing or timed out, read directly from DB
-		if errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code {
+		if isFailover(errorCode) {
 			return GetIssuancesDB(c, asset)
 		}
 
@@ -849,6 +942,8 @@ func GetIssuances(c context.Context, asset string) ([]Issuance, int64, error) {
 func GetIssuancesDB(c context.Context, asset string) ([]Issuance, int64, error) {
 	var result []Issuance
 
+	markStale(c)
+
 	// sqlite drivers are not concurrency safe, so must create a connection each time
 	db, err := sql.Open("sqlite3", counterpartyDBLocation)
 	if err != nil {
@@ -863,8 +958,8 @@ func GetIssuancesDB(c context.Context, asset string) ([]Issuance, int64, error)
 	}
 
 	//	 Query DB
-	//	log.Fluentf(consts.LOGDEBUG, "select tx_index, tx_hash, block_index, asset, quantity, divisible, source, issuer, transfer, description, fee_paid, locked, status from issuances where status='valid' and asset=%s", asset)
-	stmt, err := db.Prepare("select tx_index, tx_hash, block_index, asset, quantity, divisible, source, issuer, transfer, description, fee_paid, locked, status from issuances where status='valid' and asset=?")
+	//	log.Fluentf(consts.LOGDEBUG, "select tx_index, tx_hash, block_index, asset, quantity, divisible, source, issuer, transfer, description, fee_paid, locked, status from issuances where status='valid' and asset=%s order by tx_index asc", asset)
+	stmt, err := db.Prepare("select tx_index, tx_hash, block_index, asset, quantity, divisible, source, issuer, transfer, description, fee_paid, locked, status from issuances where status='valid' and asset=? order by tx_index asc")
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
 		return result, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
@@ -943,6 +1038,10 @@ func CreateSend(c context.Context, sourceAddress string, destinationAddress stri
 
 	//	log.Println("In counterpartyapi.CreateSend()")
 
+	if counterpartyComposer == ComposerLocal {
+		return ComposeSend(c, sourceAddress, destinationAddress, asset, quantity, pubKeyHexString)
+	}
+
 	// ["source":sourceAddress,"destination":destinationAddress,"asset":asset,"quantity":amount,"allow_unconfirmed_inputs":true,"encoding":counterpartyTransactionEncoding,"pubkey":pubkey]
 	payload.Method = "create_send"
 	payload.Jsonrpc = "2.0"
@@ -967,6 +1066,12 @@ func CreateSend(c context.Context, sourceAddress string, destinationAddress stri
 	// Post the request to counterpartyd
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
+		// Counterparty is reparsing or timed out, compose the transaction ourselves
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing send locally")
+			return ComposeSend(c, sourceAddress, destinationAddress, asset, quantity, pubKeyHexString)
+		}
+
 		return "", errorCode, err
 	}
 
@@ -975,158 +1080,41 @@ func CreateSend(c context.Context, sourceAddress string, destinationAddress stri
 		result = responseData["result"].(string)
 	}
 
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeSend(asset, quantity)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeSend(): %s", err.Error())
+			return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
 	return result, 0, nil
 }
 
 // When given the 12 word passphrase:
-// 1) Parses the raw TX to find the address being sent from
-// 2) Derives the parent key and the child key for the address found in step 1)
+// 1) Parses the raw TX to find the addresses being spent from
+// 2) Derives the parent key and the child key for each address found in step 1)
 // 3) Signs all the TX inputs
 //
-// Assumptions
-// 1) This is a Counterparty transaction so all inputs need to be signed with the same pubkeyhash
+// Pubkeyhash, bare multisig and P2SH multisig inputs are supported. Every input must be fully signed by
+// the keys held in the passphrase, otherwise an error is returned. Use SignRawTransactionPartial() when
+// co-signers hold the remaining keys.
 func SignRawTransaction(c context.Context, passphrase string, rawTxHexString string) (string, error) {
-	// Convert the hex string to a byte array
-	txBytes, err := hex.DecodeString(rawTxHexString)
+	signed, complete, err := SignRawTransactionPartial(c, passphrase, rawTxHexString, "", nil)
 	if err != nil {
-		log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
 		return "", err
 	}
 
-	//	log.Printf("Unsigned tx: %s", rawTxHexString)
-
-	// Deserialise the transaction
-	tx, err := btcutil.NewTxFromBytes(txBytes)
-	if err != nil {
-		log.FluentfContext(consts.LOGERROR, c, "Error in NewTxFromBytes(): %s", err.Error())
-		return "", err
-	}
-	//	log.Printf("Deserialised ok!: %+v", tx)
-
-	msgTx := tx.MsgTx()
-	redeemTx := wire.NewMsgTx() // Create a new transaction and copy the details from the tx that was serialised. For some reason BTCD can't sign in place transactions
-	//	log.Printf("MsgTx: %+v", msgTx)
-	//	log.Printf("Number of txes in: %d\n", len(msgTx.TxIn))
-	for i := 0; i <= len(msgTx.TxIn)-1; i++ {
-		//		log.Printf("MsgTx.TxIn[%d]:\n", i)
-		//		log.Printf("TxIn[%d].PreviousOutPoint.Hash: %s\n", i, msgTx.TxIn[i].PreviousOutPoint.Hash)
-		//		log.Printf("TxIn[%d].PreviousOutPoint.Index: %d\n", i, msgTx.TxIn[i].PreviousOutPoint.Index)
-		//		log.Printf("TxIn[%d].SignatureScript: %s\n", i, hex.EncodeToString(msgTx.TxIn[i].SignatureScript))
-		script := msgTx.TxIn[i].SignatureScript
-
-		// Following block is for debugging only
-		//		disasm, err := txscript.DisasmString(script)
-		//		if err != nil {
-		//			return "", err
-		//		}
-		//		log.Printf("TxIn[%d] Script Disassembly: %s", i, disasm)
-
-		// Extract and print details from the script.
-		// next line is for debugging only
-		//		scriptClass, addresses, reqSigs, err := txscript.ExtractPkScriptAddrs(script, &chaincfg.MainNetParams)
-		scriptClass, _, _, err := txscript.ExtractPkScriptAddrs(script, &chaincfg.MainNetParams)
-		if err != nil {
-			log.FluentfContext(consts.LOGERROR, c, "Error in ExtractPkScriptAddrs(): %s", err.Error())
-			return "", err
-		}
-
-		// This function only supports pubkeyhash signing at this time (ie not multisig or P2SH)
-		//		log.Printf("TxIn[%d] Script Class: %s\n", i, scriptClass)
-		if scriptClass.String() != "pubkeyhash" {
-			return "", errors.New("Counterparty_SignRawTransaction() currently only supports pubkeyhash script signing. However, the script type in the TX to sign was: " + scriptClass.String())
-		}
-
-		//		log.Printf("TxIn[%d] Addresses: %s\n", i, addresses)
-		//		log.Printf("TxIn[%d] Required Signatures: %d\n", i, reqSigs)
-
-		// Build txIn for new redeeming transaction
-		prevOut := wire.NewOutPoint(&msgTx.TxIn[i].PreviousOutPoint.Hash, msgTx.TxIn[i].PreviousOutPoint.Index)
-		txIn := wire.NewTxIn(prevOut, nil)
-		redeemTx.AddTxIn(txIn)
-	}
-
-	// Copy TxOuts from serialised tx
-	for _, txOut := range msgTx.TxOut {
-		out := txOut
-		redeemTx.AddTxOut(out)
-	}
-
-	// Callback to look up the signing key
-	lookupKey := func(a btcutil.Address) (*btcec.PrivateKey, bool, error) {
-		address := a.String()
-
-		//		log.Printf("Looking up the private key for: %s\n", address)
-		privateKeyString, err := counterpartycrypto.GetPrivateKey(passphrase, address)
-		if err != nil {
-			log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPrivateKey(): %s", err.Error())
-			return nil, false, nil
-		}
-		//		log.Printf("Private key retrieved!\n")
-
-		privateKeyBytes, err := hex.DecodeString(privateKeyString)
-		if err != nil {
-			log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
-			return nil, false, nil
-		}
-
-		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), privateKeyBytes)
-
-		return privKey, true, nil
-	}
-
-	// Range over TxIns and sign
-	for i, _ := range redeemTx.TxIn {
-		// Get the sigscript
-		// Notice that the script database parameter is nil here since it isn't
-		// used.  It must be specified when pay-to-script-hash transactions are
-		// being signed.
-		sigScript, err := txscript.SignTxOutput(&chaincfg.MainNetParams, redeemTx, i, msgTx.TxIn[i].SignatureScript, txscript.SigHashAll, txscript.KeyClosure(lookupKey), nil, nil)
-
-		if err != nil {
-			return "", err
-		}
-
-		// Copy the signed sigscript into the redeeming tx
-		redeemTx.TxIn[i].SignatureScript = sigScript
-		//		log.Println(hex.EncodeToString(sigScript))
+	if complete == false {
+		log.FluentfContext(consts.LOGERROR, c, "Transaction is only partially signed")
+		return "", errors.New("Transaction is only partially signed. Additional signatures are required for the multisig inputs.")
 	}
 
-	// Prove that the transaction has been validly signed by executing the
-	// script pair.
-	//	log.Println("Checking signature(s)")
-	flags := txscript.ScriptBip16 | txscript.ScriptVerifyDERSignatures | txscript.ScriptStrictMultiSig | txscript.ScriptDiscourageUpgradableNops | txscript.ScriptVerifyLowS | txscript.ScriptVerifyCleanStack | txscript.ScriptVerifyMinimalData | txscript.ScriptVerifySigPushOnly | txscript.ScriptVerifyStrictEncoding
-	var buildError string
-	for i, _ := range redeemTx.TxIn {
-		vm, err := txscript.NewEngine(msgTx.TxIn[i].SignatureScript, redeemTx, i, flags)
-		if err != nil {
-			buildError += "NewEngine() error: " + err.Error() + ","
-		}
-
-		if err := vm.Execute(); err != nil {
-			buildError += "TxIn[" + strconv.Itoa(i) + "]: " + err.Error() + ", "
-		} else {
-			// Signature verified
-			//			log.Printf("TxIn[%d] ok!\n", i)
-		}
-	}
-	if len(buildError) > 0 {
-		return "", errors.New(buildError)
-	}
-	//	log.Println("Transaction successfully signed")
-
-	// Encode the struct into BTC bytes wire format
-	var byteBuffer bytes.Buffer
-	encodeError := redeemTx.BtcEncode(&byteBuffer, wire.ProtocolVersion)
-	if encodeError != nil {
-		return "", err
-	}
-
-	// Encode bytes to hex string
-	payloadBytes := byteBuffer.Bytes()
-	payloadHexString := hex.EncodeToString(payloadBytes)
-	//	log.Printf("Signed and encoded transaction: %s\n", payloadHexString)
-
-	return payloadHexString, nil
+	return signed, nil
 }
 
 // Reproduces counterwallet function to generate a random asset name
@@ -1161,6 +1149,11 @@ func generateRandomAssetName(c context.Context) (string, error) {
 // Generates unsigned hex encoded transaction to issue an asset on Counterparty
 // This function MUST NOT be accessed by the client directly. The high level function Counterparty_CreateIssuanceAndSend() should be used instead.
 func createIssuance(c context.Context, sourceAddress string, asset string, description string, quantity uint64, divisible bool, pubKeyHexString string) (string, int64, error) {
+	return createIssuanceWithTransfer(c, sourceAddress, asset, description, quantity, divisible, "", pubKeyHexString)
+}
+
+// Issuances with a transfer destination transfer ownership of the asset to the destination
+func createIssuanceWithTransfer(c context.Context, sourceAddress string, asset string, description string, quantity uint64, divisible bool, transferDestination string, pubKeyHexString string) (string, int64, error) {
 	var payload payloadCreateIssuance_Counterparty
 	var result string
 
@@ -1168,6 +1161,10 @@ func createIssuance(c context.Context, sourceAddress string, asset string, descr
 		Init()
 	}
 
+	if counterpartyComposer == ComposerLocal {
+		return composeIssuance(c, sourceAddress, asset, description, quantity, divisible, transferDestination, pubKeyHexString)
+	}
+
 	payload.Method = "create_issuance"
 	payload.Jsonrpc = "2.0"
 	payload.Id = generateId(c)
@@ -1175,6 +1172,8 @@ func createIssuance(c context.Context, sourceAddress string, asset string, descr
 	payload.Params.Asset = asset
 	payload.Params.Description = description
 	payload.Params.Quantity = quantity
+	payload.Params.Divisible = divisible
+	payload.Params.TransferDestination = transferDestination
 	payload.Params.AllowUnconfirmedInputs = "true"
 	payload.Params.Encoding = counterpartyTransactionEncoding
 	payload.Params.PubKey = pubKeyHexString
@@ -1191,6 +1190,12 @@ func createIssuance(c context.Context, sourceAddress string, asset string, descr
 	// Post the request to counterpartyd
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
+		// Counterparty is reparsing or timed out, compose the transaction ourselves
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing issuance locally")
+			return composeIssuance(c, sourceAddress, asset, description, quantity, divisible, transferDestination, pubKeyHexString)
+		}
+
 		return "", errorCode, err
 	}
 
@@ -1199,6 +1204,18 @@ func createIssuance(c context.Context, sourceAddress string, asset string, descr
 		result = responseData["result"].(string)
 	}
 
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeIssuance(asset, quantity, divisible, description)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeIssuance(): %s", err.Error())
+			return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
 	return result, 0, nil
 }
 
@@ -1306,6 +1323,67 @@ func CreateIssuance(c context.Context, sourceAddress string, asset string, asset
 	return result, 0, nil
 }
 
+// The current state of an asset derived from its valid issuances
+type AssetState struct {
+	Asset       string `json:"asset"`
+	Issuer      string `json:"issuer"`
+	Description string `json:"description"`
+	Quantity    uint64 `json:"quantity"`
+	Divisible   bool   `json:"divisible"`
+	Locked      bool   `json:"locked"`
+}
+
+// Returns the owner, description, divisibility and lock status of an asset
+func GetAssetState(c context.Context, asset string) (AssetState, int64, error) {
+	var result AssetState
+
+	issuances, errorCode, err := GetIssuances(c, asset)
+	if err != nil {
+		return result, errorCode, err
+	}
+
+	if len(issuances) == 0 {
+		return result, consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+	}
+
+	// Issuances are ordered by tx_index so the latest issuance holds the current owner and description
+	result.Asset = asset
+	result.Divisible = issuances[0].Divisible == 1
+	for _, issuance := range issuances {
+		result.Issuer = issuance.Issuer
+		result.Description = issuance.Description
+		result.Quantity += issuance.Quantity
+
+		if issuance.Locked == 1 {
+			result.Locked = true
+		}
+	}
+
+	return result, 0, nil
+}
+
+// Generates unsigned hex encoded transaction to lock the supply of an asset so no further units can be issued
+func CreateLock(c context.Context, sourceAddress string, asset string, divisible bool, pubKeyHexString string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	return createIssuance(c, sourceAddress, asset, Counterparty_LockDescription, 0, divisible, pubKeyHexString)
+}
+
+// Generates unsigned hex encoded transaction to transfer ownership of an asset to the destination address
+func CreateTransfer(c context.Context, sourceAddress string, asset string, description string, divisible bool, destinationAddress string, pubKeyHexString string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	if len(description) > 52 {
+		description = description[0:51]
+	}
+
+	return createIssuanceWithTransfer(c, sourceAddress, asset, description, 0, divisible, destinationAddress, pubKeyHexString)
+}
+
 // Generates unsigned hex encoded transaction to pay a dividend on an asset on Counterparty
 func CreateDividend(c context.Context, sourceAddress string, asset string, dividendAsset string, quantityPerUnit uint64, pubKeyHexString string) (string, int64, error) {
 	var payload payloadCreateDividend_Counterparty
@@ -1315,6 +1393,10 @@ func CreateDividend(c context.Context, sourceAddress string, asset string, divid
 		Init()
 	}
 
+	if counterpartyComposer == ComposerLocal {
+		return ComposeDividend(c, sourceAddress, asset, dividendAsset, quantityPerUnit, pubKeyHexString)
+	}
+
 	payload.Method = "create_dividend"
 	payload.Jsonrpc = "2.0"
 	payload.Id = generateId(c)
@@ -1337,6 +1419,12 @@ func CreateDividend(c context.Context, sourceAddress string, asset string, divid
 	// Post the request to counterpartyd
 	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
 	if err != nil {
+		// Counterparty is reparsing or timed out, compose the transaction ourselves
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing dividend locally")
+			return ComposeDividend(c, sourceAddress, asset, dividendAsset, quantityPerUnit, pubKeyHexString)
+		}
+
 		return "", errorCode, err
 	}
 
@@ -1345,6 +1433,18 @@ func CreateDividend(c context.Context, sourceAddress string, asset string, divid
 		result = responseData["result"].(string)
 	}
 
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeDividend(quantityPerUnit, asset, dividendAsset)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeDividend(): %s", err.Error())
+			return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
 	return result, 0, nil
 }
 
@@ -1376,7 +1476,13 @@ func GetRunningInfo(c context.Context) (RunningInfo, int64, error) {
 	// Get result from api and create the reply
 	if responseData["result"] != nil {
 		resultMap := responseData["result"].(map[string]interface{})
-		lastBlockMap := resultMap["last_block"].(map[string]interface{})
+
+		// last_block is null until counterpartyd has parsed a block
+		lastBlockMap, ok := resultMap["last_block"].(map[string]interface{})
+		if ok == false {
+			return RunningInfo{DbCaughtUp: false}, consts.CounterpartyErrors.ReparsingOrUnavailable.Code, errors.New(consts.CounterpartyErrors.ReparsingOrUnavailable.Description)
+		}
+
 		//		log.Printf("%#v\n", resultMap)
 		//		log.Printf("%#v\n", lastBlockMap)
 		result = RunningInfo{
@@ -1,15 +1,22 @@
 package counterpartyapi
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"log"
 	"reflect"
 	"testing"
 
+	"github.com/whoisjeremylam/enu/bitcoinapi"
 	"github.com/whoisjeremylam/enu/consts"
 	"github.com/whoisjeremylam/enu/counterpartycrypto"
 	"github.com/whoisjeremylam/enu/enulib"
 
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 )
 
@@ -262,6 +269,105 @@ func TestSignRawTransaction(t *testing.T) {
 	}
 }
 
+// Builds an unsigned transaction in the same form as counterpartyd, ie with the script of the output being spent in the signature script
+func createUnsignedMultisigTx(t *testing.T, pkScript []byte) string {
+	prevHash, _ := wire.NewShaHashFromStr("41b7c8f810f2b0292f32092500829c785a9c41795a91b31171353c79b46bbe09")
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, 0), pkScript))
+
+	destination, _ := btcutil.DecodeAddress(destinationAddress, &chaincfg.MainNetParams)
+	destinationScript, _ := txscript.PayToAddrScript(destination)
+	tx.AddTxOut(wire.NewTxOut(5430, destinationScript))
+
+	var byteBuffer bytes.Buffer
+	if err := tx.BtcEncode(&byteBuffer, wire.ProtocolVersion); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return hex.EncodeToString(byteBuffer.Bytes())
+}
+
+func TestSignRawTransactionMultisig(t *testing.T) {
+	setContext()
+
+	// Two co-signers each with their own passphrase
+	var passphrases []string
+	var publicKeys []string
+	for i := 0; i < 2; i++ {
+		wallet, err := counterpartycrypto.CreateWallet(1)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		publicKey, err := counterpartycrypto.GetPublicKey(wallet.Passphrase, wallet.Addresses[0])
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		passphrases = append(passphrases, wallet.Passphrase)
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	// Bare 1 of 2 multisig can be fully signed by either co-signer
+	var addressPubKeys []*btcutil.AddressPubKey
+	for _, publicKey := range publicKeys {
+		publicKeyBytes, _ := hex.DecodeString(publicKey)
+		addressPubKey, _ := btcutil.NewAddressPubKey(publicKeyBytes, &chaincfg.MainNetParams)
+		addressPubKeys = append(addressPubKeys, addressPubKey)
+	}
+	bareScript, _ := txscript.MultiSigScript(addressPubKeys, 1)
+	unsignedBareTx := createUnsignedMultisigTx(t, bareScript)
+
+	if _, err := SignRawTransaction(c, passphrases[1], unsignedBareTx); err != nil {
+		t.Errorf("Bare multisig expected to be fully signed, got: %s\n", err.Error())
+	}
+
+	// P2SH 2 of 2 multisig
+	address, redeemScript, err := CreateMultisigAddress(c, 2, publicKeys)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if address[0] != '3' {
+		t.Errorf("Expected a P2SH address, got: %s\n", address)
+	}
+
+	p2shAddress, _ := btcutil.DecodeAddress(address, &chaincfg.MainNetParams)
+	p2shScript, _ := txscript.PayToAddrScript(p2shAddress)
+	unsignedTx := createUnsignedMultisigTx(t, p2shScript)
+
+	if _, err := SignRawTransaction(c, passphrases[0], unsignedTx); err == nil {
+		t.Errorf("Expected an error when signing a 2 of 2 multisig input with one passphrase\n")
+	}
+
+	// Sign in turn, the second co-signer derives the redeem script from the partially signed tx
+	partialTx, complete, err := SignRawTransactionPartial(c, passphrases[0], unsignedTx, "", []string{redeemScript})
+	if err != nil || complete == true {
+		t.Errorf("Expected a partially signed tx, got complete: %t, error: %v\n", complete, err)
+	}
+
+	_, complete, err = SignRawTransactionPartial(c, passphrases[1], unsignedTx, partialTx, nil)
+	if err != nil || complete == false {
+		t.Errorf("Expected a fully signed tx, got complete: %t, error: %v\n", complete, err)
+	}
+
+	// Sign independently and combine
+	otherPartialTx, _, err := SignRawTransactionPartial(c, passphrases[1], unsignedTx, "", []string{redeemScript})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, complete, err = CombineRawTransactions(c, unsignedTx, []string{partialTx, otherPartialTx}, nil)
+	if err != nil || complete == false {
+		t.Errorf("Expected the combined tx to be fully signed, got complete: %t, error: %v\n", complete, err)
+	}
+
+	_, complete, err = CombineRawTransactions(c, unsignedTx, []string{partialTx, partialTx}, nil)
+	if err != nil || complete == true {
+		t.Errorf("Expected the combined tx to be partially signed, got complete: %t, error: %v\n", complete, err)
+	}
+}
+
 func TestSendRawTransaction(t *testing.T) {
 	Init()
 	setContext()
@@ -460,3 +566,364 @@ func TestCalculateNumberOfTransactions(t *testing.T) {
 		}
 	}
 }
+
+// Unsigned sends of SHIMA composed by counterpartyd (from TestSignRawTransaction)
+var unsignedSendTx string = "010000000241b2f1a5acdf198dbb6d1f79c1f64b9bc75589ef0449f8cc6219e63af24de4c7000000001976a9148092503d3303106c4844c639db0f60298c573f7488acffffffff09be6bb4793c357111b3915a79419c5a789c82002509322f29b0f210f8c8b741000000001976a9148092503d3303106c4844c639db0f60298c573f7488acffffffff0336150000000000001976a914b889eba98a2026448b6acab4a71a1d22590ddd5888ac00000000000000001e6a1c7b4ca6c1f0494d0c3130c48853dfdde4d7c5bc46552b723f7a9500a4107a0700000000001976a9148092503d3303106c4844c639db0f60298c573f7488ac00000000"
+
+func TestParseTransaction(t *testing.T) {
+	var testData = []struct {
+		UnsignedTx          string
+		ExpectedSource      string
+		ExpectedDestination string
+		ExpectedSend        SendMessage
+		CaseDescription     string
+	}{
+		{unsignedSendTx, sendAddress, destinationAddress, SendMessage{"SHIMA", 1000}, "OP_RETURN send with 2 txins"},
+		{"010000000109be6bb4793c357111b3915a79419c5a789c82002509322f29b0f210f8c8b741010000001976a914b676d3212ba3532d234b1b09f21c83d437b9507088acffffffff0336150000000000001976a914b889eba98a2026448b6acab4a71a1d22590ddd5888ac00000000000000001e6a1ce940a04c56a5340496081336e4b77e9a9ee153672a7a49a86681a953ba1f4400000000001976a914b676d3212ba3532d234b1b09f21c83d437b9507088ac00000000", "1HdnKzzCKFzNEJbmYoa3RcY4MhKPP3NB7p", destinationAddress, SendMessage{"SHIMA", 2000}, "OP_RETURN send with 1 txin"},
+	}
+
+	for _, s := range testData {
+		tx, err := ParseTransaction(s.UnsignedTx)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		send, err := DecodeSend(tx.Message)
+		if err != nil || tx.MessageTypeId != Counterparty_SendId || tx.Sources[0] != s.ExpectedSource || tx.Destination != s.ExpectedDestination || send != s.ExpectedSend {
+			t.Errorf("Expected: %s -> %s %+v, Got: %+v %+v\nCase: %s\n", s.ExpectedSource, s.ExpectedDestination, s.ExpectedSend, tx, send, s.CaseDescription)
+		}
+	}
+}
+
+func TestVerifySend(t *testing.T) {
+	var testData = []struct {
+		InputTotal        uint64
+		Source            string
+		Destination       string
+		Asset             string
+		Quantity          uint64
+		ExpectedErrorCode int64
+		CaseDescription   string
+	}{
+		{505430, sendAddress, destinationAddress, "SHIMA", 1000, 0, "Matches the request"},
+		{505430, sendAddress, "1Bd5wrFxHYRkk4UCFttcPNMYzqJnQKfXUE", "SHIMA", 1000, consts.CounterpartyErrors.VerificationFailed.Code, "Different destination"},
+		{505430, sendAddress, destinationAddress, "XCP", 1000, consts.CounterpartyErrors.VerificationFailed.Code, "Different asset"},
+		{505430, sendAddress, destinationAddress, "SHIMA", 100, consts.CounterpartyErrors.VerificationFailed.Code, "Different quantity"},
+		{505430, "1HdnKzzCKFzNEJbmYoa3RcY4MhKPP3NB7p", destinationAddress, "SHIMA", 1000, consts.CounterpartyErrors.VerificationFailed.Code, "Different source"},
+		{905430, sendAddress, destinationAddress, "SHIMA", 1000, consts.CounterpartyErrors.VerificationFailed.Code, "Excessive fee"},
+	}
+
+	setContext()
+
+	tx, err := ParseTransaction(unsignedSendTx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, s := range testData {
+		errorCode, _ := verifySend(c, tx, s.InputTotal, s.Source, s.Destination, s.Asset, s.Quantity)
+
+		if errorCode != s.ExpectedErrorCode {
+			t.Errorf("Expected: errorCode=%d, Got: errorCode=%d\nCase: %s\n", s.ExpectedErrorCode, errorCode, s.CaseDescription)
+		}
+	}
+}
+
+func TestAssetId(t *testing.T) {
+	var testData = []struct {
+		Asset           string
+		ExpectedId      uint64
+		CaseDescription string
+	}{
+		{"BTC", 0, "BTC"},
+		{"XCP", 1, "XCP"},
+		{"SHIMA", 8354320, "Named asset"},
+		{"A95428956661682177", 95428956661682177, "Lowest numeric asset"},
+	}
+
+	for _, s := range testData {
+		id, err := AssetId(s.Asset)
+		if err != nil || id != s.ExpectedId {
+			t.Errorf("Expected: %d, Got: %d\nCase: %s\n", s.ExpectedId, id, s.CaseDescription)
+		}
+
+		name, err := AssetName(s.ExpectedId)
+		if err != nil || name != s.Asset {
+			t.Errorf("Expected: %s, Got: %s\nCase: %s\n", s.Asset, name, s.CaseDescription)
+		}
+	}
+
+	if _, err := AssetId("ABC"); err == nil {
+		t.Errorf("Expected an error for an asset name which is too short\n")
+	}
+}
+
+func TestDecodeDexMessages(t *testing.T) {
+	var testData = []struct {
+		Order           OrderMessage
+		OfferHash       string
+		OrderMatchId    string
+		CaseDescription string
+	}{
+		{OrderMessage{"XCP", 100000000, "BTC", 2000000, 1000, 0}, "b741c8f810f2b0292f32092500829c785a9c41795a91b31171353c79b46bbe09", "b741c8f810f2b0292f32092500829c785a9c41795a91b31171353c79b46bbe09_44bf1f53a98166a8497a2a6753e19e9e7a9eb7e4361308960434a5564ca040e9", "Order match id with an underscore"},
+		{OrderMessage{"SHIMA", 1000, "XCP", 500, 8064, 0}, "44bf1f53a98166a8497a2a6753e19e9e7a9eb7e4361308960434a5564ca040e9", "44bf1f53a98166a8497a2a6753e19e9e7a9eb7e4361308960434a5564ca040e9b741c8f810f2b0292f32092500829c785a9c41795a91b31171353c79b46bbe09", "Order match id without an underscore"},
+	}
+
+	for _, s := range testData {
+		message, err := EncodeOrder(s.Order.GiveAsset, s.Order.GiveQuantity, s.Order.GetAsset, s.Order.GetQuantity, s.Order.Expiration, s.Order.FeeRequired)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		order, err := DecodeOrder(message[4:])
+		if err != nil || order != s.Order {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Order, order, s.CaseDescription)
+		}
+
+		message, err = EncodeCancel(s.OfferHash)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		cancel, err := DecodeCancel(message[4:])
+		if err != nil || cancel.OfferHash != s.OfferHash {
+			t.Errorf("Expected: %s, Got: %s\nCase: %s\n", s.OfferHash, cancel.OfferHash, s.CaseDescription)
+		}
+
+		message, err = EncodeBtcPay(s.OrderMatchId)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		btcPay, err := DecodeBtcPay(message[4:])
+		if err != nil || btcPay.Tx0Hash+"_"+btcPay.Tx1Hash != s.OrderMatchId && btcPay.Tx0Hash+btcPay.Tx1Hash != s.OrderMatchId {
+			t.Errorf("Expected: %s, Got: %+v\nCase: %s\n", s.OrderMatchId, btcPay, s.CaseDescription)
+		}
+	}
+
+	if _, err := EncodeCancel("b741c8f8"); err == nil {
+		t.Errorf("Expected an error for an offer hash which is too short\n")
+	}
+}
+
+func TestDecodeBroadcast(t *testing.T) {
+	var testData = []struct {
+		Broadcast       BroadcastMessage
+		CaseDescription string
+	}{
+		{BroadcastMessage{1445000000, 245.5, 500000, "BTC/USD"}, "Price feed with a short text"},
+		{BroadcastMessage{1445000000, 0, 0, ""}, "Empty text"},
+		{BroadcastMessage{1445000000, -1, 0, "An announcement which is long enough that it is not stored as a pascal string"}, "Long text"},
+	}
+
+	for _, s := range testData {
+		message, err := EncodeBroadcast(s.Broadcast.Timestamp, s.Broadcast.Value, s.Broadcast.FeeFractionInt, s.Broadcast.Text)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		broadcast, err := DecodeBroadcast(message[4:])
+		if err != nil || broadcast != s.Broadcast {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Broadcast, broadcast, s.CaseDescription)
+		}
+	}
+
+	if FeeFractionInt(0.005) != 500000 {
+		t.Errorf("Expected: 500000, Got: %d\n", FeeFractionInt(0.005))
+	}
+}
+
+func TestVerifyOutputs(t *testing.T) {
+	// The public key of the generator point, which doesn't belong to the source address
+	otherPubKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+
+	setContext()
+
+	pubKeyHexString, err := counterpartycrypto.GetPublicKey(passphrase, sendAddress)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	sourcePubKey, _ := hex.DecodeString(pubKeyHexString)
+
+	var testData = []struct {
+		Sources           []string
+		DataOutputs       []DataOutput
+		ExpectedErrorCode int64
+		CaseDescription   string
+	}{
+		{[]string{sendAddress}, []DataOutput{{0, nil}}, 0, "OP_RETURN data output"},
+		{[]string{sendAddress, sendAddress}, []DataOutput{{Counterparty_DefaultMultisigDustSize, sourcePubKey}, {Counterparty_DefaultMultisigDustSize, sourcePubKey}}, 0, "Multisig data outputs redeemable by the source"},
+		{[]string{sendAddress}, []DataOutput{{5000, nil}}, consts.CounterpartyErrors.VerificationFailed.Code, "BTC burned in OP_RETURN data output"},
+		{[]string{sendAddress}, []DataOutput{{Counterparty_DefaultMultisigDustSize + 1, sourcePubKey}}, consts.CounterpartyErrors.VerificationFailed.Code, "Multisig data output above dust"},
+		{[]string{sendAddress}, []DataOutput{{Counterparty_DefaultMultisigDustSize, otherPubKey}}, consts.CounterpartyErrors.VerificationFailed.Code, "Multisig data output redeemable by another key"},
+		{[]string{sendAddress}, []DataOutput{{Counterparty_DefaultMultisigDustSize, []byte{2, 1}}}, consts.CounterpartyErrors.VerificationFailed.Code, "Multisig data output with an invalid public key"},
+		{[]string{sendAddress, ""}, []DataOutput{{0, nil}}, consts.CounterpartyErrors.VerificationFailed.Code, "Input not resolved to an address"},
+		{[]string{sendAddress, destinationAddress}, []DataOutput{{0, nil}}, consts.CounterpartyErrors.VerificationFailed.Code, "Input spent from another address"},
+		{[]string{}, []DataOutput{{0, nil}}, consts.CounterpartyErrors.VerificationFailed.Code, "No inputs"},
+	}
+
+	for _, s := range testData {
+		tx := CounterpartyTransaction{
+			Sources:     s.Sources,
+			Destination: destinationAddress,
+			BtcAmount:   Counterparty_DefaultDustSize,
+			DataOutputs: s.DataOutputs,
+			Change:      []TxOutput{{sendAddress, 100000}},
+			OutputTotal: Counterparty_DefaultDustSize + 100000,
+		}
+		for _, dataOutput := range s.DataOutputs {
+			tx.DataValue += dataOutput.Value
+			tx.OutputTotal += dataOutput.Value
+		}
+
+		errorCode, _ := verifyOutputs(c, tx, tx.OutputTotal+Counterparty_DefaultTxFee, sendAddress, destinationAddress)
+
+		if errorCode != s.ExpectedErrorCode {
+			t.Errorf("Expected: errorCode=%d, Got: errorCode=%d\nCase: %s\n", s.ExpectedErrorCode, errorCode, s.CaseDescription)
+		}
+	}
+}
+
+func TestComposeDataOutputs(t *testing.T) {
+	var testData = []struct {
+		UseOpReturn     bool
+		Description     string
+		CaseDescription string
+	}{
+		{true, "Short description", "Issuance in OP_RETURN"},
+		{false, "Short description", "Issuance in a single multisig output"},
+		{false, "A description which is long enough that the issuance needs to be split across more than one multisig output", "Issuance in multiple multisig outputs"},
+	}
+
+	setContext()
+
+	pubKey, err := counterpartycrypto.GetPublicKey(passphrase, sendAddress)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Locally encoded message matches the message composed by counterpartyd
+	message, _ := EncodeSend("SHIMA", 1000)
+	if errorCode, err := crossCheckMessage(c, unsignedSendTx, message); err != nil {
+		t.Errorf("Expected cross-check to pass, got errorCode=%d\n", errorCode)
+	}
+
+	for _, s := range testData {
+		message, err := EncodeIssuance("SHIMA", 1000, true, s.Description)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		tx := wire.NewMsgTx()
+		prevHash, _ := wire.NewShaHashFromStr("41b7c8f810f2b0292f32092500829c785a9c41795a91b31171353c79b46bbe09")
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, 0), nil))
+
+		key, _ := arc4Key(tx)
+		var scripts [][]byte
+		if s.UseOpReturn {
+			scripts, err = opReturnDataScripts(key, message)
+		} else {
+			scripts, err = multisigDataScripts(key, message, pubKey)
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		for _, script := range scripts {
+			tx.AddTxOut(wire.NewTxOut(0, script))
+		}
+
+		parsed, err := parseMsgTx(tx)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		issuance, err := DecodeIssuance(parsed.Message)
+		if err != nil || issuance.Asset != "SHIMA" || issuance.Quantity != 1000 || issuance.Divisible != true || issuance.Description != s.Description {
+			t.Errorf("Expected: SHIMA 1000 %s, Got: %+v\nCase: %s\n", s.Description, issuance, s.CaseDescription)
+		}
+	}
+}
+
+func TestSelectCoins(t *testing.T) {
+	var unspent = []bitcoinapi.Unspent{{TxId: "a", Amount: 10000}, {TxId: "b", Amount: 50000}, {TxId: "c", Amount: 20000}}
+
+	var testData = []struct {
+		Amount          uint64
+		ExpectedInputs  int
+		ExpectedChange  uint64
+		ExpectedOk      bool
+		CaseDescription string
+	}{
+		{40000, 1, 10000, true, "Largest output covers the amount"},
+		{50000, 1, 0, true, "Exact amount, no change"},
+		{49800, 2, 20200, true, "Change would be dust so another output is selected"},
+		{75000, 3, 5000, true, "All outputs"},
+		{90000, 0, 0, false, "Insufficient BTC"},
+	}
+
+	for _, s := range testData {
+		inputs, change, ok := selectCoins(unspent, s.Amount)
+
+		if len(inputs) != s.ExpectedInputs || change != s.ExpectedChange || ok != s.ExpectedOk {
+			t.Errorf("Expected: %d inputs %d change %t, Got: %d inputs %d change %t\nCase: %s\n", s.ExpectedInputs, s.ExpectedChange, s.ExpectedOk, len(inputs), change, ok, s.CaseDescription)
+		}
+	}
+}
+
+func TestSumBalances(t *testing.T) {
+	var testData = []struct {
+		Credits         []Credit
+		Debits          []Debit
+		Expected        []Balance
+		CaseDescription string
+	}{
+		{nil, nil, nil, "No credits or debits"},
+		{[]Credit{{Address: "a", Quantity: 100}}, nil, []Balance{{Address: "a", Asset: "TEST", Quantity: 100}}, "Issued to a single holder"},
+		{[]Credit{{Address: "a", Quantity: 100}, {Address: "b", Quantity: 40}}, []Debit{{Address: "a", Quantity: 40}}, []Balance{{Address: "a", Asset: "TEST", Quantity: 60}, {Address: "b", Asset: "TEST", Quantity: 40}}, "Send between holders, largest holding first"},
+		{[]Credit{{Address: "a", Quantity: 100}, {Address: "c", Quantity: 50}, {Address: "b", Quantity: 50}}, []Debit{{Address: "a", Quantity: 100}}, []Balance{{Address: "b", Asset: "TEST", Quantity: 50}, {Address: "c", Asset: "TEST", Quantity: 50}}, "Holders who sold out are omitted and equal holdings are ordered by address"},
+	}
+
+	for _, s := range testData {
+		result := sumBalances("TEST", s.Credits, s.Debits)
+
+		if reflect.DeepEqual(result, s.Expected) == false {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestTableQuery(t *testing.T) {
+	var testData = []struct {
+		Method          string
+		OrderBy         string
+		Filters         filters
+		Limit           int
+		Offset          int
+		ExpectedQuery   string
+		ExpectedArgs    []interface{}
+		ExpectedError   bool
+		CaseDescription string
+	}{
+		{"get_dividends", "tx_index", filters{{Field: "asset", Op: "==", Value: "TEST"}, {Field: "status", Op: "==", Value: "valid"}}, 0, 0, "select * from dividends where asset = ? and status = ? order by tx_index asc, rowid asc", []interface{}{"TEST", "valid"}, false, "Every row matching the filters"},
+		{"get_credits", "block_index", filters{{Field: "asset", Op: "==", Value: "TEST"}, {Field: "block_index", Op: "<=", Value: "400000"}}, 1000, 2000, "select * from credits where asset = ? and block_index <= ? order by block_index asc, rowid asc limit ? offset ?", []interface{}{"TEST", "400000", 1000, 2000}, false, "Page of rows"},
+		{"get_orders", "tx_index", nil, 0, 0, "select * from orders order by tx_index asc, rowid asc", nil, false, "No filters"},
+		{"get_orders", "tx_index", filters{{Field: "source", Op: "IN", Value: "a"}}, 0, 0, "", nil, true, "Unsupported operator"},
+		{"get_orders", "tx_index", filters{{Field: "source; drop table orders", Op: "==", Value: "a"}}, 0, 0, "", nil, true, "Invalid field"},
+		{"get_orders; drop table orders", "tx_index", nil, 0, 0, "", nil, true, "Invalid table"},
+	}
+
+	for _, s := range testData {
+		query, args, err := tableQuery(s.Method, s.OrderBy, s.Filters, s.Limit, s.Offset)
+
+		if (err != nil) != s.ExpectedError || query != s.ExpectedQuery || reflect.DeepEqual(args, s.ExpectedArgs) == false {
+			t.Errorf("Expected: %s %+v, Got: %s %+v %v\nCase: %s\n", s.ExpectedQuery, s.ExpectedArgs, query, args, err, s.CaseDescription)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+// Counterparty decentralized exchange
+// Orders are matched by counterpartyd. Order matches where one side gives BTC are pending until the BTC is paid with a BTCpay.
+
+package counterpartyapi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Number of blocks an order remains open if no expiration is given
+var Counterparty_DefaultOrderExpiration uint16 = 1000
+
+type payloadCreateOrder_Counterparty struct {
+	Method  string                                `json:"method"`
+	Params  payloadCreateOrderParams_Counterparty `json:"params"`
+	Jsonrpc string                                `json:"jsonrpc"`
+	Id      uint32                                `json:"id"`
+}
+
+type payloadCreateOrderParams_Counterparty struct {
+	Source                 string `json:"source"`
+	GiveAsset              string `json:"give_asset"`
+	GiveQuantity           uint64 `json:"give_quantity"`
+	GetAsset               string `json:"get_asset"`
+	GetQuantity            uint64 `json:"get_quantity"`
+	Expiration             uint16 `json:"expiration"`
+	FeeRequired            uint64 `json:"fee_required"`
+	AllowUnconfirmedInputs string `json:"allow_unconfirmed_inputs"`
+	Encoding               string `json:"encoding"`
+	PubKey                 string `json:"pubkey"`
+	Fee                    uint64 `json:"fee"`
+	DustSize               uint64 `json:"regular_dust_size"`
+}
+
+type payloadCreateCancel_Counterparty struct {
+	Method  string                                 `json:"method"`
+	Params  payloadCreateCancelParams_Counterparty `json:"params"`
+	Jsonrpc string                                 `json:"jsonrpc"`
+	Id      uint32                                 `json:"id"`
+}
+
+type payloadCreateCancelParams_Counterparty struct {
+	Source                 string `json:"source"`
+	OfferHash              string `json:"offer_hash"`
+	AllowUnconfirmedInputs string `json:"allow_unconfirmed_inputs"`
+	Encoding               string `json:"encoding"`
+	PubKey                 string `json:"pubkey"`
+	Fee                    uint64 `json:"fee"`
+	DustSize               uint64 `json:"regular_dust_size"`
+}
+
+type payloadCreateBtcPay_Counterparty struct {
+	Method  string                                 `json:"method"`
+	Params  payloadCreateBtcPayParams_Counterparty `json:"params"`
+	Jsonrpc string                                 `json:"jsonrpc"`
+	Id      uint32                                 `json:"id"`
+}
+
+type payloadCreateBtcPayParams_Counterparty struct {
+	Source                 string `json:"source"`
+	OrderMatchId           string `json:"order_match_id"`
+	AllowUnconfirmedInputs string `json:"allow_unconfirmed_inputs"`
+	Encoding               string `json:"encoding"`
+	PubKey                 string `json:"pubkey"`
+	Fee                    uint64 `json:"fee"`
+	DustSize               uint64 `json:"regular_dust_size"`
+}
+
+type payloadGetTable struct {
+	Method  string                `json:"method"`
+	Params  payloadGetTableParams `json:"params"`
+	Jsonrpc string                `json:"jsonrpc"`
+	Id      uint32                `json:"id"`
+}
+
+type payloadGetTableParams struct {
+	OrderBy  string  `json:"order_by"`
+	OrderDir string  `json:"order_dir"`
+	Filters  filters `json:"filters"`
+	FilterOp string  `json:"filterop"`
+	Limit    int     `json:"limit,omitempty"`
+	Offset   int     `json:"offset,omitempty"`
+}
+
+// An order as returned by get_orders
+type Order struct {
+	TxIndex              uint64 `json:"tx_index"`
+	TxHash               string `json:"tx_hash"`
+	BlockIndex           uint64 `json:"block_index"`
+	Source               string `json:"source"`
+	GiveAsset            string `json:"give_asset"`
+	GiveQuantity         uint64 `json:"give_quantity"`
+	GiveRemaining        int64  `json:"give_remaining"`
+	GetAsset             string `json:"get_asset"`
+	GetQuantity          uint64 `json:"get_quantity"`
+	GetRemaining         int64  `json:"get_remaining"`
+	Expiration           uint64 `json:"expiration"`
+	ExpireIndex          uint64 `json:"expire_index"`
+	FeeRequired          uint64 `json:"fee_required"`
+	FeeRequiredRemaining int64  `json:"fee_required_remaining"`
+	FeeProvided          uint64 `json:"fee_provided"`
+	FeeProvidedRemaining int64  `json:"fee_provided_remaining"`
+	Status               string `json:"status"`
+}
+
+// An order match as returned by get_order_matches. The forward asset is given by tx0 and the backward asset by tx1
+type OrderMatch struct {
+	Id               string `json:"id"`
+	Tx0Index         uint64 `json:"tx0_index"`
+	Tx0Hash          string `json:"tx0_hash"`
+	Tx0Address       string `json:"tx0_address"`
+	Tx1Index         uint64 `json:"tx1_index"`
+	Tx1Hash          string `json:"tx1_hash"`
+	Tx1Address       string `json:"tx1_address"`
+	ForwardAsset     string `json:"forward_asset"`
+	ForwardQuantity  uint64 `json:"forward_quantity"`
+	BackwardAsset    string `json:"backward_asset"`
+	BackwardQuantity uint64 `json:"backward_quantity"`
+	BlockIndex       uint64 `json:"block_index"`
+	MatchExpireIndex uint64 `json:"match_expire_index"`
+	Status           string `json:"status"`
+}
+
+// Generates unsigned hex encoded transaction to place an order on the Counterparty DEX
+func CreateOrder(c context.Context, sourceAddress string, giveAsset string, giveQuantity uint64, getAsset string, getQuantity uint64, expiration uint16, pubKeyHexString string) (string, int64, error) {
+	var payload payloadCreateOrder_Counterparty
+	var result string
+
+	if isInit == false {
+		Init()
+	}
+
+	if counterpartyComposer == ComposerLocal {
+		return ComposeOrder(c, sourceAddress, giveAsset, giveQuantity, getAsset, getQuantity, expiration, pubKeyHexString)
+	}
+
+	payload.Method = "create_order"
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+	payload.Params.Source = sourceAddress
+	payload.Params.GiveAsset = giveAsset
+	payload.Params.GiveQuantity = giveQuantity
+	payload.Params.GetAsset = getAsset
+	payload.Params.GetQuantity = getQuantity
+	payload.Params.Expiration = expiration
+	payload.Params.FeeRequired = 0
+	payload.Params.AllowUnconfirmedInputs = "true"
+	payload.Params.Encoding = counterpartyTransactionEncoding
+	payload.Params.PubKey = pubKeyHexString
+	payload.Params.Fee = Counterparty_DefaultTxFee
+	payload.Params.DustSize = Counterparty_DefaultDustSize
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		// Counterparty is reparsing or timed out, compose the transaction ourselves
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing order locally")
+			return ComposeOrder(c, sourceAddress, giveAsset, giveQuantity, getAsset, getQuantity, expiration, pubKeyHexString)
+		}
+
+		return "", errorCode, err
+	}
+
+	if responseData["result"] != nil {
+		result = responseData["result"].(string)
+	}
+
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeOrder(giveAsset, giveQuantity, getAsset, getQuantity, expiration, 0)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeOrder(): %s", err.Error())
+			return "", consts.CounterpartyErrors.NoSuchAsset.Code, errors.New(consts.CounterpartyErrors.NoSuchAsset.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
+	return result, 0, nil
+}
+
+// Generates unsigned hex encoded transaction to cancel an open order. The offer hash is the tx hash of the order
+func CreateCancel(c context.Context, sourceAddress string, offerHash string, pubKeyHexString string) (string, int64, error) {
+	var payload payloadCreateCancel_Counterparty
+	var result string
+
+	if isInit == false {
+		Init()
+	}
+
+	if counterpartyComposer == ComposerLocal {
+		return ComposeCancel(c, sourceAddress, offerHash, pubKeyHexString)
+	}
+
+	payload.Method = "create_cancel"
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+	payload.Params.Source = sourceAddress
+	payload.Params.OfferHash = offerHash
+	payload.Params.AllowUnconfirmedInputs = "true"
+	payload.Params.Encoding = counterpartyTransactionEncoding
+	payload.Params.PubKey = pubKeyHexString
+	payload.Params.Fee = Counterparty_DefaultTxFee
+	payload.Params.DustSize = Counterparty_DefaultDustSize
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing cancel locally")
+			return ComposeCancel(c, sourceAddress, offerHash, pubKeyHexString)
+		}
+
+		return "", errorCode, err
+	}
+
+	if responseData["result"] != nil {
+		result = responseData["result"].(string)
+	}
+
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeCancel(offerHash)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeCancel(): %s", err.Error())
+			return "", consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
+	return result, 0, nil
+}
+
+// Generates unsigned hex encoded transaction to pay the BTC owed by the source address for an order match.
+// The destination and BTC quantity are only used when composing locally, counterpartyd looks them up from the order match.
+func CreateBtcPay(c context.Context, sourceAddress string, orderMatchId string, destinationAddress string, btcQuantity uint64, pubKeyHexString string) (string, int64, error) {
+	var payload payloadCreateBtcPay_Counterparty
+	var result string
+
+	if isInit == false {
+		Init()
+	}
+
+	if counterpartyComposer == ComposerLocal {
+		return ComposeBtcPay(c, sourceAddress, orderMatchId, destinationAddress, btcQuantity, pubKeyHexString)
+	}
+
+	payload.Method = "create_btcpay"
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+	payload.Params.Source = sourceAddress
+	payload.Params.OrderMatchId = orderMatchId
+	payload.Params.AllowUnconfirmedInputs = "true"
+	payload.Params.Encoding = counterpartyTransactionEncoding
+	payload.Params.PubKey = pubKeyHexString
+	payload.Params.Fee = Counterparty_DefaultTxFee
+	payload.Params.DustSize = Counterparty_DefaultDustSize
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return "", consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		if composeLocally(errorCode) {
+			log.FluentfContext(consts.LOGINFO, c, "counterpartyd unavailable, composing BTCpay locally")
+			return ComposeBtcPay(c, sourceAddress, orderMatchId, destinationAddress, btcQuantity, pubKeyHexString)
+		}
+
+		return "", errorCode, err
+	}
+
+	if responseData["result"] != nil {
+		result = responseData["result"].(string)
+	}
+
+	if counterpartyComposer == ComposerFallback {
+		message, err := EncodeBtcPay(orderMatchId)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in EncodeBtcPay(): %s", err.Error())
+			return "", consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+		}
+
+		if errorCode, err := crossCheckMessage(c, result, message); err != nil {
+			return "", errorCode, err
+		}
+	}
+
+	return result, 0, nil
+}
+
+// Returns the order placed by the transaction
+func GetOrder(c context.Context, txHash string) (Order, int64, error) {
+	var result []Order
+
+	errorCode, err := getTable(c, "get_orders", "tx_index", filters{filter{Field: "tx_hash", Op: "==", Value: txHash}}, &result)
+	if err != nil {
+		return Order{}, errorCode, err
+	}
+
+	if len(result) == 0 {
+		return Order{}, consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+	}
+
+	return result[0], 0, nil
+}
+
+// Returns the open orders placed by the address
+func GetOpenOrdersByAddress(c context.Context, address string) ([]Order, int64, error) {
+	var result []Order
+
+	filterList := filters{filter{Field: "source", Op: "==", Value: address}, filter{Field: "status", Op: "==", Value: "open"}}
+	errorCode, err := getTable(c, "get_orders", "tx_index", filterList, &result)
+
+	return result, errorCode, err
+}
+
+// Returns the open orders giving the give asset in exchange for the get asset, ie one side of the order book
+func GetOpenOrdersByAssets(c context.Context, giveAsset string, getAsset string) ([]Order, int64, error) {
+	var result []Order
+
+	filterList := filters{filter{Field: "give_asset", Op: "==", Value: giveAsset}, filter{Field: "get_asset", Op: "==", Value: getAsset}, filter{Field: "status", Op: "==", Value: "open"}}
+	errorCode, err := getTable(c, "get_orders", "tx_index", filterList, &result)
+
+	return result, errorCode, err
+}
+
+// Returns the order matches of the order which are waiting for a BTCpay
+func GetPendingOrderMatches(c context.Context, orderTxHash string) ([]OrderMatch, int64, error) {
+	var result []OrderMatch
+	var tx0Matches []OrderMatch
+	var tx1Matches []OrderMatch
+
+	// The order may be on either side of the match
+	errorCode, err := getTable(c, "get_order_matches", "tx0_index", filters{filter{Field: "tx0_hash", Op: "==", Value: orderTxHash}, filter{Field: "status", Op: "==", Value: "pending"}}, &tx0Matches)
+	if err != nil {
+		return result, errorCode, err
+	}
+
+	errorCode, err = getTable(c, "get_order_matches", "tx0_index", filters{filter{Field: "tx1_hash", Op: "==", Value: orderTxHash}, filter{Field: "status", Op: "==", Value: "pending"}}, &tx1Matches)
+	if err != nil {
+		return result, errorCode, err
+	}
+
+	result = append(tx0Matches, tx1Matches...)
+
+	return result, 0, nil
+}
+
+// Returns the address which must pay BTC for the order match, the address being paid and the quantity of BTC
+func (o OrderMatch) BtcPayment() (string, string, uint64, bool) {
+	if o.ForwardAsset == "BTC" {
+		return o.Tx0Address, o.Tx1Address, o.ForwardQuantity, true
+	}
+
+	if o.BackwardAsset == "BTC" {
+		return o.Tx1Address, o.Tx0Address, o.BackwardQuantity, true
+	}
+
+	return "", "", 0, false
+}
+
+// Calls one of the counterpartyd get_{table} methods with the filters and decodes the rows into result
+func getTable(c context.Context, method string, orderBy string, filterList filters, result interface{}) (int64, error) {
+	return getTablePage(c, method, orderBy, filterList, 0, 0, result)
+}
+
+// As getTable() for the page of rows starting at the offset. A limit of 0 uses the default limit of counterpartyd.
+// The rows are read from the database of counterpartyd instead if counterpartyd isn't caught up or doesn't answer
+func getTablePage(c context.Context, method string, orderBy string, filterList filters, limit int, offset int, result interface{}) (int64, error) {
+	var payload payloadGetTable
+
+	if isInit == false {
+		Init()
+	}
+
+	if readFromDB(c) {
+		return getTablePageDB(c, method, orderBy, filterList, limit, offset, result)
+	}
+
+	payload.Method = method
+	payload.Params.OrderBy = orderBy
+	payload.Params.OrderDir = "asc"
+	payload.Params.Filters = filterList
+	payload.Params.FilterOp = "and"
+	payload.Params.Limit = limit
+	payload.Params.Offset = offset
+	payload.Jsonrpc = "2.0"
+	payload.Id = generateId(c)
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	responseData, errorCode, err := postAPI(c, payloadJsonBytes)
+	if err != nil {
+		// Counterparty DB is behind backend / reparsing or timed out, read directly from DB
+		if isFailover(errorCode) {
+			return getTablePageDB(c, method, orderBy, filterList, limit, offset, result)
+		}
+
+		return errorCode, err
+	}
+
+	// Round trip the rows through json rather than asserting each field
+	rows, err := json.Marshal(responseData["result"])
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	if err := json.Unmarshal(rows, result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Unmarshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,240 @@
+// Failover of reads from counterpartyd to its SQLite database
+// Reads are served from the database of counterpartyd when counterpartyd times out, is reparsing or unavailable (-32000 or -10000),
+// or reports its database isn't caught up with the blockchain. The database may be behind the blockchain, so requests which were
+// answered from it are marked as possibly stale.
+
+package counterpartyapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// How long the db_caught_up status of counterpartyd is remembered before it is checked again
+var Counterparty_CaughtUpCheckRate = 10000 // milliseconds
+
+var caughtUpMutex sync.Mutex
+var caughtUp = true
+var caughtUpChecked time.Time
+
+// Columns which the SQLite driver returns as "true" or "false" but counterpartyd returns as 1 or 0
+var boolColumns = map[string]bool{"divisible": true, "locked": true, "transfer": true, "callable": true}
+
+// Filter operators of counterpartyd and their SQL equivalents
+var filterOps = map[string]string{"==": "=", "!=": "!=", ">": ">", "<": "<", ">=": ">=", "<=": "<=", "LIKE": "like", "like": "like"}
+
+// Table and column names are only ever given by Enu, but are checked since they can't be bound as parameters
+var identifierRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type staleRead struct {
+	mutex sync.Mutex
+	stale bool
+}
+
+// Returns a context which records whether any read made with it was answered from the database of counterpartyd
+func TrackStaleReads(c context.Context) context.Context {
+	return context.WithValue(c, consts.StaleReadKey, &staleRead{})
+}
+
+// Returns true if a read made with the context was answered from the database of counterpartyd and so may be stale
+func IsStale(c context.Context) bool {
+	s, ok := c.Value(consts.StaleReadKey).(*staleRead)
+	if ok == false {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stale
+}
+
+func markStale(c context.Context) {
+	s, ok := c.Value(consts.StaleReadKey).(*staleRead)
+	if ok == false {
+		return
+	}
+
+	s.mutex.Lock()
+	s.stale = true
+	s.mutex.Unlock()
+}
+
+// Returns true if a read which failed with the error code should be answered from the database instead
+func isFailover(errorCode int64) bool {
+	return errorCode == consts.CounterpartyErrors.ReparsingOrUnavailable.Code || errorCode == consts.CounterpartyErrors.Timeout.Code
+}
+
+// Returns true if reads should go straight to the database because counterpartyd isn't caught up or couldn't be asked.
+// The status is cached so that every read doesn't need a call to get_running_info
+func readFromDB(c context.Context) bool {
+	caughtUpMutex.Lock()
+	if time.Since(caughtUpChecked) < time.Duration(Counterparty_CaughtUpCheckRate)*time.Millisecond {
+		defer caughtUpMutex.Unlock()
+		return caughtUp == false
+	}
+	caughtUpMutex.Unlock()
+
+	// The lock isn't held while counterpartyd is asked so that reads aren't held up if it times out
+	runningInfo, _, err := GetRunningInfo(c)
+	isCaughtUp := err == nil && runningInfo.DbCaughtUp
+
+	caughtUpMutex.Lock()
+	defer caughtUpMutex.Unlock()
+
+	if isCaughtUp == false && caughtUp == true {
+		log.FluentfContext(consts.LOGINFO, c, "counterpartyd isn't caught up or is unavailable, reads will be served from its database")
+	}
+
+	caughtUp = isCaughtUp
+	caughtUpChecked = time.Now()
+
+	return caughtUp == false
+}
+
+// sqlite drivers are not concurrency safe, so must create a connection each time
+func openCounterpartyDB(c context.Context) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", counterpartyDBLocation)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to open DB. Reason: %s", err.Error())
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to ping DB. Reason: %s", err.Error())
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Builds the query of the counterpartyd database equivalent to calling the get_ method with the filters. A limit of 0 returns every row
+func tableQuery(method string, orderBy string, filterList filters, limit int, offset int) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	table := strings.TrimPrefix(method, "get_")
+	if identifierRegexp.MatchString(table) == false || identifierRegexp.MatchString(orderBy) == false {
+		return "", nil, fmt.Errorf("Invalid table or order: %s %s", method, orderBy)
+	}
+
+	for _, f := range filterList {
+		op, ok := filterOps[f.Op]
+		if ok == false || identifierRegexp.MatchString(f.Field) == false {
+			return "", nil, fmt.Errorf("Invalid filter: %s %s", f.Field, f.Op)
+		}
+
+		conditions = append(conditions, f.Field+" "+op+" ?")
+		args = append(args, f.Value)
+	}
+
+	query := "select * from " + table
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	// The rowid breaks ties so that pages of rows ordered by a column which isn't unique, such as block_index, don't overlap
+	query += " order by " + orderBy + " asc, rowid asc"
+
+	if limit > 0 {
+		query += " limit ? offset ?"
+		args = append(args, limit, offset)
+	}
+
+	return query, args, nil
+}
+
+// As getTablePage() but reads the table directly from the database of counterpartyd
+func getTablePageDB(c context.Context, method string, orderBy string, filterList filters, limit int, offset int, result interface{}) (int64, error) {
+	var resultRows []map[string]interface{}
+
+	markStale(c)
+
+	query, args, err := tableQuery(method, orderBy, filterList, limit, offset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in tableQuery(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	db, err := openCounterpartyDB(c)
+	if err != nil {
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to get columns. Reason: %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+		}
+
+		// Give each row the same shape as counterpartyd returns
+		row := make(map[string]interface{})
+		for i, column := range columns {
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			if s, ok := value.(string); ok && boolColumns[column] {
+				value = 0
+				if s == "true" || s == "1" {
+					value = 1
+				}
+			}
+			if b, ok := value.(bool); ok && boolColumns[column] {
+				value = 0
+				if b {
+					value = 1
+				}
+			}
+
+			row[column] = value
+		}
+
+		resultRows = append(resultRows, row)
+	}
+
+	// Round trip the rows through json rather than asserting each field
+	rowsJson, err := json.Marshal(resultRows)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	if err := json.Unmarshal(rowsJson, result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Unmarshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,650 @@
+// Decoding of Counterparty messages embedded in bitcoin transactions
+// Counterparty data is obfuscated with ARC4 keyed on the txid of the first input and is embedded either in an
+// OP_RETURN output or in the fake public keys of 1-of-n multisig outputs.
+
+package counterpartyapi
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
+)
+
+// Prefix of all Counterparty messages
+const Counterparty_Prefix = "CNTRPRTY"
+
+// Counterparty message type ids
+const (
+	Counterparty_SendId      uint32 = 0
+	Counterparty_OrderId     uint32 = 10
+	Counterparty_BtcPayId    uint32 = 11
+	Counterparty_IssuanceId  uint32 = 20
+	Counterparty_BroadcastId uint32 = 30
+	Counterparty_DividendId  uint32 = 50
+	Counterparty_CancelId    uint32 = 70
+)
+
+const b26Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Lengths of the fixed portion of each message
+const sendMessageLength = 8 + 8
+const issuanceMessageLength = 8 + 8 + 1 + 1 + 4 + 4
+const dividendMessageLength = 8 + 8 + 8
+const orderMessageLength = 8 + 8 + 8 + 8 + 2 + 8
+const btcPayMessageLength = 32 + 32
+const cancelMessageLength = 32
+const broadcastMessageLength = 4 + 8 + 4
+
+// An output of a bitcoin transaction which pays to an address
+type TxOutput struct {
+	Address string `json:"address"`
+	Value   uint64 `json:"value"`
+}
+
+// An output of a bitcoin transaction which holds Counterparty data
+type DataOutput struct {
+	Value  uint64 `json:"value"`
+	PubKey []byte `json:"pubKey"` // The real public key of a multisig data output, nil for OP_RETURN
+}
+
+// A Counterparty transaction decoded from a raw bitcoin transaction
+type CounterpartyTransaction struct {
+	Sources       []string     `json:"sources"`       // Addresses of the outputs being spent, "" for an input which doesn't pay to an address. Only known for unsigned transactions composed by counterpartyd
+	Destination   string       `json:"destination"`   // Output before the data, if any
+	BtcAmount     uint64       `json:"btcAmount"`     // BTC paid to the destination
+	DataValue     uint64       `json:"dataValue"`     // BTC in the data outputs
+	DataOutputs   []DataOutput `json:"dataOutputs"`   // Outputs holding the data
+	Change        []TxOutput   `json:"change"`        // Outputs after the data
+	OutputTotal   uint64       `json:"outputTotal"`   // Sum of all outputs
+	MessageTypeId uint32       `json:"messageTypeId"` // Counterparty message type
+	Message       []byte       `json:"message"`       // Message without the prefix and message type id
+}
+
+type SendMessage struct {
+	Asset    string `json:"asset"`
+	Quantity uint64 `json:"quantity"`
+}
+
+type IssuanceMessage struct {
+	Asset       string  `json:"asset"`
+	Quantity    uint64  `json:"quantity"`
+	Divisible   bool    `json:"divisible"`
+	Callable    bool    `json:"callable"`
+	CallDate    uint32  `json:"callDate"`
+	CallPrice   float32 `json:"callPrice"`
+	Description string  `json:"description"`
+}
+
+type DividendMessage struct {
+	QuantityPerUnit uint64 `json:"quantityPerUnit"`
+	Asset           string `json:"asset"`
+	DividendAsset   string `json:"dividendAsset"`
+}
+
+type OrderMessage struct {
+	GiveAsset    string `json:"giveAsset"`
+	GiveQuantity uint64 `json:"giveQuantity"`
+	GetAsset     string `json:"getAsset"`
+	GetQuantity  uint64 `json:"getQuantity"`
+	Expiration   uint16 `json:"expiration"`
+	FeeRequired  uint64 `json:"feeRequired"`
+}
+
+type BtcPayMessage struct {
+	Tx0Hash string `json:"tx0Hash"`
+	Tx1Hash string `json:"tx1Hash"`
+}
+
+type CancelMessage struct {
+	OfferHash string `json:"offerHash"`
+}
+
+type BroadcastMessage struct {
+	Timestamp      uint32  `json:"timestamp"`
+	Value          float64 `json:"value"`
+	FeeFractionInt uint32  `json:"feeFractionInt"`
+	Text           string  `json:"text"`
+}
+
+// Returns the ARC4 key used to obfuscate the data in the transaction. This is the txid of the first input.
+func arc4Key(tx *wire.MsgTx) ([]byte, error) {
+	if len(tx.TxIn) == 0 {
+		return nil, errors.New("Transaction has no inputs")
+	}
+
+	return hex.DecodeString(tx.TxIn[0].PreviousOutPoint.Hash.String())
+}
+
+func arc4Crypt(key []byte, data []byte) ([]byte, error) {
+	cipher, err := rc4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, len(data))
+	cipher.XORKeyStream(result, data)
+
+	return result, nil
+}
+
+// Returns the Counterparty data held in the output, if any, and the real public key of a multisig data output
+func decodeDataOutput(key []byte, pkScript []byte) ([]byte, []byte, bool, error) {
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.NullDataTy:
+		pushes, err := txscript.PushedData(pkScript)
+		if err != nil || len(pushes) == 0 {
+			return nil, nil, false, err
+		}
+
+		data, err := arc4Crypt(key, pushes[0])
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		if bytes.HasPrefix(data, []byte(Counterparty_Prefix)) == false {
+			return nil, nil, false, nil
+		}
+
+		return data[len(Counterparty_Prefix):], nil, true, nil
+	case txscript.MultiSigTy:
+		pushes, err := txscript.PushedData(pkScript)
+		if err != nil || len(pushes) < 2 {
+			return nil, nil, false, err
+		}
+
+		// The last public key is the real public key of the source. The sign and nonce bytes of the others are skipped.
+		var chunk []byte
+		for _, pubKey := range pushes[:len(pushes)-1] {
+			if len(pubKey) < 2 {
+				return nil, nil, false, nil
+			}
+			chunk = append(chunk, pubKey[1:len(pubKey)-1]...)
+		}
+
+		chunk, err = arc4Crypt(key, chunk)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		if len(chunk) < len(Counterparty_Prefix)+1 || bytes.Equal(chunk[1:len(Counterparty_Prefix)+1], []byte(Counterparty_Prefix)) == false {
+			return nil, nil, false, nil
+		}
+
+		// First byte is the length of the prefix and data, the remainder is padding
+		chunkLength := int(chunk[0])
+		if chunkLength+1 > len(chunk) || chunkLength < len(Counterparty_Prefix) {
+			return nil, nil, false, errors.New("Invalid length of multisig data chunk")
+		}
+
+		return chunk[len(Counterparty_Prefix)+1 : chunkLength+1], pushes[len(pushes)-1], true, nil
+	}
+
+	return nil, nil, false, nil
+}
+
+// Returns the address paid by the output script or an empty string if the script doesn't pay to a single address
+func outputAddress(pkScript []byte) string {
+	class, addresses, _, err := txscript.ExtractPkScriptAddrs(pkScript, &chaincfg.MainNetParams)
+	if err != nil || len(addresses) != 1 {
+		return ""
+	}
+
+	if class != txscript.PubKeyHashTy && class != txscript.ScriptHashTy {
+		return ""
+	}
+
+	return addresses[0].EncodeAddress()
+}
+
+// ParseTransaction decodes the Counterparty message in the hex encoded bitcoin transaction.
+// The output before the data outputs is the destination and the outputs following the data are change.
+func ParseTransaction(rawTxHexString string) (CounterpartyTransaction, error) {
+	var result CounterpartyTransaction
+
+	txBytes, err := hex.DecodeString(rawTxHexString)
+	if err != nil {
+		return result, err
+	}
+
+	tx := wire.NewMsgTx()
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return result, err
+	}
+
+	return parseMsgTx(tx)
+}
+
+func parseMsgTx(tx *wire.MsgTx) (CounterpartyTransaction, error) {
+	var result CounterpartyTransaction
+	var data []byte
+	var foundData bool
+
+	key, err := arc4Key(tx)
+	if err != nil {
+		return result, err
+	}
+
+	// counterpartyd places the script of the output being spent in the signature script of unsigned transactions
+	for _, txIn := range tx.TxIn {
+		result.Sources = append(result.Sources, outputAddress(txIn.SignatureScript))
+	}
+
+	for _, txOut := range tx.TxOut {
+		value := uint64(txOut.Value)
+		result.OutputTotal += value
+
+		chunk, pubKey, isData, err := decodeDataOutput(key, txOut.PkScript)
+		if err != nil {
+			return result, err
+		}
+
+		if isData {
+			data = append(data, chunk...)
+			result.DataValue += value
+			result.DataOutputs = append(result.DataOutputs, DataOutput{Value: value, PubKey: pubKey})
+			foundData = true
+			continue
+		}
+
+		address := outputAddress(txOut.PkScript)
+		if foundData == false && result.Destination == "" && address != "" {
+			result.Destination = address
+			result.BtcAmount = value
+		} else {
+			result.Change = append(result.Change, TxOutput{Address: address, Value: value})
+		}
+	}
+
+	if foundData == false {
+		return result, errors.New("Transaction does not contain Counterparty data")
+	}
+
+	// Newer messages use a single byte message type id
+	if len(data) > 0 && data[0] != 0 {
+		result.MessageTypeId = uint32(data[0])
+		result.Message = data[1:]
+	} else {
+		if len(data) < 4 {
+			return result, errors.New("Counterparty message is too short")
+		}
+
+		result.MessageTypeId = binary.BigEndian.Uint32(data[:4])
+		result.Message = data[4:]
+	}
+
+	return result, nil
+}
+
+func DecodeSend(message []byte) (SendMessage, error) {
+	var result SendMessage
+
+	if len(message) != sendMessageLength {
+		return result, errors.New("Invalid send message length: " + strconv.Itoa(len(message)))
+	}
+
+	asset, err := AssetName(binary.BigEndian.Uint64(message[0:8]))
+	if err != nil {
+		return result, err
+	}
+
+	result.Asset = asset
+	result.Quantity = binary.BigEndian.Uint64(message[8:16])
+
+	return result, nil
+}
+
+func DecodeIssuance(message []byte) (IssuanceMessage, error) {
+	var result IssuanceMessage
+
+	if len(message) < issuanceMessageLength {
+		return result, errors.New("Invalid issuance message length: " + strconv.Itoa(len(message)))
+	}
+
+	asset, err := AssetName(binary.BigEndian.Uint64(message[0:8]))
+	if err != nil {
+		return result, err
+	}
+
+	result.Asset = asset
+	result.Quantity = binary.BigEndian.Uint64(message[8:16])
+	result.Divisible = message[16] != 0
+	result.Callable = message[17] != 0
+	result.CallDate = binary.BigEndian.Uint32(message[18:22])
+	binary.Read(bytes.NewReader(message[22:26]), binary.BigEndian, &result.CallPrice)
+
+	// Descriptions of up to 42 bytes are pascal strings, ie prefixed with the length
+	description := message[issuanceMessageLength:]
+	if len(description) <= 42 {
+		if len(description) > 0 {
+			length := int(description[0])
+			if length > len(description)-1 {
+				length = len(description) - 1
+			}
+			description = description[1 : length+1]
+		}
+	}
+	result.Description = string(description)
+
+	return result, nil
+}
+
+func DecodeDividend(message []byte) (DividendMessage, error) {
+	var result DividendMessage
+	var dividendAssetId uint64 = 1 // Older dividends are always paid in XCP
+
+	if len(message) != dividendMessageLength && len(message) != dividendMessageLength-8 {
+		return result, errors.New("Invalid dividend message length: " + strconv.Itoa(len(message)))
+	}
+
+	if len(message) == dividendMessageLength {
+		dividendAssetId = binary.BigEndian.Uint64(message[16:24])
+	}
+
+	asset, err := AssetName(binary.BigEndian.Uint64(message[8:16]))
+	if err != nil {
+		return result, err
+	}
+
+	dividendAsset, err := AssetName(dividendAssetId)
+	if err != nil {
+		return result, err
+	}
+
+	result.QuantityPerUnit = binary.BigEndian.Uint64(message[0:8])
+	result.Asset = asset
+	result.DividendAsset = dividendAsset
+
+	return result, nil
+}
+
+func DecodeOrder(message []byte) (OrderMessage, error) {
+	var result OrderMessage
+
+	if len(message) != orderMessageLength {
+		return result, errors.New("Invalid order message length: " + strconv.Itoa(len(message)))
+	}
+
+	giveAsset, err := AssetName(binary.BigEndian.Uint64(message[0:8]))
+	if err != nil {
+		return result, err
+	}
+
+	getAsset, err := AssetName(binary.BigEndian.Uint64(message[16:24]))
+	if err != nil {
+		return result, err
+	}
+
+	result.GiveAsset = giveAsset
+	result.GiveQuantity = binary.BigEndian.Uint64(message[8:16])
+	result.GetAsset = getAsset
+	result.GetQuantity = binary.BigEndian.Uint64(message[24:32])
+	result.Expiration = binary.BigEndian.Uint16(message[32:34])
+	result.FeeRequired = binary.BigEndian.Uint64(message[34:42])
+
+	return result, nil
+}
+
+func DecodeBtcPay(message []byte) (BtcPayMessage, error) {
+	var result BtcPayMessage
+
+	if len(message) != btcPayMessageLength {
+		return result, errors.New("Invalid BTCpay message length: " + strconv.Itoa(len(message)))
+	}
+
+	result.Tx0Hash = hex.EncodeToString(message[0:32])
+	result.Tx1Hash = hex.EncodeToString(message[32:64])
+
+	return result, nil
+}
+
+func DecodeCancel(message []byte) (CancelMessage, error) {
+	var result CancelMessage
+
+	if len(message) != cancelMessageLength {
+		return result, errors.New("Invalid cancel message length: " + strconv.Itoa(len(message)))
+	}
+
+	result.OfferHash = hex.EncodeToString(message)
+
+	return result, nil
+}
+
+func DecodeBroadcast(message []byte) (BroadcastMessage, error) {
+	var result BroadcastMessage
+
+	if len(message) < broadcastMessageLength {
+		return result, errors.New("Invalid broadcast message length: " + strconv.Itoa(len(message)))
+	}
+
+	result.Timestamp = binary.BigEndian.Uint32(message[0:4])
+	binary.Read(bytes.NewReader(message[4:12]), binary.BigEndian, &result.Value)
+	result.FeeFractionInt = binary.BigEndian.Uint32(message[12:16])
+
+	// Text of up to 52 bytes is a pascal string, ie prefixed with the length
+	text := message[broadcastMessageLength:]
+	if len(text) > 0 && len(text) <= 53 && int(text[0]) == len(text)-1 {
+		text = text[1:]
+	}
+	result.Text = string(text)
+
+	return result, nil
+}
+
+// AssetId returns the Counterparty asset id of the asset name
+func AssetId(asset string) (uint64, error) {
+	switch asset {
+	case "BTC":
+		return 0, nil
+	case "XCP":
+		return 1, nil
+	}
+
+	if len(asset) < 4 {
+		return 0, errors.New("Asset name is too short: " + asset)
+	}
+
+	// Numeric assets
+	if strings.HasPrefix(asset, "A") {
+		id, ok := new(big.Int).SetString(asset[1:], 10)
+		if ok == false || id.Cmp(minimumNumericAssetId()) < 0 || id.BitLen() > 64 {
+			return 0, errors.New("Invalid numeric asset name: " + asset)
+		}
+
+		return id.Uint64(), nil
+	}
+
+	id := new(big.Int)
+	for _, character := range asset {
+		digit := strings.IndexRune(b26Digits, character)
+		if digit < 0 {
+			return 0, errors.New("Invalid character in asset name: " + asset)
+		}
+
+		id.Mul(id, big.NewInt(26))
+		id.Add(id, big.NewInt(int64(digit)))
+	}
+
+	if id.BitLen() > 64 || id.Uint64() < 26*26*26 {
+		return 0, errors.New("Invalid asset name: " + asset)
+	}
+
+	return id.Uint64(), nil
+}
+
+// AssetName returns the Counterparty asset name of the asset id
+func AssetName(id uint64) (string, error) {
+	switch id {
+	case 0:
+		return "BTC", nil
+	case 1:
+		return "XCP", nil
+	}
+
+	if id < 26*26*26 {
+		return "", errors.New(fmt.Sprintf("Asset id is too low: %d", id))
+	}
+
+	// Numeric assets
+	if new(big.Int).SetUint64(id).Cmp(minimumNumericAssetId()) >= 0 {
+		return "A" + strconv.FormatUint(id, 10), nil
+	}
+
+	var name []byte
+	for n := id; n > 0; n = n / 26 {
+		name = append([]byte{b26Digits[n%26]}, name...)
+	}
+
+	return string(name), nil
+}
+
+// Numeric asset ids start at 26^12 + 1
+func minimumNumericAssetId() *big.Int {
+	result := new(big.Int).Exp(big.NewInt(26), big.NewInt(12), nil)
+
+	return result.Add(result, big.NewInt(1))
+}
+
+// Returns the message type id and message as embedded in the transaction, without the prefix
+func encodeMessage(messageTypeId uint32, message []byte) []byte {
+	var buffer bytes.Buffer
+
+	binary.Write(&buffer, binary.BigEndian, messageTypeId)
+	buffer.Write(message)
+
+	return buffer.Bytes()
+}
+
+func EncodeSend(asset string, quantity uint64) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	assetId, err := AssetId(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	binary.Write(&buffer, binary.BigEndian, assetId)
+	binary.Write(&buffer, binary.BigEndian, quantity)
+
+	return encodeMessage(Counterparty_SendId, buffer.Bytes()), nil
+}
+
+func EncodeIssuance(asset string, quantity uint64, divisible bool, description string) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	assetId, err := AssetId(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	binary.Write(&buffer, binary.BigEndian, assetId)
+	binary.Write(&buffer, binary.BigEndian, quantity)
+	binary.Write(&buffer, binary.BigEndian, divisible)
+	binary.Write(&buffer, binary.BigEndian, false)      // callable
+	binary.Write(&buffer, binary.BigEndian, uint32(0))  // call date
+	binary.Write(&buffer, binary.BigEndian, float32(0)) // call price
+
+	// Descriptions of up to 42 bytes are pascal strings, ie prefixed with the length
+	if len(description) <= 42 {
+		buffer.WriteByte(byte(len(description)))
+	}
+	buffer.WriteString(description)
+
+	return encodeMessage(Counterparty_IssuanceId, buffer.Bytes()), nil
+}
+
+func EncodeDividend(quantityPerUnit uint64, asset string, dividendAsset string) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	assetId, err := AssetId(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	dividendAssetId, err := AssetId(dividendAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	binary.Write(&buffer, binary.BigEndian, quantityPerUnit)
+	binary.Write(&buffer, binary.BigEndian, assetId)
+	binary.Write(&buffer, binary.BigEndian, dividendAssetId)
+
+	return encodeMessage(Counterparty_DividendId, buffer.Bytes()), nil
+}
+
+func EncodeOrder(giveAsset string, giveQuantity uint64, getAsset string, getQuantity uint64, expiration uint16, feeRequired uint64) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	giveAssetId, err := AssetId(giveAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	getAssetId, err := AssetId(getAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	binary.Write(&buffer, binary.BigEndian, giveAssetId)
+	binary.Write(&buffer, binary.BigEndian, giveQuantity)
+	binary.Write(&buffer, binary.BigEndian, getAssetId)
+	binary.Write(&buffer, binary.BigEndian, getQuantity)
+	binary.Write(&buffer, binary.BigEndian, expiration)
+	binary.Write(&buffer, binary.BigEndian, feeRequired)
+
+	return encodeMessage(Counterparty_OrderId, buffer.Bytes()), nil
+}
+
+// The order match id is the hash of the first order followed by the hash of the matching order, optionally separated by an underscore
+func EncodeBtcPay(orderMatchId string) ([]byte, error) {
+	hashes, err := hex.DecodeString(strings.Replace(orderMatchId, "_", "", 1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hashes) != btcPayMessageLength {
+		return nil, errors.New("Invalid order match id: " + orderMatchId)
+	}
+
+	return encodeMessage(Counterparty_BtcPayId, hashes), nil
+}
+
+func EncodeCancel(offerHash string) ([]byte, error) {
+	hash, err := hex.DecodeString(offerHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hash) != cancelMessageLength {
+		return nil, errors.New("Invalid offer hash: " + offerHash)
+	}
+
+	return encodeMessage(Counterparty_CancelId, hash), nil
+}
+
+// The fee fraction is expressed in units of 1e-8
+func EncodeBroadcast(timestamp uint32, value float64, feeFractionInt uint32, text string) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	binary.Write(&buffer, binary.BigEndian, timestamp)
+	binary.Write(&buffer, binary.BigEndian, value)
+	binary.Write(&buffer, binary.BigEndian, feeFractionInt)
+
+	// Text of up to 52 bytes is a pascal string, ie prefixed with the length
+	if len(text) <= 52 {
+		buffer.WriteByte(byte(len(text)))
+	}
+	buffer.WriteString(text)
+
+	return encodeMessage(Counterparty_BroadcastId, buffer.Bytes()), nil
+}
@@ -0,0 +1,106 @@
+package counterpartyapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/nodepool"
+)
+
+type payloadWrite struct {
+	Method string `json:"method"`
+	Params struct {
+		Source string `json:"source"`
+	} `json:"params"`
+}
+
+// Posts to the given counterparty JSON RPC call on the counterpartyd node which has seen the most blocks, moving on to the next node
+// if one times out, is reparsing or unavailable. Transactions are created on the node the source address is pinned to
+func postAPI(c context.Context, postData []byte) (map[string]interface{}, int64, error) {
+	var result map[string]interface{}
+	var errorCode int64 = consts.CounterpartyErrors.ReparsingOrUnavailable.Code
+	var err error = errors.New(consts.CounterpartyErrors.ReparsingOrUnavailable.Description)
+
+	if isInit == false {
+		Init()
+	}
+
+	account := writeAccount(postData)
+	nodes := counterpartyNodes.ReadNodes()
+	if account != "" {
+		nodes = counterpartyNodes.WriteNodes(account)
+	}
+
+	answered := counterpartyNodes.Try(c, nodes, func(node nodepool.Node) (bool, error) {
+		result, errorCode, err = postAPINode(c, node, postData)
+
+		return isFailover(errorCode) == false, err
+	})
+
+	if answered == -1 {
+		log.FluentfContext(consts.LOGERROR, c, "No counterpartyd node was available")
+	} else if account != "" {
+		counterpartyNodes.Pin(account, answered)
+	}
+
+	return result, errorCode, err
+}
+
+// Returns the source address of a call which creates a transaction, or "" if the call is a read
+func writeAccount(postData []byte) string {
+	var payload payloadWrite
+
+	if err := json.Unmarshal(postData, &payload); err != nil {
+		return ""
+	}
+
+	if strings.HasPrefix(payload.Method, "create_") == false {
+		return ""
+	}
+
+	return payload.Params.Source
+}
+
+// Checks the health and last parsed block of each counterpartyd node so that reads go to the node which is most caught up. Runs until Enu is stopped
+func MonitorNodes(c context.Context) {
+	var payload payloadGetRunningInfo
+
+	if isInit == false {
+		Init()
+	}
+
+	payload.Method = "get_running_info"
+	payload.Jsonrpc = "2.0"
+
+	counterpartyNodes.Monitor(c, func(c context.Context, node nodepool.Node) (int64, error) {
+		payload.Id = generateId(c)
+
+		payloadJsonBytes, err := json.Marshal(payload)
+		if err != nil {
+			return -1, err
+		}
+
+		responseData, _, err := postAPINode(c, node, payloadJsonBytes)
+		if err != nil {
+			return -1, err
+		}
+
+		// last_block is null until counterpartyd has parsed a block
+		resultMap, _ := responseData["result"].(map[string]interface{})
+		lastBlockMap, ok := resultMap["last_block"].(map[string]interface{})
+		if ok == false {
+			return -1, errors.New(consts.CounterpartyErrors.ReparsingOrUnavailable.Description)
+		}
+
+		blockIndex, ok := lastBlockMap["block_index"].(float64)
+		if ok == false {
+			return -1, errors.New(consts.CounterpartyErrors.ReparsingOrUnavailable.Description)
+		}
+
+		return int64(blockIndex), nil
+	})
+}
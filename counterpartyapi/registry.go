@@ -0,0 +1,155 @@
+// Holders of a Counterparty asset at a past block height
+// counterpartyd only returns current balances, so the balance of each holder at a height is the sum of the credits less the debits
+// of the asset up to and including the block.
+
+package counterpartyapi
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Number of rows requested from counterpartyd at a time when every row of a table is needed
+var Counterparty_TablePageSize = 1000
+
+// A dividend as returned by get_dividends
+type Dividend struct {
+	TxIndex         uint64 `json:"tx_index"`
+	TxHash          string `json:"tx_hash"`
+	BlockIndex      uint64 `json:"block_index"`
+	Source          string `json:"source"`
+	Asset           string `json:"asset"`
+	DividendAsset   string `json:"dividend_asset"`
+	QuantityPerUnit uint64 `json:"quantity_per_unit"`
+	FeePaid         uint64 `json:"fee_paid"`
+	Status          string `json:"status"`
+}
+
+// Returns the balance of every holder of the asset once the block had been parsed, largest holding first
+func GetBalancesByAssetAtBlock(c context.Context, asset string, blockIndex uint64) ([]Balance, int64, error) {
+	var credits []Credit
+	var debits []Debit
+
+	filterList := filters{filter{Field: "asset", Op: "==", Value: asset}}
+
+	if errorCode, err := getTableByBlocks(c, "get_credits", "block_index", filterList, 0, blockIndex, &credits); err != nil {
+		return nil, errorCode, err
+	}
+
+	if errorCode, err := getTableByBlocks(c, "get_debits", "block_index", filterList, 0, blockIndex, &debits); err != nil {
+		return nil, errorCode, err
+	}
+
+	return sumBalances(asset, credits, debits), 0, nil
+}
+
+// Returns every row of the table matching the filters in the blocks from fromBlock to toBlock inclusive. counterpartyd can only order by
+// one column and block_index doesn't order the rows within a block, so paging by offset could skip or repeat rows at the edge of a page.
+// Instead the blocks are split into ranges small enough that each is read in a single request
+func getTableByBlocks(c context.Context, method string, orderBy string, filterList filters, fromBlock uint64, toBlock uint64, result interface{}) (int64, error) {
+	var rows []json.RawMessage
+
+	if errorCode, err := getTableBlockRange(c, method, orderBy, filterList, fromBlock, toBlock, &rows); err != nil {
+		return errorCode, err
+	}
+
+	rowsJson, err := json.Marshal(rows)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	if err := json.Unmarshal(rowsJson, result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Unmarshal(): %s", err.Error())
+		return consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	return 0, nil
+}
+
+// Appends the rows of the blocks in the range, halving the range until the rows fit in a page. The rows of a single block which fill
+// a page are read whole from the database of counterpartyd
+func getTableBlockRange(c context.Context, method string, orderBy string, filterList filters, fromBlock uint64, toBlock uint64, rows *[]json.RawMessage) (int64, error) {
+	var page []json.RawMessage
+
+	rangeFilters := append(filters{filter{Field: "block_index", Op: ">=", Value: strconv.FormatUint(fromBlock, 10)}, filter{Field: "block_index", Op: "<=", Value: strconv.FormatUint(toBlock, 10)}}, filterList...)
+
+	if errorCode, err := getTablePage(c, method, orderBy, rangeFilters, Counterparty_TablePageSize, 0, &page); err != nil {
+		return errorCode, err
+	}
+
+	if len(page) < Counterparty_TablePageSize {
+		*rows = append(*rows, page...)
+		return 0, nil
+	}
+
+	if fromBlock == toBlock {
+		page = nil
+		if errorCode, err := getTablePageDB(c, method, orderBy, rangeFilters, 0, 0, &page); err != nil {
+			return errorCode, err
+		}
+
+		*rows = append(*rows, page...)
+		return 0, nil
+	}
+
+	middle := fromBlock + (toBlock-fromBlock)/2
+	if errorCode, err := getTableBlockRange(c, method, orderBy, filterList, fromBlock, middle, rows); err != nil {
+		return errorCode, err
+	}
+
+	return getTableBlockRange(c, method, orderBy, filterList, middle+1, toBlock, rows)
+}
+
+// Returns the valid dividends paid on the asset, oldest first
+func GetDividends(c context.Context, asset string) ([]Dividend, int64, error) {
+	var result []Dividend
+
+	filterList := filters{filter{Field: "asset", Op: "==", Value: asset}, filter{Field: "status", Op: "==", Value: "valid"}}
+	errorCode, err := getTable(c, "get_dividends", "tx_index", filterList, &result)
+
+	return result, errorCode, err
+}
+
+// Nets the credits and debits of each address. Addresses with nothing left are omitted
+func sumBalances(asset string, credits []Credit, debits []Debit) []Balance {
+	var result []Balance
+	balances := make(map[string]int64)
+
+	for _, credit := range credits {
+		balances[credit.Address] += int64(credit.Quantity)
+	}
+
+	for _, debit := range debits {
+		balances[debit.Address] -= int64(debit.Quantity)
+	}
+
+	for address, quantity := range balances {
+		if quantity > 0 {
+			result = append(result, Balance{Address: address, Asset: asset, Quantity: uint64(quantity)})
+		}
+	}
+
+	sort.Sort(byHolding(result))
+
+	return result
+}
+
+// Sorts balances largest first, then by address so the order is stable
+type byHolding []Balance
+
+func (b byHolding) Len() int      { return len(b) }
+func (b byHolding) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byHolding) Less(i, j int) bool {
+	if b[i].Quantity != b[j].Quantity {
+		return b[i].Quantity > b[j].Quantity
+	}
+
+	return b[i].Address < b[j].Address
+}
@@ -0,0 +1,397 @@
+// Signing of raw transactions composed by counterpartyd
+// Pubkeyhash, bare multisig and P2SH multisig inputs are supported. Multisig inputs may be signed by several
+// co-signers in turn, with each partially signed transaction passed on to the next co-signer, or the partially
+// signed transactions may be combined once all the signatures have been collected.
+
+package counterpartyapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcec"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/txscript"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/wire"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Maximum number of public keys in a P2SH multisig redeem script
+var Counterparty_MaxMultisigKeys = 15
+
+// CreateMultisigAddress builds an m-of-n multisig redeem script from the hex encoded public keys given.
+// Returns the P2SH address which pays to the redeem script and the redeem script as a hex string.
+func CreateMultisigAddress(c context.Context, requiredSignatures int, publicKeys []string) (string, string, error) {
+	if requiredSignatures < 1 || requiredSignatures > len(publicKeys) || len(publicKeys) > Counterparty_MaxMultisigKeys {
+		errorString := fmt.Sprintf("Invalid multisig parameters. Required signatures: %d, number of public keys: %d", requiredSignatures, len(publicKeys))
+		log.FluentfContext(consts.LOGERROR, c, "%s", errorString)
+
+		return "", "", errors.New(errorString)
+	}
+
+	var addressPubKeys []*btcutil.AddressPubKey
+	for _, publicKey := range publicKeys {
+		publicKeyBytes, err := hex.DecodeString(publicKey)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
+			return "", "", err
+		}
+
+		addressPubKey, err := btcutil.NewAddressPubKey(publicKeyBytes, &chaincfg.MainNetParams)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in NewAddressPubKey(): %s", err.Error())
+			return "", "", err
+		}
+
+		addressPubKeys = append(addressPubKeys, addressPubKey)
+	}
+
+	redeemScript, err := txscript.MultiSigScript(addressPubKeys, requiredSignatures)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in MultiSigScript(): %s", err.Error())
+		return "", "", err
+	}
+
+	address, err := btcutil.NewAddressScriptHash(redeemScript, &chaincfg.MainNetParams)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in NewAddressScriptHash(): %s", err.Error())
+		return "", "", err
+	}
+
+	return address.EncodeAddress(), hex.EncodeToString(redeemScript), nil
+}
+
+// SignRawTransactionPartial signs as many inputs of the unsigned transaction as the passphrase holds keys for.
+//
+// unsignedTxHexString is the transaction as composed by counterpartyd, ie with the script of the output being spent
+// in the signature script of each input. partialTxHexString is optional and holds the signatures collected so far
+// from other co-signers. redeemScripts are the hex encoded redeem scripts for P2SH inputs. If a redeem script isn't
+// supplied it is derived from the partially signed transaction.
+//
+// Returns the signed transaction and true if all inputs are fully signed. If false is returned, the signed
+// transaction should be passed to the next co-signer.
+func SignRawTransactionPartial(c context.Context, passphrase string, unsignedTxHexString string, partialTxHexString string, redeemScripts []string) (string, bool, error) {
+	unsignedTx, err := decodeRawTransaction(c, unsignedTxHexString)
+	if err != nil {
+		return "", false, err
+	}
+
+	var partialTxes []*wire.MsgTx
+	if partialTxHexString != "" {
+		partialTx, err := decodeRawTransaction(c, partialTxHexString)
+		if err != nil {
+			return "", false, err
+		}
+
+		partialTxes = append(partialTxes, partialTx)
+	}
+
+	signedTx, complete, err := signRawTransaction(c, passphrase, unsignedTx, partialTxes, redeemScripts)
+	if err != nil {
+		return "", false, err
+	}
+
+	signedTxHexString, err := encodeRawTransaction(c, signedTx)
+	if err != nil {
+		return "", false, err
+	}
+
+	return signedTxHexString, complete, nil
+}
+
+// CombineRawTransactions merges the signatures of partially signed copies of the same unsigned transaction,
+// for example when each co-signer has signed the unsigned transaction independently.
+// Returns the combined transaction and true if all inputs are fully signed.
+func CombineRawTransactions(c context.Context, unsignedTxHexString string, partialTxHexStrings []string, redeemScripts []string) (string, bool, error) {
+	if len(partialTxHexStrings) == 0 {
+		log.FluentfContext(consts.LOGERROR, c, "No partially signed transactions to combine")
+		return "", false, errors.New("No partially signed transactions to combine")
+	}
+
+	unsignedTx, err := decodeRawTransaction(c, unsignedTxHexString)
+	if err != nil {
+		return "", false, err
+	}
+
+	var partialTxes []*wire.MsgTx
+	for _, partialTxHexString := range partialTxHexStrings {
+		partialTx, err := decodeRawTransaction(c, partialTxHexString)
+		if err != nil {
+			return "", false, err
+		}
+
+		partialTxes = append(partialTxes, partialTx)
+	}
+
+	// No passphrase so no new signatures are added, only the existing signatures are merged
+	combinedTx, complete, err := signRawTransaction(c, "", unsignedTx, partialTxes, redeemScripts)
+	if err != nil {
+		return "", false, err
+	}
+
+	combinedTxHexString, err := encodeRawTransaction(c, combinedTx)
+	if err != nil {
+		return "", false, err
+	}
+
+	return combinedTxHexString, complete, nil
+}
+
+// signRawTransaction signs the inputs of unsignedTx with the keys derived from the passphrase and merges in the
+// signatures from partialTxes. Returns the signed transaction and true if every input is fully signed.
+func signRawTransaction(c context.Context, passphrase string, unsignedTx *wire.MsgTx, partialTxes []*wire.MsgTx, redeemScriptHexStrings []string) (*wire.MsgTx, bool, error) {
+	// Partially signed transactions must spend the same outputs as the unsigned transaction
+	for _, partialTx := range partialTxes {
+		if len(partialTx.TxIn) != len(unsignedTx.TxIn) {
+			log.FluentfContext(consts.LOGERROR, c, "Partially signed transaction has %d inputs, expected %d", len(partialTx.TxIn), len(unsignedTx.TxIn))
+			return nil, false, errors.New("Partially signed transaction does not match the unsigned transaction")
+		}
+
+		for i, txIn := range partialTx.TxIn {
+			if txIn.PreviousOutPoint != unsignedTx.TxIn[i].PreviousOutPoint {
+				log.FluentfContext(consts.LOGERROR, c, "Partially signed transaction TxIn[%d] spends a different output", i)
+				return nil, false, errors.New("Partially signed transaction does not match the unsigned transaction")
+			}
+		}
+	}
+
+	// Redeem scripts for the P2SH inputs, keyed by P2SH address
+	redeemScripts := make(map[string][]byte)
+	addRedeemScript := func(script []byte) error {
+		address, err := btcutil.NewAddressScriptHash(script, &chaincfg.MainNetParams)
+		if err != nil {
+			return err
+		}
+
+		redeemScripts[address.EncodeAddress()] = script
+		return nil
+	}
+
+	for _, redeemScriptHexString := range redeemScriptHexStrings {
+		redeemScript, err := hex.DecodeString(redeemScriptHexString)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
+			return nil, false, err
+		}
+
+		if err := addRedeemScript(redeemScript); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in NewAddressScriptHash(): %s", err.Error())
+			return nil, false, err
+		}
+	}
+
+	// The redeem script is the last push of a P2SH signature script, so derive any redeem scripts that weren't supplied
+	// from the inputs already signed by co-signers
+	for _, partialTx := range partialTxes {
+		for i, txIn := range partialTx.TxIn {
+			if txscript.GetScriptClass(unsignedTx.TxIn[i].SignatureScript) != txscript.ScriptHashTy {
+				continue
+			}
+
+			pushes, err := txscript.PushedData(txIn.SignatureScript)
+			if err != nil || len(pushes) == 0 {
+				continue
+			}
+
+			addRedeemScript(pushes[len(pushes)-1])
+		}
+	}
+
+	// Create a new transaction and copy the details from the tx that was serialised. For some reason BTCD can't sign in place transactions
+	redeemTx := wire.NewMsgTx()
+	redeemTx.Version = unsignedTx.Version
+	redeemTx.LockTime = unsignedTx.LockTime
+	for _, txIn := range unsignedTx.TxIn {
+		prevOut := wire.NewOutPoint(&txIn.PreviousOutPoint.Hash, txIn.PreviousOutPoint.Index)
+		redeemTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+	}
+
+	for _, txOut := range unsignedTx.TxOut {
+		redeemTx.AddTxOut(txOut)
+	}
+
+	// Callback to look up the signing key
+	lookupKey := func(a btcutil.Address) (*btcec.PrivateKey, bool, error) {
+		if passphrase == "" {
+			return nil, false, errors.New("No passphrase to derive the private key from")
+		}
+
+		// Multisig scripts contain public keys so the key is found by the pubkeyhash address of the public key
+		address := a.EncodeAddress()
+
+		privateKeyString, err := counterpartycrypto.GetPrivateKey(passphrase, address)
+		if err != nil {
+			// Expected for multisig keys which are held by co-signers
+			log.FluentfContext(consts.LOGDEBUG, c, "Error in counterpartycrypto.GetPrivateKey(): %s", err.Error())
+			return nil, false, err
+		}
+
+		privateKeyBytes, err := hex.DecodeString(privateKeyString)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
+			return nil, false, err
+		}
+
+		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), privateKeyBytes)
+
+		return privKey, true, nil
+	}
+
+	// Callback to look up the redeem script for P2SH inputs
+	lookupScript := func(a btcutil.Address) ([]byte, error) {
+		script, ok := redeemScripts[a.EncodeAddress()]
+		if !ok {
+			return nil, errors.New("No redeem script for P2SH address: " + a.EncodeAddress())
+		}
+
+		return script, nil
+	}
+
+	// Range over TxIns and sign
+	for i, txIn := range unsignedTx.TxIn {
+		// counterpartyd places the script of the output being spent in the signature script
+		prevPkScript := txIn.SignatureScript
+
+		scriptClass := txscript.GetScriptClass(prevPkScript)
+		switch scriptClass {
+		case txscript.PubKeyHashTy, txscript.MultiSigTy, txscript.ScriptHashTy:
+		default:
+			return nil, false, errors.New("SignRawTransaction() only supports pubkeyhash, multisig and P2SH script signing. However, the script type in the TX to sign was: " + scriptClass.String())
+		}
+
+		previousScript := previousSignatureScript(scriptClass, i, partialTxes)
+
+		sigScript, err := txscript.SignTxOutput(&chaincfg.MainNetParams, redeemTx, i, prevPkScript, txscript.SigHashAll, txscript.KeyClosure(lookupKey), txscript.ScriptClosure(lookupScript), previousScript)
+		if err != nil {
+			// A pubkeyhash input may have already been signed by a co-signer
+			if scriptClass == txscript.PubKeyHashTy && len(previousScript) > 0 {
+				sigScript = previousScript
+			} else {
+				log.FluentfContext(consts.LOGERROR, c, "Error in SignTxOutput() for TxIn[%d]: %s", i, err.Error())
+				return nil, false, err
+			}
+		}
+
+		// Copy the signed sigscript into the redeeming tx
+		redeemTx.TxIn[i].SignatureScript = sigScript
+	}
+
+	// Prove that the transaction has been validly signed by executing the script pair.
+	// Multisig inputs which fail are still waiting on signatures from co-signers.
+	flags := txscript.ScriptBip16 | txscript.ScriptVerifyDERSignatures | txscript.ScriptStrictMultiSig | txscript.ScriptDiscourageUpgradableNops | txscript.ScriptVerifyLowS | txscript.ScriptVerifyCleanStack | txscript.ScriptVerifyMinimalData | txscript.ScriptVerifySigPushOnly | txscript.ScriptVerifyStrictEncoding
+	var buildError string
+	complete := true
+	for i, txIn := range unsignedTx.TxIn {
+		vm, err := txscript.NewEngine(txIn.SignatureScript, redeemTx, i, flags)
+		if err != nil {
+			buildError += "NewEngine() error: " + err.Error() + ","
+			continue
+		}
+
+		if err := vm.Execute(); err != nil {
+			if txscript.GetScriptClass(txIn.SignatureScript) == txscript.PubKeyHashTy {
+				buildError += "TxIn[" + strconv.Itoa(i) + "]: " + err.Error() + ", "
+			} else {
+				complete = false
+			}
+		}
+	}
+	if len(buildError) > 0 {
+		log.FluentfContext(consts.LOGERROR, c, "Transaction failed verification: %s", buildError)
+		return nil, false, errors.New(buildError)
+	}
+
+	return redeemTx, complete, nil
+}
+
+// previousSignatureScript combines the signature scripts for input idx of each partially signed transaction into
+// a single script which SignTxOutput() merges with the new signatures. Signatures which don't verify against the
+// multisig public keys are discarded by the merge.
+func previousSignatureScript(scriptClass txscript.ScriptClass, idx int, partialTxes []*wire.MsgTx) []byte {
+	var previousScript []byte
+
+	switch scriptClass {
+	case txscript.MultiSigTy, txscript.ScriptHashTy:
+		var redeemScript []byte
+		numberOfSignatures := 0
+
+		// Leading OP_FALSE for the extra value popped by OP_CHECKMULTISIG
+		builder := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE)
+		for _, partialTx := range partialTxes {
+			pushes, err := txscript.PushedData(partialTx.TxIn[idx].SignatureScript)
+			if err != nil || len(pushes) == 0 {
+				continue
+			}
+
+			if scriptClass == txscript.ScriptHashTy {
+				redeemScript = pushes[len(pushes)-1]
+				pushes = pushes[:len(pushes)-1]
+			}
+
+			for _, push := range pushes {
+				if len(push) > 0 {
+					builder.AddData(push)
+					numberOfSignatures++
+				}
+			}
+		}
+
+		if numberOfSignatures == 0 {
+			return nil
+		}
+
+		if redeemScript != nil {
+			builder.AddData(redeemScript)
+		}
+
+		previousScript, _ = builder.Script()
+	default:
+		// Single signature scripts are either signed or not so take the longest
+		for _, partialTx := range partialTxes {
+			if len(partialTx.TxIn[idx].SignatureScript) > len(previousScript) {
+				previousScript = partialTx.TxIn[idx].SignatureScript
+			}
+		}
+	}
+
+	return previousScript
+}
+
+func decodeRawTransaction(c context.Context, rawTxHexString string) (*wire.MsgTx, error) {
+	// Convert the hex string to a byte array
+	txBytes, err := hex.DecodeString(rawTxHexString)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DecodeString(): %s", err.Error())
+		return nil, err
+	}
+
+	// Deserialise the transaction
+	tx, err := btcutil.NewTxFromBytes(txBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in NewTxFromBytes(): %s", err.Error())
+		return nil, err
+	}
+
+	return tx.MsgTx(), nil
+}
+
+func encodeRawTransaction(c context.Context, tx *wire.MsgTx) (string, error) {
+	// Encode the struct into BTC bytes wire format
+	var byteBuffer bytes.Buffer
+	err := tx.BtcEncode(&byteBuffer, wire.ProtocolVersion)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in BtcEncode(): %s", err.Error())
+		return "", err
+	}
+
+	// Encode bytes to hex string
+	return hex.EncodeToString(byteBuffer.Bytes()), nil
+}
@@ -0,0 +1,342 @@
+// Verification of unsigned transactions returned by counterpartyd before they are signed
+// A compromised or faulty counterpartyd could otherwise redirect funds by composing a transaction which differs from the request.
+
+package counterpartyapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// VerifySend checks the unsigned transaction sends the quantity of the asset from the source address to the destination
+func VerifySend(c context.Context, rawTxHexString string, sourceAddress string, destinationAddress string, asset string, quantity uint64) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	return verifySend(c, tx, inputTotal, sourceAddress, destinationAddress, asset, quantity)
+}
+
+// VerifyIssuance checks the unsigned transaction issues the quantity of the asset from the source address
+func VerifyIssuance(c context.Context, rawTxHexString string, sourceAddress string, asset string, description string, quantity uint64, divisible bool) (int64, error) {
+	// Descriptions are truncated in the same way as CreateIssuance()
+	if len(description) > 52 {
+		description = description[0:51]
+	}
+
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	return verifyIssuance(c, tx, inputTotal, sourceAddress, asset, description, quantity, divisible, "")
+}
+
+// VerifyTransfer checks the unsigned transaction transfers ownership of the asset from the source address to the destination
+func VerifyTransfer(c context.Context, rawTxHexString string, sourceAddress string, asset string, description string, divisible bool, destinationAddress string) (int64, error) {
+	if len(description) > 52 {
+		description = description[0:51]
+	}
+
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	return verifyIssuance(c, tx, inputTotal, sourceAddress, asset, description, 0, divisible, destinationAddress)
+}
+
+// VerifyDividend checks the unsigned transaction pays the dividend on the asset from the source address
+func VerifyDividend(c context.Context, rawTxHexString string, sourceAddress string, asset string, dividendAsset string, quantityPerUnit uint64) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	return verifyDividend(c, tx, inputTotal, sourceAddress, asset, dividendAsset, quantityPerUnit)
+}
+
+// VerifyOrder checks the unsigned transaction places the order on the DEX from the source address
+func VerifyOrder(c context.Context, rawTxHexString string, sourceAddress string, giveAsset string, giveQuantity uint64, getAsset string, getQuantity uint64, expiration uint16) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	if tx.MessageTypeId != Counterparty_OrderId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not an order", tx.MessageTypeId))
+	}
+
+	order, err := DecodeOrder(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if order.GiveAsset != giveAsset || order.GiveQuantity != giveQuantity || order.GetAsset != getAsset || order.GetQuantity != getQuantity || order.Expiration != expiration {
+		return verificationFailed(c, fmt.Sprintf("order of %+v, expected give %d %s, get %d %s, expiration %d", order, giveQuantity, giveAsset, getQuantity, getAsset, expiration))
+	}
+
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, "")
+}
+
+// VerifyBtcPay checks the unsigned transaction pays the BTC owed for the order match to the destination address
+func VerifyBtcPay(c context.Context, rawTxHexString string, sourceAddress string, orderMatchId string, destinationAddress string, btcQuantity uint64) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	if tx.MessageTypeId != Counterparty_BtcPayId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not a BTCpay", tx.MessageTypeId))
+	}
+
+	btcPay, err := DecodeBtcPay(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if btcPay.Tx0Hash+btcPay.Tx1Hash != strings.Replace(orderMatchId, "_", "", 1) {
+		return verificationFailed(c, fmt.Sprintf("BTCpay of %+v, expected order match %s", btcPay, orderMatchId))
+	}
+
+	return verifyOutputsWithAmount(c, tx, inputTotal, sourceAddress, destinationAddress, btcQuantity)
+}
+
+// VerifyCancel checks the unsigned transaction cancels the order from the source address
+func VerifyCancel(c context.Context, rawTxHexString string, sourceAddress string, offerHash string) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	if tx.MessageTypeId != Counterparty_CancelId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not a cancel", tx.MessageTypeId))
+	}
+
+	cancel, err := DecodeCancel(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if cancel.OfferHash != offerHash {
+		return verificationFailed(c, "cancel of "+cancel.OfferHash+", expected "+offerHash)
+	}
+
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, "")
+}
+
+// VerifyBroadcast checks the unsigned transaction broadcasts the text and value from the source address
+func VerifyBroadcast(c context.Context, rawTxHexString string, sourceAddress string, text string, value float64, feeFraction float64, timestamp uint32) (int64, error) {
+	tx, inputTotal, errorCode, err := parseAndGetInputTotal(c, rawTxHexString)
+	if err != nil {
+		return errorCode, err
+	}
+
+	if tx.MessageTypeId != Counterparty_BroadcastId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not a broadcast", tx.MessageTypeId))
+	}
+
+	broadcast, err := DecodeBroadcast(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if broadcast.Text != text || broadcast.Value != value || broadcast.FeeFractionInt != FeeFractionInt(feeFraction) || broadcast.Timestamp != timestamp {
+		return verificationFailed(c, fmt.Sprintf("broadcast of %+v, expected text: %s, value: %f, fee fraction: %f, timestamp: %d", broadcast, text, value, feeFraction, timestamp))
+	}
+
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, "")
+}
+
+func verifySend(c context.Context, tx CounterpartyTransaction, inputTotal uint64, sourceAddress string, destinationAddress string, asset string, quantity uint64) (int64, error) {
+	if tx.MessageTypeId != Counterparty_SendId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not a send", tx.MessageTypeId))
+	}
+
+	send, err := DecodeSend(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if send.Asset != asset || send.Quantity != quantity {
+		return verificationFailed(c, fmt.Sprintf("send of %d %s, expected %d %s", send.Quantity, send.Asset, quantity, asset))
+	}
+
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, destinationAddress)
+}
+
+func verifyIssuance(c context.Context, tx CounterpartyTransaction, inputTotal uint64, sourceAddress string, asset string, description string, quantity uint64, divisible bool, transferDestination string) (int64, error) {
+	if tx.MessageTypeId != Counterparty_IssuanceId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not an issuance", tx.MessageTypeId))
+	}
+
+	issuance, err := DecodeIssuance(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if issuance.Asset != asset || issuance.Quantity != quantity || issuance.Divisible != divisible || issuance.Description != description {
+		return verificationFailed(c, fmt.Sprintf("issuance of %+v, expected asset: %s, quantity: %d, divisible: %t, description: %s", issuance, asset, quantity, divisible, description))
+	}
+
+	// Issuances without a transfer have no destination
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, transferDestination)
+}
+
+func verifyDividend(c context.Context, tx CounterpartyTransaction, inputTotal uint64, sourceAddress string, asset string, dividendAsset string, quantityPerUnit uint64) (int64, error) {
+	if tx.MessageTypeId != Counterparty_DividendId {
+		return verificationFailed(c, fmt.Sprintf("message type %d is not a dividend", tx.MessageTypeId))
+	}
+
+	dividend, err := DecodeDividend(tx.Message)
+	if err != nil {
+		return verificationFailed(c, err.Error())
+	}
+
+	if dividend.Asset != asset || dividend.DividendAsset != dividendAsset || dividend.QuantityPerUnit != quantityPerUnit {
+		return verificationFailed(c, fmt.Sprintf("dividend of %+v, expected asset: %s, dividend asset: %s, quantity per unit: %d", dividend, asset, dividendAsset, quantityPerUnit))
+	}
+
+	return verifyOutputs(c, tx, inputTotal, sourceAddress, "")
+}
+
+// Checks the BTC movements in the transaction:
+// 1) All inputs are spent from the source address
+// 2) The destination, if any, receives the dust amount
+// 3) OP_RETURN data outputs hold no BTC and multisig data outputs hold no more than dust and are redeemable by the source address
+// 4) All remaining BTC is returned to the source address as change
+// 5) The miners fee is no more than the fee requested from counterpartyd
+func verifyOutputs(c context.Context, tx CounterpartyTransaction, inputTotal uint64, sourceAddress string, destinationAddress string) (int64, error) {
+	return verifyOutputsWithAmount(c, tx, inputTotal, sourceAddress, destinationAddress, Counterparty_DefaultDustSize)
+}
+
+// As verifyOutputs() where the destination receives the given amount rather than dust, ie a BTCpay
+func verifyOutputsWithAmount(c context.Context, tx CounterpartyTransaction, inputTotal uint64, sourceAddress string, destinationAddress string, destinationAmount uint64) (int64, error) {
+	if len(tx.Sources) == 0 {
+		return verificationFailed(c, "no inputs spent from the source address")
+	}
+
+	for i, source := range tx.Sources {
+		if source == "" {
+			return verificationFailed(c, fmt.Sprintf("input %d couldn't be resolved to an address, expected %s", i, sourceAddress))
+		}
+
+		if source != sourceAddress {
+			return verificationFailed(c, "input spent from "+source+", expected "+sourceAddress)
+		}
+	}
+
+	if tx.Destination != destinationAddress {
+		return verificationFailed(c, "destination "+tx.Destination+", expected "+destinationAddress)
+	}
+
+	if destinationAddress != "" && tx.BtcAmount != destinationAmount {
+		return verificationFailed(c, fmt.Sprintf("%d satoshis paid to destination, expected %d", tx.BtcAmount, destinationAmount))
+	}
+
+	for _, dataOutput := range tx.DataOutputs {
+		if dataOutput.PubKey == nil {
+			if dataOutput.Value != 0 {
+				return verificationFailed(c, fmt.Sprintf("%d satoshis burned in an OP_RETURN data output", dataOutput.Value))
+			}
+			continue
+		}
+
+		if dataOutput.Value > Counterparty_DefaultMultisigDustSize {
+			return verificationFailed(c, fmt.Sprintf("%d satoshis paid to a multisig data output, expected no more than %d", dataOutput.Value, Counterparty_DefaultMultisigDustSize))
+		}
+
+		// The real public key lets the source redeem the dust, anyone else's key would hand the dust to them
+		if address := pubKeyAddress(dataOutput.PubKey); address != sourceAddress {
+			return verificationFailed(c, "multisig data output redeemable by "+address+", expected "+sourceAddress)
+		}
+	}
+
+	for _, change := range tx.Change {
+		if change.Address != sourceAddress {
+			return verificationFailed(c, fmt.Sprintf("%d satoshis paid to %s, expected change to be returned to %s", change.Value, change.Address, sourceAddress))
+		}
+	}
+
+	if inputTotal < tx.OutputTotal {
+		return verificationFailed(c, fmt.Sprintf("outputs of %d satoshis exceed the inputs of %d satoshis", tx.OutputTotal, inputTotal))
+	}
+
+	if inputTotal-tx.OutputTotal > Counterparty_DefaultTxFee {
+		return verificationFailed(c, fmt.Sprintf("fee of %d satoshis exceeds the requested fee of %d satoshis", inputTotal-tx.OutputTotal, Counterparty_DefaultTxFee))
+	}
+
+	return 0, nil
+}
+
+// Returns the address of the public key, or "" if it isn't a valid public key
+func pubKeyAddress(pubKey []byte) string {
+	address, err := btcutil.NewAddressPubKey(pubKey, &chaincfg.MainNetParams)
+	if err != nil {
+		return ""
+	}
+
+	return address.AddressPubKeyHash().EncodeAddress()
+}
+
+func verificationFailed(c context.Context, reason string) (int64, error) {
+	log.FluentfContext(consts.LOGERROR, c, "Transaction composed by counterpartyd failed verification: %s", reason)
+
+	return consts.CounterpartyErrors.VerificationFailed.Code, errors.New(consts.CounterpartyErrors.VerificationFailed.Description)
+}
+
+// Parses the unsigned transaction and looks up the value and address of the outputs being spent from bitcoind. The addresses from bitcoind
+// replace the sources given by counterpartyd so that they can't be misrepresented
+func parseAndGetInputTotal(c context.Context, rawTxHexString string) (CounterpartyTransaction, uint64, int64, error) {
+	var inputTotal uint64
+
+	tx, err := ParseTransaction(rawTxHexString)
+	if err != nil {
+		code, err := verificationFailed(c, err.Error())
+		return tx, 0, code, err
+	}
+
+	msgTx, err := decodeRawTransaction(c, rawTxHexString)
+	if err != nil {
+		return tx, 0, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+	}
+
+	tx.Sources = nil
+	for _, txIn := range msgTx.TxIn {
+		prevTx, err := bitcoinapi.GetRawTransaction(txIn.PreviousOutPoint.Hash.String())
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in GetRawTransaction(): %s", err.Error())
+			return tx, 0, consts.CounterpartyErrors.MiscError.Code, errors.New(consts.CounterpartyErrors.MiscError.Description)
+		}
+
+		if int(txIn.PreviousOutPoint.Index) >= len(prevTx.Vout) {
+			code, err := verificationFailed(c, "input spends an output which doesn't exist")
+			return tx, 0, code, err
+		}
+
+		prevOut := prevTx.Vout[txIn.PreviousOutPoint.Index]
+
+		// An output which doesn't pay to a single address can't be checked against the source
+		source := ""
+		if len(prevOut.ScriptPubKey.Addresses) == 1 {
+			source = prevOut.ScriptPubKey.Addresses[0]
+		}
+		tx.Sources = append(tx.Sources, source)
+
+		// Values from bitcoind are in BTC
+		inputTotal += uint64(math.Floor(prevOut.Value*1e8 + 0.5))
+	}
+
+	return tx, inputTotal, 0, nil
+}
@@ -2,93 +2,47 @@
 package counterpartycrypto
 
 import (
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
-	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/whoisjeremylam/enu/internal/github.com/vennd/mneumonic"
 )
 
 type CounterpartyWallet struct {
-	Passphrase string   `json:"passphrase"`
-	HexSeed    string   `json:"hexSeed"`
-	Addresses  []string `json:"addresses"`
-	RequestId  string   `json:"requestId"`
+	Passphrase  string   `json:"passphrase"`
+	HexSeed     string   `json:"hexSeed"`
+	AccountPath string   `json:"accountPath"`
+	Xpub        string   `json:"xpub"`
+	Addresses   []string `json:"addresses"`
+	RequestId   string   `json:"requestId"`
 }
 
 type CounterpartyAddress struct {
-	Value      string `json:"value"`
-	PublicKey  string `json:"publicKey"`
-	PrivateKey string `json:"privateKey"`
+	Value          string `json:"value"`
+	PublicKey      string `json:"publicKey"`
+	PrivateKey     string `json:"privateKey"`
+	DerivationPath string `json:"derivationPath,omitempty"`
 }
 
 func getAddressFromPassphrase(passphrase string, position uint32) (CounterpartyAddress, error) {
-	var returnValue CounterpartyAddress
-
-	m := mneumonic.FromWords(strings.Split(passphrase, " "))
-	hexSeed := m.ToHex()
-
-	hexValue, err := hex.DecodeString(hexSeed)
-
-	if err != nil {
-		return returnValue, err
-	}
-
-	masterKey, err := hdkeychain.NewMaster(hexValue)
-	if err != nil {
-		return returnValue, err
-	}
-
-	// get m/0'/0/0
-	// Hardened key for account 0. ie 0'
-	acct0, err := masterKey.Child(hdkeychain.HardenedKeyStart + 0)
-	if err != nil {
-		return returnValue, err
-	}
-
-	// External account for 0'
-	extAcct0, err := acct0.Child(0)
-	if err != nil {
-		return returnValue, err
-	}
-
-	key, err := extAcct0.Child(uint32(position))
-	if err != nil {
-		return returnValue, err
-	}
-
-	// Get the address
-	address, err := key.Address(&chaincfg.MainNetParams)
-	if err != nil {
-		return returnValue, err
-	}
-
-	//		log.Printf("Address: %s", address)
-
-	// Get the pubkey and serialise the compressed public key
-	privKey, err := key.ECPrivKey()
-	if err != nil {
-		return returnValue, err
-	}
-
-	returnValue.Value = fmt.Sprintf("%s", address)
-	returnValue.PrivateKey = hex.EncodeToString(privKey.Serialize())
-	returnValue.PublicKey = hex.EncodeToString(privKey.PubKey().SerializeCompressed())
-
-	return returnValue, nil
+	return GetPublicPrivateKeyAtPath(passphrase, fmt.Sprintf("%s/0/%d", LegacyAccountPath, position))
 }
 
+// CreateWallet generates a new passphrase and the first addresses of the legacy Counterwallet account m/0'
 func CreateWallet(numberOfAddressesToGenerate int) (CounterpartyWallet, error) {
+	return CreateWalletForAccount(numberOfAddressesToGenerate, LegacyAccountPath)
+}
+
+// CreateWalletForAccount generates a new passphrase and the first addresses of the external chain of the given account
+func CreateWalletForAccount(numberOfAddressesToGenerate int, accountPath string) (CounterpartyWallet, error) {
 	var wallet CounterpartyWallet
 	var numAddresses int
 
 	if numberOfAddressesToGenerate <= 0 {
 		numAddresses = 20
-	} else if numberOfAddressesToGenerate > 100 {
-		numAddresses = 100
+	} else if numberOfAddressesToGenerate > MaxAddressesPerRequest {
+		numAddresses = MaxAddressesPerRequest
 	} else {
 		numAddresses = numberOfAddressesToGenerate
 	}
@@ -97,58 +51,26 @@ func CreateWallet(numberOfAddressesToGenerate int) (CounterpartyWallet, error) {
 	wallet.Passphrase = strings.Join(m.ToWords(), " ")
 	wallet.HexSeed = m.ToHex()
 
-	hexValue, err := hex.DecodeString(wallet.HexSeed)
-
+	accountKey, err := deriveAccountKey(wallet.Passphrase, accountPath)
 	if err != nil {
 		return wallet, err
 	}
 
-	masterKey, err := hdkeychain.NewMaster(hexValue)
+	xpub, err := accountKey.Neuter()
 	if err != nil {
 		return wallet, err
 	}
 
-	// get m/0'/0/0
-	// Hardened key for account 0. ie 0'
-	acct0, err := masterKey.Child(hdkeychain.HardenedKeyStart + 0)
-	if err != nil {
-		return wallet, err
-	}
+	wallet.AccountPath = accountPath
+	wallet.Xpub = xpub.String()
 
-	// External account for 0'
-	extAcct0, err := acct0.Child(0)
+	addresses, err := deriveAddresses(accountKey, accountPath, 0, uint32(numAddresses))
 	if err != nil {
 		return wallet, err
 	}
 
-	// Derive extended key (repeat this from 0 to number of addresses-1)
-	for i := 0; i <= numAddresses-1; i++ {
-		var counterpartyAddress CounterpartyAddress
-
-		key, err := extAcct0.Child(uint32(i))
-		if err != nil {
-			return wallet, err
-		}
-
-		// Get the address
-		address, err := key.Address(&chaincfg.MainNetParams)
-		if err != nil {
-			return wallet, err
-		}
-
-		//		log.Printf("Address: %s", address)
-
-		// Get the pubkey and serialise the compressed public key
-		privKey, err := key.ECPrivKey()
-		if err != nil {
-			return wallet, err
-		}
-
-		counterpartyAddress.Value = fmt.Sprintf("%s", address.String())
-		counterpartyAddress.PrivateKey = hex.EncodeToString(privKey.Serialize())
-		counterpartyAddress.PublicKey = hex.EncodeToString(privKey.PubKey().SerializeCompressed())
-
-		wallet.Addresses = append(wallet.Addresses, counterpartyAddress.Value)
+	for _, address := range addresses {
+		wallet.Addresses = append(wallet.Addresses, address.Value)
 	}
 
 	return wallet, nil
@@ -176,8 +98,7 @@ func CreateWallet(numberOfAddressesToGenerate int) (CounterpartyWallet, error) {
 //}
 
 // GetPrivateKey_Counterparty will retrieve the private key that corresponds to the address given.
-// The hierarchical master key is derived from the passphrase and then searches up to the first
-// 20 addresses for a match
+// See GetPublicPrivateKey() for how the address is found
 func GetPrivateKey(passphrase string, address string) (string, error) {
 	keys, err := GetPublicPrivateKey(passphrase, address)
 
@@ -185,17 +106,28 @@ func GetPrivateKey(passphrase string, address string) (string, error) {
 }
 
 // GetPublicKey_Counterparty will retrieve the public key that corresponds to the address given.
-// The hierarchical master key is derived from the passphrase and then searches up to the first
-// 20 addresses for a match
+// See GetPublicPrivateKey() for how the address is found
 func GetPublicKey(passphrase string, address string) (string, error) {
 	keys, err := GetPublicPrivateKey(passphrase, address)
 
 	return keys.PublicKey, err
 }
 
+// GetPublicPrivateKey retrieves the keys for the address given.
+// If the derivation path of the address has been registered with RegisterAddressPath() the keys are derived directly,
+// otherwise the hierarchical master key is derived from the passphrase and the first 20 addresses of the legacy account are searched for a match
 func GetPublicPrivateKey(passphrase string, address string) (CounterpartyAddress, error) {
 	var result CounterpartyAddress
 
+	if path, ok := GetAddressPath(address); ok {
+		generatedAddress, err := GetPublicPrivateKeyAtPath(passphrase, path)
+
+		// The address may have been registered against a different passphrase, so fall through to the search
+		if err == nil && generatedAddress.Value == address {
+			return generatedAddress, nil
+		}
+	}
+
 	for i := 0; i <= 19; i++ {
 		generatedAddress, err := getAddressFromPassphrase(passphrase, uint32(i))
 
@@ -205,11 +137,9 @@ func GetPublicPrivateKey(passphrase string, address string) (CounterpartyAddress
 		}
 
 		if generatedAddress.Value == address {
-			result.Value = address
-			result.PrivateKey = generatedAddress.PrivateKey
-			result.PublicKey = generatedAddress.PublicKey
+			RegisterAddressPath(address, generatedAddress.DerivationPath)
 
-			return result, nil
+			return generatedAddress, nil
 		}
 	}
 
@@ -0,0 +1,237 @@
+// Derivation paths, accounts and extended public keys for Counterwallet compatible HD wallets
+package counterpartycrypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/whoisjeremylam/enu/internal/github.com/vennd/mneumonic"
+)
+
+// Account used by Counterwallet and by all wallets created before accounts were supported
+const LegacyAccountPath = "m/0'"
+
+// Prefix of the paths of addresses derived from an extended public key
+const WatchOnlyAccountPath = "M"
+
+// Maximum number of addresses which will be derived in a single call
+const MaxAddressesPerRequest = 100
+
+// Index of address to derivation path so keys can be derived directly rather than searched for
+var addressPaths = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// AccountPath returns the BIP44 path for the given bitcoin account. ie m/44'/0'/account'
+func AccountPath(account uint32) string {
+	return fmt.Sprintf("m/44'/0'/%d'", account)
+}
+
+// ParseDerivationPath converts a path such as m/44'/0'/0'/0/1 into the child indexes to derive from the master key
+func ParseDerivationPath(path string) ([]uint32, error) {
+	var result []uint32
+
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return result, errors.New("Derivation path must begin with m: " + path)
+	}
+
+	for _, part := range parts[1:] {
+		var hardened bool
+
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			hardened = true
+			part = part[:len(part)-1]
+		}
+
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || index >= hdkeychain.HardenedKeyStart {
+			return result, errors.New("Invalid index in derivation path: " + path)
+		}
+
+		if hardened {
+			index += hdkeychain.HardenedKeyStart
+		}
+
+		result = append(result, uint32(index))
+	}
+
+	return result, nil
+}
+
+// GetPublicPrivateKeyAtPath derives the address and keys at the full derivation path given
+func GetPublicPrivateKeyAtPath(passphrase string, path string) (CounterpartyAddress, error) {
+	var result CounterpartyAddress
+
+	key, err := deriveAccountKey(passphrase, path)
+	if err != nil {
+		return result, err
+	}
+
+	return addressFromKey(key, path)
+}
+
+// DeriveAddresses derives count addresses on the external chain of the account, starting at the given index
+func DeriveAddresses(passphrase string, accountPath string, start uint32, count uint32) ([]CounterpartyAddress, error) {
+	var result []CounterpartyAddress
+
+	accountKey, err := deriveAccountKey(passphrase, accountPath)
+	if err != nil {
+		return result, err
+	}
+
+	return deriveAddresses(accountKey, accountPath, start, count)
+}
+
+// ExportXpub returns the extended public key of the account, which can derive the addresses of the account but not sign for them
+func ExportXpub(passphrase string, accountPath string) (string, error) {
+	accountKey, err := deriveAccountKey(passphrase, accountPath)
+	if err != nil {
+		return "", err
+	}
+
+	xpub, err := accountKey.Neuter()
+	if err != nil {
+		return "", err
+	}
+
+	return xpub.String(), nil
+}
+
+// DeriveAddressesFromXpub derives count addresses on the external chain of a watch-only account, starting at the given index.
+// Paths are relative to the account and begin with M as the position of the account key isn't known
+func DeriveAddressesFromXpub(xpub string, start uint32, count uint32) ([]CounterpartyAddress, error) {
+	var result []CounterpartyAddress
+
+	accountKey, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return result, err
+	}
+
+	if accountKey.IsPrivate() {
+		return result, errors.New("An extended public key must be given rather than a private key")
+	}
+
+	if accountKey.IsForNet(&chaincfg.MainNetParams) == false {
+		return result, errors.New("The extended public key is not for the bitcoin main network")
+	}
+
+	return deriveAddresses(accountKey, WatchOnlyAccountPath, start, count)
+}
+
+// RegisterAddressPath records the derivation path of an address so signing doesn't need to search for it
+func RegisterAddressPath(address string, path string) {
+	if address == "" || path == "" {
+		return
+	}
+
+	addressPaths.Lock()
+	defer addressPaths.Unlock()
+
+	addressPaths.m[address] = path
+}
+
+// GetAddressPath returns the derivation path previously registered for the address
+func GetAddressPath(address string) (string, bool) {
+	addressPaths.RLock()
+	defer addressPaths.RUnlock()
+
+	path, ok := addressPaths.m[address]
+
+	return path, ok
+}
+
+// Derives the extended private key at the path from the master key of the passphrase
+func deriveAccountKey(passphrase string, path string) (*hdkeychain.ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := mneumonic.FromWords(strings.Split(passphrase, " "))
+
+	hexValue, err := hex.DecodeString(m.ToHex())
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hdkeychain.NewMaster(hexValue)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// Derives addresses on the external chain (ie /0/i) of the account key. Private keys are only populated if the account key is private
+func deriveAddresses(accountKey *hdkeychain.ExtendedKey, accountPath string, start uint32, count uint32) ([]CounterpartyAddress, error) {
+	var result []CounterpartyAddress
+
+	if count > MaxAddressesPerRequest {
+		return result, fmt.Errorf("A maximum of %d addresses may be derived at once", MaxAddressesPerRequest)
+	}
+
+	external, err := accountKey.Child(0)
+	if err != nil {
+		return result, err
+	}
+
+	for i := start; i < start+count; i++ {
+		key, err := external.Child(i)
+		if err != nil {
+			return result, err
+		}
+
+		address, err := addressFromKey(key, fmt.Sprintf("%s/0/%d", accountPath, i))
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, address)
+	}
+
+	return result, nil
+}
+
+func addressFromKey(key *hdkeychain.ExtendedKey, path string) (CounterpartyAddress, error) {
+	var result CounterpartyAddress
+
+	address, err := key.Address(&chaincfg.MainNetParams)
+	if err != nil {
+		return result, err
+	}
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return result, err
+	}
+
+	result.Value = address.String()
+	result.PublicKey = hex.EncodeToString(pubKey.SerializeCompressed())
+	result.DerivationPath = path
+
+	if key.IsPrivate() {
+		privKey, err := key.ECPrivKey()
+		if err != nil {
+			return result, err
+		}
+
+		result.PrivateKey = hex.EncodeToString(privKey.Serialize())
+	}
+
+	return result, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/whoisjeremylam/enu/bitcoinapi"
 	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
 	"github.com/whoisjeremylam/enu/database"
 	"github.com/whoisjeremylam/enu/enulib"
 	"github.com/whoisjeremylam/enu/handlers"
@@ -47,3 +48,18 @@ func AddressCreate(c context.Context, w http.ResponseWriter, r *http.Request, m
 	return nil
 
 }
+
+// LoadAddressPaths populates the index of derived addresses used when signing from the database
+func LoadAddressPaths(c context.Context) error {
+	paths, err := database.GetAddressPaths(c)
+	if err != nil {
+		return err
+	}
+
+	for address, path := range paths {
+		counterpartycrypto.RegisterAddressPath(address, path)
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Loaded derivation paths for %d addresses", len(paths))
+
+	return nil
+}
@@ -80,7 +80,7 @@ func AssetCreate(c context.Context, w http.ResponseWriter, r *http.Request, m ma
 // Concurrency safe to create and send transactions from a single address.
 func delegatedCreateIssuance(c context.Context, accessKey string, passphrase string, sourceAddress string, assetId string, asset string, assetDescription string, quantity uint64, divisible bool) (string, int64, error) {
 	// Write the asset with the generated asset id to the database
-	go database.InsertAsset(accessKey, c.Value(consts.BlockchainIdKey).(string), assetId, sourceAddress, "", asset, assetDescription, quantity, divisible, "valid")
+	go database.InsertAsset(c, accessKey, c.Value(consts.BlockchainIdKey).(string), assetId, sourceAddress, "", asset, assetDescription, quantity, divisible, "valid")
 
 	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
 	if err != nil {
@@ -120,6 +120,14 @@ func delegatedCreateIssuance(c context.Context, accessKey string, passphrase str
 	log.FluentfContext(consts.LOGINFO, c, "Created issuance of %d %s (%s) at %s: %s\n", quantity, asset, assetDescription, sourceAddress, createResult)
 	//	database.UpdateAssetNameByAssetId(c, accessKey, assetId, asset)
 
+	// Check the transaction composed by counterpartyd matches the request before signing
+	errCode, err = counterpartyapi.VerifyIssuance(c, createResult, sourceAddress, asset, assetDescription, quantity, divisible)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyIssuance(): %s", err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, errCode, err.Error())
+		return "", errCode, err
+	}
+
 	// Sign the transactions
 	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
 	if err != nil {
@@ -141,6 +149,8 @@ func delegatedCreateIssuance(c context.Context, accessKey string, passphrase str
 
 	database.UpdateAssetCompleteByAssetId(c, accessKey, assetId, txIdSignedTx)
 
+	chargeFee(c, enulib.FeeIssuance, assetId, asset, quantity)
+
 	return txIdSignedTx, 0, nil
 }
 
@@ -206,7 +216,7 @@ func DividendCreate(c context.Context, w http.ResponseWriter, r *http.Request, m
 // Concurrency safe to create and send transactions from a single address.
 func delegatedCreateDividend(c context.Context, accessKey string, passphrase string, dividendId string, sourceAddress string, asset string, dividendAsset string, quantityPerUnit uint64) (string, int64, error) {
 	// Write the dividend with the generated dividend id to the database
-	go database.InsertDividend(accessKey, dividendId, sourceAddress, asset, dividendAsset, quantityPerUnit, "valid")
+	go database.InsertDividend(c, accessKey, dividendId, sourceAddress, asset, dividendAsset, quantityPerUnit, "valid")
 
 	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
 	if err != nil {
@@ -245,6 +255,14 @@ func delegatedCreateDividend(c context.Context, accessKey string, passphrase str
 
 	log.FluentfContext(consts.LOGINFO, c, "Created dividend of %d %s for each %s from address %s: %s\n", quantityPerUnit, dividendAsset, asset, sourceAddress, createResult)
 
+	// Check the transaction composed by counterpartyd matches the request before signing
+	errorCode, err = counterpartyapi.VerifyDividend(c, createResult, sourceAddress, asset, dividendAsset, quantityPerUnit)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyDividend(): %s", err.Error())
+		database.UpdateDividendWithErrorByDividendId(c, accessKey, dividendId, errorCode, err.Error())
+		return "", errorCode, err
+	}
+
 	// Sign the transactions
 	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
 	if err != nil {
@@ -265,6 +283,8 @@ func delegatedCreateDividend(c context.Context, accessKey string, passphrase str
 
 	database.UpdateDividendCompleteByDividendId(c, accessKey, dividendId, txIdSignedTx)
 
+	chargeFee(c, enulib.FeeDividend, dividendId, dividendAsset, quantityPerUnit)
+
 	return txIdSignedTx, 0, nil
 }
 
@@ -359,18 +379,56 @@ func AssetLedger(c context.Context, w http.ResponseWriter, r *http.Request, m ma
 		return nil
 	}
 
-	log.FluentfContext(consts.LOGINFO, c, "AssetLedger: received request asset: %s from accessKey: %s\n", asset, c.Value(consts.AccessKeyKey).(string))
+	log.FluentfContext(consts.LOGINFO, c, "AssetLedger: received request asset: %s, block: %s from accessKey: %s\n", asset, r.URL.Query().Get("block"), c.Value(consts.AccessKeyKey).(string))
 
-	result, errorCode, err := counterpartyapi.GetBalancesByAsset(c, asset)
-	if err != nil {
-		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
-		return nil
-	}
+	var result []counterpartyapi.Balance
+	var resultIssuances []counterpartyapi.Issuance
 
-	resultIssuances, errorCode, err := counterpartyapi.GetIssuances(c, asset)
-	if err != nil {
-		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
-		return nil
+	// The block query parameter returns the holders from the registry snapshot at that height instead of the live balances
+	if r.URL.Query().Get("block") != "" {
+		blockId, ok := parseRegistryBlock(c, w, r, "block")
+		if !ok {
+			return nil
+		}
+
+		_, holdings, errorCode, err := registrySnapshot(c, asset, blockId, enulib.SnapshotRequest)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+			return nil
+		}
+
+		for _, holding := range holdings {
+			result = append(result, counterpartyapi.Balance{Address: holding.Address, Asset: asset, Quantity: holding.Quantity})
+		}
+
+		issuances, errorCode, err := counterpartyapi.GetIssuances(c, asset)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+			return nil
+		}
+
+		for _, issuance := range issuances {
+			if issuance.BlockIndex <= blockId {
+				resultIssuances = append(resultIssuances, issuance)
+			}
+		}
+
+		assetBalances.BlockId = blockId
+	} else {
+		var errorCode int64
+		var err error
+
+		result, errorCode, err = counterpartyapi.GetBalancesByAsset(c, asset)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+			return nil
+		}
+
+		resultIssuances, errorCode, err = counterpartyapi.GetIssuances(c, asset)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+			return nil
+		}
 	}
 
 	// Summarise asset information
@@ -419,7 +477,7 @@ func AssetLedger(c context.Context, w http.ResponseWriter, r *http.Request, m ma
 		assetBalances.Balances = append(assetBalances.Balances, balance)
 	}
 
-	if err = json.NewEncoder(w).Encode(assetBalances); err != nil {
+	if err := json.NewEncoder(w).Encode(assetBalances); err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
 		handlers.ReturnServerError(c, w)
 
@@ -0,0 +1,226 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Operations on an existing asset, recorded in the operation column of the assets table
+const (
+	assetOperationReissue     = "reissue"
+	assetOperationLock        = "lock"
+	assetOperationTransfer    = "transfer"
+	assetOperationDescription = "description"
+)
+
+// Issues additional supply of an existing asset. The asset must not be locked
+func AssetReissue(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	quantity := uint64(m["quantity"].(float64))
+
+	return assetOperation(c, w, m, assetOperationReissue, quantity, "", "")
+}
+
+// Locks the supply of an asset so that no further units can be issued
+func AssetLock(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	return assetOperation(c, w, m, assetOperationLock, 0, "", "")
+}
+
+// Transfers ownership of an asset to the destination address
+func AssetTransfer(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	destinationAddress := m["destinationAddress"].(string)
+
+	return assetOperation(c, w, m, assetOperationTransfer, 0, "", destinationAddress)
+}
+
+// Changes the description of an asset
+func AssetDescription(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	description := m["description"].(string)
+
+	return assetOperation(c, w, m, assetOperationDescription, 0, description, "")
+}
+
+// Checks the source address is the current issuer of the asset, returns the assetId to the client and performs the operation in async mode
+func assetOperation(c context.Context, w http.ResponseWriter, m map[string]interface{}, operation string, quantity uint64, description string, destinationAddress string) *enulib.AppError {
+	var assetStruct enulib.Asset
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	assetStruct.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	asset := m["asset"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "AssetOperation: received %s request sourceAddress: %s, asset: %s, quantity: %d, description: %s, destinationAddress: %s from accessKey: %s\n", operation, sourceAddress, asset, quantity, description, destinationAddress, accessKey)
+
+	_, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPublicKey(): %s\n", err)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	assetState, errorCode, err := counterpartyapi.GetAssetState(c, asset)
+	if err != nil {
+		if errorCode == consts.CounterpartyErrors.NoSuchAsset.Code {
+			handlers.ReturnNotFoundWithCustomError(c, w, errorCode, err.Error())
+		} else {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+		}
+
+		return nil
+	}
+
+	// Counterparty only accepts changes to an asset from its current issuer
+	if assetState.Issuer != sourceAddress {
+		log.FluentfContext(consts.LOGERROR, c, "%s is not the issuer of %s. Issuer: %s", sourceAddress, asset, assetState.Issuer)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.OnlyIssuerCanModifyAsset.Code, consts.CounterpartyErrors.OnlyIssuerCanModifyAsset.Description)
+
+		return nil
+	}
+
+	if operation == assetOperationReissue && assetState.Locked {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to reissue %s, the asset is locked", asset)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.AssetLocked.Code, consts.CounterpartyErrors.AssetLocked.Description)
+
+		return nil
+	}
+
+	// Only a change of description replaces the current description
+	if operation != assetOperationDescription {
+		description = assetState.Description
+	}
+
+	// Generate an assetId
+	assetId := enulib.GenerateAssetId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated assetId: %s", assetId)
+	assetStruct.AssetId = assetId
+	assetStruct.Operation = operation
+	assetStruct.Asset = asset
+	assetStruct.Description = description
+	assetStruct.Quantity = quantity
+	assetStruct.Divisible = assetState.Divisible
+	assetStruct.SourceAddress = sourceAddress
+	assetStruct.DistributionAddress = destinationAddress
+	assetStruct.BlockchainId = consts.CounterpartyBlockchainId
+
+	// Return to the client the assetId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(assetStruct); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedAssetOperation(c, accessKey, passphrase, assetId, operation, sourceAddress, destinationAddress, asset, description, quantity, assetState.Divisible)
+
+	return nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedAssetOperation(c context.Context, accessKey string, passphrase string, assetId string, operation string, sourceAddress string, destinationAddress string, asset string, description string, quantity uint64, divisible bool) (string, int64, error) {
+	// Write the operation with the generated asset id to the database
+	err := database.InsertAssetOperation(c, accessKey, c.Value(consts.BlockchainIdKey).(string), assetId, operation, sourceAddress, destinationAddress, asset, description, quantity, divisible, "valid")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in InsertAssetOperation(): %s", err.Error())
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error with GetPublicKey(): %s", err)
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.InvalidPassphrase.Code, errors.New(consts.CounterpartyErrors.InvalidPassphrase.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s\n", sourceAddress)
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	counterparty_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping")
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+3000) * time.Millisecond)
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	// Compose the issuance which performs the operation
+	var createResult string
+	var errCode int64
+	switch operation {
+	case assetOperationLock:
+		createResult, errCode, err = counterpartyapi.CreateLock(c, sourceAddress, asset, divisible, sourceAddressPubKey)
+	case assetOperationTransfer:
+		createResult, errCode, err = counterpartyapi.CreateTransfer(c, sourceAddress, asset, description, divisible, destinationAddress, sourceAddressPubKey)
+	default:
+		createResult, errCode, err = counterpartyapi.CreateIssuance(c, sourceAddress, asset, description, quantity, divisible, sourceAddressPubKey)
+	}
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error composing %s of %s: %s", operation, asset, err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Created %s of %s at %s: %s\n", operation, asset, sourceAddress, createResult)
+
+	// Check the transaction composed by counterpartyd matches the request before signing
+	switch operation {
+	case assetOperationLock:
+		errCode, err = counterpartyapi.VerifyIssuance(c, createResult, sourceAddress, asset, counterpartyapi.Counterparty_LockDescription, 0, divisible)
+	case assetOperationTransfer:
+		errCode, err = counterpartyapi.VerifyTransfer(c, createResult, sourceAddress, asset, description, divisible, destinationAddress)
+	default:
+		errCode, err = counterpartyapi.VerifyIssuance(c, createResult, sourceAddress, asset, description, quantity, divisible)
+	}
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error verifying %s of %s: %s", operation, asset, err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	// Sign the transactions
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransaction(): %s", err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Signed tx: %s\n", signed)
+
+	//	 Transmit the transaction
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdateAssetCompleteByAssetId(c, accessKey, assetId, txIdSignedTx)
+
+	return txIdSignedTx, 0, nil
+}
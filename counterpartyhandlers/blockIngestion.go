@@ -0,0 +1,342 @@
+package counterpartyhandlers
+
+import (
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/btcjson"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// How often counterpartyd is checked for new blocks
+var counterparty_BlockPollRate = 60000 // milliseconds
+
+// How often bitcoind is checked for new blocks
+var bitcoin_BlockPollRate = 60000 // milliseconds
+
+// Records the credits and debits of the addresses owned by access keys for each new block parsed by counterpartyd.
+// Processing starts from the current block the first time Enu is started, history before then isn't ingested.
+// The activity of blocks which are reorganised out of the chain is removed and ingested again. Runs until Enu is stopped
+func IngestBlocks(c context.Context) {
+	c = context.WithValue(c, consts.BlockchainIdKey, consts.CounterpartyBlockchainId)
+
+	for {
+		time.Sleep(time.Duration(counterparty_BlockPollRate) * time.Millisecond)
+
+		// Only blocks which counterpartyd has parsed can be processed
+		runningInfo, _, err := counterpartyapi.GetRunningInfo(c)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in counterpartyapi.GetRunningInfo(): %s", err.Error())
+			continue
+		}
+
+		addresses, err := database.GetAddressesByBlockchainId(c, consts.CounterpartyBlockchainId)
+		if err != nil {
+			continue
+		}
+
+		processor := handlers.BlockProcessor{
+			BlockchainId: consts.CounterpartyBlockchainId,
+			GetBlockHash: func(c context.Context, blockId int64) (string, error) {
+				block, _, err := counterpartyapi.GetBlockInfo(c, uint64(blockId))
+				return block.BlockHash, err
+			},
+			Process: func(c context.Context, blockId int64) error {
+				return ingestBlock(c, blockId, addresses)
+			},
+			Rollback: rollbackBlock,
+		}
+
+		handlers.ProcessBlocks(c, processor, int64(runningInfo.LastBlock.BlockIndex))
+	}
+}
+
+// Records the hash of each new block of bitcoind so that how far bitcoind is behind and reorgs of its chain can be reported.
+// Runs until Enu is stopped
+func ProcessBitcoinBlocks(c context.Context) {
+	c = context.WithValue(c, consts.BlockchainIdKey, consts.CounterpartyBlockchainId)
+
+	processor := handlers.BlockProcessor{
+		BlockchainId: consts.BitcoinBlockchainId,
+		GetBlockHash: bitcoinapi.GetBlockHash,
+	}
+
+	for {
+		time.Sleep(time.Duration(bitcoin_BlockPollRate) * time.Millisecond)
+
+		blockCount, err := bitcoinapi.GetBlockCount()
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in bitcoinapi.GetBlockCount(): %s", err.Error())
+			continue
+		}
+
+		handlers.ProcessBlocks(c, processor, blockCount)
+	}
+}
+
+// Removes the activity of a block which is no longer in the chain along with the deposits in it and their gateway transfers
+func rollbackBlock(c context.Context, blockId int64) error {
+	if err := database.DeleteBlockActivity(c, blockId); err != nil {
+		return err
+	}
+
+	reorged, err := database.RollbackCounterpartyGatewayDeposits(c, blockId)
+	if err != nil {
+		return err
+	}
+	if reorged > 0 {
+		log.FluentfContext(consts.LOGERROR, c, "%d gateway transfers of block %d were paid out before the deposit was reorganised out of the chain", reorged, blockId)
+	}
+
+	swept, journaled, err := database.RollbackDeposits(c, blockId)
+	if err != nil {
+		return err
+	}
+	if swept > 0 {
+		log.FluentfContext(consts.LOGERROR, c, "%d deposits of block %d were swept before they were reorganised out of the chain", swept, blockId)
+	}
+	if journaled > 0 {
+		log.FluentfContext(consts.LOGERROR, c, "%d deposit journals reference transactions of block %d which was reorganised out of the chain", journaled, blockId)
+	}
+
+	return nil
+}
+
+// Records the BTC and Counterparty asset credits and debits in the block of the monitored addresses, along with the transactions which caused them
+func ingestBlock(c context.Context, blockId int64, monitored map[string][]string) error {
+	if err := database.DeleteBlockActivity(c, blockId); err != nil {
+		return err
+	}
+
+	credits, _, err := counterpartyapi.GetCreditsByBlock(c, uint64(blockId))
+	if err != nil {
+		return err
+	}
+
+	debits, _, err := counterpartyapi.GetDebitsByBlock(c, uint64(blockId))
+	if err != nil {
+		return err
+	}
+
+	sends, _, err := counterpartyapi.GetSendsByBlock(c, uint64(blockId))
+	if err != nil {
+		return err
+	}
+
+	// Transactions which touched a monitored address on Counterparty
+	relevant := make(map[string]bool)
+	for _, credit := range credits {
+		if monitored[credit.Address] != nil {
+			relevant[credit.Event] = true
+		}
+	}
+	for _, debit := range debits {
+		if monitored[debit.Address] != nil {
+			relevant[debit.Event] = true
+		}
+	}
+
+	_, txIds, err := bitcoinapi.GetBlockTransactions(c, blockId)
+	if err != nil {
+		return err
+	}
+
+	transactions := make(transactionCache)
+
+	// Only outputs of recorded transactions are checked for spends, so they are looked up once for the whole block
+	recorded, err := getRecordedInputs(c, transactions, txIds)
+	if err != nil {
+		return err
+	}
+
+	// Input addresses of the relevant transactions, used as the sender of credits which weren't made by a send
+	inputs := make(map[string][]string)
+
+	for _, txId := range txIds {
+		txInputs, err := ingestBitcoinTransaction(c, blockId, txId, monitored, relevant[txId], transactions, recorded)
+		if err != nil {
+			return err
+		}
+
+		if txInputs != nil {
+			inputs[txId] = txInputs
+		}
+	}
+
+	sendsByTxId := make(map[string]counterpartyapi.ResultGetSends)
+	for _, send := range sends {
+		sendsByTxId[send.TxHash] = send
+	}
+
+	for _, credit := range credits {
+		if monitored[credit.Address] == nil {
+			continue
+		}
+
+		sourceAddress := sendsByTxId[credit.Event].Source
+		if sourceAddress == "" && len(inputs[credit.Event]) > 0 {
+			sourceAddress = inputs[credit.Event][0]
+		}
+
+		if err := database.InsertCredit(c, blockId, credit.Event, sourceAddress, credit.Address, credit.Asset, credit.Quantity, "valid"); err != nil {
+			return err
+		}
+	}
+
+	for _, debit := range debits {
+		if monitored[debit.Address] == nil {
+			continue
+		}
+
+		if err := database.InsertDebit(c, blockId, debit.Event, debit.Address, sendsByTxId[debit.Event].Destination, debit.Asset, debit.Quantity, "valid"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Transactions fetched from bitcoind while a block is ingested, keyed by txid. Transactions of a block often spend the outputs of
+// transactions earlier in the same block, and the inputs of a recorded transaction are read both for spends and for its input addresses
+type transactionCache map[string]*btcjson.TxRawResult
+
+func (t transactionCache) get(txId string) (*btcjson.TxRawResult, error) {
+	if tx, ok := t[txId]; ok {
+		return tx, nil
+	}
+
+	tx, err := bitcoinapi.GetRawTransaction(txId)
+	if err != nil {
+		return nil, err
+	}
+	t[txId] = tx
+
+	return tx, nil
+}
+
+// Fetches the transactions of the block and returns which of the transactions they spend from have been recorded
+func getRecordedInputs(c context.Context, transactions transactionCache, txIds []string) (map[string]bool, error) {
+	var previousTxIds []string
+
+	for _, txId := range txIds {
+		tx, err := transactions.get(txId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, vin := range tx.Vin {
+			if vin.IsCoinBase() == false {
+				previousTxIds = append(previousTxIds, vin.Txid)
+			}
+		}
+	}
+
+	return database.GetRecordedTransactions(c, previousTxIds)
+}
+
+// Records the BTC paid to and spent by the monitored addresses in the transaction.
+// Only outputs of transactions which were previously recorded are checked for spends, so BTC received before ingestion started isn't debited.
+// If the transaction is relevant or touched a monitored address it is recorded and the addresses of its inputs are returned
+func ingestBitcoinTransaction(c context.Context, blockId int64, txId string, monitored map[string][]string, relevant bool, transactions transactionCache, recorded map[string]bool) ([]string, error) {
+	tx, err := transactions.get(txId)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := bitcoinapi.GetOutputAddresses(tx)
+
+	// Find the monitored addresses which were paid
+	var outputAddresses []string
+	var received []bitcoinapi.OutputAddress
+	for _, vout := range tx.Vout {
+		output, ok := outputs[vout.N]
+		if ok == false {
+			continue
+		}
+
+		outputAddresses = append(outputAddresses, output.Address)
+
+		if monitored[output.Address] != nil {
+			received = append(received, output)
+		}
+	}
+
+	// Find the monitored addresses which spent previously recorded outputs
+	spent := make(map[string]uint64)
+	for _, vin := range tx.Vin {
+		if vin.IsCoinBase() || recorded[vin.Txid] == false {
+			continue
+		}
+
+		previousTx, err := transactions.get(vin.Txid)
+		if err != nil {
+			return nil, err
+		}
+
+		if output, ok := bitcoinapi.GetOutputAddresses(previousTx)[vin.Vout]; ok && monitored[output.Address] != nil {
+			spent[output.Address] += output.Value
+		}
+	}
+
+	if relevant == false && len(received) == 0 && len(spent) == 0 {
+		return nil, nil
+	}
+
+	// The addresses of the inputs are only known from the outputs they spend
+	var inputAddresses []string
+	for _, vin := range tx.Vin {
+		if vin.IsCoinBase() {
+			continue
+		}
+
+		previousTx, err := transactions.get(vin.Txid)
+		if err != nil {
+			return nil, err
+		}
+
+		if output, ok := bitcoinapi.GetOutputAddresses(previousTx)[vin.Vout]; ok {
+			inputAddresses = append(inputAddresses, output.Address)
+		}
+	}
+
+	if err := database.InsertTransaction(c, blockId, txId, inputAddresses, outputAddresses); err != nil {
+		return nil, err
+	}
+	recorded[txId] = true
+
+	var sourceAddress string
+	if len(inputAddresses) > 0 {
+		sourceAddress = inputAddresses[0]
+	}
+
+	for _, output := range received {
+		if err := database.InsertCredit(c, blockId, txId, sourceAddress, output.Address, "BTC", output.Value, "valid"); err != nil {
+			return nil, err
+		}
+	}
+
+	for address, value := range spent {
+		// The recipient is the first output which isn't change
+		var destinationAddress string
+		for _, output := range outputAddresses {
+			if output != address {
+				destinationAddress = output
+				break
+			}
+		}
+
+		if err := database.InsertDebit(c, blockId, txId, address, destinationAddress, "BTC", value, "valid"); err != nil {
+			return nil, err
+		}
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Recorded transaction %s in block %d", txId, blockId)
+
+	return inputAddresses, nil
+}
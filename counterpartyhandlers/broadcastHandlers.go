@@ -0,0 +1,245 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/validation"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Publishes a broadcast from the source address, eg an oracle price or an announcement to asset holders
+func BroadcastCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var broadcast enulib.Broadcast
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	broadcast.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	text := m["text"].(string)
+
+	var value float64
+	if m["value"] != nil {
+		value = m["value"].(float64)
+	}
+
+	var feeFraction float64
+	if m["feeFraction"] != nil {
+		feeFraction = m["feeFraction"].(float64)
+	}
+
+	// Default to the time the request was received
+	timestamp := uint32(time.Now().Unix())
+	if m["timestamp"] != nil {
+		timestamp = uint32(m["timestamp"].(float64))
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "BroadcastCreate: received request sourceAddress: %s, text: %s, value: %f, feeFraction: %f, timestamp: %d from accessKey: %s\n", sourceAddress, text, value, feeFraction, timestamp, accessKey)
+
+	_, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPublicKey(): %s\n", err)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	// Generate a broadcastId
+	broadcastId := enulib.GenerateBroadcastId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated broadcastId: %s", broadcastId)
+	broadcast.BroadcastId = broadcastId
+	broadcast.SourceAddress = sourceAddress
+	broadcast.Text = text
+	broadcast.Value = value
+	broadcast.FeeFraction = feeFraction
+	broadcast.Timestamp = uint64(timestamp)
+	broadcast.Status = "valid"
+	broadcast.BlockchainId = consts.CounterpartyBlockchainId
+
+	// Return to the client the broadcastId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(broadcast); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedCreateBroadcast(c, accessKey, passphrase, broadcastId, sourceAddress, text, value, feeFraction, timestamp)
+
+	return nil
+}
+
+// Returns the status of a broadcast made through Enu
+func GetBroadcast(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	broadcastId := vars["broadcastId"]
+
+	if broadcastId == "" || len(broadcastId) < 16 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid broadcastId")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBroadcastId.Code, consts.GenericErrors.InvalidBroadcastId.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "GetBroadcast called for '%s' by '%s'\n", broadcastId, c.Value(consts.AccessKeyKey).(string))
+
+	broadcast, err := database.GetBroadcastByBroadcastId(c, c.Value(consts.AccessKeyKey).(string), broadcastId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if broadcast.BroadcastId == "" {
+		handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidBroadcastId.Code, consts.GenericErrors.InvalidBroadcastId.Description)
+
+		return nil
+	}
+	broadcast.RequestId = requestId
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(broadcast); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the valid broadcasts published on chain by the address
+func BroadcastsByAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var broadcasts enulib.Broadcasts
+	requestId := c.Value(consts.RequestIdKey).(string)
+	broadcasts.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	address := vars["address"]
+
+	if validation.IsValidBitcoinAddress(address, validation.BitcoinNetwork) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "BroadcastsByAddress: received request address: %s from accessKey: %s\n", address, c.Value(consts.AccessKeyKey).(string))
+
+	result, errorCode, err := counterpartyapi.GetBroadcastsByAddress(c, address)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	broadcasts.Address = address
+	broadcasts.BlockchainId = consts.CounterpartyBlockchainId
+	broadcasts.Broadcasts = []enulib.Broadcast{}
+	for _, b := range result {
+		broadcasts.Broadcasts = append(broadcasts.Broadcasts, enulib.Broadcast{SourceAddress: b.Source, Text: b.Text, Value: b.Value, FeeFraction: float64(b.FeeFractionInt) / 1e8, Timestamp: b.Timestamp, TxHash: b.TxHash, BlockIndex: b.BlockIndex, Locked: b.Locked != 0, Status: b.Status})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(broadcasts); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedCreateBroadcast(c context.Context, accessKey string, passphrase string, broadcastId string, sourceAddress string, text string, value float64, feeFraction float64, timestamp uint32) (string, int64, error) {
+	// Write the broadcast with the generated broadcast id to the database
+	err := database.InsertBroadcast(c, accessKey, broadcastId, consts.CounterpartyBlockchainId, sourceAddress, text, value, feeFraction, uint64(timestamp), "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error with GetPublicKey(): %s", err)
+		database.UpdateBroadcastWithErrorByBroadcastId(c, accessKey, broadcastId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.InvalidPassphrase.Code, errors.New(consts.CounterpartyErrors.InvalidPassphrase.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s\n", sourceAddress)
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	counterparty_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping")
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+3000) * time.Millisecond)
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	createResult, errCode, err := counterpartyapi.CreateBroadcast(c, sourceAddress, text, value, feeFraction, timestamp, sourceAddressPubKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in CreateBroadcast(): %s", err.Error())
+		database.UpdateBroadcastWithErrorByBroadcastId(c, accessKey, broadcastId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Created broadcast of '%s' value %f at %s: %s\n", text, value, sourceAddress, createResult)
+
+	// Check the transaction composed by counterpartyd matches the request before signing
+	errCode, err = counterpartyapi.VerifyBroadcast(c, createResult, sourceAddress, text, value, feeFraction, timestamp)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyBroadcast(): %s", err.Error())
+		database.UpdateBroadcastWithErrorByBroadcastId(c, accessKey, broadcastId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransaction(): %s", err.Error())
+		database.UpdateBroadcastWithErrorByBroadcastId(c, accessKey, broadcastId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Signed tx: %s\n", signed)
+
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+		database.UpdateBroadcastWithErrorByBroadcastId(c, accessKey, broadcastId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdateBroadcastCompleteByBroadcastId(c, accessKey, broadcastId, txIdSignedTx)
+
+	return txIdSignedTx, 0, nil
+}
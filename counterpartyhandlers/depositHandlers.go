@@ -0,0 +1,344 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// How often deposits received by deposit addresses are credited to customers
+var counterparty_DepositPollRate = 60000 // milliseconds
+
+// Deposit addresses are derived one at a time so each index of the deposit wallet is only used once
+var counterparty_DepositAddressMutex sync.Mutex
+
+// Returns the deposit address of the customer, deriving a new address from the deposit wallet the first time the customer reference is seen
+func DepositAddressCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	customerReference := m["customerReference"].(string)
+
+	wallet, ok := counterpartyapi.GetDepositWallet()
+	if ok == false {
+		log.FluentfContext(consts.LOGERROR, c, "Deposit address requested but no deposit wallet is configured")
+		handlers.ReturnServerErrorWithCustomError(c, w, consts.CounterpartyErrors.DepositWalletUnavailable.Code, consts.CounterpartyErrors.DepositWalletUnavailable.Description)
+
+		return nil
+	}
+
+	counterparty_DepositAddressMutex.Lock()
+	defer counterparty_DepositAddressMutex.Unlock()
+
+	// A customer only ever has one deposit address
+	depositAddress, err := database.GetDepositAddress(c, accessKey, customerReference)
+	if err == nil {
+		depositAddress.RequestId = requestId
+
+		w.WriteHeader(http.StatusOK)
+		if err = json.NewEncoder(w).Encode(depositAddress); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+		}
+
+		return nil
+	}
+	if err.Error() != consts.SqlNotFound {
+		log.FluentfContext(consts.LOGERROR, c, "Error in GetDepositAddress(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	index, err := database.GetNextDepositAddressIndex(c)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	xpub, err := counterpartycrypto.ExportXpub(wallet.Passphrase, wallet.AccountPath)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ExportXpub(): %s", err.Error())
+		handlers.ReturnServerErrorWithCustomError(c, w, consts.CounterpartyErrors.DepositWalletUnavailable.Code, consts.CounterpartyErrors.DepositWalletUnavailable.Description)
+
+		return nil
+	}
+
+	addresses, err := counterpartycrypto.DeriveAddresses(wallet.Passphrase, wallet.AccountPath, index, 1)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DeriveAddresses(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+	address := addresses[0]
+
+	if err := database.InsertDepositAddress(c, accessKey, customerReference, consts.CounterpartyBlockchainId, address.Value, address.DerivationPath, index); err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	// The address is also owned by the access key so it appears in the transaction feed and can be signed for when swept
+	recordDerivedAddress(c, address.Value, xpub, address.DerivationPath, int64(index))
+	log.FluentfContext(consts.LOGINFO, c, "Derived deposit address %s at %s for customer %s of access key: %s", address.Value, address.DerivationPath, customerReference, accessKey)
+
+	depositAddress = enulib.DepositAddress{CustomerReference: customerReference, Address: address.Value, RequestId: requestId, BlockchainId: consts.CounterpartyBlockchainId}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(depositAddress); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the deposit address of the customer along with the deposits credited to the customer and the total of each asset
+func CustomerDeposits(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.CustomerDeposits
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	vars := mux.Vars(r)
+	customerReference := vars["customerReference"]
+
+	depositAddress, err := database.GetDepositAddress(c, accessKey, customerReference)
+	if err != nil {
+		if err.Error() == consts.SqlNotFound {
+			handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidCustomerRef.Code, consts.GenericErrors.InvalidCustomerRef.Description)
+		} else {
+			log.FluentfContext(consts.LOGERROR, c, "Error in GetDepositAddress(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+		}
+
+		return nil
+	}
+
+	balances, err := database.GetDepositBalances(c, accessKey, customerReference)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	deposits, err := database.GetDeposits(c, accessKey, customerReference)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	result.CustomerReference = customerReference
+	result.Address = depositAddress.Address
+	result.Balances = balances
+	result.Deposits = deposits
+	result.BlockchainId = depositAddress.BlockchainId
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Credits customers with the deposits recorded by IngestBlocks and sweeps the deposit addresses to the treasury address at the configured interval.
+// Sweeps are paid for by the fee address of the deposit wallet, which must be kept funded with BTC. Runs until Enu is stopped
+func SweepDeposits(c context.Context) {
+	wallet, ok := counterpartyapi.GetDepositWallet()
+	if ok == false {
+		log.FluentfContext(consts.LOGINFO, c, "No deposit wallet is configured, deposits won't be credited or swept")
+		return
+	}
+
+	c = handlers.BackgroundContext(c, consts.CounterpartyBlockchainId)
+
+	feeAddress, err := depositFeeAddress(wallet)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to derive the fee address of the deposit wallet: %s", err.Error())
+		return
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Sweeping deposits to %s every %d minutes with fees paid by %s", wallet.TreasuryAddress, wallet.SweepInterval, feeAddress)
+
+	var lastSweep time.Time
+	for {
+		time.Sleep(time.Duration(counterparty_DepositPollRate) * time.Millisecond)
+
+		credited, err := database.InsertNewDeposits(c, feeAddress)
+		if err != nil {
+			continue
+		}
+		if credited > 0 {
+			log.FluentfContext(consts.LOGINFO, c, "Credited %d new deposits", credited)
+		}
+
+		if time.Since(lastSweep) < time.Duration(wallet.SweepInterval)*time.Minute {
+			continue
+		}
+		lastSweep = time.Now()
+
+		addresses, err := database.GetUnsweptDepositAddresses(c)
+		if err != nil {
+			continue
+		}
+
+		for address, accessKey := range addresses {
+			sweepDepositAddress(context.WithValue(c, consts.AccessKeyKey, accessKey), wallet, feeAddress, address)
+		}
+	}
+}
+
+// The first address on the internal chain of the deposit account pays the fees of sweeps, so it is never handed out as a deposit address
+func depositFeeAddress(wallet counterpartyapi.DepositWallet) (string, error) {
+	path := wallet.AccountPath + "/1/0"
+
+	address, err := counterpartycrypto.GetPublicPrivateKeyAtPath(wallet.Passphrase, path)
+	if err != nil {
+		return "", err
+	}
+	counterpartycrypto.RegisterAddressPath(address.Value, path)
+
+	return address.Value, nil
+}
+
+// Sends the assets held by the deposit address to the treasury address. The BTC at the address pays for the sends so it is only swept,
+// along with the dust left by the asset sends, on the next sweep once no assets remain. The deposits are then marked as swept.
+// The sweep interval should be far longer than a block so the sends of the previous sweep have confirmed before balances are read again
+func sweepDepositAddress(c context.Context, wallet counterpartyapi.DepositWallet, feeAddress string, address string) {
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+
+	balances, _, err := getAddressBalances(c, address)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in getAddressBalances(): %s", err.Error())
+		return
+	}
+
+	var assets []enulib.Amount
+	var btcBalance uint64
+	for _, balance := range balances.Balances {
+		if balance.Asset == "BTC" {
+			btcBalance = balance.Quantity
+		} else if balance.Quantity > 0 {
+			assets = append(assets, balance)
+		}
+	}
+
+	if len(assets) > 0 {
+		required := uint64(len(assets)) * (counterpartyapi.Counterparty_DefaultDustSize + counterpartyapi.Counterparty_DefaultTxFee)
+
+		// Top up the deposit address and sweep once the BTC has arrived
+		if btcBalance < required {
+			paymentId := enulib.GeneratePaymentId()
+			log.FluentfContext(consts.LOGINFO, c, "Funding sweep of %s with %d satoshis from %s, paymentId: %s", address, required-btcBalance, feeAddress, paymentId)
+
+			delegatedSendBtc(c, accessKey, wallet.Passphrase, feeAddress, address, required-btcBalance, paymentId, "sweepfee")
+
+			return
+		}
+
+		for _, asset := range assets {
+			paymentId := enulib.GeneratePaymentId()
+			log.FluentfContext(consts.LOGINFO, c, "Sweeping %d %s from %s to %s, paymentId: %s", asset.Quantity, asset.Asset, address, wallet.TreasuryAddress, paymentId)
+
+			if _, _, err := delegatedSend(c, accessKey, wallet.Passphrase, address, wallet.TreasuryAddress, asset.Asset, asset.Quantity, paymentId, "sweep"); err != nil {
+				return
+			}
+
+			database.UpdateDepositsSweepPaymentId(c, address, asset.Asset, paymentId)
+		}
+
+		return
+	}
+
+	// BTC which wouldn't cover the fee of sending it is left at the address
+	var paymentId string
+	if btcBalance > counterpartyapi.Counterparty_DefaultTxFee+counterpartyapi.Counterparty_DefaultDustSize {
+		paymentId = enulib.GeneratePaymentId()
+		log.FluentfContext(consts.LOGINFO, c, "Sweeping %d satoshis from %s to %s, paymentId: %s", btcBalance-counterpartyapi.Counterparty_DefaultTxFee, address, wallet.TreasuryAddress, paymentId)
+
+		if _, _, err := delegatedSendBtc(c, accessKey, wallet.Passphrase, address, wallet.TreasuryAddress, btcBalance-counterpartyapi.Counterparty_DefaultTxFee, paymentId, "sweep"); err != nil {
+			return
+		}
+	}
+
+	database.UpdateDepositsSwept(c, address, paymentId)
+}
+
+// As delegatedSend() for a payment of BTC only. The transaction is composed locally as there is no Counterparty message to verify
+func delegatedSendBtc(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, quantity uint64, paymentId string, paymentTag string) (string, int64, error) {
+	// Write the payment with the generated payment id to the database
+	go database.InsertPayment(c, accessKey, 0, c.Value(consts.BlockchainIdKey).(string), paymentId, sourceAddress, destinationAddress, "BTC", "", quantity, "valid", 0, 1500, paymentTag)
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Err in GetPublicKey(): %s\n", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+	counterparty_Mutexes.m[sourceAddress].Lock()
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+10000) * time.Millisecond)
+
+	createResult, errorCode, err := counterpartyapi.ComposeBtcSend(c, sourceAddress, destinationAddress, quantity, sourceAddressPubKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Err in ComposeBtcSend(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errorCode, err.Error())
+		return "", errorCode, err
+	}
+
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Err in SignRawTransaction(): %s\n", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	database.UpdatePaymentSignedRawTxByPaymentId(c, accessKey, paymentId, signed)
+
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdatePaymentCompleteByPaymentId(c, accessKey, paymentId, txIdSignedTx)
+	log.FluentfContext(consts.LOGINFO, c, "Sent %d satoshis to %s: %s", quantity, destinationAddress, txIdSignedTx)
+
+	return txIdSignedTx, 0, nil
+}
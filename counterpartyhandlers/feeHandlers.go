@@ -0,0 +1,35 @@
+package counterpartyhandlers
+
+import (
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Sends a payment for the access key and charges the payment fee once the payment has been broadcast
+func delegatedSendWithFee(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, quantity uint64, paymentId string, paymentTag string) {
+	if _, _, err := delegatedSend(c, accessKey, passphrase, sourceAddress, destinationAddress, asset, quantity, paymentId, paymentTag); err != nil {
+		return
+	}
+
+	chargeFee(c, enulib.FeePayment, paymentId, asset, quantity)
+}
+
+// Records the fee of an operation which has been broadcast. Counterparty fees are only billed in the monthly statement,
+// schedules which ask for them to be collected on chain are rejected when they are set
+func chargeFee(c context.Context, operation string, operationId string, asset string, quantity uint64) {
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+
+	fee, schedule, recorded, err := database.RecordFee(c, accessKey, consts.CounterpartyBlockchainId, operation, operationId, asset, "", quantity)
+	if err != nil || recorded == false {
+		return
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Charged %d %s for %s %s", fee.FeeAmount, fee.FeeAsset, operation, operationId)
+
+	if schedule.Collect {
+		log.FluentfContext(consts.LOGERROR, c, "The %s fee schedule of access key %s asks for collection which Counterparty doesn't support. The fee for %s will be billed", operation, accessKey, operationId)
+	}
+}
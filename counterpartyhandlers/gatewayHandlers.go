@@ -0,0 +1,184 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// How often the Ripple IOUs sent back to the gateway issuer are paid out as Counterparty assets
+var counterparty_GatewayPollRate = 60000 // milliseconds
+
+// Gateway payment addresses are derived one at a time so each index of the gateway wallet is only used once
+var counterparty_GatewayAddressMutex sync.Mutex
+
+// Maps an external Ripple account to a Counterparty address so the Counterparty asset can be moved between the blockchains.
+// A new Counterparty payment address is derived from the gateway wallet to receive the asset, and the IOUs are issued by the gateway issuer
+func AddressMapCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	addressMap := enulib.AddressMap{
+		ExternalAddress:       m["externalAddress"].(string),
+		CounterpartyAddress:   m["counterpartyAddress"].(string),
+		CounterpartyAssetName: m["counterpartyAssetName"].(string),
+		NativeAssetName:       m["nativeAssetName"].(string),
+	}
+
+	wallet, ok := counterpartyapi.GetGatewayWallet()
+	issuer, issuerOk := rippleapi.GetGatewayIssuer()
+	if ok == false || issuerOk == false {
+		log.FluentfContext(consts.LOGERROR, c, "Address map requested but the gateway wallet or issuer isn't configured")
+		handlers.ReturnServerErrorWithCustomError(c, w, consts.GenericErrors.GatewayUnavailable.Code, consts.GenericErrors.GatewayUnavailable.Description)
+
+		return nil
+	}
+	addressMap.NativePaymentAddress = issuer.Address
+
+	// Ripple amounts are always to 8 decimal places so the quantities of an asset which isn't divisible are scaled when they are transferred
+	assetState, errorCode, err := counterpartyapi.GetAssetState(c, addressMap.CounterpartyAssetName)
+	if err != nil {
+		if errorCode == consts.CounterpartyErrors.NoSuchAsset.Code {
+			handlers.ReturnNotFoundWithCustomError(c, w, errorCode, err.Error())
+		} else {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+		}
+
+		return nil
+	}
+	addressMap.CounterpartyDivisible = assetState.Divisible
+
+	counterparty_GatewayAddressMutex.Lock()
+	defer counterparty_GatewayAddressMutex.Unlock()
+
+	exists, err := database.AddressMapExists(c, addressMap.ExternalAddress)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if exists == true {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.DuplicateAddressMap.Code, consts.GenericErrors.DuplicateAddressMap.Description)
+
+		return nil
+	}
+
+	index, err := database.GetNextGatewayAddressIndex(c)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	xpub, err := counterpartycrypto.ExportXpub(wallet.Passphrase, wallet.AccountPath)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ExportXpub(): %s", err.Error())
+		handlers.ReturnServerErrorWithCustomError(c, w, consts.GenericErrors.GatewayUnavailable.Code, consts.GenericErrors.GatewayUnavailable.Description)
+
+		return nil
+	}
+
+	addresses, err := counterpartycrypto.DeriveAddresses(wallet.Passphrase, wallet.AccountPath, index, 1)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DeriveAddresses(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+	address := addresses[0]
+	addressMap.CounterpartyPaymentAddress = address.Value
+
+	addressMap.AddressMapId, err = database.InsertAddressMap(c, accessKey, addressMap, index)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	// The address is also owned by the access key so it appears in the transaction feed and can be signed for when paying out
+	recordDerivedAddress(c, address.Value, xpub, address.DerivationPath, int64(index))
+	log.FluentfContext(consts.LOGINFO, c, "Mapped %s to %s through payment address %s for access key: %s", addressMap.ExternalAddress, addressMap.CounterpartyAddress, address.Value, accessKey)
+
+	addressMap.RequestId = requestId
+	addressMap.BlockchainId = c.Value(consts.BlockchainIdKey).(string)
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(addressMap); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Records the Ripple IOUs sent back to the gateway issuer and pays out the Counterparty asset from the Counterparty payment address of the map.
+// Each payment address pays out only what was deposited to it, and must hold BTC to pay the fees. Runs until Enu is stopped
+func PayGatewayTransfers(c context.Context) {
+	wallet, ok := counterpartyapi.GetGatewayWallet()
+	issuer, issuerOk := rippleapi.GetGatewayIssuer()
+	if ok == false || issuerOk == false {
+		log.FluentfContext(consts.LOGINFO, c, "No gateway wallet or issuer is configured, Ripple IOUs won't be paid out as Counterparty assets")
+		return
+	}
+
+	c = handlers.BackgroundContext(c, consts.CounterpartyBlockchainId)
+
+	for {
+		time.Sleep(time.Duration(counterparty_GatewayPollRate) * time.Millisecond)
+
+		received, err := database.InsertRippleGatewayDeposits(c, issuer.Address)
+		if err != nil {
+			continue
+		}
+		if received > 0 {
+			log.FluentfContext(consts.LOGINFO, c, "Received %d new gateway deposits on Ripple", received)
+		}
+
+		transfers, err := database.GetPendingGatewayTransfers(c, consts.CounterpartyBlockchainId)
+		if err != nil {
+			continue
+		}
+
+		for accessKey, accessKeyTransfers := range transfers {
+			for _, transfer := range accessKeyTransfers {
+				payGatewayTransfer(context.WithValue(c, consts.AccessKeyKey, accessKey), wallet, transfer)
+			}
+		}
+	}
+}
+
+func payGatewayTransfer(c context.Context, wallet counterpartyapi.GatewayWallet, transfer enulib.GatewayTransfer) {
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+
+	paymentId := enulib.GeneratePaymentId()
+	if err := database.UpdateGatewayTransferPaying(c, transfer.GatewayTransferId, paymentId); err != nil {
+		return
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Paying out gateway transfer %d of %d %s from %s to %s, paymentId: %s", transfer.GatewayTransferId, transfer.PayoutQuantity, transfer.PayoutAsset, transfer.PayoutSourceAddress, transfer.PayoutAddress, paymentId)
+
+	_, errCode, err := delegatedSend(c, accessKey, wallet.Passphrase, transfer.PayoutSourceAddress, transfer.PayoutAddress, transfer.PayoutAsset, transfer.PayoutQuantity, paymentId, "gateway")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Gateway transfer %d failed: %s", transfer.GatewayTransferId, err.Error())
+		database.UpdateGatewayTransferStatus(c, transfer.GatewayTransferId, database.GatewayErrorStatus, errCode, err.Error())
+
+		return
+	}
+
+	database.UpdateGatewayTransferStatus(c, transfer.GatewayTransferId, database.GatewayCompleteStatus, 0, "")
+}
@@ -0,0 +1,154 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// The transaction signed by a co-signer or combined from the signatures of the co-signers
+type multisigTransaction struct {
+	SignedTx  string `json:"signedTx"`
+	Complete  bool   `json:"complete"`       // every input is fully signed
+	TxId      string `json:"txId,omitempty"` // set when a complete transaction was broadcast
+	RequestId string `json:"requestId"`
+}
+
+// Creates the P2SH address of an m-of-n multisig redeem script. Each co-signer gives the public key of one of their addresses
+func MultisigAddressCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result struct {
+		Address            string   `json:"address"`
+		RedeemScript       string   `json:"redeemScript"`
+		RequiredSignatures int      `json:"requiredSignatures"`
+		PublicKeys         []string `json:"publicKeys"`
+		RequestId          string   `json:"requestId"`
+	}
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	requiredSignatures := int(m["requiredSignatures"].(float64))
+	publicKeys := stringsFromRequest(m, "publicKeys")
+
+	address, redeemScript, err := counterpartyapi.CreateMultisigAddress(c, requiredSignatures, publicKeys)
+	if err != nil {
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.MalformedAddress.Code, consts.CounterpartyErrors.MalformedAddress.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Created %d of %d multisig address %s", requiredSignatures, len(publicKeys), address)
+
+	result.Address = address
+	result.RedeemScript = redeemScript
+	result.RequiredSignatures = requiredSignatures
+	result.PublicKeys = publicKeys
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Signs the inputs of a transaction spending from a multisig address which the passphrase holds keys for, adding to the signatures
+// of the partially signed transaction if one is given. The unsigned transaction is composed by counterpartyd with the multisig address
+// as the source. Once every input is fully signed the transaction is broadcast if asked for, otherwise it is passed on to the next co-signer
+func MultisigSign(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var partialTx string
+
+	passphrase := m["passphrase"].(string)
+	unsignedTx := m["unsignedTx"].(string)
+	redeemScripts := stringsFromRequest(m, "redeemScripts")
+
+	if m["partialTx"] != nil {
+		partialTx = m["partialTx"].(string)
+	}
+
+	signedTx, complete, err := counterpartyapi.SignRawTransactionPartial(c, passphrase, unsignedTx, partialTx, redeemScripts)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransactionPartial(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+
+		return nil
+	}
+
+	returnMultisigTransaction(c, w, m, signedTx, complete)
+
+	return nil
+}
+
+// Merges the signatures of copies of the same unsigned transaction which the co-signers have signed independently.
+// Once every input is fully signed the transaction is broadcast if asked for
+func MultisigCombine(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	unsignedTx := m["unsignedTx"].(string)
+	partialTxs := stringsFromRequest(m, "partialTxs")
+	redeemScripts := stringsFromRequest(m, "redeemScripts")
+
+	signedTx, complete, err := counterpartyapi.CombineRawTransactions(c, unsignedTx, partialTxs, redeemScripts)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in CombineRawTransactions(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+
+		return nil
+	}
+
+	returnMultisigTransaction(c, w, m, signedTx, complete)
+
+	return nil
+}
+
+// Broadcasts the transaction if it is complete and the request asked for it, then returns it to the client
+func returnMultisigTransaction(c context.Context, w http.ResponseWriter, m map[string]interface{}, signedTx string, complete bool) {
+	var result multisigTransaction
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = c.Value(consts.RequestIdKey).(string)
+	result.SignedTx = signedTx
+	result.Complete = complete
+
+	if complete && m["broadcast"] == true {
+		txId, err := bitcoinapi.SendRawTransaction(c, signedTx)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+			handlers.ReturnServerErrorWithCustomError(c, w, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+
+			return
+		}
+
+		log.FluentfContext(consts.LOGINFO, c, "Broadcast multisig transaction %s", txId)
+		result.TxId = txId
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+	}
+}
+
+// Returns the strings of an optional array in the request
+func stringsFromRequest(m map[string]interface{}, key string) []string {
+	var result []string
+
+	values, _ := m[key].([]interface{})
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
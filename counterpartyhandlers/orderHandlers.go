@@ -0,0 +1,634 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/validation"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// How often the order matches of an order giving BTC are checked for BTCpays which are due
+var counterparty_OrderMatchPollRate = 60000 // milliseconds
+
+// Order matches which aren't paid within this number of blocks expire
+var counterparty_OrderMatchExpiration = 20
+
+// The passphrase of an order giving BTC is kept in memory to pay its matches for at most this number of blocks. BTCpays due later are
+// made through OrderBtcPay
+var counterparty_OrderMatchWatchLimit = 36
+
+// Places an order on the Counterparty DEX
+func OrderCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var order enulib.Order
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	order.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	giveAsset := m["giveAsset"].(string)
+	giveQuantity := uint64(m["giveQuantity"].(float64))
+	getAsset := m["getAsset"].(string)
+	getQuantity := uint64(m["getQuantity"].(float64))
+
+	expiration := counterpartyapi.Counterparty_DefaultOrderExpiration
+	if m["expiration"] != nil {
+		expiration = uint16(m["expiration"].(float64))
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderCreate: received request sourceAddress: %s, give: %d %s, get: %d %s, expiration: %d from accessKey: %s\n", sourceAddress, giveQuantity, giveAsset, getQuantity, getAsset, expiration, accessKey)
+
+	if giveAsset == getAsset {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	_, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPublicKey(): %s\n", err)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	// Generate an orderId
+	orderId := enulib.GenerateOrderId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated orderId: %s", orderId)
+	order.OrderId = orderId
+	order.Operation = "order"
+	order.SourceAddress = sourceAddress
+	order.GiveAsset = giveAsset
+	order.GiveQuantity = giveQuantity
+	order.GetAsset = getAsset
+	order.GetQuantity = getQuantity
+	order.Expiration = uint64(expiration)
+	order.Status = "valid"
+	order.BlockchainId = consts.CounterpartyBlockchainId
+
+	// Return to the client the orderId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedCreateOrder(c, accessKey, passphrase, orderId, sourceAddress, giveAsset, giveQuantity, getAsset, getQuantity, expiration)
+
+	return nil
+}
+
+// Cancels an open order. The offer hash is the broadcastTxId of the order
+func OrderCancel(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var order enulib.Order
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	order.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	offerHash := m["offerHash"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderCancel: received request sourceAddress: %s, offerHash: %s from accessKey: %s\n", sourceAddress, offerHash, accessKey)
+
+	_, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPublicKey(): %s\n", err)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	// Only open orders placed by the source address can be cancelled
+	openOrder, errorCode, err := counterpartyapi.GetOrder(c, offerHash)
+	if err != nil {
+		if errorCode == consts.CounterpartyErrors.InvalidOrder.Code {
+			handlers.ReturnNotFoundWithCustomError(c, w, errorCode, err.Error())
+		} else {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+		}
+
+		return nil
+	}
+
+	if openOrder.Source != sourceAddress || openOrder.Status != "open" {
+		log.FluentfContext(consts.LOGERROR, c, "Order %s cannot be cancelled by %s. Source: %s, status: %s", offerHash, sourceAddress, openOrder.Source, openOrder.Status)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidOrder.Code, consts.CounterpartyErrors.InvalidOrder.Description)
+
+		return nil
+	}
+
+	orderId := enulib.GenerateOrderId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated orderId: %s", orderId)
+	order.OrderId = orderId
+	order.Operation = "cancel"
+	order.SourceAddress = sourceAddress
+	order.OfferHash = offerHash
+	order.Status = "valid"
+	order.BlockchainId = consts.CounterpartyBlockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedCancelOrder(c, accessKey, passphrase, orderId, sourceAddress, offerHash)
+
+	return nil
+}
+
+// Pays the BTC owed for a pending order match. Used for matches which weren't paid automatically after the order was placed
+func OrderBtcPay(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var order enulib.Order
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	order.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	orderMatchId := m["orderMatchId"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderBtcPay: received request sourceAddress: %s, orderMatchId: %s from accessKey: %s\n", sourceAddress, orderMatchId, accessKey)
+
+	_, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in counterpartycrypto.GetPublicKey(): %s\n", err)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	// The id of an order match is the hashes of its two orders
+	matches, errorCode, err := counterpartyapi.GetPendingOrderMatches(c, strings.Split(orderMatchId, "_")[0])
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	var payee string
+	var btcQuantity uint64
+	found := false
+	for _, match := range matches {
+		payer, matchPayee, matchQuantity, ok := match.BtcPayment()
+		if match.Id == orderMatchId && ok && payer == sourceAddress {
+			payee = matchPayee
+			btcQuantity = matchQuantity
+			found = true
+		}
+	}
+
+	// Only pending matches owing BTC from the source address can be paid, and only once
+	btcPay, err := database.GetOrderByOfferHash(c, accessKey, "btcpay", orderMatchId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+	if found == false || (btcPay.OrderId != "" && btcPay.Status != "error") {
+		log.FluentfContext(consts.LOGERROR, c, "Order match %s can't be paid by %s", orderMatchId, sourceAddress)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.InvalidOrder.Code, consts.CounterpartyErrors.InvalidOrder.Description)
+
+		return nil
+	}
+
+	orderId := enulib.GenerateOrderId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated orderId: %s", orderId)
+	order.OrderId = orderId
+	order.Operation = "btcpay"
+	order.SourceAddress = sourceAddress
+	order.GiveAsset = "BTC"
+	order.GiveQuantity = btcQuantity
+	order.OfferHash = orderMatchId
+	order.Status = "valid"
+	order.BlockchainId = consts.CounterpartyBlockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedBtcPay(c, accessKey, passphrase, orderId, sourceAddress, orderMatchId, payee, btcQuantity)
+
+	return nil
+}
+
+// Returns the open orders placed by the address
+func OpenOrdersByAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var openOrders enulib.OpenOrders
+	requestId := c.Value(consts.RequestIdKey).(string)
+	openOrders.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	address := vars["address"]
+
+	if validation.IsValidBitcoinAddress(address, validation.BitcoinNetwork) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OpenOrdersByAddress: received request address: %s from accessKey: %s\n", address, c.Value(consts.AccessKeyKey).(string))
+
+	orders, errorCode, err := counterpartyapi.GetOpenOrdersByAddress(c, address)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	openOrders.Address = address
+	openOrders.BlockchainId = consts.CounterpartyBlockchainId
+	for _, o := range orders {
+		openOrders.Orders = append(openOrders.Orders, toOpenOrder(o, orderPrice(o.GetQuantity, o.GiveQuantity)))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(openOrders); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the open orders between the base and quote assets with the best prices first
+func OrderBook(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var orderBook enulib.OrderBook
+	requestId := c.Value(consts.RequestIdKey).(string)
+	orderBook.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	baseAsset := vars["baseAsset"]
+	quoteAsset := vars["quoteAsset"]
+
+	if len(baseAsset) < 3 || len(quoteAsset) < 3 || baseAsset == quoteAsset {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid asset")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderBook: received request baseAsset: %s, quoteAsset: %s from accessKey: %s\n", baseAsset, quoteAsset, c.Value(consts.AccessKeyKey).(string))
+
+	asks, errorCode, err := counterpartyapi.GetOpenOrdersByAssets(c, baseAsset, quoteAsset)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	bids, errorCode, err := counterpartyapi.GetOpenOrdersByAssets(c, quoteAsset, baseAsset)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	orderBook.BaseAsset = baseAsset
+	orderBook.QuoteAsset = quoteAsset
+	orderBook.BlockchainId = consts.CounterpartyBlockchainId
+
+	// Asks give the base asset, bids get the base asset
+	for _, o := range asks {
+		orderBook.Asks = append(orderBook.Asks, toOpenOrder(o, orderPrice(o.GetQuantity, o.GiveQuantity)))
+	}
+	for _, o := range bids {
+		orderBook.Bids = append(orderBook.Bids, toOpenOrder(o, orderPrice(o.GiveQuantity, o.GetQuantity)))
+	}
+
+	sort.Sort(byPrice(orderBook.Asks))
+	sort.Sort(sort.Reverse(byPrice(orderBook.Bids)))
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(orderBook); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+type byPrice []enulib.OpenOrder
+
+func (o byPrice) Len() int           { return len(o) }
+func (o byPrice) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o byPrice) Less(i, j int) bool { return o[i].Price < o[j].Price }
+
+// Prices are in raw units of each asset, ie satoshis for divisible assets
+func orderPrice(quoteQuantity uint64, baseQuantity uint64) float64 {
+	if baseQuantity == 0 {
+		return 0
+	}
+
+	return float64(quoteQuantity) / float64(baseQuantity)
+}
+
+func toOpenOrder(o counterpartyapi.Order, price float64) enulib.OpenOrder {
+	return enulib.OpenOrder{TxHash: o.TxHash, SourceAddress: o.Source, GiveAsset: o.GiveAsset, GiveQuantity: o.GiveQuantity, GiveRemaining: o.GiveRemaining, GetAsset: o.GetAsset, GetQuantity: o.GetQuantity, GetRemaining: o.GetRemaining, Price: price, ExpireIndex: o.ExpireIndex, Status: o.Status}
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedCreateOrder(c context.Context, accessKey string, passphrase string, orderId string, sourceAddress string, giveAsset string, giveQuantity uint64, getAsset string, getQuantity uint64, expiration uint16) (string, int64, error) {
+	// Write the order with the generated order id to the database
+	err := database.InsertOrder(c, accessKey, orderId, consts.CounterpartyBlockchainId, "order", sourceAddress, giveAsset, "", giveQuantity, getAsset, "", getQuantity, uint64(expiration), "", 0, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error with GetPublicKey(): %s", err)
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.InvalidPassphrase.Code, errors.New(consts.CounterpartyErrors.InvalidPassphrase.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s\n", sourceAddress)
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	counterparty_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping")
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+3000) * time.Millisecond)
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	createResult, errCode, err := counterpartyapi.CreateOrder(c, sourceAddress, giveAsset, giveQuantity, getAsset, getQuantity, expiration, sourceAddressPubKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in CreateOrder(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Created order giving %d %s for %d %s at %s: %s\n", giveQuantity, giveAsset, getQuantity, getAsset, sourceAddress, createResult)
+
+	// Check the transaction composed by counterpartyd matches the request before signing
+	errCode, err = counterpartyapi.VerifyOrder(c, createResult, sourceAddress, giveAsset, giveQuantity, getAsset, getQuantity, expiration)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyOrder(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Signed tx: %s\n", signed)
+
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdateOrderCompleteByOrderId(c, accessKey, orderId, txIdSignedTx)
+
+	// Matches against an order giving BTC aren't settled until the BTC is paid
+	if giveAsset == "BTC" {
+		go watchOrderMatches(c, accessKey, passphrase, sourceAddress, txIdSignedTx, expiration)
+	}
+
+	return txIdSignedTx, 0, nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedCancelOrder(c context.Context, accessKey string, passphrase string, orderId string, sourceAddress string, offerHash string) (string, int64, error) {
+	err := database.InsertOrder(c, accessKey, orderId, consts.CounterpartyBlockchainId, "cancel", sourceAddress, "", "", 0, "", "", 0, 0, offerHash, 0, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error with GetPublicKey(): %s", err)
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.InvalidPassphrase.Code, errors.New(consts.CounterpartyErrors.InvalidPassphrase.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s\n", sourceAddress)
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	counterparty_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping")
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+3000) * time.Millisecond)
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	createResult, errCode, err := counterpartyapi.CreateCancel(c, sourceAddress, offerHash, sourceAddressPubKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in CreateCancel(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	errCode, err = counterpartyapi.VerifyCancel(c, createResult, sourceAddress, offerHash)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyCancel(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdateOrderCompleteByOrderId(c, accessKey, orderId, txIdSignedTx)
+
+	return txIdSignedTx, 0, nil
+}
+
+// Pays the BTC owed for each match of the order until the order and its matches have expired, or for at most the watch limit.
+// The passphrase is only held in memory, so BTCpays due after the watch limit or a restart of Enu must be paid through OrderBtcPay
+func watchOrderMatches(c context.Context, accessKey string, passphrase string, sourceAddress string, orderTxHash string, expiration uint16) {
+	blocks := int(expiration) + counterparty_OrderMatchExpiration
+	if blocks > counterparty_OrderMatchWatchLimit {
+		blocks = counterparty_OrderMatchWatchLimit
+	}
+
+	// Blocks are found every 10 minutes on average
+	deadline := time.Now().Add(time.Duration(blocks) * 10 * time.Minute)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Duration(counterparty_OrderMatchPollRate) * time.Millisecond)
+
+		matches, _, err := counterpartyapi.GetPendingOrderMatches(c, orderTxHash)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in GetPendingOrderMatches(): %s", err.Error())
+			continue
+		}
+
+		for _, match := range matches {
+			payer, payee, btcQuantity, ok := match.BtcPayment()
+			if ok == false || payer != sourceAddress {
+				continue
+			}
+
+			// Don't pay twice for the same match
+			btcPay, err := database.GetOrderByOfferHash(c, accessKey, "btcpay", match.Id)
+			if err != nil || (btcPay.OrderId != "" && btcPay.Status != "error") {
+				continue
+			}
+
+			delegatedBtcPay(c, accessKey, passphrase, enulib.GenerateOrderId(), sourceAddress, match.Id, payee, btcQuantity)
+		}
+
+		if len(matches) == 0 {
+			order, _, err := counterpartyapi.GetOrder(c, orderTxHash)
+			if err == nil && order.Status != "open" {
+				log.FluentfContext(consts.LOGINFO, c, "Order %s is %s with no pending matches", orderTxHash, order.Status)
+				return
+			}
+		}
+	}
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedBtcPay(c context.Context, accessKey string, passphrase string, orderId string, sourceAddress string, orderMatchId string, destinationAddress string, btcQuantity uint64) (string, int64, error) {
+	err := database.InsertOrder(c, accessKey, orderId, consts.CounterpartyBlockchainId, "btcpay", sourceAddress, "BTC", "", btcQuantity, "", "", 0, 0, orderMatchId, 0, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	sourceAddressPubKey, err := counterpartycrypto.GetPublicKey(passphrase, sourceAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error with GetPublicKey(): %s", err)
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.InvalidPassphrase.Code, consts.CounterpartyErrors.InvalidPassphrase.Description)
+		return "", consts.CounterpartyErrors.InvalidPassphrase.Code, errors.New(consts.CounterpartyErrors.InvalidPassphrase.Description)
+	}
+
+	// Mutex lock this address
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if counterparty_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s\n", sourceAddress)
+		counterparty_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	counterparty_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer counterparty_Mutexes.Unlock()
+	defer counterparty_Mutexes.m[sourceAddress].Unlock()
+
+	// Concurrent requests for the same match can all pass the check in the handler, so check again now that the payer's address is locked
+	paid, err := database.GetOrderByOfferHashAndStatus(c, accessKey, "btcpay", orderMatchId, "complete")
+	if err != nil {
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.GenericErrors.GeneralError.Code, consts.GenericErrors.GeneralError.Description)
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+	if paid.OrderId != "" {
+		log.FluentfContext(consts.LOGERROR, c, "Order match %s has already been paid by order %s", orderMatchId, paid.OrderId)
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.InvalidOrder.Code, consts.CounterpartyErrors.InvalidOrder.Description)
+		return "", consts.CounterpartyErrors.InvalidOrder.Code, errors.New(consts.CounterpartyErrors.InvalidOrder.Description)
+	}
+
+	// We must sleep for at least the time it takes for any transactions to propagate through to the counterparty mempool
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping")
+	time.Sleep(time.Duration(counterparty_BackEndPollRate+3000) * time.Millisecond)
+
+	createResult, errCode, err := counterpartyapi.CreateBtcPay(c, sourceAddress, orderMatchId, destinationAddress, btcQuantity, sourceAddressPubKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in CreateBtcPay(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Created BTCpay of %d satoshis from %s to %s for order match %s: %s\n", btcQuantity, sourceAddress, destinationAddress, orderMatchId, createResult)
+
+	errCode, err = counterpartyapi.VerifyBtcPay(c, createResult, sourceAddress, orderMatchId, destinationAddress, btcQuantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in VerifyBtcPay(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+		return "", errCode, err
+	}
+
+	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SignRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.SigningError.Code, consts.CounterpartyErrors.SigningError.Description)
+		return "", consts.CounterpartyErrors.SigningError.Code, errors.New(consts.CounterpartyErrors.SigningError.Description)
+	}
+
+	txIdSignedTx, err := bitcoinapi.SendRawTransaction(c, signed)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in SendRawTransaction(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.CounterpartyErrors.BroadcastError.Code, consts.CounterpartyErrors.BroadcastError.Description)
+		return "", consts.CounterpartyErrors.BroadcastError.Code, errors.New(consts.CounterpartyErrors.BroadcastError.Description)
+	}
+
+	database.UpdateOrderCompleteByOrderId(c, accessKey, orderId, txIdSignedTx)
+
+	return txIdSignedTx, 0, nil
+}
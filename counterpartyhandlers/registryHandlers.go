@@ -0,0 +1,297 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+var counterparty_RegistryPollRate = 60000 // milliseconds
+
+// Returns the holders of the asset at the block, largest holding first. The snapshot is taken from counterpartyd and recorded the first time the block is requested
+func registrySnapshot(c context.Context, asset string, blockId uint64, trigger string) (enulib.RegistrySnapshot, []enulib.AddressAmount, int64, error) {
+	snapshot, holdings, found, err := database.GetRegistrySnapshot(c, asset, blockId)
+	if err != nil {
+		return snapshot, holdings, consts.GenericErrors.GeneralError.Code, err
+	}
+	if found {
+		return snapshot, holdings, 0, nil
+	}
+
+	balances, errorCode, err := counterpartyapi.GetBalancesByAssetAtBlock(c, asset, blockId)
+	if err != nil {
+		return snapshot, holdings, errorCode, err
+	}
+
+	snapshot = enulib.RegistrySnapshot{Asset: asset, BlockId: blockId, Trigger: trigger}
+	for _, balance := range balances {
+		holdings = append(holdings, enulib.AddressAmount{Address: balance.Address, Quantity: balance.Quantity})
+		snapshot.Holders++
+		snapshot.Total += balance.Quantity
+	}
+
+	if err := database.InsertRegistrySnapshot(c, snapshot, holdings); err != nil {
+		return snapshot, holdings, consts.GenericErrors.GeneralError.Code, err
+	}
+
+	return snapshot, holdings, 0, nil
+}
+
+// Parses a block height from the query parameter and checks that counterpartyd has parsed it. Writes the error response and returns false if the height can't be used
+func parseRegistryBlock(c context.Context, w http.ResponseWriter, r *http.Request, parameter string) (uint64, bool) {
+	blockId, err := strconv.ParseUint(r.URL.Query().Get(parameter), 10, 64)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid %s: %s", parameter, r.URL.Query().Get(parameter))
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBlockId.Code, consts.GenericErrors.InvalidBlockId.Description)
+
+		return 0, false
+	}
+
+	runningInfo, errorCode, err := counterpartyapi.GetRunningInfo(c)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return 0, false
+	}
+
+	if blockId > runningInfo.LastBlock.BlockIndex {
+		log.FluentfContext(consts.LOGERROR, c, "Block %d has not been parsed. Last parsed block: %d", blockId, runningInfo.LastBlock.BlockIndex)
+		handlers.ReturnBadRequest(c, w, consts.CounterpartyErrors.BlockNotParsed.Code, consts.CounterpartyErrors.BlockNotParsed.Description)
+
+		return 0, false
+	}
+
+	return blockId, true
+}
+
+// Returns the holdings of the asset which changed between the from and to block heights
+func AssetRegistryDiff(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var diff enulib.RegistryDiff
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	diff.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	asset := vars["asset"]
+
+	if asset == "" || len(asset) < 5 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid asset")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	fromBlockId, ok := parseRegistryBlock(c, w, r, "from")
+	if !ok {
+		return nil
+	}
+
+	toBlockId, ok := parseRegistryBlock(c, w, r, "to")
+	if !ok {
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "AssetRegistryDiff: received request asset: %s, from: %d, to: %d from accessKey: %s\n", asset, fromBlockId, toBlockId, c.Value(consts.AccessKeyKey).(string))
+
+	_, fromHoldings, errorCode, err := registrySnapshot(c, asset, fromBlockId, enulib.SnapshotRequest)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	_, toHoldings, errorCode, err := registrySnapshot(c, asset, toBlockId, enulib.SnapshotRequest)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	diff.Asset = asset
+	diff.FromBlockId = fromBlockId
+	diff.ToBlockId = toBlockId
+	diff.Changes = enulib.DiffHoldings(fromHoldings, toHoldings)
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(diff); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the snapshots which have been taken of the asset, oldest block first
+func AssetRegistrySnapshots(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var snapshots enulib.RegistrySnapshots
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	snapshots.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	asset := vars["asset"]
+
+	if asset == "" || len(asset) < 5 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid asset")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	result, err := database.GetRegistrySnapshots(c, asset)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	snapshots.Asset = asset
+	snapshots.Snapshots = result
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(snapshots); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Schedules snapshots of the asset at block heights and at each issuance or dividend. The schedule of the access key for the asset is returned
+func AssetRegistrySchedule(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	asset := m["asset"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "AssetRegistrySchedule: received request asset: %s from accessKey: %s\n", asset, accessKey)
+
+	if m["blockIds"] != nil {
+		for _, blockId := range m["blockIds"].([]interface{}) {
+			if err := database.InsertRegistrySchedule(c, accessKey, asset, enulib.SnapshotBlock, uint64(blockId.(float64))); err != nil {
+				handlers.ReturnServerError(c, w)
+
+				return nil
+			}
+		}
+	}
+
+	if m["onIssuance"] != nil && m["onIssuance"].(bool) {
+		if err := database.InsertRegistrySchedule(c, accessKey, asset, enulib.SnapshotIssuance, 0); err != nil {
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+	}
+
+	if m["onDividend"] != nil && m["onDividend"].(bool) {
+		if err := database.InsertRegistrySchedule(c, accessKey, asset, enulib.SnapshotDividend, 0); err != nil {
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+	}
+
+	schedules, err := database.GetRegistrySchedules(c, accessKey)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	schedule := schedules[asset]
+	schedule.Asset = asset
+	schedule.RequestId = requestId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(schedule); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Takes the scheduled snapshots of every asset once counterpartyd has parsed the block. Snapshots which have already been taken are skipped
+func SnapshotRegistry(c context.Context) {
+	c = context.WithValue(c, consts.BlockchainIdKey, consts.CounterpartyBlockchainId)
+
+	for {
+		time.Sleep(time.Duration(counterparty_RegistryPollRate) * time.Millisecond)
+
+		runningInfo, _, err := counterpartyapi.GetRunningInfo(c)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in counterpartyapi.GetRunningInfo(): %s", err.Error())
+			continue
+		}
+		parsedBlockId := runningInfo.LastBlock.BlockIndex
+
+		schedules, err := database.GetRegistrySchedules(c, "")
+		if err != nil {
+			continue
+		}
+
+		for asset, schedule := range schedules {
+			for _, blockId := range schedule.BlockIds {
+				if blockId <= parsedBlockId {
+					takeRegistrySnapshot(c, asset, blockId, enulib.SnapshotBlock)
+				}
+			}
+
+			if schedule.OnIssuance {
+				issuances, _, err := counterpartyapi.GetIssuances(c, asset)
+				if err != nil {
+					log.FluentfContext(consts.LOGERROR, c, "Error in counterpartyapi.GetIssuances(): %s", err.Error())
+				}
+
+				for _, issuance := range issuances {
+					if issuance.BlockIndex <= parsedBlockId {
+						takeRegistrySnapshot(c, asset, issuance.BlockIndex, enulib.SnapshotIssuance)
+					}
+				}
+			}
+
+			if schedule.OnDividend {
+				dividends, _, err := counterpartyapi.GetDividends(c, asset)
+				if err != nil {
+					log.FluentfContext(consts.LOGERROR, c, "Error in counterpartyapi.GetDividends(): %s", err.Error())
+				}
+
+				for _, dividend := range dividends {
+					if dividend.BlockIndex <= parsedBlockId {
+						takeRegistrySnapshot(c, asset, dividend.BlockIndex, enulib.SnapshotDividend)
+					}
+				}
+			}
+		}
+	}
+}
+
+func takeRegistrySnapshot(c context.Context, asset string, blockId uint64, trigger string) {
+	snapshot, _, _, err := registrySnapshot(c, asset, blockId, trigger)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to snapshot %s at block %d: %s", asset, blockId, err.Error())
+		return
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Snapshot of %s at block %d: %d holders", asset, blockId, snapshot.Holders)
+}
@@ -0,0 +1,76 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/validation"
+)
+
+// Returns the credits and debits recorded by block ingestion for the addresses owned by the access key, newest first.
+// If an address is given in the path only that address is returned. The fromBlock query parameter returns only blocks from that height onwards
+func AddressTransactions(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var transactions enulib.AddressTransactions
+	var fromBlock int64
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	transactions.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	address := vars["address"]
+
+	if address != "" && validation.IsValidAddress(consts.CounterpartyBlockchainId, address) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return nil
+	}
+
+	if r.URL.Query().Get("fromBlock") != "" {
+		var err error
+
+		fromBlock, err = strconv.ParseInt(r.URL.Query().Get("fromBlock"), 10, 64)
+		if err != nil || fromBlock < 0 {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid fromBlock: %s", r.URL.Query().Get("fromBlock"))
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBlockId.Code, consts.GenericErrors.InvalidBlockId.Description)
+
+			return nil
+		}
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "AddressTransactions called for address: '%s', fromBlock: %d by '%s'\n", address, fromBlock, accessKey)
+
+	result, err := database.GetAddressTransactions(c, accessKey, address, fromBlock)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	transactions.Transactions = result
+	if transactions.Transactions == nil {
+		transactions.Transactions = []enulib.AddressTransaction{}
+	}
+	transactions.BlockchainId = consts.CounterpartyBlockchainId
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(transactions); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package counterpartyhandlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/validation"
 )
 
 func WalletCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
@@ -34,8 +36,16 @@ func WalletCreate(c context.Context, w http.ResponseWriter, r *http.Request, m m
 		number = int(m["numberOfAddresses"].(float64))
 	}
 
+	accountPath, err := accountPathFromRequest(m)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in accountPathFromRequest(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidDerivationPath.Code, consts.GenericErrors.InvalidDerivationPath.Description)
+
+		return nil
+	}
+
 	// Create the wallet
-	wallet, err = counterpartycrypto.CreateWallet(number)
+	wallet, err = counterpartycrypto.CreateWalletForAccount(number, accountPath)
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in CreateWallet(): %s", err.Error())
 		handlers.ReturnServerError(c, w)
@@ -44,6 +54,11 @@ func WalletCreate(c context.Context, w http.ResponseWriter, r *http.Request, m m
 	}
 	log.FluentfContext(consts.LOGINFO, c, "Created a new wallet with first address: %s for access key: %s\n (requestID: %s)", wallet.Addresses[0], c.Value(consts.AccessKeyKey).(string), requestId)
 
+	// Record the derivation path of each address so they can be signed for without searching
+	for i, address := range wallet.Addresses {
+		recordDerivedAddress(c, address, wallet.Xpub, fmt.Sprintf("%s/0/%d", accountPath, i), int64(i))
+	}
+
 	// Return the wallet
 	wallet.RequestId = requestId
 	w.WriteHeader(http.StatusCreated)
@@ -57,6 +72,175 @@ func WalletCreate(c context.Context, w http.ResponseWriter, r *http.Request, m m
 	return nil
 }
 
+// Derives the next addresses of an account in an existing wallet
+func WalletAddresses(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result struct {
+		AccountPath string                                   `json:"accountPath"`
+		Xpub        string                                   `json:"xpub"`
+		Addresses   []counterpartycrypto.CounterpartyAddress `json:"addresses"`
+		RequestId   string                                   `json:"requestId"`
+	}
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	passphrase := m["passphrase"].(string)
+
+	number := 1
+	if m["numberOfAddresses"] != nil {
+		number = int(m["numberOfAddresses"].(float64))
+	}
+
+	accountPath, err := accountPathFromRequest(m)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in accountPathFromRequest(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidDerivationPath.Code, consts.GenericErrors.InvalidDerivationPath.Description)
+
+		return nil
+	}
+
+	xpub, err := counterpartycrypto.ExportXpub(passphrase, accountPath)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ExportXpub(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	// Concurrent requests for the same account must not derive the same indexes
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+	if counterparty_Mutexes.m[xpub] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", xpub)
+		counterparty_Mutexes.m[xpub] = new(sync.Mutex)
+	}
+	// The mutex is read while the map is locked, the map may be written to by other addresses once it is unlocked
+	xpubMutex := counterparty_Mutexes.m[xpub]
+	counterparty_Mutexes.Unlock()
+	log.FluentfContext(consts.LOGINFO, c, "Unlocked the map")
+
+	xpubMutex.Lock()
+	defer xpubMutex.Unlock()
+
+	// Continue from the last address derived for this account
+	start, err := database.GetNextAddressIndex(c, accessKey, xpub)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in GetNextAddressIndex(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	addresses, err := counterpartycrypto.DeriveAddresses(passphrase, accountPath, start, uint32(number))
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DeriveAddresses(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	for i, address := range addresses {
+		if err := recordDerivedAddress(c, address.Value, xpub, address.DerivationPath, int64(start)+int64(i)); err != nil {
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		// Private keys are never returned by this call
+		address.PrivateKey = ""
+		result.Addresses = append(result.Addresses, address)
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Derived %d addresses from index %d of account %s for access key: %s", len(addresses), start, accountPath, accessKey)
+
+	result.AccountPath = accountPath
+	result.Xpub = xpub
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the extended public key of an account so its addresses can be watched without the passphrase
+func WalletXpub(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result struct {
+		AccountPath string `json:"accountPath"`
+		Xpub        string `json:"xpub"`
+		RequestId   string `json:"requestId"`
+	}
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	passphrase := m["passphrase"].(string)
+
+	accountPath, err := accountPathFromRequest(m)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in accountPathFromRequest(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidDerivationPath.Code, consts.GenericErrors.InvalidDerivationPath.Description)
+
+		return nil
+	}
+
+	xpub, err := counterpartycrypto.ExportXpub(passphrase, accountPath)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ExportXpub(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return nil
+	}
+
+	result.AccountPath = accountPath
+	result.Xpub = xpub
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Determines the account to derive from. An explicit accountPath takes precedence over a BIP44 account number.
+// Where neither is given the legacy Counterwallet account is used so existing wallets continue to work
+func accountPathFromRequest(m map[string]interface{}) (string, error) {
+	if m["accountPath"] != nil {
+		accountPath := m["accountPath"].(string)
+		if _, err := counterpartycrypto.ParseDerivationPath(accountPath); err != nil {
+			return "", err
+		}
+
+		return accountPath, nil
+	}
+
+	if m["account"] != nil {
+		return counterpartycrypto.AccountPath(uint32(m["account"].(float64))), nil
+	}
+
+	return counterpartycrypto.LegacyAccountPath, nil
+}
+
+// Persists the address against the access key and adds it to the index used when signing
+func recordDerivedAddress(c context.Context, address string, xpub string, path string, index int64) error {
+	counterpartycrypto.RegisterAddressPath(address, path)
+
+	err := database.CreateDerivedAddress(c, c.Value(consts.AccessKeyKey).(string), address, xpub, path, index)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to persist derived address %s to database. Error: %s", address, err.Error())
+	}
+
+	return err
+}
+
 func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
 
 	var walletPayment enulib.WalletPayment
@@ -100,7 +284,7 @@ func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map
 		return nil
 	}
 
-	go delegatedSend(c, c.Value(consts.AccessKeyKey).(string), passphrase, sourceAddress, destinationAddress, asset, quantity, paymentId, paymentTag)
+	go delegatedSendWithFee(c, c.Value(consts.AccessKeyKey).(string), passphrase, sourceAddress, destinationAddress, asset, quantity, paymentId, paymentTag)
 
 	return nil
 }
@@ -149,6 +333,14 @@ func delegatedSend(c context.Context, accessKey string, passphrase string, sourc
 
 	log.FluentfContext(consts.LOGINFO, c, "Created send of %d %s to %s: %s", quantity, asset, destinationAddress, createResult)
 
+	// Check the transaction composed by counterpartyd matches the request before signing
+	errorCode, err = counterpartyapi.VerifySend(c, createResult, sourceAddress, destinationAddress, asset, quantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Err in VerifySend(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errorCode, err.Error())
+		return "", errorCode, err
+	}
+
 	// Sign the transactions
 	signed, err := counterpartyapi.SignRawTransaction(c, passphrase, createResult)
 	if err != nil {
@@ -187,7 +379,7 @@ func WalletBalance(c context.Context, w http.ResponseWriter, r *http.Request, m
 	vars := mux.Vars(r)
 	address := vars["address"]
 
-	if address == "" || len(address) != 34 {
+	if validation.IsValidBitcoinAddress(address, validation.BitcoinNetwork) == false {
 		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
 		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
 
@@ -196,12 +388,33 @@ func WalletBalance(c context.Context, w http.ResponseWriter, r *http.Request, m
 
 	log.FluentfContext(consts.LOGINFO, c, "WalletBalance: received request address: %s from accessKey: %s\n", address, c.Value(consts.AccessKeyKey).(string))
 
-	// Get counterparty balances
-	result, errorCode, err := counterpartyapi.GetBalancesByAddress(c, address)
+	walletbalance, errorCode, err := getAddressBalances(c, address)
 	if err != nil {
 		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
 		return nil
 	}
+	walletbalance.RequestId = requestId
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(walletbalance); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Gathers the Counterparty and BTC balances of the address
+func getAddressBalances(c context.Context, address string) (enulib.AddressBalances, int64, error) {
+	var walletbalance enulib.AddressBalances
+
+	// Get counterparty balances
+	result, errorCode, err := counterpartyapi.GetBalancesByAddress(c, address)
+	if err != nil {
+		return walletbalance, errorCode, err
+	}
 
 	// Iterate and gather the balances to return
 	walletbalance.Address = address
@@ -227,15 +440,7 @@ func WalletBalance(c context.Context, w http.ResponseWriter, r *http.Request, m
 	}
 	walletbalance.NumberOfTransactions = numberOfTransactions
 
-	w.WriteHeader(http.StatusOK)
-	if err = json.NewEncoder(w).Encode(walletbalance); err != nil {
-		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
-		handlers.ReturnServerError(c, w)
-
-		return nil
-	}
-
-	return nil
+	return walletbalance, 0, nil
 }
 
 func ActivateAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
@@ -248,7 +453,7 @@ func ActivateAddress(c context.Context, w http.ResponseWriter, r *http.Request,
 	vars := mux.Vars(r)
 	address := vars["address"]
 
-	if address == "" {
+	if validation.IsValidBitcoinAddress(address, validation.BitcoinNetwork) == false {
 		w.WriteHeader(http.StatusBadRequest)
 		returnCode := enulib.ReturnCode{RequestId: c.Value(consts.RequestIdKey).(string), Code: consts.GenericErrors.InvalidAddress.Code, Description: consts.GenericErrors.InvalidAddress.Description}
 		if err := json.NewEncoder(w).Encode(returnCode); err != nil {
@@ -348,6 +553,7 @@ func delegatedActivateAddress(c context.Context, addressToActivate string, amoun
 	}
 
 	database.UpdatePaymentCompleteByPaymentId(c, accessKey, activationId, txId)
+	chargeFee(c, enulib.FeeActivation, activationId, "", amount)
 
 	return txId, 0, nil
 }
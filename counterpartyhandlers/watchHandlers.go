@@ -0,0 +1,279 @@
+package counterpartyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartycrypto"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Number of consecutive unused addresses after which the scan of a watch-only wallet stops
+var counterparty_DefaultGapLimit uint32 = 20
+
+// Registers a watch-only wallet from an extended public key and starts scanning for its used addresses
+func WatchWalletCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var watchWallet enulib.WatchWallet
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	watchWallet.RequestId = requestId
+
+	xpub := m["xpub"].(string)
+
+	gapLimit := counterparty_DefaultGapLimit
+	if m["gapLimit"] != nil {
+		gapLimit = uint32(m["gapLimit"].(float64))
+	}
+
+	// Check the xpub can be derived from before accepting it
+	_, err := counterpartycrypto.DeriveAddressesFromXpub(xpub, 0, 1)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in DeriveAddressesFromXpub(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidExtendedKey.Code, consts.GenericErrors.InvalidExtendedKey.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "WatchWalletCreate: received request xpub: %s, gapLimit: %d from accessKey: %s\n", xpub, gapLimit, accessKey)
+
+	watchWalletId := enulib.GenerateWatchWalletId()
+
+	err = database.InsertWatchWallet(c, accessKey, watchWalletId, consts.CounterpartyBlockchainId, xpub, gapLimit, "scanning")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in InsertWatchWallet(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	watchWallet.WatchWalletId = watchWalletId
+	watchWallet.Xpub = xpub
+	watchWallet.GapLimit = gapLimit
+	watchWallet.LastUsedIndex = -1
+	watchWallet.Status = "scanning"
+	watchWallet.BlockchainId = consts.CounterpartyBlockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(watchWallet); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedScanWatchWallet(c, accessKey, watchWalletId)
+
+	return nil
+}
+
+func GetWatchWallet(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	watchWallet, ok := getWatchWalletFromRequest(c, w, r)
+	if ok == false {
+		return nil
+	}
+	watchWallet.RequestId = requestId
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(watchWallet); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Rescans a watch-only wallet from its last used address to pick up addresses which have since been used
+func WatchWalletScan(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	watchWallet, ok := getWatchWalletFromRequest(c, w, r)
+	if ok == false {
+		return nil
+	}
+	watchWallet.RequestId = requestId
+
+	err := database.UpdateWatchWalletStatusByWatchWalletId(c, accessKey, watchWallet.WatchWalletId, "scanning")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in UpdateWatchWalletStatusByWatchWalletId(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+	watchWallet.Status = "scanning"
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(watchWallet); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedScanWatchWallet(c, accessKey, watchWallet.WatchWalletId)
+
+	return nil
+}
+
+// Returns the balances of every address derived from the watch-only wallet along with the totals of each asset
+func WatchWalletBalance(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var walletBalances enulib.WatchWalletBalances
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	walletBalances.RequestId = requestId
+
+	watchWallet, ok := getWatchWalletFromRequest(c, w, r)
+	if ok == false {
+		return nil
+	}
+
+	walletBalances.WatchWalletId = watchWallet.WatchWalletId
+	walletBalances.BlockchainId = consts.CounterpartyBlockchainId
+
+	// Totals are kept in order of first appearance so the response is stable
+	totals := make(map[string]int)
+	for _, address := range watchWallet.Addresses {
+		addressBalances, errorCode, err := getAddressBalances(c, address)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+			return nil
+		}
+
+		for _, balance := range addressBalances.Balances {
+			if i, ok := totals[balance.Asset]; ok {
+				walletBalances.Balances[i].Quantity += balance.Quantity
+			} else {
+				totals[balance.Asset] = len(walletBalances.Balances)
+				walletBalances.Balances = append(walletBalances.Balances, enulib.Amount{Asset: balance.Asset, Quantity: balance.Quantity})
+			}
+		}
+
+		walletBalances.Addresses = append(walletBalances.Addresses, addressBalances)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(walletBalances); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Looks up the watch wallet in the resource path and returns a not found error if it doesn't belong to the access key
+func getWatchWalletFromRequest(c context.Context, w http.ResponseWriter, r *http.Request) (enulib.WatchWallet, bool) {
+	vars := mux.Vars(r)
+	watchWalletId := vars["watchWalletId"]
+
+	if watchWalletId == "" || len(watchWalletId) < 16 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid watchWalletId")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidWatchWalletId.Code, consts.GenericErrors.InvalidWatchWalletId.Description)
+
+		return enulib.WatchWallet{}, false
+	}
+
+	watchWallet, err := database.GetWatchWalletByWatchWalletId(c, c.Value(consts.AccessKeyKey).(string), watchWalletId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in GetWatchWalletByWatchWalletId(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return watchWallet, false
+	}
+
+	if watchWallet.WatchWalletId == "" {
+		handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidWatchWalletId.Code, consts.GenericErrors.InvalidWatchWalletId.Description)
+
+		return watchWallet, false
+	}
+
+	return watchWallet, true
+}
+
+// Derives addresses from the xpub until gapLimit consecutive addresses have no transactions.
+// Addresses already derived are reused and the scan restarts after the last address known to be used
+func delegatedScanWatchWallet(c context.Context, accessKey string, watchWalletId string) {
+	watchWallet, err := database.GetWatchWalletByWatchWalletId(c, accessKey, watchWalletId)
+	if err != nil || watchWallet.WatchWalletId == "" {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to retrieve watch wallet %s for scanning", watchWalletId)
+		return
+	}
+
+	// Only one scan of each xpub at a time
+	counterparty_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map. If we have a new source address, this will modify the map
+	if counterparty_Mutexes.m[watchWallet.Xpub] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", watchWallet.Xpub)
+		counterparty_Mutexes.m[watchWallet.Xpub] = new(sync.Mutex)
+	}
+	// The mutex is read while the map is locked, the map may be written to by other addresses once it is unlocked
+	xpubMutex := counterparty_Mutexes.m[watchWallet.Xpub]
+	counterparty_Mutexes.Unlock()
+	log.FluentfContext(consts.LOGINFO, c, "Unlocked the map")
+
+	xpubMutex.Lock()
+	defer xpubMutex.Unlock()
+
+	addresses := watchWallet.Addresses
+	lastUsedIndex := watchWallet.LastUsedIndex
+	var unused uint32
+
+	for i := lastUsedIndex + 1; unused < watchWallet.GapLimit; i++ {
+		if i >= int64(len(addresses)) {
+			derived, err := counterpartycrypto.DeriveAddressesFromXpub(watchWallet.Xpub, uint32(i), 1)
+			if err != nil {
+				log.FluentfContext(consts.LOGERROR, c, "Error in DeriveAddressesFromXpub(): %s", err.Error())
+				database.UpdateWatchWalletWithErrorByWatchWalletId(c, accessKey, watchWalletId, consts.GenericErrors.InvalidExtendedKey.Code, consts.GenericErrors.InvalidExtendedKey.Description)
+
+				return
+			}
+
+			err = database.CreateDerivedAddress(c, accessKey, derived[0].Value, watchWallet.Xpub, derived[0].DerivationPath, i)
+			if err != nil {
+				log.FluentfContext(consts.LOGERROR, c, "Error in CreateDerivedAddress(): %s", err.Error())
+				database.UpdateWatchWalletWithErrorByWatchWalletId(c, accessKey, watchWalletId, consts.GenericErrors.GeneralError.Code, consts.GenericErrors.GeneralError.Description)
+
+				return
+			}
+
+			addresses = append(addresses, derived[0].Value)
+		}
+
+		numberOfTransactions, err := bitcoinapi.GetTransactionCount(c, addresses[i])
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in GetTransactionCount(): %s", err.Error())
+			database.UpdateWatchWalletWithErrorByWatchWalletId(c, accessKey, watchWalletId, consts.GenericErrors.GeneralError.Code, consts.GenericErrors.GeneralError.Description)
+
+			return
+		}
+
+		if numberOfTransactions > 0 {
+			lastUsedIndex = i
+			unused = 0
+		} else {
+			unused++
+		}
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Scanned watch wallet %s. Last used index: %d, addresses derived: %d", watchWalletId, lastUsedIndex, len(addresses))
+	database.UpdateWatchWalletScannedByWatchWalletId(c, accessKey, watchWalletId, lastUsedIndex)
+}
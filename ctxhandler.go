@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
 	"github.com/whoisjeremylam/enu/counterpartyhandlers"
 	"github.com/whoisjeremylam/enu/database"
 	"github.com/whoisjeremylam/enu/enulib"
@@ -40,6 +42,19 @@ var blockchainFunctions = map[string]blockchainFunction{
 		"walletPayment":   counterpartyhandlers.WalletSend,
 		"walletBalance":   counterpartyhandlers.WalletBalance,
 		"activateaddress": counterpartyhandlers.ActivateAddress,
+		"walletAddresses": counterpartyhandlers.WalletAddresses,
+		"walletXpub":      counterpartyhandlers.WalletXpub,
+
+		// Multisig handlers
+		"multisigAddressCreate": counterpartyhandlers.MultisigAddressCreate,
+		"multisigSign":          counterpartyhandlers.MultisigSign,
+		"multisigCombine":       counterpartyhandlers.MultisigCombine,
+
+		// Watch-only wallet handlers
+		"watchWalletCreate":  counterpartyhandlers.WatchWalletCreate,
+		"getWatchWallet":     counterpartyhandlers.GetWatchWallet,
+		"watchWalletScan":    counterpartyhandlers.WatchWalletScan,
+		"watchWalletBalance": counterpartyhandlers.WatchWalletBalance,
 
 		// Asset handlers
 		"asset":       counterpartyhandlers.AssetCreate,
@@ -49,11 +64,65 @@ var blockchainFunctions = map[string]blockchainFunction{
 		"ledger":      counterpartyhandlers.AssetLedger,
 		"getdividend": counterpartyhandlers.GetDividend,
 
+		// Asset lifecycle handlers
+		"assetReissue":     counterpartyhandlers.AssetReissue,
+		"assetLock":        counterpartyhandlers.AssetLock,
+		"assetTransfer":    counterpartyhandlers.AssetTransfer,
+		"assetDescription": counterpartyhandlers.AssetDescription,
+
+		// Asset registry handlers
+		"assetRegistryDiff":      counterpartyhandlers.AssetRegistryDiff,
+		"assetRegistrySnapshots": counterpartyhandlers.AssetRegistrySnapshots,
+		"assetRegistrySchedule":  counterpartyhandlers.AssetRegistrySchedule,
+
+		// DEX handlers
+		"orderCreate": counterpartyhandlers.OrderCreate,
+		"getOrder":    generalhandlers.GetOrder,
+		"orderCancel": counterpartyhandlers.OrderCancel,
+		"orderBtcPay": counterpartyhandlers.OrderBtcPay,
+		"openOrders":  counterpartyhandlers.OpenOrdersByAddress,
+		"orderBook":   counterpartyhandlers.OrderBook,
+
+		// Broadcast handlers
+		"broadcastCreate":     counterpartyhandlers.BroadcastCreate,
+		"getBroadcast":        counterpartyhandlers.GetBroadcast,
+		"broadcastsByAddress": counterpartyhandlers.BroadcastsByAddress,
+
 		// Payment handlers
 		"simplepayment":    counterpartyhandlers.PaymentCreate,
 		"paymentretry":     counterpartyhandlers.PaymentRetry,
 		"getpayment":       generalhandlers.GetPayment,
 		"paymentbyaddress": generalhandlers.GetPaymentsByAddress,
+
+		// Transaction feed handlers
+		"addressTransactions": counterpartyhandlers.AddressTransactions,
+
+		// Deposit address handlers
+		"depositAddressCreate": counterpartyhandlers.DepositAddressCreate,
+		"customerDeposits":     counterpartyhandlers.CustomerDeposits,
+
+		// Ledger handlers
+		"ledgerAccountCreate": generalhandlers.LedgerAccountCreate,
+		"getLedgerAccount":    generalhandlers.GetLedgerAccount,
+		"ledgerTransfer":      generalhandlers.LedgerTransfer,
+		"ledgerDeposit":       generalhandlers.LedgerDeposit,
+		"ledgerWithdrawal":    counterpartyhandlers.LedgerWithdrawal,
+
+		// Gateway handlers
+		"addressMapCreate": counterpartyhandlers.AddressMapCreate,
+		"addressMaps":      generalhandlers.AddressMaps,
+		"gatewayTransfers": generalhandlers.GatewayTransfers,
+
+		// Audit handlers
+		"auditEntries": generalhandlers.AuditEntries,
+		"auditVerify":  generalhandlers.AuditVerify,
+
+		// Fee handlers
+		"feeSchedules": generalhandlers.FeeSchedules,
+		"feeStatement": generalhandlers.FeeStatement,
+
+		// Block handlers
+		"blocks": generalhandlers.Blocks,
 	},
 	"ripple": {
 		// Address handlers
@@ -70,9 +139,83 @@ var blockchainFunctions = map[string]blockchainFunction{
 		"asset":    ripplehandlers.AssetCreate,
 		"getasset": generalhandlers.GetAsset,
 
+		// DEX handlers
+		"orderCreate": ripplehandlers.OrderCreate,
+		"getOrder":    generalhandlers.GetOrder,
+		"orderCancel": ripplehandlers.OrderCancel,
+		"openOrders":  ripplehandlers.OpenOrdersByAddress,
+		"orderBook":   ripplehandlers.OrderBook,
+
+		// Issuer controls
+		"assetRequireAuth":  ripplehandlers.AssetRequireAuth,
+		"assetAuthorize":    ripplehandlers.AssetAuthorize,
+		"assetFreeze":       ripplehandlers.AssetFreeze,
+		"assetGlobalFreeze": ripplehandlers.AssetGlobalFreeze,
+		"assetNoFreeze":     ripplehandlers.AssetNoFreeze,
+		"assetTransferRate": ripplehandlers.AssetTransferRate,
+
+		// Trust line handlers
+		"trustLineCreate":     ripplehandlers.TrustLineCreate,
+		"trustLineRemove":     ripplehandlers.TrustLineRemove,
+		"trustLinesByAddress": ripplehandlers.TrustLinesByAddress,
+		"getTrustLine":        ripplehandlers.GetTrustLine,
+
+		// Ledger handlers
+		"ledgerAccountCreate": generalhandlers.LedgerAccountCreate,
+		"getLedgerAccount":    generalhandlers.GetLedgerAccount,
+		"ledgerTransfer":      generalhandlers.LedgerTransfer,
+		"ledgerDeposit":       generalhandlers.LedgerDeposit,
+		"ledgerWithdrawal":    ripplehandlers.LedgerWithdrawal,
+
+		// Gateway handlers. Address maps are created through the Counterparty handler as the payment address is derived from the Counterparty gateway wallet
+		"addressMapCreate": counterpartyhandlers.AddressMapCreate,
+		"addressMaps":      generalhandlers.AddressMaps,
+		"gatewayTransfers": generalhandlers.GatewayTransfers,
+
+		// Audit handlers
+		"auditEntries": generalhandlers.AuditEntries,
+		"auditVerify":  generalhandlers.AuditVerify,
+
+		// Fee handlers
+		"feeSchedules": generalhandlers.FeeSchedules,
+		"feeStatement": generalhandlers.FeeStatement,
+
+		// Block handlers
+		"blocks": generalhandlers.Blocks,
+
 		// Unsupported
-		"address":  ripplehandlers.Unhandled,
-		"dividend": ripplehandlers.Unhandled,
+		"address":         ripplehandlers.Unhandled,
+		"orderBtcPay":     ripplehandlers.Unhandled,
+		"dividend":        ripplehandlers.Unhandled,
+		"walletAddresses": ripplehandlers.Unhandled,
+		"walletXpub":      ripplehandlers.Unhandled,
+
+		"multisigAddressCreate": ripplehandlers.Unhandled,
+		"multisigSign":          ripplehandlers.Unhandled,
+		"multisigCombine":       ripplehandlers.Unhandled,
+
+		"watchWalletCreate":  ripplehandlers.Unhandled,
+		"getWatchWallet":     ripplehandlers.Unhandled,
+		"watchWalletScan":    ripplehandlers.Unhandled,
+		"watchWalletBalance": ripplehandlers.Unhandled,
+
+		"assetReissue":     ripplehandlers.Unhandled,
+		"assetLock":        ripplehandlers.Unhandled,
+		"assetTransfer":    ripplehandlers.Unhandled,
+		"assetDescription": ripplehandlers.Unhandled,
+
+		"broadcastCreate":     ripplehandlers.Unhandled,
+		"getBroadcast":        ripplehandlers.Unhandled,
+		"broadcastsByAddress": ripplehandlers.Unhandled,
+
+		"addressTransactions": ripplehandlers.Unhandled,
+
+		"depositAddressCreate": ripplehandlers.Unhandled,
+		"customerDeposits":     ripplehandlers.Unhandled,
+
+		"assetRegistryDiff":      ripplehandlers.Unhandled,
+		"assetRegistrySnapshots": ripplehandlers.Unhandled,
+		"assetRegistrySchedule":  ripplehandlers.Unhandled,
 	},
 }
 
@@ -98,11 +241,49 @@ func handle(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.A
 		return nil
 	}
 
-	blockchainFunctions[blockchainId][requestType](c2, w, r, m)
+	// Reads which counterpartyd couldn't answer are served from its database, the response is marked as possibly stale if any were
+	c2 = counterpartyapi.TrackStaleReads(c2)
+
+	aw := &auditResponseWriter{ResponseWriter: w, c: c2, status: http.StatusOK}
+	blockchainFunctions[blockchainId][requestType](c2, aw, r, m)
+
+	// Every call which can change state is audited along with the status of its response
+	if r.Method != "GET" {
+		if err := database.InsertAudit(c2, c2.Value(consts.AccessKeyKey).(string), enulib.AuditRequest, r.URL.Path, requestType, "", strconv.Itoa(aw.status)); err != nil {
+			log.FluentfContext(consts.LOGERROR, c2, "Unable to audit requestType: %s, status: %d: %s", requestType, aw.status, err.Error())
+		}
+	}
 
 	return nil
 }
 
+// Keeps the status code written by a handler so the request can be audited, and sets the Stale header before the response
+// is written if it was read from the database of counterpartyd
+type auditResponseWriter struct {
+	http.ResponseWriter
+	c           context.Context
+	status      int
+	wroteHeader bool
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader == false && counterpartyapi.IsStale(w.c) {
+		w.Header().Set("Stale", "true")
+	}
+
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.wroteHeader == false {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
 type ctxHandler func(context.Context, http.ResponseWriter, *http.Request) *enulib.AppError
 
 func (fn ctxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
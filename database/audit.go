@@ -0,0 +1,204 @@
+// audit.go
+package database
+
+import (
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Number of entries read at a time when verifying the audit chain
+var Audit_VerifyPageSize = 1000
+
+// Serialises appends from this process. The unique key on previousHash stops another process forking the chain
+var auditMutex sync.Mutex
+
+// Appends an entry to the audit chain. The requestId is taken from the context when the change was made by an API call
+func InsertAudit(c context.Context, accessKey string, entityType string, entityId string, action string, fromStatus string, toStatus string) error {
+	var previousHash []byte
+
+	if isInit == false {
+		Init()
+	}
+
+	entry := enulib.AuditEntry{AccessKey: accessKey, EntityType: entityType, EntityId: entityId, Action: action, FromStatus: fromStatus, ToStatus: toStatus}
+	if requestId, ok := c.Value(consts.RequestIdKey).(string); ok {
+		entry.RequestId = requestId
+	}
+
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	tx, err := Db.Begin()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to begin transaction. Reason: %s", err.Error())
+		return err
+	}
+
+	err = tx.QueryRow("select hash from audit order by auditId desc limit 1 for update").Scan(&previousHash)
+	if err != nil && err.Error() != consts.SqlNotFound {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	entry.PreviousHash = string(previousHash)
+	entry.Created = time.Now().UTC().Format("2006-01-02 15:04:05")
+	entry.Hash = enulib.HashAuditEntry(entry)
+
+	_, err = tx.Exec("insert into audit(accessKey, requestId, entityType, entityId, action, fromStatus, toStatus, created, previousHash, hash) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", entry.AccessKey, entry.RequestId, entry.EntityType, entry.EntityId, entry.Action, entry.FromStatus, entry.ToStatus, entry.Created, entry.PreviousHash, entry.Hash)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to commit. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Records a status change which has already been made. A failure to audit is logged rather than undoing the change
+func auditStatus(c context.Context, accessKey string, entityType string, entityId string, action string, fromStatus string, toStatus string) {
+	if err := InsertAudit(c, accessKey, entityType, entityId, action, fromStatus, toStatus); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to audit %s %s %s -> %s: %s", entityType, entityId, fromStatus, toStatus, err.Error())
+	}
+}
+
+// A row whose status is about to be changed by a statement which can change many rows
+type auditRow struct {
+	accessKey string
+	entityId  string
+	status    string
+}
+
+// Returns the access key, id and current status of the rows the query selects so that a change to many rows can be audited row by row
+func getAuditRows(c context.Context, query string, args ...interface{}) ([]auditRow, error) {
+	var result []auditRow
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accessKey, entityId, status []byte
+
+		if err := rows.Scan(&accessKey, &entityId, &status); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, auditRow{accessKey: string(accessKey), entityId: string(entityId), status: string(status)})
+	}
+
+	return result, nil
+}
+
+// Returns up to limit audit entries after fromAuditId, oldest first. Empty values of accessKey, entityType and entityId match every entry
+func GetAuditEntries(c context.Context, accessKey string, entityType string, entityId string, fromAuditId int64, limit int64) ([]enulib.AuditEntry, error) {
+	result := []enulib.AuditEntry{}
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select auditId, accessKey, requestId, entityType, entityId, action, fromStatus, toStatus, created, previousHash, hash from audit where auditId > ? and (accessKey = ? or ? = '') and (entityType = ? or ? = '') and (entityId = ? or ? = '') order by auditId limit ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(fromAuditId, accessKey, accessKey, entityType, entityType, entityId, entityId, limit)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry enulib.AuditEntry
+		var accessKey, requestId, entityType, entityId, action, fromStatus, toStatus, created, previousHash, hash []byte
+
+		if err := rows.Scan(&entry.AuditId, &accessKey, &requestId, &entityType, &entityId, &action, &fromStatus, &toStatus, &created, &previousHash, &hash); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		entry.AccessKey = string(accessKey)
+		entry.RequestId = string(requestId)
+		entry.EntityType = string(entityType)
+		entry.EntityId = string(entityId)
+		entry.Action = string(action)
+		entry.FromStatus = string(fromStatus)
+		entry.ToStatus = string(toStatus)
+		entry.Created = string(created)
+		entry.PreviousHash = string(previousHash)
+		entry.Hash = string(hash)
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// Checks every entry of the audit table continues the hash chain, oldest first. Stops at the first entry which doesn't.
+// The check covers the entries of every access key so the entry which broke the chain is only reported to the operator, through utils/audit
+func VerifyAudit(c context.Context) (enulib.AuditVerification, error) {
+	var result enulib.AuditVerification
+	var fromAuditId int64
+
+	for {
+		entries, err := GetAuditEntries(c, "", "", "", fromAuditId, int64(Audit_VerifyPageSize))
+		if err != nil {
+			return result, err
+		}
+
+		if brokenAuditId := enulib.VerifyAuditChain(result.LastHash, entries); brokenAuditId != 0 {
+			for _, entry := range entries {
+				if entry.AuditId == brokenAuditId {
+					break
+				}
+				result.Entries++
+				result.LastHash = entry.Hash
+			}
+
+			result.BrokenAuditId = brokenAuditId
+			log.FluentfContext(consts.LOGERROR, c, "The audit chain is broken at auditId %d", brokenAuditId)
+
+			return result, nil
+		}
+
+		if len(entries) > 0 {
+			result.Entries += int64(len(entries))
+			result.LastHash = entries[len(entries)-1].Hash
+			fromAuditId = entries[len(entries)-1].AuditId
+		}
+
+		if len(entries) < Audit_VerifyPageSize {
+			break
+		}
+	}
+
+	result.Valid = true
+
+	return result, nil
+}
@@ -0,0 +1,407 @@
+// blocks.go
+package database
+
+import (
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Returns the highest block of the chain which has been processed along with its hash, or -1 if no blocks have been processed
+func GetLastBlock(c context.Context, blockchainId string) (int64, string, error) {
+	var blockId int64
+	var blockHash []byte
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockId, blockHash from blocks where blockchainId = ? and status = ? order by blockId desc limit 1")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return -1, "", err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(blockchainId, consts.BlockProcessedStatus).Scan(&blockId, &blockHash)
+	if err != nil && err.Error() == consts.SqlNotFound {
+		return -1, "", nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return -1, "", err
+	}
+
+	return blockId, string(blockHash), nil
+}
+
+// Returns the hash of the block of the chain which was processed at the height, or an empty string if no block was processed there
+func GetBlockHash(c context.Context, blockchainId string, blockId int64) (string, error) {
+	var blockHash []byte
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockHash from blocks where blockchainId = ? and blockId = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return "", err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(blockchainId, blockId, consts.BlockProcessedStatus).Scan(&blockHash)
+	if err != nil && err.Error() == consts.SqlNotFound {
+		return "", nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return "", err
+	}
+
+	return string(blockHash), nil
+}
+
+// Records the outcome of processing the block and how long it took in milliseconds. A block which is processed again is updated
+func InsertBlock(c context.Context, blockchainId string, blockId int64, blockHash string, status string, duration int64) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into blocks(blockchainId, blockId, blockHash, status, duration) values(?, ?, ?, ?, ?) on duplicate key update blockHash = values(blockHash), status = values(status), duration = values(duration)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(blockchainId, blockId, blockHash, status, duration)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Marks the processed blocks of the chain above the height as no longer in the chain so they are processed again
+func UpdateBlocksReorged(c context.Context, blockchainId string, aboveBlockId int64) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("update blocks set status = ? where blockchainId = ? and blockId > ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(consts.BlockReorgedStatus, blockchainId, aboveBlockId, consts.BlockProcessedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns a page of the recorded blocks, highest first. If blockchainId is empty the blocks of every chain are returned
+func GetBlocks(c context.Context, blockchainId string, offset int64, limit int64) ([]enulib.Block, error) {
+	result := []enulib.Block{}
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockchainId, blockId, blockHash, status, duration, updated from blocks where (blockchainId = ? or ? = '') order by blockId desc, blockchainId limit ? offset ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(blockchainId, blockchainId, limit, offset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var block enulib.Block
+		var blockchainId, blockHash, status, updated []byte
+
+		if err := rows.Scan(&blockchainId, &block.BlockId, &blockHash, &status, &block.Duration, &updated); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		block.BlockchainId = string(blockchainId)
+		block.BlockHash = string(blockHash)
+		block.Status = string(status)
+		block.Updated = string(updated)
+
+		result = append(result, block)
+	}
+
+	return result, nil
+}
+
+// Returns the ranges of heights below the last processed block of the chain which haven't been processed, highest first.
+// Blocks before the first processed block aren't gaps since history before processing started is never processed
+func GetBlockGaps(c context.Context, blockchainId string, limit int64) ([]enulib.BlockGap, error) {
+	result := []enulib.BlockGap{}
+
+	if isInit == false {
+		Init()
+	}
+
+	// Each processed block which isn't followed by a processed block starts a gap, which ends below the next processed block
+	stmt, err := Db.Prepare("select b.blockId + 1, (select min(n.blockId) - 1 from blocks n where n.blockchainId = b.blockchainId and n.status = ? and n.blockId > b.blockId) as toBlockId from blocks b where b.blockchainId = ? and b.status = ? and not exists (select 1 from blocks f where f.blockchainId = b.blockchainId and f.status = ? and f.blockId = b.blockId + 1) having toBlockId is not null order by b.blockId desc limit ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(consts.BlockProcessedStatus, blockchainId, consts.BlockProcessedStatus, consts.BlockProcessedStatus, limit)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gap enulib.BlockGap
+
+		if err := rows.Scan(&gap.FromBlockId, &gap.ToBlockId); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, gap)
+	}
+
+	return result, nil
+}
+
+// Removes everything recorded for the block so a block which was partially processed can be processed again
+func DeleteBlockActivity(c context.Context, blockId int64) error {
+	if isInit == false {
+		Init()
+	}
+
+	queries := []string{
+		"delete from inputaddresses where txid in (select txid from transactions where blockId = ?)",
+		"delete from outputaddresses where txid in (select txid from transactions where blockId = ?)",
+		"delete from transactions where blockId = ?",
+		"delete from credits where blockIdSource = ?",
+		"delete from debits where blockIdSource = ?",
+	}
+
+	for _, query := range queries {
+		stmt, err := Db.Prepare(query)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+			return err
+		}
+
+		_, err = stmt.Exec(blockId)
+		stmt.Close()
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to delete. Reason: %s", err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Records a transaction in the block along with the addresses of its inputs and outputs
+func InsertTransaction(c context.Context, blockId int64, txId string, inputAddresses []string, outputAddresses []string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into transactions(blockId, txid) values(?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(blockId, txId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	if err := insertTransactionAddresses(c, "insert into inputaddresses(txid, address) values(?, ?)", txId, inputAddresses); err != nil {
+		return err
+	}
+
+	return insertTransactionAddresses(c, "insert into outputaddresses(txid, address) values(?, ?)", txId, outputAddresses)
+}
+
+func insertTransactionAddresses(c context.Context, query string, txId string, addresses []string) error {
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	for _, address := range addresses {
+		_, err = stmt.Exec(txId, address)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Number of transactions looked up by each query of GetRecordedTransactions()
+var Blocks_RecordedTransactionsPageSize = 1000
+
+// Returns which of the transactions have been recorded
+func GetRecordedTransactions(c context.Context, txIds []string) (map[string]bool, error) {
+	result := make(map[string]bool)
+
+	if isInit == false {
+		Init()
+	}
+
+	for len(txIds) > 0 {
+		page := txIds
+		if len(page) > Blocks_RecordedTransactionsPageSize {
+			page = page[:Blocks_RecordedTransactionsPageSize]
+		}
+		txIds = txIds[len(page):]
+
+		args := make([]interface{}, len(page))
+		for i, txId := range page {
+			args[i] = txId
+		}
+		placeholders := strings.Repeat("?, ", len(page)-1) + "?"
+
+		rows, err := Db.Query("select distinct txid from transactions where txid in ("+placeholders+")", args...)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+			return result, err
+		}
+
+		for rows.Next() {
+			var txId []byte
+
+			if err := rows.Scan(&txId); err != nil {
+				log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+				rows.Close()
+				return result, err
+			}
+
+			result[string(txId)] = true
+		}
+		rows.Close()
+	}
+
+	return result, nil
+}
+
+// Records an asset received by the destination address
+func InsertCredit(c context.Context, blockId int64, txId string, sourceAddress string, destinationAddress string, asset string, amount uint64, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into credits(blockIdSource, txid, sourceAddress, destinationAddress, inAsset, inAmount, status) values(?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(blockId, txId, sourceAddress, destinationAddress, asset, amount, status)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Records an asset sent by the source address
+func InsertDebit(c context.Context, blockId int64, txId string, sourceAddress string, destinationAddress string, asset string, amount uint64, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into debits(blockIdSource, txid, sourceAddress, destinationAddress, outAsset, outAmount, status, lastUpdatedBlockId) values(?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(blockId, txId, sourceAddress, destinationAddress, asset, amount, status, blockId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the credits and debits of the addresses owned by the access key from the block onwards, newest first.
+// If an address is given only the credits and debits of that address are returned
+func GetAddressTransactions(c context.Context, accessKey string, address string, fromBlockId int64) ([]enulib.AddressTransaction, error) {
+	var result []enulib.AddressTransaction
+
+	if isInit == false {
+		Init()
+	}
+
+	query := "select * from (" +
+		"select cr.blockIdSource, cr.txid, 'in', cr.destinationAddress, cr.sourceAddress, cr.inAsset, cr.inAmount, cr.status from credits cr inner join addresses a on a.sourceAddress = cr.destinationAddress where a.accessKey = ? and cr.blockIdSource >= ? and (? = '' or cr.destinationAddress = ?) " +
+		"union all " +
+		"select db.blockIdSource, db.txid, 'out', db.sourceAddress, db.destinationAddress, db.outAsset, db.outAmount, db.status from debits db inner join addresses a on a.sourceAddress = db.sourceAddress where a.accessKey = ? and db.blockIdSource >= ? and (? = '' or db.sourceAddress = ?)" +
+		") t order by 1 desc"
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, fromBlockId, address, address, accessKey, fromBlockId, address, address)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blockId int64
+		var txId []byte
+		var direction []byte
+		var ownAddress []byte
+		var otherAddress []byte
+		var asset []byte
+		var quantity uint64
+		var status []byte
+
+		if err := rows.Scan(&blockId, &txId, &direction, &ownAddress, &otherAddress, &asset, &quantity, &status); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, enulib.AddressTransaction{BlockId: blockId, TxId: string(txId), Direction: string(direction), Address: string(ownAddress), OtherAddress: string(otherAddress), Asset: string(asset), Quantity: quantity, Status: string(status)})
+	}
+
+	return result, nil
+}
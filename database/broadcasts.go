@@ -0,0 +1,117 @@
+// broadcasts.go
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func InsertBroadcast(c context.Context, accessKey string, broadcastId string, blockchainId string, sourceAddress string, text string, value float64, feeFraction float64, timestamp uint64, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into broadcasts(accessKey, broadcastId, blockchainId, sourceAddress, text, value, feeFraction, timestamp, status) values(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	// Perform the insert
+	_, err = stmt.Exec(accessKey, broadcastId, blockchainId, sourceAddress, text, value, feeFraction, timestamp, status)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditBroadcast, broadcastId, "broadcast", "", status)
+
+	return nil
+}
+
+func GetBroadcastByBroadcastId(c context.Context, accessKey string, broadcastId string) (enulib.Broadcast, error) {
+	if isInit == false {
+		Init()
+	}
+
+	// Set some initial values
+	var broadcast = enulib.Broadcast{}
+	broadcast.Status = consts.NotFound
+
+	stmt, err := Db.Prepare("select broadcastId, blockchainId, sourceAddress, text, value, feeFraction, timestamp, status, broadcastTxId, errorDescription from broadcasts where broadcastId=? and accessKey=?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return broadcast, err
+	}
+	defer stmt.Close()
+
+	var id []byte
+	var blockchainId []byte
+	var sourceAddress []byte
+	var text []byte
+	var value float64
+	var feeFraction float64
+	var timestamp uint64
+	var status []byte
+	var broadcastTxId []byte
+	var errorMessage []byte
+
+	if err := stmt.QueryRow(broadcastId, accessKey).Scan(&id, &blockchainId, &sourceAddress, &text, &value, &feeFraction, &timestamp, &status, &broadcastTxId, &errorMessage); err == sql.ErrNoRows {
+		return broadcast, nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return broadcast, err
+	}
+
+	broadcast = enulib.Broadcast{BroadcastId: string(id), BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), Text: string(text), Value: value, FeeFraction: feeFraction, Timestamp: timestamp, Status: string(status), BroadcastTxId: string(broadcastTxId), ErrorMessage: string(errorMessage)}
+
+	return broadcast, nil
+}
+
+func UpdateBroadcastCompleteByBroadcastId(c context.Context, accessKey string, broadcastId string, txId string) error {
+	return updateBroadcast(c, accessKey, broadcastId, "complete", "update broadcasts set status='complete', broadcastTxId=? where accessKey=? and broadcastId=?", txId, accessKey, broadcastId)
+}
+
+func UpdateBroadcastWithErrorByBroadcastId(c context.Context, accessKey string, broadcastId string, errorCode int64, errorDescription string) error {
+	return updateBroadcast(c, accessKey, broadcastId, "error", "update broadcasts set status='error', errorCode=?, errorDescription=? where accessKey=? and broadcastId=?", errorCode, errorDescription, accessKey, broadcastId)
+}
+
+func updateBroadcast(c context.Context, accessKey string, broadcastId string, status string, query string, args ...interface{}) error {
+	if isInit == false {
+		Init()
+	}
+
+	broadcast, err := GetBroadcastByBroadcastId(c, accessKey, broadcastId)
+	if err != nil {
+		return err
+	}
+
+	if broadcast.BroadcastId == "" {
+		errorString := fmt.Sprintf("Broadcast does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditBroadcast, broadcastId, status, broadcast.Status, status)
+
+	return nil
+}
@@ -101,23 +101,30 @@ func InitWithConfigPath(configFilePath string) {
 }
 
 // Inserts an asset into the assets database
-func InsertAsset(accessKey string, blockchainId string, assetId string, sourceAddressValue string, distributionAddressValue string, assetValue string, descriptionValue string, quantityValue uint64, divisibleValue bool, status string) error {
+func InsertAsset(c context.Context, accessKey string, blockchainId string, assetId string, sourceAddressValue string, distributionAddressValue string, assetValue string, descriptionValue string, quantityValue uint64, divisibleValue bool, status string) error {
+	return InsertAssetOperation(c, accessKey, blockchainId, assetId, "issuance", sourceAddressValue, distributionAddressValue, assetValue, descriptionValue, quantityValue, divisibleValue, status)
+}
+
+// Records an operation on an asset. The operation is one of issuance, reissue, lock, transfer or description
+// For a transfer the distribution address holds the new owner of the asset
+func InsertAssetOperation(c context.Context, accessKey string, blockchainId string, assetId string, operation string, sourceAddressValue string, distributionAddressValue string, assetValue string, descriptionValue string, quantityValue uint64, divisibleValue bool, status string) error {
 	if isInit == false {
 		Init()
 	}
 
-	stmt, err := Db.Prepare("insert into assets(accessKey, blockchainId, assetId, sourceAddress, distributionAddress, asset, description, quantity, divisible, status) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := Db.Prepare("insert into assets(accessKey, blockchainId, assetId, operation, sourceAddress, distributionAddress, asset, description, quantity, divisible, status) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	// Perform the insert
-	_, err = stmt.Exec(accessKey, blockchainId, assetId, sourceAddressValue, distributionAddressValue, assetValue, descriptionValue, quantityValue, divisibleValue, status)
+	_, err = stmt.Exec(accessKey, blockchainId, assetId, operation, sourceAddressValue, distributionAddressValue, assetValue, descriptionValue, quantityValue, divisibleValue, status)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+
+	auditStatus(c, accessKey, enulib.AuditAsset, assetId, operation, "", status)
 
 	return nil
 }
@@ -133,8 +140,8 @@ func GetAssetByAssetId(c context.Context, accessKey string, assetId string) (enu
 	assetStruct.Status = consts.NotFound
 
 	//	 Query DB
-	log.FluentfContext(consts.LOGINFO, c, "select rowId, assetId, blockchainId, sourceAddress, distributionAddress, asset, description, quantity, divisible, status, errorDescription, broadcastTxId from assets where assetId=%s and accessKey=%s", assetId, accessKey)
-	stmt, err := Db.Prepare("select rowId, assetId, blockchainId, sourceAddress, distributionAddress, asset, description, quantity, divisible, status, errorDescription, broadcastTxId from assets where assetId=? and accessKey=?")
+	log.FluentfContext(consts.LOGINFO, c, "select rowId, assetId, blockchainId, operation, sourceAddress, distributionAddress, asset, description, quantity, divisible, status, errorDescription, broadcastTxId from assets where assetId=%s and accessKey=%s", assetId, accessKey)
+	stmt, err := Db.Prepare("select rowId, assetId, blockchainId, operation, sourceAddress, distributionAddress, asset, description, quantity, divisible, status, errorDescription, broadcastTxId from assets where assetId=? and accessKey=?")
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
 		return assetStruct, err
@@ -150,6 +157,7 @@ func GetAssetByAssetId(c context.Context, accessKey string, assetId string) (enu
 
 	var rowId string
 	var blockchainId []byte
+	var operation []byte
 	var sourceAddress []byte
 	var distributionAddress []byte
 	var asset []byte
@@ -160,14 +168,14 @@ func GetAssetByAssetId(c context.Context, accessKey string, assetId string) (enu
 	var errorMessage []byte
 	var broadcastTxId []byte
 
-	if err := row.Scan(&rowId, &assetId, &blockchainId, &sourceAddress, &distributionAddress, &asset, &description, &quantity, &divisible, &status, &errorMessage, &broadcastTxId); err == sql.ErrNoRows {
+	if err := row.Scan(&rowId, &assetId, &blockchainId, &operation, &sourceAddress, &distributionAddress, &asset, &description, &quantity, &divisible, &status, &errorMessage, &broadcastTxId); err == sql.ErrNoRows {
 		if err.Error() == "sql: no rows in result set" {
 		}
 	} else if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
 		return assetStruct, err
 	} else {
-		assetStruct = enulib.Asset{BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), DistributionAddress: string(distributionAddress), Asset: string(asset), Description: string(description), Quantity: quantity, AssetId: assetId, Status: string(status), ErrorMessage: string(errorMessage)}
+		assetStruct = enulib.Asset{BlockchainId: string(blockchainId), Operation: string(operation), SourceAddress: string(sourceAddress), DistributionAddress: string(distributionAddress), Asset: string(asset), Description: string(description), Quantity: quantity, AssetId: assetId, Status: string(status), ErrorMessage: string(errorMessage)}
 	}
 
 	return assetStruct, nil
@@ -201,6 +209,8 @@ func UpdateAssetWithErrorByAssetId(c context.Context, accessKey string, assetId
 		return err2
 	}
 
+	auditStatus(c, accessKey, enulib.AuditAsset, assetId, "error", asset.Status, "error")
+
 	return nil
 }
 
@@ -231,6 +241,8 @@ func UpdateAssetStatusByAssetId(c context.Context, accessKey string, assetId str
 		return err2
 	}
 
+	auditStatus(c, accessKey, enulib.AuditAsset, assetId, "status", asset.Status, status)
+
 	return nil
 }
 
@@ -293,11 +305,13 @@ func UpdateAssetCompleteByAssetId(c context.Context, accessKey string, assetId s
 		return err2
 	}
 
+	auditStatus(c, accessKey, enulib.AuditAsset, assetId, "complete", asset.Status, "complete")
+
 	return nil
 }
 
 // Inserts a dividend into the dividends database
-func InsertDividend(accessKey string, dividendId string, sourceAddressValue string, assetValue string, dividendAssetValue string, quantityPerUnitValue uint64, status string) {
+func InsertDividend(c context.Context, accessKey string, dividendId string, sourceAddressValue string, assetValue string, dividendAssetValue string, quantityPerUnitValue uint64, status string) {
 	if isInit == false {
 		Init()
 	}
@@ -315,6 +329,8 @@ func InsertDividend(accessKey string, dividendId string, sourceAddressValue stri
 		panic(err.Error())
 	}
 	defer stmt.Close()
+
+	auditStatus(c, accessKey, enulib.AuditDividend, dividendId, "dividend", "", status)
 }
 
 func GetDividendByDividendId(c context.Context, accessKey string, dividendId string) (enulib.Dividend, error) {
@@ -388,6 +404,8 @@ func UpdateDividendWithErrorByDividendId(c context.Context, accessKey string, di
 		return err2
 	}
 
+	auditStatus(c, accessKey, enulib.AuditDividend, dividendId, "error", dividend.Status, "error")
+
 	return nil
 }
 
@@ -418,6 +436,8 @@ func UpdateDividendCompleteByDividendId(c context.Context, accessKey string, div
 		return err2
 	}
 
+	auditStatus(c, accessKey, enulib.AuditDividend, dividendId, "complete", dividend.Status, "complete")
+
 	return nil
 }
 
@@ -440,6 +460,8 @@ func InsertPayment(c context.Context, accessKey string, blockIdValue int64, bloc
 		panic(err.Error())
 	}
 	defer stmt.Close()
+
+	auditStatus(c, accessKey, enulib.AuditPayment, sourceTxidValue, "payment", "", statusValue)
 }
 
 func GetPaymentByPaymentId(c context.Context, accessKey string, paymentId string) enulib.SimplePayment {
@@ -448,7 +470,7 @@ func GetPaymentByPaymentId(c context.Context, accessKey string, paymentId string
 	}
 
 	//	 Query DB
-	stmt, err := Db.Prepare("select rowId, blockId, blockchainId, sourceTxId, sourceAddress, destinationAddress, outAsset, issuer, outAmount, status, lastUpdatedBlockId, txFee, broadcastTxId, paymentTag, errorDescription from payments where sourceTxid=? and accessKey=?")
+	stmt, err := Db.Prepare("select rowId, blockId, blockchainId, sourceTxId, sourceAddress, destinationAddress, outAsset, issuer, outAmount, sourceAsset, sourceIssuer, coalesce(sendMax, 0), coalesce(deliveredAmount, 0), destinationTag, sourceTag, invoiceId, memos, status, lastUpdatedBlockId, txFee, broadcastTxId, paymentTag, errorDescription from payments where sourceTxid=? and accessKey=?")
 	if err != nil {
 		log.Println("Failed to prepare statement. Reason: ")
 		panic(err.Error())
@@ -469,6 +491,14 @@ func GetPaymentByPaymentId(c context.Context, accessKey string, paymentId string
 	var asset []byte
 	var issuer []byte
 	var amount uint64
+	var sourceAsset []byte
+	var sourceIssuer []byte
+	var sendMax uint64
+	var deliveredAmount uint64
+	var destinationTag sql.NullInt64
+	var sourceTag sql.NullInt64
+	var invoiceId []byte
+	var memos []byte
 	var txFee int64
 	var broadcastTxId []byte
 	var status []byte
@@ -478,7 +508,7 @@ func GetPaymentByPaymentId(c context.Context, accessKey string, paymentId string
 	var paymentTag []byte
 	var errorMessage []byte
 
-	if err := row.Scan(&rowId, &blockId, &blockchainId, &sourceTxId, &sourceAddress, &destinationAddress, &asset, &issuer, &amount, &status, &lastUpdatedBlockId, &txFee, &broadcastTxId, &paymentTag, &errorMessage); err == sql.ErrNoRows {
+	if err := row.Scan(&rowId, &blockId, &blockchainId, &sourceTxId, &sourceAddress, &destinationAddress, &asset, &issuer, &amount, &sourceAsset, &sourceIssuer, &sendMax, &deliveredAmount, &destinationTag, &sourceTag, &invoiceId, &memos, &status, &lastUpdatedBlockId, &txFee, &broadcastTxId, &paymentTag, &errorMessage); err == sql.ErrNoRows {
 		payment = enulib.SimplePayment{}
 		if err.Error() == "sql: no rows in result set" {
 			payment.PaymentId = paymentId
@@ -488,7 +518,7 @@ func GetPaymentByPaymentId(c context.Context, accessKey string, paymentId string
 		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
 	}
 
-	payment = enulib.SimplePayment{BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), DestinationAddress: string(destinationAddress), Asset: string(asset), Amount: amount, PaymentId: string(sourceTxId), Status: string(status), BroadcastTxId: string(broadcastTxId), TxFee: txFee, ErrorMessage: string(errorMessage)}
+	payment = enulib.SimplePayment{BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), DestinationAddress: string(destinationAddress), Asset: string(asset), Amount: amount, SourceAsset: string(sourceAsset), SourceIssuer: string(sourceIssuer), SendMax: sendMax, DeliveredAmount: deliveredAmount, DestinationTag: toTag(destinationTag), SourceTag: toTag(sourceTag), InvoiceId: string(invoiceId), Memos: toPaymentMemos(c, memos), PaymentId: string(sourceTxId), Status: string(status), BroadcastTxId: string(broadcastTxId), TxFee: txFee, ErrorMessage: string(errorMessage)}
 
 	return payment
 }
@@ -552,7 +582,7 @@ func GetPaymentsByAddress(c context.Context, accessKey string, address string) [
 
 	//	 Query DB
 	//	log.Fluentf(consts.LOGDEBUG, "select rowId, blockId, blockchainId, sourceTxId, sourceAddress, destinationAddress, outAsset, issuer, outAmount, status, lastUpdatedBlockId, txFee, broadcastTxId, paymentTag, errorDescription from payments where accessKey = %s and (sourceAddress = %s or destinationAddress = %s)", accessKey, address, address)
-	stmt, err := Db.Prepare("select rowId, blockId, blockchainId, sourceTxId, sourceAddress, destinationAddress, outAsset, outAmount, issuer, status, lastUpdatedBlockId, txFee, broadcastTxId, paymentTag, errorDescription from payments where accessKey = ? and (sourceAddress = ? or destinationAddress = ?)")
+	stmt, err := Db.Prepare("select rowId, blockId, blockchainId, sourceTxId, sourceAddress, destinationAddress, outAsset, outAmount, issuer, sourceAsset, sourceIssuer, coalesce(sendMax, 0), coalesce(deliveredAmount, 0), destinationTag, sourceTag, invoiceId, memos, status, lastUpdatedBlockId, txFee, broadcastTxId, paymentTag, errorDescription from payments where accessKey = ? and (sourceAddress = ? or destinationAddress = ?)")
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
 		return result
@@ -576,6 +606,14 @@ func GetPaymentsByAddress(c context.Context, accessKey string, address string) [
 		var asset []byte
 		var issuer []byte
 		var amount uint64
+		var sourceAsset []byte
+		var sourceIssuer []byte
+		var sendMax uint64
+		var deliveredAmount uint64
+		var destinationTag sql.NullInt64
+		var sourceTag sql.NullInt64
+		var invoiceId []byte
+		var memos []byte
 		var txFee int64
 		var broadcastTxId []byte
 		var status []byte
@@ -585,7 +623,7 @@ func GetPaymentsByAddress(c context.Context, accessKey string, address string) [
 		var errorMessage []byte
 		var paymentTag []byte
 
-		if err := rows.Scan(&rowId, &blockId, &blockchainId, &sourceTxId, &sourceAddress, &destinationAddress, &asset, &amount, &issuer, &status, &lastUpdatedBlockId, &txFee, &broadcastTxId, &paymentTag, &errorMessage); err == sql.ErrNoRows {
+		if err := rows.Scan(&rowId, &blockId, &blockchainId, &sourceTxId, &sourceAddress, &destinationAddress, &asset, &amount, &issuer, &sourceAsset, &sourceIssuer, &sendMax, &deliveredAmount, &destinationTag, &sourceTag, &invoiceId, &memos, &status, &lastUpdatedBlockId, &txFee, &broadcastTxId, &paymentTag, &errorMessage); err == sql.ErrNoRows {
 			payment = enulib.SimplePayment{}
 			if err.Error() == "sql: no rows in result set" {
 				payment.Status = consts.NotFound
@@ -594,7 +632,7 @@ func GetPaymentsByAddress(c context.Context, accessKey string, address string) [
 			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
 		}
 
-		payment = enulib.SimplePayment{BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), DestinationAddress: string(destinationAddress), Asset: string(asset), Issuer: string(issuer), Amount: amount, PaymentId: string(sourceTxId), Status: string(status), BroadcastTxId: string(broadcastTxId), TxFee: txFee, ErrorMessage: string(errorMessage), PaymentTag: string(paymentTag)}
+		payment = enulib.SimplePayment{BlockchainId: string(blockchainId), SourceAddress: string(sourceAddress), DestinationAddress: string(destinationAddress), Asset: string(asset), Issuer: string(issuer), Amount: amount, SourceAsset: string(sourceAsset), SourceIssuer: string(sourceIssuer), SendMax: sendMax, DeliveredAmount: deliveredAmount, DestinationTag: toTag(destinationTag), SourceTag: toTag(sourceTag), InvoiceId: string(invoiceId), Memos: toPaymentMemos(c, memos), PaymentId: string(sourceTxId), Status: string(status), BroadcastTxId: string(broadcastTxId), TxFee: txFee, ErrorMessage: string(errorMessage), PaymentTag: string(paymentTag)}
 
 		result = append(result, payment)
 	}
@@ -602,6 +640,21 @@ func GetPaymentsByAddress(c context.Context, accessKey string, address string) [
 	return result
 }
 
+// An activation is sent as a payment whose paymentId is the activationId, so changes to the payment are audited as changes to the activation
+func paymentAuditType(c context.Context, paymentId string) string {
+	var count int64
+
+	if err := Db.QueryRow("select count(*) from activations where activationId = ?", paymentId).Scan(&count); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+	}
+
+	if count > 0 {
+		return enulib.AuditActivation
+	}
+
+	return enulib.AuditPayment
+}
+
 func UpdatePaymentStatusByPaymentId(c context.Context, accessKey string, paymentId string, status string) error {
 	if isInit == false {
 		Init()
@@ -626,6 +679,8 @@ func UpdatePaymentStatusByPaymentId(c context.Context, accessKey string, payment
 		return err2
 	}
 
+	auditStatus(c, accessKey, paymentAuditType(c, paymentId), paymentId, "status", payment.Status, status)
+
 	return nil
 }
 
@@ -653,6 +708,8 @@ func UpdatePaymentWithErrorByPaymentId(c context.Context, accessKey string, paym
 		return err2
 	}
 
+	auditStatus(c, accessKey, paymentAuditType(c, paymentId), paymentId, "error", payment.Status, "error")
+
 	return nil
 }
 
@@ -680,6 +737,8 @@ func UpdatePaymentCompleteByPaymentId(c context.Context, accessKey string, payme
 		return err2
 	}
 
+	auditStatus(c, accessKey, paymentAuditType(c, paymentId), paymentId, "complete", payment.Status, "complete")
+
 	return nil
 }
 
@@ -710,6 +769,135 @@ func UpdatePaymentSignedRawTxByPaymentId(c context.Context, accessKey string, pa
 	return nil
 }
 
+// Records the asset spent by a cross currency payment and the most that may be spent
+func UpdatePaymentSourceByPaymentId(c context.Context, accessKey string, paymentId string, sourceAsset string, sourceIssuer string, sendMax uint64) error {
+	if isInit == false {
+		Init()
+	}
+
+	payment := GetPaymentByPaymentId(c, accessKey, paymentId)
+
+	if payment.PaymentId == "" {
+		errorString := fmt.Sprintf("Payment does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare("update payments set sourceAsset=?, sourceIssuer=?, sendMax=? where accessKey=? and sourceTxId = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err2 := stmt.Exec(sourceAsset, sourceIssuer, sendMax, accessKey, paymentId)
+	if err2 != nil {
+		return err2
+	}
+
+	return nil
+}
+
+func UpdatePaymentDeliveredAmountByPaymentId(c context.Context, accessKey string, paymentId string, deliveredAmount uint64) error {
+	if isInit == false {
+		Init()
+	}
+
+	payment := GetPaymentByPaymentId(c, accessKey, paymentId)
+
+	if payment.PaymentId == "" {
+		errorString := fmt.Sprintf("Payment does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare("update payments set deliveredAmount=? where accessKey=? and sourceTxId = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err2 := stmt.Exec(deliveredAmount, accessKey, paymentId)
+	if err2 != nil {
+		return err2
+	}
+
+	return nil
+}
+
+// Records the destination tag, source tag, invoice id and memos sent with a payment
+func UpdatePaymentTagsByPaymentId(c context.Context, accessKey string, paymentId string, destinationTag *uint32, sourceTag *uint32, invoiceId string, memos []enulib.PaymentMemo) error {
+	if isInit == false {
+		Init()
+	}
+
+	payment := GetPaymentByPaymentId(c, accessKey, paymentId)
+
+	if payment.PaymentId == "" {
+		errorString := fmt.Sprintf("Payment does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	// Missing values are stored as null so they can be told apart from a tag of 0
+	var destinationTagValue interface{}
+	if destinationTag != nil {
+		destinationTagValue = *destinationTag
+	}
+
+	var sourceTagValue interface{}
+	if sourceTag != nil {
+		sourceTagValue = *sourceTag
+	}
+
+	var memosValue interface{}
+	if len(memos) > 0 {
+		memosJson, err := json.Marshal(memos)
+		if err != nil {
+			return err
+		}
+		memosValue = string(memosJson)
+	}
+
+	stmt, err := Db.Prepare("update payments set destinationTag=?, sourceTag=?, invoiceId=?, memos=? where accessKey=? and sourceTxId = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err2 := stmt.Exec(destinationTagValue, sourceTagValue, invoiceId, memosValue, accessKey, paymentId)
+	if err2 != nil {
+		return err2
+	}
+
+	return nil
+}
+
+// Returns nil if the payment was made without the tag
+func toTag(tag sql.NullInt64) *uint32 {
+	if tag.Valid == false {
+		return nil
+	}
+
+	result := uint32(tag.Int64)
+
+	return &result
+}
+
+// Memos are stored as JSON
+func toPaymentMemos(c context.Context, memos []byte) []enulib.PaymentMemo {
+	var result []enulib.PaymentMemo
+
+	if len(memos) == 0 {
+		return result
+	}
+
+	if err := json.Unmarshal(memos, &result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to unmarshal memos. Reason: %s", err.Error())
+	}
+
+	return result
+}
+
 // create table userKeys (userId BIGINT, accessKey varchar(64), secret varchar(64), nonce bigint, assetId varchar(100), blockchainId varchar(100), sourceAddress varchar(100))
 // Used to verify if the current request has a nonce > the value stored in the DB
 func GetNonceByAccessKey(accessKey string) int64 {
@@ -875,10 +1063,22 @@ func CreateUserKey(userId int64, assetId string, blockchainId string, sourceAddr
 
 	defer stmt.Close()
 
+	// Keys created by another key are attributed to it
+	actor := parentAccessKey
+	if actor == "" {
+		actor = key
+	}
+	auditStatus(context.TODO(), actor, enulib.AuditAccessKey, key, "create", "", consts.AccessKeyValidStatus)
+
 	return key, secret, nil
 }
 
 func CreateSecondaryAddress(c context.Context, accessKey string, newAddress string) error {
+	return CreateDerivedAddress(c, accessKey, newAddress, "", "", -1)
+}
+
+// Records an address derived from an HD wallet account along with the account xpub, derivation path and index on the external chain
+func CreateDerivedAddress(c context.Context, accessKey string, newAddress string, accountXpub string, derivationPath string, addressIndex int64) error {
 	if isInit == false {
 		Init()
 	}
@@ -890,21 +1090,94 @@ func CreateSecondaryAddress(c context.Context, accessKey string, newAddress stri
 		return errors.New("Call to CreateSecondaryAddress() with an invalid access key")
 	}
 
-	stmt, err := Db.Prepare("insert into addresses(accessKey, sourceAddress) values(?, ?)")
+	stmt, err := Db.Prepare("insert into addresses(accessKey, sourceAddress, accountXpub, derivationPath, addressIndex) values(?, ?, ?, ?, ?)")
 	if err != nil {
 		//		log.Println("Failed to prepare statement. Reason: ")
 		return err
 	}
+	defer stmt.Close()
+
+	// Addresses which weren't derived from a wallet have no path
+	var xpub, path, index interface{}
+	if derivationPath != "" {
+		xpub = accountXpub
+		path = derivationPath
+		index = addressIndex
+	}
 
 	// Perform the insert
-	_, err = stmt.Exec(accessKey, newAddress)
+	_, err = stmt.Exec(accessKey, newAddress, xpub, path, index)
 	if err != nil {
 		return err
 	}
 
+	return nil
+}
+
+// Returns the index on the external chain of the next address to derive for the account
+func GetNextAddressIndex(c context.Context, accessKey string, accountXpub string) (uint32, error) {
+	if isInit == false {
+		Init()
+	}
+
+	var maxIndex sql.NullInt64
+
+	stmt, err := Db.Prepare("select max(addressIndex) from addresses where accessKey = ? and accountXpub = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
 	defer stmt.Close()
 
-	return nil
+	err = stmt.QueryRow(accessKey, accountXpub).Scan(&maxIndex)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	if maxIndex.Valid == false {
+		return 0, nil
+	}
+
+	return uint32(maxIndex.Int64 + 1), nil
+}
+
+// Returns the derivation path of every address which was derived from an HD wallet account, keyed by address.
+// Addresses of watch-only wallets are excluded as they can't be signed for
+func GetAddressPaths(c context.Context) (map[string]string, error) {
+	result := make(map[string]string)
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select sourceAddress, derivationPath from addresses where derivationPath like 'm/%'")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		var path []byte
+
+		if err := rows.Scan(&address, &path); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result[string(address)] = string(path)
+	}
+
+	return result, nil
 }
 
 // Only return true where an accessKey exists and also has a valid status
@@ -950,6 +1223,8 @@ func UpdateUserKeyStatus(accessKey string, status string) error {
 		return errors.New(e)
 	}
 
+	fromStatus := GetStatusByUserKey(accessKey)
+
 	stmt, err := Db.Prepare("update userkeys set status=? where accessKey=?")
 	if err != nil {
 		//		log.Println("Failed to prepare statement. Reason: ")
@@ -964,6 +1239,8 @@ func UpdateUserKeyStatus(accessKey string, status string) error {
 
 	defer stmt.Close()
 
+	auditStatus(context.TODO(), accessKey, enulib.AuditAccessKey, accessKey, "status", fromStatus, status)
+
 	return nil
 }
 
@@ -1027,6 +1304,8 @@ func InsertActivation(c context.Context, accessKey string, activationId string,
 		return
 	}
 	defer stmt.Close()
+
+	auditStatus(c, accessKey, enulib.AuditActivation, activationId, "activation", "", "requested")
 }
 
 func GetActivationByActivationId(c context.Context, accessKey string, activationId string) map[string]interface{} {
@@ -1090,13 +1369,13 @@ func GetActivationByActivationId(c context.Context, accessKey string, activation
 	return result
 }
 
-// Inserts an activation request into the database
-func InsertTrustAsset(c context.Context, accessKey string, activationId string, blockchainId string, asset string, issuer string, amount uint64) {
+// Inserts a trust line created when an address is activated. The trust line is tracked by the trustId
+func InsertTrustAsset(c context.Context, accessKey string, activationId string, trustId string, blockchainId string, address string, asset string, issuer string, amount uint64) {
 	if isInit == false {
 		Init()
 	}
 
-	stmt, err := Db.Prepare("insert into trustassets(activationId, blockchainId, accessKey, asset, issuer, trustAmount) values(?, ?, ?, ?, ?, ?)")
+	stmt, err := Db.Prepare("insert into trustassets(activationId, trustId, blockchainId, accessKey, address, asset, issuer, trustAmount, status) values(?, ?, ?, ?, ?, ?, ?, ?, 'valid')")
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, err.Error())
 		return
@@ -1104,10 +1383,12 @@ func InsertTrustAsset(c context.Context, accessKey string, activationId string,
 	defer stmt.Close()
 
 	// Perform the insert
-	_, err = stmt.Exec(activationId, blockchainId, accessKey, asset, issuer, amount)
+	_, err = stmt.Exec(activationId, trustId, blockchainId, accessKey, address, asset, issuer, amount)
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, err.Error())
 		return
 	}
 	defer stmt.Close()
+
+	auditStatus(c, accessKey, enulib.AuditTrustLine, trustId, "activation", "", "valid")
 }
@@ -205,7 +205,7 @@ func TestInsertTrustAsset(t *testing.T) {
 	ctx = context.WithValue(ctx, consts.RequestIdKey, requestId)
 
 	// Insert trustasset
-	InsertTrustAsset(ctx, "TestAccessKey", activationId, "BlockchainId", "coolasset", "niceissuer", 1000000)
+	InsertTrustAsset(ctx, "TestAccessKey", activationId, "test_"+enulib.GenerateTrustId(), "BlockchainId", "TestAddress", "coolasset", "niceissuer", 1000000)
 
 	// Retrieve the trustasset
 	//	payment := GetPaymentByPaymentId(ctx, "TestAccessKey", activationId)
@@ -230,3 +230,45 @@ func TestGetAssetByAssetId(t *testing.T) {
 
 	t.Logf("%+#v", assetRequest)
 }
+
+// Also tests CreateDerivedAddress and GetNextAddressIndex
+func TestInsertWatchWallet(t *testing.T) {
+	var accessKey string = "71625888dc50d8915b871912aa6bbdce67fd1ed77d409ef1cf0726c6d9d7cf16"
+	watchWalletId := "test_" + enulib.GenerateWatchWalletId()
+	xpub := "test_" + enulib.GenerateWatchWalletId()
+	requestId := "test_" + enulib.GenerateRequestId()
+
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, consts.RequestIdKey, requestId)
+
+	err := InsertWatchWallet(ctx, accessKey, watchWalletId, consts.CounterpartyBlockchainId, xpub, 20, "scanning")
+	if err != nil {
+		t.Errorf("Unable to insert watch wallet: %s\n", err.Error())
+	}
+
+	for i, address := range []string{"unittesting1", "unittesting2"} {
+		err = CreateDerivedAddress(ctx, accessKey, address, xpub, fmt.Sprintf("M/0/%d", i), int64(i))
+		if err != nil {
+			t.Errorf("Unable to create derived address: %s\n", err.Error())
+		}
+	}
+
+	next, err := GetNextAddressIndex(ctx, accessKey, xpub)
+	if err != nil || next != 2 {
+		t.Errorf("Expected: %d, Got: %d\n", 2, next)
+	}
+
+	err = UpdateWatchWalletScannedByWatchWalletId(ctx, accessKey, watchWalletId, 1)
+	if err != nil {
+		t.Errorf("Unable to update watch wallet: %s\n", err.Error())
+	}
+
+	watchWallet, err := GetWatchWalletByWatchWalletId(ctx, accessKey, watchWalletId)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if watchWallet.Xpub != xpub || watchWallet.LastUsedIndex != 1 || watchWallet.Status != "complete" || len(watchWallet.Addresses) != 2 || watchWallet.Addresses[1] != "unittesting2" {
+		t.Errorf("Expected: %s, %d, %s, %d addresses. Got: %+v\n", xpub, 1, "complete", 2, watchWallet)
+	}
+}
@@ -0,0 +1,341 @@
+// deposits.go
+package database
+
+import (
+	"database/sql"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+const DepositCreditedStatus = "credited" // the deposit has been credited to the customer and is waiting to be swept
+const DepositSweptStatus = "swept"       // the deposit has been sent on to the treasury address
+
+// Returns the deposit address of the customer of the access key. Returns consts.SqlNotFound if the customer has no deposit address
+func GetDepositAddress(c context.Context, accessKey string, customerReference string) (enulib.DepositAddress, error) {
+	var result enulib.DepositAddress
+	var address []byte
+	var blockchainId []byte
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select address, blockchainId from depositaddresses where accessKey = ? and customerReference = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(accessKey, customerReference).Scan(&address, &blockchainId)
+	if err != nil {
+		return result, err
+	}
+
+	result.CustomerReference = customerReference
+	result.Address = string(address)
+	result.BlockchainId = string(blockchainId)
+
+	return result, nil
+}
+
+// Returns the index of the next address to derive from the deposit wallet. Addresses are shared by every access key so the index is global
+func GetNextDepositAddressIndex(c context.Context) (uint32, error) {
+	var maxIndex sql.NullInt64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select max(addressIndex) from depositaddresses")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow().Scan(&maxIndex)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	if maxIndex.Valid == false {
+		return 0, nil
+	}
+
+	return uint32(maxIndex.Int64 + 1), nil
+}
+
+// Records the address derived from the deposit wallet for the customer of the access key
+func InsertDepositAddress(c context.Context, accessKey string, customerReference string, blockchainId string, address string, derivationPath string, addressIndex uint32) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into depositaddresses(accessKey, customerReference, blockchainId, address, derivationPath, addressIndex) values(?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(accessKey, customerReference, blockchainId, address, derivationPath, addressIndex)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Credits the customers with the assets received by their deposit addresses which haven't yet been credited, returning the number of new deposits.
+// The BTC dust which accompanies a Counterparty send, change from sweeps and BTC sent by the fee address to pay for sweeps aren't deposits
+func InsertNewDeposits(c context.Context, feeAddress string) (int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	query := "insert ignore into deposits(accessKey, customerReference, address, blockId, txid, sourceAddress, asset, quantity, status) " +
+		"select d.accessKey, d.customerReference, d.address, cr.blockIdSource, cr.txid, cr.sourceAddress, cr.inAsset, cr.inAmount, ? " +
+		"from credits cr inner join depositaddresses d on d.address = cr.destinationAddress " +
+		"where cr.status = 'valid' and cr.sourceAddress <> cr.destinationAddress and cr.sourceAddress <> ? " +
+		"and not (cr.inAsset = 'BTC' and exists (select 1 from credits o where o.txid = cr.txid and o.destinationAddress = cr.destinationAddress and o.inAsset <> 'BTC'))"
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(DepositCreditedStatus, feeAddress)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Returns the deposits received by the customer of the access key, newest first
+func GetDeposits(c context.Context, accessKey string, customerReference string) ([]enulib.Deposit, error) {
+	var result []enulib.Deposit
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select address, blockId, txid, sourceAddress, asset, quantity, status, sweepPaymentId from deposits where accessKey = ? and customerReference = ? order by blockId desc, rowId desc")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, customerReference)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		var blockId int64
+		var txId []byte
+		var sourceAddress []byte
+		var asset []byte
+		var quantity uint64
+		var status []byte
+		var sweepPaymentId []byte
+
+		if err := rows.Scan(&address, &blockId, &txId, &sourceAddress, &asset, &quantity, &status, &sweepPaymentId); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, enulib.Deposit{CustomerReference: customerReference, Address: string(address), BlockId: blockId, TxId: string(txId), SourceAddress: string(sourceAddress), Asset: string(asset), Quantity: quantity, Status: string(status), SweepPaymentId: string(sweepPaymentId)})
+	}
+
+	return result, nil
+}
+
+// Returns the total of each asset deposited by the customer of the access key
+func GetDepositBalances(c context.Context, accessKey string, customerReference string) ([]enulib.Amount, error) {
+	var result []enulib.Amount
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select asset, sum(quantity) from deposits where accessKey = ? and customerReference = ? group by asset order by asset")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, customerReference)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var asset []byte
+		var quantity uint64
+
+		if err := rows.Scan(&asset, &quantity); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, enulib.Amount{Asset: string(asset), Quantity: quantity})
+	}
+
+	return result, nil
+}
+
+// Returns the access key which owns each deposit address holding deposits which haven't been swept, keyed by address
+func GetUnsweptDepositAddresses(c context.Context) (map[string]string, error) {
+	result := make(map[string]string)
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select distinct address, accessKey from deposits where status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(DepositCreditedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		var accessKey []byte
+
+		if err := rows.Scan(&address, &accessKey); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result[string(address)] = string(accessKey)
+	}
+
+	return result, nil
+}
+
+// Records the payment which sent the asset received by the address on to the treasury address
+func UpdateDepositsSweepPaymentId(c context.Context, address string, asset string, sweepPaymentId string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("update deposits set sweepPaymentId = ? where address = ? and asset = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(sweepPaymentId, address, asset, DepositCreditedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Marks the deposits received by the address as swept once the address has been emptied.
+// Deposits which weren't swept by an asset send were swept by the BTC payment, if the BTC was worth sweeping
+func UpdateDepositsSwept(c context.Context, address string, btcSweepPaymentId string) error {
+	if isInit == false {
+		Init()
+	}
+
+	deposits, err := getAuditRows(c, "select accessKey, rowId, status from deposits where address = ? and status = ?", address, DepositCreditedStatus)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := Db.Prepare("update deposits set status = ?, sweepPaymentId = coalesce(sweepPaymentId, nullif(?, '')) where address = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(DepositSweptStatus, btcSweepPaymentId, address, DepositCreditedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	for _, deposit := range deposits {
+		auditStatus(c, deposit.accessKey, enulib.AuditDeposit, deposit.entityId, "swept", deposit.status, DepositSweptStatus)
+	}
+
+	return nil
+}
+
+// Removes the deposits of a block which is no longer in the chain and haven't been swept. Returns the number of deposits of the block which
+// had already been swept and the number of deposit journals posted for its transactions, neither of which can be undone automatically
+func RollbackDeposits(c context.Context, blockId int64) (int64, int64, error) {
+	var swept int64
+	var journaled int64
+
+	if isInit == false {
+		Init()
+	}
+
+	removed, err := getAuditRows(c, "select accessKey, rowId, status from deposits where blockId = ? and status = ?", blockId, DepositCreditedStatus)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = Db.QueryRow("select count(*) from deposits where blockId = ? and status <> ?", blockId, DepositCreditedStatus).Scan(&swept)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return 0, 0, err
+	}
+
+	err = Db.QueryRow("select count(*) from journals where journalType = ? and blockchainId = ? and reference in (select txid from deposits where blockId = ?)", enulib.JournalDeposit, consts.CounterpartyBlockchainId, blockId).Scan(&journaled)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return 0, 0, err
+	}
+
+	stmt, err := Db.Prepare("delete from deposits where blockId = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, 0, err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(blockId, DepositCreditedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to delete. Reason: %s", err.Error())
+		return 0, 0, err
+	}
+
+	for _, deposit := range removed {
+		auditStatus(c, deposit.accessKey, enulib.AuditDeposit, deposit.entityId, "rollback", deposit.status, "")
+	}
+
+	return swept, journaled, nil
+}
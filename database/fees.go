@@ -0,0 +1,236 @@
+// fees.go
+package database
+
+import (
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+const FeeRecordedStatus = enulib.FeeRecordedStatus
+const FeeCollectingStatus = enulib.FeeCollectingStatus
+const FeeCollectedStatus = enulib.FeeCollectedStatus
+const FeeErrorStatus = enulib.FeeErrorStatus
+
+// Sets the fee schedule of an operation on a blockchain for the access key, replacing any existing schedule
+func SetFeeSchedule(c context.Context, accessKey string, schedule enulib.FeeSchedule) error {
+	if isInit == false {
+		Init()
+	}
+
+	if err := enulib.ValidateFeeSchedule(schedule); err != nil {
+		return err
+	}
+
+	stmt, err := Db.Prepare("insert into feeschedules(accessKey, blockchainId, operation, feeType, feeAsset, feeIssuer, amount, basisPoints, collect) values(?, ?, ?, ?, ?, ?, ?, ?, ?) on duplicate key update feeType = values(feeType), feeAsset = values(feeAsset), feeIssuer = values(feeIssuer), amount = values(amount), basisPoints = values(basisPoints), collect = values(collect)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(accessKey, schedule.BlockchainId, schedule.Operation, schedule.FeeType, schedule.FeeAsset, schedule.FeeIssuer, schedule.Amount, schedule.BasisPoints, schedule.Collect)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Removes the fee schedule of an operation on a blockchain so the access key is no longer charged for it
+func DeleteFeeSchedule(c context.Context, accessKey string, blockchainId string, operation string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("delete from feeschedules where accessKey = ? and blockchainId = ? and operation = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(accessKey, blockchainId, operation)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to delete. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the fee schedules of the access key. If blockchainId is empty the schedules of every blockchain are returned
+func GetFeeSchedules(c context.Context, accessKey string, blockchainId string) ([]enulib.FeeSchedule, error) {
+	result := []enulib.FeeSchedule{}
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockchainId, operation, feeType, feeAsset, feeIssuer, amount, basisPoints, collect from feeschedules where accessKey = ? and (blockchainId = ? or ? = '') order by blockchainId, operation")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, blockchainId, blockchainId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schedule enulib.FeeSchedule
+		var blockchainId, operation, feeType, feeAsset, feeIssuer []byte
+
+		if err := rows.Scan(&blockchainId, &operation, &feeType, &feeAsset, &feeIssuer, &schedule.Amount, &schedule.BasisPoints, &schedule.Collect); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		schedule.BlockchainId = string(blockchainId)
+		schedule.Operation = string(operation)
+		schedule.FeeType = string(feeType)
+		schedule.FeeAsset = string(feeAsset)
+		schedule.FeeIssuer = string(feeIssuer)
+
+		result = append(result, schedule)
+	}
+
+	return result, nil
+}
+
+// Records the fee of an operation according to the schedule of the access key. Returns false if the access key has no schedule
+// for the operation or the operation has already been charged
+func RecordFee(c context.Context, accessKey string, blockchainId string, operation string, operationId string, asset string, issuer string, quantity uint64) (enulib.Fee, enulib.FeeSchedule, bool, error) {
+	var fee enulib.Fee
+	var schedule enulib.FeeSchedule
+
+	schedules, err := GetFeeSchedules(c, accessKey, blockchainId)
+	if err != nil {
+		return fee, schedule, false, err
+	}
+
+	found := false
+	for _, s := range schedules {
+		if s.Operation == operation {
+			schedule = s
+			found = true
+		}
+	}
+	if found == false {
+		return fee, schedule, false, nil
+	}
+
+	fee = enulib.Fee{BlockchainId: blockchainId, Operation: operation, OperationId: operationId, FeeType: schedule.FeeType, Asset: asset, Quantity: quantity, Status: FeeRecordedStatus}
+	fee.FeeAsset, fee.FeeIssuer, fee.FeeAmount = enulib.CalculateFee(schedule, asset, issuer, quantity)
+
+	// Recorded in UTC as the statement months are UTC, whatever the time zone of the database session
+	fee.Created = time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	stmt, err := Db.Prepare("insert ignore into fees(accessKey, blockchainId, operation, operationId, feeType, asset, quantity, feeAsset, feeIssuer, feeAmount, status, created) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return fee, schedule, false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(accessKey, fee.BlockchainId, fee.Operation, fee.OperationId, fee.FeeType, fee.Asset, fee.Quantity, fee.FeeAsset, fee.FeeIssuer, fee.FeeAmount, fee.Status, fee.Created)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return fee, schedule, false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fee, schedule, false, err
+	}
+
+	return fee, schedule, inserted > 0, nil
+}
+
+// Updates the collection status of the fee of an operation
+func UpdateFeeStatus(c context.Context, operation string, operationId string, status string, collectionPaymentId string) error {
+	if isInit == false {
+		Init()
+	}
+
+	fees, err := getAuditRows(c, "select accessKey, operationId, status from fees where operation = ? and operationId = ?", operation, operationId)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := Db.Prepare("update fees set status = ?, collectionPaymentId = ? where operation = ? and operationId = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(status, collectionPaymentId, operation, operationId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	for _, fee := range fees {
+		auditStatus(c, fee.accessKey, enulib.AuditFee, fee.entityId, operation, fee.status, status)
+	}
+
+	return nil
+}
+
+// Returns the fees charged to the access key from the start time up to but not including the end time, oldest first. Times are UTC, formatted as 2006-01-02 15:04:05
+func GetFees(c context.Context, accessKey string, from string, to string) ([]enulib.Fee, error) {
+	result := []enulib.Fee{}
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockchainId, operation, operationId, feeType, asset, quantity, feeAsset, feeIssuer, feeAmount, status, collectionPaymentId, created from fees where accessKey = ? and created >= ? and created < ? order by created, rowId")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, from, to)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fee enulib.Fee
+		var blockchainId, operation, operationId, feeType, asset, feeAsset, feeIssuer, status, collectionPaymentId, created []byte
+
+		if err := rows.Scan(&blockchainId, &operation, &operationId, &feeType, &asset, &fee.Quantity, &feeAsset, &feeIssuer, &fee.FeeAmount, &status, &collectionPaymentId, &created); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		fee.BlockchainId = string(blockchainId)
+		fee.Operation = string(operation)
+		fee.OperationId = string(operationId)
+		fee.FeeType = string(feeType)
+		fee.Asset = string(asset)
+		fee.FeeAsset = string(feeAsset)
+		fee.FeeIssuer = string(feeIssuer)
+		fee.Status = string(status)
+		fee.CollectionPaymentId = string(collectionPaymentId)
+		fee.Created = string(created)
+
+		result = append(result, fee)
+	}
+
+	return result, nil
+}
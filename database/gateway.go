@@ -0,0 +1,424 @@
+// gateway.go
+package database
+
+import (
+	"database/sql"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+const GatewayPendingStatus = "pending"   // the deposit has been received and is waiting to be paid out on the other blockchain
+const GatewayPayingStatus = "paying"     // the payout has been handed to the payment processor
+const GatewayCompleteStatus = "complete" // the payout was sent
+const GatewayErrorStatus = "error"       // the payout failed and needs the attention of the operator
+const GatewayReorgedStatus = "reorged"   // the deposit was reorganised out of the chain after it was paid out and needs the attention of the operator
+
+// Returns the index of the next payment address to derive from the gateway wallet
+func GetNextGatewayAddressIndex(c context.Context) (uint32, error) {
+	var maxIndex sql.NullInt64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select max(addressIndex) from addressmaps")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow().Scan(&maxIndex)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	if maxIndex.Valid == false {
+		return 0, nil
+	}
+
+	return uint32(maxIndex.Int64 + 1), nil
+}
+
+// Returns true if the external Ripple account is already mapped. Each external account can only be mapped once
+// so the IOUs it sends back to the issuer can be matched to a Counterparty address
+func AddressMapExists(c context.Context, externalAddress string) (bool, error) {
+	var count int64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select count(*) from addressmaps where externalAddress = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return false, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(externalAddress).Scan(&count)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Records the address map of the access key, returning its id
+func InsertAddressMap(c context.Context, accessKey string, addressMap enulib.AddressMap, addressIndex uint32) (int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into addressmaps(accessKey, counterpartyPaymentAddress, nativePaymentAddress, externalAddress, counterpartyAddress, counterpartyAssetName, nativeAssetName, counterpartyDivisible, addressIndex) values(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(accessKey, addressMap.CounterpartyPaymentAddress, addressMap.NativePaymentAddress, addressMap.ExternalAddress, addressMap.CounterpartyAddress, addressMap.CounterpartyAssetName, addressMap.NativeAssetName, addressMap.CounterpartyDivisible, addressIndex)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Returns the address maps of the access key, oldest first
+func GetAddressMaps(c context.Context, accessKey string) ([]enulib.AddressMap, error) {
+	var result []enulib.AddressMap
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select rowid, counterpartyPaymentAddress, nativePaymentAddress, externalAddress, counterpartyAddress, counterpartyAssetName, nativeAssetName, counterpartyDivisible from addressmaps where accessKey = ? order by rowid")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var addressMapId int64
+		var counterpartyPaymentAddress []byte
+		var nativePaymentAddress []byte
+		var externalAddress []byte
+		var counterpartyAddress []byte
+		var counterpartyAssetName []byte
+		var nativeAssetName []byte
+		var counterpartyDivisible bool
+
+		if err := rows.Scan(&addressMapId, &counterpartyPaymentAddress, &nativePaymentAddress, &externalAddress, &counterpartyAddress, &counterpartyAssetName, &nativeAssetName, &counterpartyDivisible); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, enulib.AddressMap{AddressMapId: addressMapId, CounterpartyPaymentAddress: string(counterpartyPaymentAddress), NativePaymentAddress: string(nativePaymentAddress), ExternalAddress: string(externalAddress), CounterpartyAddress: string(counterpartyAddress), CounterpartyAssetName: string(counterpartyAssetName), NativeAssetName: string(nativeAssetName), CounterpartyDivisible: counterpartyDivisible})
+	}
+
+	return result, nil
+}
+
+// Records the Counterparty assets received by the Counterparty payment addresses which haven't yet been recorded, to be issued as Ripple IOUs.
+// Only credits with at least the given number of confirmations of the ingested blocks are recorded. Ripple amounts are always to 8 decimal
+// places so the quantity of an asset which isn't divisible is scaled up. Returns the number of new transfers
+func InsertCounterpartyGatewayDeposits(c context.Context, confirmations int64) (int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	query := "insert ignore into gatewaytransfers(accessKey, addressMapId, depositBlockchainId, depositBlockId, depositTxId, depositSourceAddress, depositAddress, depositAsset, depositQuantity, " +
+		"payoutBlockchainId, payoutSourceAddress, payoutAddress, payoutAsset, payoutQuantity, status) " +
+		"select m.accessKey, m.rowid, ?, cr.blockIdSource, cr.txid, cr.sourceAddress, cr.destinationAddress, cr.inAsset, cr.inAmount, ?, m.nativePaymentAddress, m.externalAddress, m.nativeAssetName, " +
+		"case when m.counterpartyDivisible then cr.inAmount else cr.inAmount * ? end, ? " +
+		"from credits cr inner join addressmaps m on m.counterpartyPaymentAddress = cr.destinationAddress and m.counterpartyAssetName = cr.inAsset " +
+		"where cr.status = 'valid' and cr.sourceAddress <> cr.destinationAddress and cr.inAmount > 0 " +
+		"and cr.blockIdSource <= (select max(b.blockId) from blocks b where b.blockchainId = ? and b.status = ?) - ? + 1"
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(consts.CounterpartyBlockchainId, consts.RippleBlockchainId, consts.Satoshi, GatewayPendingStatus, consts.CounterpartyBlockchainId, consts.BlockProcessedStatus, confirmations)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Records the Ripple IOUs of the gateway issuer sent back to it by the external accounts which haven't yet been recorded, to be paid out as Counterparty assets.
+// Only payments whose delivered amount is known are recorded. The amount is scaled down for an asset which isn't divisible, an amount which
+// isn't a whole number of units is recorded as an error for the operator. Returns the number of new transfers
+func InsertRippleGatewayDeposits(c context.Context, issuer string) (int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	query := "insert ignore into gatewaytransfers(accessKey, addressMapId, depositBlockchainId, depositBlockId, depositTxId, depositSourceAddress, depositAddress, depositAsset, depositQuantity, " +
+		"payoutBlockchainId, payoutSourceAddress, payoutAddress, payoutAsset, payoutQuantity, status, errorDescription) " +
+		"select m.accessKey, m.rowid, ?, p.blockId, p.broadcastTxId, p.sourceAddress, p.destinationAddress, p.outAsset, p.deliveredAmount, ?, m.counterpartyPaymentAddress, m.counterpartyAddress, m.counterpartyAssetName, " +
+		"case when m.counterpartyDivisible then p.deliveredAmount else p.deliveredAmount div ? end, " +
+		"case when m.counterpartyDivisible or p.deliveredAmount mod ? = 0 then ? else ? end, " +
+		"case when m.counterpartyDivisible or p.deliveredAmount mod ? = 0 then null else ? end " +
+		"from payments p inner join addressmaps m on m.accessKey = p.accessKey and m.nativePaymentAddress = p.destinationAddress and m.externalAddress = p.sourceAddress and m.nativeAssetName = p.outAsset " +
+		"where p.blockchainId = ? and p.status = ? and p.issuer = ? and p.errorCode is null and p.deliveredAmount > 0"
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(consts.RippleBlockchainId, consts.CounterpartyBlockchainId, consts.Satoshi, consts.Satoshi, GatewayPendingStatus, GatewayErrorStatus,
+		consts.Satoshi, "The amount isn't a whole number of units of the Counterparty asset, which isn't divisible", consts.RippleBlockchainId, IncomingPaymentStatus, issuer)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Undoes the transfers of the Counterparty deposits in the block when the block is reorganised out of the chain. Transfers which haven't
+// been paid out are removed so they are recorded again if the deposit is mined again, the rest are marked as reorged for the operator.
+// Returns the number of transfers marked as reorged
+func RollbackCounterpartyGatewayDeposits(c context.Context, blockId int64) (int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	removed, err := getAuditRows(c, "select accessKey, rowId, status from gatewaytransfers where depositBlockchainId = ? and depositBlockId = ? and status = ?", consts.CounterpartyBlockchainId, blockId, GatewayPendingStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	reorged, err := getAuditRows(c, "select accessKey, rowId, status from gatewaytransfers where depositBlockchainId = ? and depositBlockId = ? and status not in (?, ?)", consts.CounterpartyBlockchainId, blockId, GatewayPendingStatus, GatewayReorgedStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := Db.Prepare("delete from gatewaytransfers where depositBlockchainId = ? and depositBlockId = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(consts.CounterpartyBlockchainId, blockId, GatewayPendingStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to delete. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	stmt2, err := Db.Prepare("update gatewaytransfers set status = ?, errorDescription = ? where depositBlockchainId = ? and depositBlockId = ? and status <> ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return 0, err
+	}
+	defer stmt2.Close()
+
+	_, err = stmt2.Exec(GatewayReorgedStatus, "The deposit was reorganised out of the chain", consts.CounterpartyBlockchainId, blockId, GatewayReorgedStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return 0, err
+	}
+
+	for _, transfer := range removed {
+		auditStatus(c, transfer.accessKey, enulib.AuditGateway, transfer.entityId, "rollback", transfer.status, "")
+	}
+	for _, transfer := range reorged {
+		auditStatus(c, transfer.accessKey, enulib.AuditGateway, transfer.entityId, GatewayReorgedStatus, transfer.status, GatewayReorgedStatus)
+	}
+
+	return int64(len(reorged)), nil
+}
+
+// Returns the transfers waiting to be paid out on the blockchain, oldest first, keyed by the access key which owns them
+func GetPendingGatewayTransfers(c context.Context, payoutBlockchainId string) (map[string][]enulib.GatewayTransfer, error) {
+	result := make(map[string][]enulib.GatewayTransfer)
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select accessKey, rowId, payoutSourceAddress, payoutAddress, payoutAsset, payoutQuantity from gatewaytransfers where payoutBlockchainId = ? and status = ? order by rowId")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(payoutBlockchainId, GatewayPendingStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accessKey []byte
+		var transfer enulib.GatewayTransfer
+		var payoutSourceAddress []byte
+		var payoutAddress []byte
+		var payoutAsset []byte
+
+		if err := rows.Scan(&accessKey, &transfer.GatewayTransferId, &payoutSourceAddress, &payoutAddress, &payoutAsset, &transfer.PayoutQuantity); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		transfer.PayoutBlockchainId = payoutBlockchainId
+		transfer.PayoutSourceAddress = string(payoutSourceAddress)
+		transfer.PayoutAddress = string(payoutAddress)
+		transfer.PayoutAsset = string(payoutAsset)
+		transfer.Status = GatewayPendingStatus
+
+		result[string(accessKey)] = append(result[string(accessKey)], transfer)
+	}
+
+	return result, nil
+}
+
+// Records the payment which pays out the transfer before it is sent. A transfer left as paying was interrupted and must be checked
+// against its payment by the operator, as paying it out again could pay twice
+func UpdateGatewayTransferPaying(c context.Context, gatewayTransferId int64, payoutPaymentId string) error {
+	if isInit == false {
+		Init()
+	}
+
+	transfers, err := getAuditRows(c, "select accessKey, rowId, status from gatewaytransfers where rowId = ? and status = ?", gatewayTransferId, GatewayPendingStatus)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := Db.Prepare("update gatewaytransfers set status = ?, payoutPaymentId = ? where rowId = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(GatewayPayingStatus, payoutPaymentId, gatewayTransferId, GatewayPendingStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	for _, transfer := range transfers {
+		auditStatus(c, transfer.accessKey, enulib.AuditGateway, transfer.entityId, GatewayPayingStatus, transfer.status, GatewayPayingStatus)
+	}
+
+	return nil
+}
+
+// Records the outcome of the payout. The error code is 0 if the payout was sent
+func UpdateGatewayTransferStatus(c context.Context, gatewayTransferId int64, status string, errorCode int64, errorDescription string) error {
+	if isInit == false {
+		Init()
+	}
+
+	transfers, err := getAuditRows(c, "select accessKey, rowId, status from gatewaytransfers where rowId = ?", gatewayTransferId)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := Db.Prepare("update gatewaytransfers set status = ?, errorCode = nullif(?, 0), errorDescription = nullif(?, '') where rowId = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(status, errorCode, errorDescription, gatewayTransferId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to update. Reason: %s", err.Error())
+		return err
+	}
+
+	for _, transfer := range transfers {
+		auditStatus(c, transfer.accessKey, enulib.AuditGateway, transfer.entityId, status, transfer.status, status)
+	}
+
+	return nil
+}
+
+// Returns both legs of every transfer through the gateway of the access key, newest first
+func GetGatewayTransfers(c context.Context, accessKey string) ([]enulib.GatewayTransfer, error) {
+	var result []enulib.GatewayTransfer
+
+	if isInit == false {
+		Init()
+	}
+
+	query := "select g.rowId, g.addressMapId, g.depositBlockchainId, g.depositBlockId, g.depositTxId, g.depositSourceAddress, g.depositAddress, g.depositAsset, g.depositQuantity, " +
+		"g.payoutBlockchainId, g.payoutSourceAddress, g.payoutAddress, g.payoutAsset, g.payoutQuantity, coalesce(g.payoutPaymentId, ''), coalesce(p.broadcastTxId, ''), g.status, coalesce(g.errorCode, 0), coalesce(g.errorDescription, '') " +
+		"from gatewaytransfers g left outer join payments p on p.accessKey = g.accessKey and p.sourceTxid = g.payoutPaymentId " +
+		"where g.accessKey = ? order by g.rowId desc"
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transfer enulib.GatewayTransfer
+		var depositBlockchainId, depositTxId, depositSourceAddress, depositAddress, depositAsset []byte
+		var payoutBlockchainId, payoutSourceAddress, payoutAddress, payoutAsset, payoutPaymentId, payoutTxId []byte
+		var status, errorDescription []byte
+
+		if err := rows.Scan(&transfer.GatewayTransferId, &transfer.AddressMapId, &depositBlockchainId, &transfer.DepositBlockId, &depositTxId, &depositSourceAddress, &depositAddress, &depositAsset, &transfer.DepositQuantity,
+			&payoutBlockchainId, &payoutSourceAddress, &payoutAddress, &payoutAsset, &transfer.PayoutQuantity, &payoutPaymentId, &payoutTxId, &status, &transfer.ErrorCode, &errorDescription); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		transfer.DepositBlockchainId = string(depositBlockchainId)
+		transfer.DepositTxId = string(depositTxId)
+		transfer.DepositSourceAddress = string(depositSourceAddress)
+		transfer.DepositAddress = string(depositAddress)
+		transfer.DepositAsset = string(depositAsset)
+		transfer.PayoutBlockchainId = string(payoutBlockchainId)
+		transfer.PayoutSourceAddress = string(payoutSourceAddress)
+		transfer.PayoutAddress = string(payoutAddress)
+		transfer.PayoutAsset = string(payoutAsset)
+		transfer.PayoutPaymentId = string(payoutPaymentId)
+		transfer.PayoutTxId = string(payoutTxId)
+		transfer.Status = string(status)
+		transfer.ErrorDescription = string(errorDescription)
+
+		result = append(result, transfer)
+	}
+
+	return result, nil
+}
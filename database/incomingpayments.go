@@ -0,0 +1,165 @@
+// incomingpayments.go
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Status of a payment which was received by an address rather than sent by Enu
+const IncomingPaymentStatus = "received"
+
+// Returns the access keys which own each address on the blockchain, keyed by address.
+// Deposit addresses and gateway payment addresses are included whatever the default blockchain of the access key
+func GetAddressesByBlockchainId(c context.Context, blockchainId string) (map[string][]string, error) {
+	result := make(map[string][]string)
+
+	if isInit == false {
+		Init()
+	}
+
+	// The gateway receives Counterparty assets at the Counterparty payment address and Ripple IOUs at the native payment address
+	gatewayAddress := "m.counterpartyPaymentAddress"
+	if blockchainId == consts.RippleBlockchainId {
+		gatewayAddress = "m.nativePaymentAddress"
+	}
+
+	stmt, err := Db.Prepare("select a.sourceAddress, a.accessKey from addresses a inner join userkeys u on u.accessKey = a.accessKey where u.blockchainId = ? and u.status = ? " +
+		"union select d.address, d.accessKey from depositaddresses d inner join userkeys u on u.accessKey = d.accessKey where d.blockchainId = ? and u.status = ? " +
+		"union select " + gatewayAddress + ", m.accessKey from addressmaps m inner join userkeys u on u.accessKey = m.accessKey where u.status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(blockchainId, consts.AccessKeyValidStatus, blockchainId, consts.AccessKeyValidStatus, consts.AccessKeyValidStatus)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		var accessKey []byte
+
+		if err := rows.Scan(&address, &accessKey); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result[string(address)] = append(result[string(address)], string(accessKey))
+	}
+
+	return result, nil
+}
+
+// Returns the block of the latest payment received by the address, or -1 if it hasn't received any
+func GetLastIncomingPaymentBlockId(c context.Context, blockchainId string, address string) (int64, error) {
+	var blockId sql.NullInt64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select max(blockId) from payments where blockchainId = ? and destinationAddress = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return -1, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(blockchainId, address, IncomingPaymentStatus).Scan(&blockId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return -1, err
+	}
+
+	if blockId.Valid == false {
+		return -1, nil
+	}
+
+	return blockId.Int64, nil
+}
+
+// Returns true if the payment in the transaction has already been recorded as received by the address
+func IncomingPaymentExists(c context.Context, accessKey string, address string, txId string) (bool, error) {
+	var count int64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select count(*) from payments where accessKey = ? and destinationAddress = ? and broadcastTxId = ? and status = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return false, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(accessKey, address, txId, IncomingPaymentStatus).Scan(&count)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Records a payment received by an address. The amount is the amount delivered to the address, not the amount given by the sender.
+// An error code is recorded when the delivered amount can't be determined
+func InsertIncomingPayment(c context.Context, accessKey string, paymentId string, blockId int64, blockchainId string, txId string, sourceAddress string, destinationAddress string, asset string, issuer string, deliveredAmount uint64, destinationTag *uint32, sourceTag *uint32, invoiceId string, memos []enulib.PaymentMemo, errorCode int64, errorDescription string) error {
+	if isInit == false {
+		Init()
+	}
+
+	// Missing values are stored as null so they can be told apart from a tag of 0
+	var destinationTagValue interface{}
+	if destinationTag != nil {
+		destinationTagValue = *destinationTag
+	}
+
+	var sourceTagValue interface{}
+	if sourceTag != nil {
+		sourceTagValue = *sourceTag
+	}
+
+	var memosValue interface{}
+	if len(memos) > 0 {
+		memosJson, err := json.Marshal(memos)
+		if err != nil {
+			return err
+		}
+		memosValue = string(memosJson)
+	}
+
+	var errorCodeValue interface{}
+	if errorCode != 0 {
+		errorCodeValue = errorCode
+	}
+
+	stmt, err := Db.Prepare("insert into payments(accessKey, blockId, blockchainId, sourceTxid, sourceAddress, destinationAddress, outAsset, issuer, outAmount, deliveredAmount, destinationTag, sourceTag, invoiceId, memos, status, lastUpdatedBlockId, txFee, broadcastTxId, errorCode, errorDescription, paymentTag) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, '')")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	// Perform the insert
+	_, err = stmt.Exec(accessKey, blockId, blockchainId, paymentId, sourceAddress, destinationAddress, asset, issuer, deliveredAmount, deliveredAmount, destinationTagValue, sourceTagValue, invoiceId, memosValue, IncomingPaymentStatus, blockId, txId, errorCodeValue, errorDescription)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditPayment, paymentId, "received", "", IncomingPaymentStatus)
+
+	return nil
+}
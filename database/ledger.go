@@ -0,0 +1,344 @@
+// ledger.go
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+var ErrUnknownLedgerAccount = errors.New("The ledger account doesn't exist")
+var ErrDuplicateJournal = errors.New("A journal of the same type with the same reference has already been posted")
+var ErrUnverifiedDeposit = errors.New("The transaction didn't credit an address of the access key with the asset and quantity")
+
+// Creates an internal account for the access key. Returns false if the account already exists
+func CreateLedgerAccount(c context.Context, accessKey string, accountId string) (bool, error) {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert ignore into ledgeraccounts(accessKey, accountId) values(?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(accessKey, accountId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// Returns true if the access key has the internal account
+func LedgerAccountExists(c context.Context, accessKey string, accountId string) (bool, error) {
+	var count int64
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select count(*) from ledgeraccounts where accessKey = ? and accountId = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return false, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(accessKey, accountId).Scan(&count)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Posts the journal to the ledger of the access key. The accounts are locked while their balances are checked so concurrent journals
+// can't spend the same balance. Every entry must be of an asset on the given blockchain.
+// Returns an enulib ledger error if the journal is invalid or would leave an account negative,
+// ErrUnknownLedgerAccount if an account doesn't exist and ErrDuplicateJournal if the reference has already been posted.
+// Deposits return ErrUnverifiedDeposit unless the transaction was received on chain, see verifyDeposit()
+func PostJournal(c context.Context, accessKey string, blockchainId string, requestId string, journal enulib.Journal) error {
+	if isInit == false {
+		Init()
+	}
+
+	if err := journal.Validate(); err != nil {
+		return err
+	}
+
+	for _, entry := range journal.Entries {
+		if entry.BlockchainId != blockchainId {
+			return enulib.ErrInvalidLedgerEntry
+		}
+	}
+
+	tx, err := Db.Begin()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to begin transaction. Reason: %s", err.Error())
+		return err
+	}
+
+	if err := postJournal(c, tx, accessKey, blockchainId, requestId, journal); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to commit. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func postJournal(c context.Context, tx *sql.Tx, accessKey string, blockchainId string, requestId string, journal enulib.Journal) error {
+	// The external account is created on first use so deposits can be posted without any setup
+	if _, err := tx.Exec("insert ignore into ledgeraccounts(accessKey, accountId) values(?, ?)", accessKey, enulib.LedgerExternalAccountId); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	accountIds := journal.AccountIds()
+	placeholders := strings.Repeat("?, ", len(accountIds)-1) + "?"
+	args := []interface{}{accessKey}
+	for _, accountId := range accountIds {
+		args = append(args, accountId)
+	}
+
+	rows, err := tx.Query("select accountId from ledgeraccounts where accessKey = ? and accountId in ("+placeholders+") for update", args...)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return err
+	}
+
+	var found int
+	for rows.Next() {
+		found++
+	}
+	rows.Close()
+
+	if found != len(accountIds) {
+		return ErrUnknownLedgerAccount
+	}
+
+	if journal.Reference != "" {
+		var count int64
+
+		err := tx.QueryRow("select count(*) from journals where accessKey = ? and journalType = ? and reference = ?", accessKey, journal.JournalType, journal.Reference).Scan(&count)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return err
+		}
+
+		if count > 0 {
+			return ErrDuplicateJournal
+		}
+	}
+
+	if journal.JournalType == enulib.JournalDeposit {
+		if err := verifyDeposit(c, tx, accessKey, blockchainId, journal); err != nil {
+			return err
+		}
+	}
+
+	balances, err := getLedgerBalances(c, tx, placeholders, args)
+	if err != nil {
+		return err
+	}
+
+	if err := balances.Apply(journal); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("insert into journals(accessKey, journalId, journalType, reference, blockchainId, requestId) values(?, ?, ?, nullif(?, ''), ?, ?)", accessKey, journal.JournalId, journal.JournalType, journal.Reference, blockchainId, requestId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	for _, entry := range journal.Entries {
+		_, err = tx.Exec("insert into journalentries(accessKey, journalId, accountId, blockchainId, asset, issuer, quantity) values(?, ?, ?, ?, ?, ?, ?)", accessKey, journal.JournalId, entry.AccountId, entry.BlockchainId, entry.Asset, entry.Issuer, entry.Quantity)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks the deposit against what was received on chain. The transaction must have credited addresses of the access key with exactly the
+// quantity of the asset being deposited: Counterparty and BTC credits from the ingested blocks, or Ripple payments of the asset from the same issuer which were received.
+// A transaction can only be deposited once whichever access key deposits it, since an address may belong to more than one access key
+func verifyDeposit(c context.Context, tx *sql.Tx, accessKey string, blockchainId string, journal enulib.Journal) error {
+	var count int64
+	var received int64
+	var asset enulib.LedgerAsset
+	var quantity int64
+
+	for _, entry := range journal.Entries {
+		if entry.AccountId != enulib.LedgerExternalAccountId {
+			asset = entry.LedgerAsset
+			quantity = entry.Quantity
+		}
+	}
+
+	err := tx.QueryRow("select count(*) from journals where journalType = ? and blockchainId = ? and reference = ?", journal.JournalType, blockchainId, journal.Reference).Scan(&count)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return err
+	}
+
+	if count > 0 {
+		return ErrDuplicateJournal
+	}
+
+	switch blockchainId {
+	case consts.CounterpartyBlockchainId:
+		err = tx.QueryRow("select coalesce(sum(cr.inAmount), 0) from credits cr where cr.txid = ? and cr.inAsset = ? and cr.status = 'valid' and cr.sourceAddress <> cr.destinationAddress "+
+			"and cr.destinationAddress in (select sourceAddress from addresses where accessKey = ? union select address from depositaddresses where accessKey = ?)", journal.Reference, asset.Asset, accessKey, accessKey).Scan(&received)
+	case consts.RippleBlockchainId:
+		// Payments whose delivered amount couldn't be determined have an error code and can't be deposited. XRP is recorded without an issuer
+		err = tx.QueryRow("select coalesce(sum(deliveredAmount), 0) from payments where accessKey = ? and blockchainId = ? and broadcastTxId = ? and outAsset = ? and coalesce(issuer, '') = ? and status = ? and errorCode is null",
+			accessKey, blockchainId, journal.Reference, asset.Asset, asset.Issuer, IncomingPaymentStatus).Scan(&received)
+	default:
+		return ErrUnverifiedDeposit
+	}
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return err
+	}
+
+	if received != quantity {
+		log.FluentfContext(consts.LOGERROR, c, "Deposit of %d %s %s in %s doesn't match the %d received by the addresses of the access key", quantity, asset.Asset, asset.Issuer, journal.Reference, received)
+		return ErrUnverifiedDeposit
+	}
+
+	return nil
+}
+
+// Returns the balances of the accounts given as arguments after the access key
+func getLedgerBalances(c context.Context, tx *sql.Tx, placeholders string, args []interface{}) (enulib.LedgerBalances, error) {
+	result := make(enulib.LedgerBalances)
+
+	rows, err := tx.Query("select accountId, blockchainId, asset, issuer, sum(quantity) from journalentries where accessKey = ? and accountId in ("+placeholders+") group by accountId, blockchainId, asset, issuer", args...)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accountId []byte
+		var blockchainId []byte
+		var asset []byte
+		var issuer []byte
+		var balance int64
+
+		if err := rows.Scan(&accountId, &blockchainId, &asset, &issuer, &balance); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		if result[string(accountId)] == nil {
+			result[string(accountId)] = make(map[enulib.LedgerAsset]int64)
+		}
+		result[string(accountId)][enulib.LedgerAsset{BlockchainId: string(blockchainId), Asset: string(asset), Issuer: string(issuer)}] = balance
+	}
+
+	return result, nil
+}
+
+// Returns the balance of each asset on the blockchain held by the internal account. Not used for the external account as its balances are negative
+func GetLedgerBalances(c context.Context, accessKey string, blockchainId string, accountId string) ([]enulib.Amount, error) {
+	var result []enulib.Amount
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select asset, issuer, sum(quantity) from journalentries where accessKey = ? and blockchainId = ? and accountId = ? group by asset, issuer having sum(quantity) <> 0 order by asset, issuer")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, blockchainId, accountId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var asset []byte
+		var issuer []byte
+		var balance uint64
+
+		if err := rows.Scan(&asset, &issuer, &balance); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, enulib.Amount{Asset: string(asset), Issuer: string(issuer), Quantity: balance})
+	}
+
+	return result, nil
+}
+
+// Returns the entries of assets on the blockchain posted to the internal account, newest first
+func GetLedgerEntries(c context.Context, accessKey string, blockchainId string, accountId string) ([]enulib.LedgerEntry, error) {
+	var result []enulib.LedgerEntry
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select journalId, asset, issuer, quantity from journalentries where accessKey = ? and blockchainId = ? and accountId = ? order by rowId desc")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, blockchainId, accountId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var journalId []byte
+		var asset []byte
+		var issuer []byte
+		var quantity int64
+
+		if err := rows.Scan(&journalId, &asset, &issuer, &quantity); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		ledgerAsset := enulib.LedgerAsset{BlockchainId: blockchainId, Asset: string(asset), Issuer: string(issuer)}
+		result = append(result, enulib.LedgerEntry{JournalId: string(journalId), AccountId: accountId, LedgerAsset: ledgerAsset, Quantity: quantity})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,151 @@
+// orders.go
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Inserts an order, a cancel or a BTCpay. For a cancel the offer hash is the tx hash of the order being cancelled and for a BTCpay it is the order match id.
+// Ripple offers are identified by the offer sequence instead of the offer hash and give or get currencies of an issuer
+func InsertOrder(c context.Context, accessKey string, orderId string, blockchainId string, operation string, sourceAddress string, giveAsset string, giveIssuer string, giveQuantity uint64, getAsset string, getIssuer string, getQuantity uint64, expiration uint64, offerHash string, offerSequence uint64, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into orders(accessKey, orderId, blockchainId, operation, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, offerHash, offerSequence, status) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	// Perform the insert
+	_, err = stmt.Exec(accessKey, orderId, blockchainId, operation, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, offerHash, offerSequence, status)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditOrder, orderId, operation, "", status)
+
+	return nil
+}
+
+func GetOrderByOrderId(c context.Context, accessKey string, orderId string) (enulib.Order, error) {
+	return getOrder(c, "select orderId, blockchainId, operation, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, offerHash, offerSequence, status, broadcastTxId, errorDescription from orders where orderId=? and accessKey=?", orderId, accessKey)
+}
+
+// Returns the most recent operation of the given type on the offer hash, eg the BTCpay of an order match
+func GetOrderByOfferHash(c context.Context, accessKey string, operation string, offerHash string) (enulib.Order, error) {
+	return getOrder(c, "select orderId, blockchainId, operation, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, offerHash, offerSequence, status, broadcastTxId, errorDescription from orders where offerHash=? and operation=? and accessKey=? order by rowId desc limit 1", offerHash, operation, accessKey)
+}
+
+// Returns the most recent operation of the given type on the offer hash that has reached the given status
+func GetOrderByOfferHashAndStatus(c context.Context, accessKey string, operation string, offerHash string, status string) (enulib.Order, error) {
+	return getOrder(c, "select orderId, blockchainId, operation, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, offerHash, offerSequence, status, broadcastTxId, errorDescription from orders where offerHash=? and operation=? and status=? and accessKey=? order by rowId desc limit 1", offerHash, operation, status, accessKey)
+}
+
+func getOrder(c context.Context, query string, args ...interface{}) (enulib.Order, error) {
+	if isInit == false {
+		Init()
+	}
+
+	// Set some initial values
+	var order = enulib.Order{}
+	order.Status = consts.NotFound
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return order, err
+	}
+	defer stmt.Close()
+
+	var orderId []byte
+	var blockchainId []byte
+	var operation []byte
+	var sourceAddress []byte
+	var giveAsset []byte
+	var giveIssuer []byte
+	var giveQuantity uint64
+	var getAsset []byte
+	var getIssuer []byte
+	var getQuantity uint64
+	var expiration uint64
+	var offerHash []byte
+	var offerSequence uint64
+	var status []byte
+	var broadcastTxId []byte
+	var errorMessage []byte
+
+	if err := stmt.QueryRow(args...).Scan(&orderId, &blockchainId, &operation, &sourceAddress, &giveAsset, &giveIssuer, &giveQuantity, &getAsset, &getIssuer, &getQuantity, &expiration, &offerHash, &offerSequence, &status, &broadcastTxId, &errorMessage); err == sql.ErrNoRows {
+		return order, nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return order, err
+	}
+
+	order = enulib.Order{OrderId: string(orderId), BlockchainId: string(blockchainId), Operation: string(operation), SourceAddress: string(sourceAddress), GiveAsset: string(giveAsset), GiveIssuer: string(giveIssuer), GiveQuantity: giveQuantity, GetAsset: string(getAsset), GetIssuer: string(getIssuer), GetQuantity: getQuantity, Expiration: expiration, OfferHash: string(offerHash), OfferSequence: offerSequence, Status: string(status), BroadcastTxId: string(broadcastTxId), ErrorMessage: string(errorMessage)}
+
+	return order, nil
+}
+
+func UpdateOrderStatusByOrderId(c context.Context, accessKey string, orderId string, status string) error {
+	return updateOrder(c, accessKey, orderId, "status", status, "update orders set status=? where accessKey=? and orderId=?", status, accessKey, orderId)
+}
+
+// Ripple offers are only assigned a sequence when the OfferCreate is signed
+func UpdateOrderOfferSequenceByOrderId(c context.Context, accessKey string, orderId string, offerSequence uint64) error {
+	return updateOrder(c, accessKey, orderId, "", "", "update orders set offerSequence=? where accessKey=? and orderId=?", offerSequence, accessKey, orderId)
+}
+
+func UpdateOrderCompleteByOrderId(c context.Context, accessKey string, orderId string, txId string) error {
+	return updateOrder(c, accessKey, orderId, "complete", "complete", "update orders set status='complete', broadcastTxId=? where accessKey=? and orderId=?", txId, accessKey, orderId)
+}
+
+func UpdateOrderWithErrorByOrderId(c context.Context, accessKey string, orderId string, errorCode int64, errorDescription string) error {
+	return updateOrder(c, accessKey, orderId, "error", "error", "update orders set status='error', errorCode=?, errorDescription=? where accessKey=? and orderId=?", errorCode, errorDescription, accessKey, orderId)
+}
+
+// Changes which don't change the status pass an empty action and aren't audited
+func updateOrder(c context.Context, accessKey string, orderId string, action string, status string, query string, args ...interface{}) error {
+	if isInit == false {
+		Init()
+	}
+
+	order, err := GetOrderByOrderId(c, accessKey, orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.OrderId == "" {
+		errorString := fmt.Sprintf("Order does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	if action != "" {
+		auditStatus(c, accessKey, enulib.AuditOrder, orderId, action, order.Status, status)
+	}
+
+	return nil
+}
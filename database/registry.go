@@ -0,0 +1,224 @@
+// registry.go
+package database
+
+import (
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Returns the snapshot of the holders of the asset at the block and whether one has been taken. Holdings are largest first
+func GetRegistrySnapshot(c context.Context, asset string, blockId uint64) (enulib.RegistrySnapshot, []enulib.AddressAmount, bool, error) {
+	var snapshot enulib.RegistrySnapshot
+	var holdings []enulib.AddressAmount
+	var triggerType []byte
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select triggerType, holders, total from registrysnapshots where asset = ? and blockId = ?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return snapshot, holdings, false, err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(asset, blockId).Scan(&triggerType, &snapshot.Holders, &snapshot.Total)
+	if err != nil {
+		if err.Error() == consts.SqlNotFound {
+			return snapshot, holdings, false, nil
+		}
+
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return snapshot, holdings, false, err
+	}
+
+	snapshot.Asset = asset
+	snapshot.BlockId = blockId
+	snapshot.Trigger = string(triggerType)
+
+	stmt2, err := Db.Prepare("select ownerAddress, quantity from registry where asset = ? and blockId = ? order by quantity desc, ownerAddress")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return snapshot, holdings, false, err
+	}
+	defer stmt2.Close()
+
+	rows, err := stmt2.Query(asset, blockId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return snapshot, holdings, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		var quantity uint64
+
+		if err := rows.Scan(&address, &quantity); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return snapshot, holdings, false, err
+		}
+
+		holdings = append(holdings, enulib.AddressAmount{Address: string(address), Quantity: quantity})
+	}
+
+	return snapshot, holdings, true, nil
+}
+
+// Records the holders of the asset at the block. A snapshot is only recorded once for each block as the holdings at a parsed block don't change
+func InsertRegistrySnapshot(c context.Context, snapshot enulib.RegistrySnapshot, holdings []enulib.AddressAmount) error {
+	if isInit == false {
+		Init()
+	}
+
+	tx, err := Db.Begin()
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to begin transaction. Reason: %s", err.Error())
+		return err
+	}
+
+	result, err := tx.Exec("insert ignore into registrysnapshots(asset, blockId, triggerType, holders, total) values(?, ?, ?, ?, ?)", snapshot.Asset, snapshot.BlockId, snapshot.Trigger, snapshot.Holders, snapshot.Total)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil || inserted == 0 {
+		tx.Rollback()
+		return err
+	}
+
+	for _, holding := range holdings {
+		_, err := tx.Exec("insert into registry(blockId, ownerAddress, asset, quantity) values(?, ?, ?, ?)", snapshot.BlockId, holding.Address, snapshot.Asset, holding.Quantity)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to commit. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the snapshots taken of the asset, oldest block first
+func GetRegistrySnapshots(c context.Context, asset string) ([]enulib.RegistrySnapshot, error) {
+	result := []enulib.RegistrySnapshot{}
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select blockId, triggerType, holders, total from registrysnapshots where asset = ? order by blockId")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(asset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot enulib.RegistrySnapshot
+		var triggerType []byte
+
+		if err := rows.Scan(&snapshot.BlockId, &triggerType, &snapshot.Holders, &snapshot.Total); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		snapshot.Asset = asset
+		snapshot.Trigger = string(triggerType)
+
+		result = append(result, snapshot)
+	}
+
+	return result, nil
+}
+
+// Schedules a snapshot of the asset for the access key. The block is only used for the block trigger
+func InsertRegistrySchedule(c context.Context, accessKey string, asset string, triggerType string, blockId uint64) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert ignore into registryschedules(accessKey, asset, triggerType, blockId) values(?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(accessKey, asset, triggerType, blockId)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the snapshots scheduled for each asset, keyed by asset. If accessKey is empty the schedules of every access key are combined
+func GetRegistrySchedules(c context.Context, accessKey string) (map[string]enulib.RegistrySchedule, error) {
+	result := make(map[string]enulib.RegistrySchedule)
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select distinct asset, triggerType, blockId from registryschedules where accessKey = ? or ? = '' order by asset, blockId")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, accessKey)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var asset []byte
+		var triggerType []byte
+		var blockId uint64
+
+		if err := rows.Scan(&asset, &triggerType, &blockId); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		schedule := result[string(asset)]
+		schedule.Asset = string(asset)
+
+		switch string(triggerType) {
+		case enulib.SnapshotBlock:
+			schedule.BlockIds = append(schedule.BlockIds, blockId)
+		case enulib.SnapshotIssuance:
+			schedule.OnIssuance = true
+		case enulib.SnapshotDividend:
+			schedule.OnDividend = true
+		}
+
+		result[string(asset)] = schedule
+	}
+
+	return result, nil
+}
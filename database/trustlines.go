@@ -0,0 +1,120 @@
+// trustlines.go
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Records an operation on the trust line held by the address for the asset issued by the issuer
+func InsertTrustLine(c context.Context, accessKey string, trustId string, blockchainId string, operation string, address string, asset string, issuer string, limit uint64, qualityIn uint32, qualityOut uint32, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into trustassets(accessKey, trustId, blockchainId, operation, address, asset, issuer, trustAmount, qualityIn, qualityOut, status) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	// Perform the insert
+	_, err = stmt.Exec(accessKey, trustId, blockchainId, operation, address, asset, issuer, limit, qualityIn, qualityOut, status)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditTrustLine, trustId, operation, "", status)
+
+	return nil
+}
+
+func GetTrustLineByTrustId(c context.Context, accessKey string, trustId string) (enulib.TrustLine, error) {
+	if isInit == false {
+		Init()
+	}
+
+	// Set some initial values
+	var trustLine = enulib.TrustLine{}
+	trustLine.Status = consts.NotFound
+
+	stmt, err := Db.Prepare("select trustId, blockchainId, operation, address, asset, issuer, coalesce(trustAmount, 0), coalesce(qualityIn, 0), coalesce(qualityOut, 0), status, broadcastTxId, errorDescription from trustassets where trustId=? and accessKey=?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return trustLine, err
+	}
+	defer stmt.Close()
+
+	var id []byte
+	var blockchainId []byte
+	var operation []byte
+	var address []byte
+	var asset []byte
+	var issuer []byte
+	var limit uint64
+	var qualityIn uint32
+	var qualityOut uint32
+	var status []byte
+	var broadcastTxId []byte
+	var errorMessage []byte
+
+	if err := stmt.QueryRow(trustId, accessKey).Scan(&id, &blockchainId, &operation, &address, &asset, &issuer, &limit, &qualityIn, &qualityOut, &status, &broadcastTxId, &errorMessage); err == sql.ErrNoRows {
+		return trustLine, nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return trustLine, err
+	}
+
+	trustLine = enulib.TrustLine{TrustId: string(id), BlockchainId: string(blockchainId), Operation: string(operation), Address: string(address), Asset: string(asset), Issuer: string(issuer), Limit: limit, QualityIn: qualityIn, QualityOut: qualityOut, Status: string(status), BroadcastTxId: string(broadcastTxId), ErrorMessage: string(errorMessage)}
+
+	return trustLine, nil
+}
+
+func UpdateTrustLineCompleteByTrustId(c context.Context, accessKey string, trustId string, txId string) error {
+	return updateTrustLine(c, accessKey, trustId, "complete", "update trustassets set status='complete', broadcastTxId=? where accessKey=? and trustId=?", txId, accessKey, trustId)
+}
+
+func UpdateTrustLineWithErrorByTrustId(c context.Context, accessKey string, trustId string, errorCode int64, errorDescription string) error {
+	return updateTrustLine(c, accessKey, trustId, "error", "update trustassets set status='error', errorCode=?, errorDescription=? where accessKey=? and trustId=?", errorCode, errorDescription, accessKey, trustId)
+}
+
+func updateTrustLine(c context.Context, accessKey string, trustId string, status string, query string, args ...interface{}) error {
+	if isInit == false {
+		Init()
+	}
+
+	trustLine, err := GetTrustLineByTrustId(c, accessKey, trustId)
+	if err != nil {
+		return err
+	}
+
+	if trustLine.TrustId == "" {
+		errorString := fmt.Sprintf("Trust line operation does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	auditStatus(c, accessKey, enulib.AuditTrustLine, trustId, status, trustLine.Status, status)
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// watchwallets.go
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Inserts a watch-only wallet registered from an extended public key
+func InsertWatchWallet(c context.Context, accessKey string, watchWalletId string, blockchainId string, xpub string, gapLimit uint32, status string) error {
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("insert into watchwallets(accessKey, watchWalletId, blockchainId, xpub, gapLimit, lastUsedIndex, status) values(?, ?, ?, ?, ?, -1, ?)")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return err
+	}
+	defer stmt.Close()
+
+	// Perform the insert
+	_, err = stmt.Exec(accessKey, watchWalletId, blockchainId, xpub, gapLimit, status)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to insert. Reason: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func GetWatchWalletByWatchWalletId(c context.Context, accessKey string, watchWalletId string) (enulib.WatchWallet, error) {
+	if isInit == false {
+		Init()
+	}
+
+	// Set some initial values
+	var watchWallet = enulib.WatchWallet{}
+	watchWallet.Status = consts.NotFound
+
+	stmt, err := Db.Prepare("select watchWalletId, blockchainId, xpub, gapLimit, lastUsedIndex, status, errorDescription from watchwallets where watchWalletId=? and accessKey=?")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return watchWallet, err
+	}
+	defer stmt.Close()
+
+	var blockchainId []byte
+	var xpub []byte
+	var gapLimit uint32
+	var lastUsedIndex int64
+	var status []byte
+	var errorMessage []byte
+
+	if err := stmt.QueryRow(watchWalletId, accessKey).Scan(&watchWalletId, &blockchainId, &xpub, &gapLimit, &lastUsedIndex, &status, &errorMessage); err == sql.ErrNoRows {
+		return watchWallet, nil
+	} else if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+		return watchWallet, err
+	}
+
+	watchWallet = enulib.WatchWallet{WatchWalletId: watchWalletId, BlockchainId: string(blockchainId), Xpub: string(xpub), GapLimit: gapLimit, LastUsedIndex: lastUsedIndex, Status: string(status), ErrorMessage: string(errorMessage)}
+
+	watchWallet.Addresses, err = GetAddressesByAccountXpub(c, accessKey, watchWallet.Xpub)
+	if err != nil {
+		return watchWallet, err
+	}
+
+	return watchWallet, nil
+}
+
+// Returns the addresses derived from the account in order of their index on the external chain
+func GetAddressesByAccountXpub(c context.Context, accessKey string, accountXpub string) ([]string, error) {
+	var result []string
+
+	if isInit == false {
+		Init()
+	}
+
+	stmt, err := Db.Prepare("select sourceAddress from addresses where accessKey = ? and accountXpub = ? order by addressIndex")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to prepare statement. Reason: %s", err.Error())
+		return result, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accessKey, accountXpub)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Failed to query. Reason: %s", err.Error())
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+
+		if err := rows.Scan(&address); err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Failed to Scan. Reason: %s", err.Error())
+			return result, err
+		}
+
+		result = append(result, string(address))
+	}
+
+	return result, nil
+}
+
+func UpdateWatchWalletStatusByWatchWalletId(c context.Context, accessKey string, watchWalletId string, status string) error {
+	return updateWatchWallet(c, accessKey, watchWalletId, "update watchwallets set status=? where accessKey=? and watchWalletId=?", status, accessKey, watchWalletId)
+}
+
+func UpdateWatchWalletScannedByWatchWalletId(c context.Context, accessKey string, watchWalletId string, lastUsedIndex int64) error {
+	return updateWatchWallet(c, accessKey, watchWalletId, "update watchwallets set status='complete', lastUsedIndex=?, errorCode=null, errorDescription=null where accessKey=? and watchWalletId=?", lastUsedIndex, accessKey, watchWalletId)
+}
+
+func UpdateWatchWalletWithErrorByWatchWalletId(c context.Context, accessKey string, watchWalletId string, errorCode int64, errorDescription string) error {
+	return updateWatchWallet(c, accessKey, watchWalletId, "update watchwallets set status='error', errorCode=?, errorDescription=? where accessKey=? and watchWalletId=?", errorCode, errorDescription, accessKey, watchWalletId)
+}
+
+func updateWatchWallet(c context.Context, accessKey string, watchWalletId string, query string, args ...interface{}) error {
+	if isInit == false {
+		Init()
+	}
+
+	watchWallet, err := GetWatchWalletByWatchWalletId(c, accessKey, watchWalletId)
+	if err != nil {
+		return err
+	}
+
+	if watchWallet.WatchWalletId == "" {
+		errorString := fmt.Sprintf("Watch wallet does not exist or cannot be accessed by %s\n", accessKey)
+
+		return errors.New(errorString)
+	}
+
+	stmt, err := Db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Returns the deposit address of a customer of the access key, creating it if the customer reference is new
+func DepositAddressCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "depositAddressCreate")
+
+	return handle(c, w, r)
+}
+
+// Deposits credited to a customer of the access key
+func CustomerDeposits(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "customerDeposits")
+
+	return handle(c, w, r)
+}
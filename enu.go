@@ -4,6 +4,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+
+	"github.com/whoisjeremylam/enu/bitcoinapi"
+	"github.com/whoisjeremylam/enu/counterpartyapi"
+	"github.com/whoisjeremylam/enu/counterpartyhandlers"
+	"github.com/whoisjeremylam/enu/rippleapi"
+	"github.com/whoisjeremylam/enu/ripplehandlers"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 )
 
 func main() {
@@ -18,6 +26,36 @@ func main() {
 		env = "unknown host"
 	}
 
+	// Load the derivation paths of wallet addresses so signing doesn't need to search for them
+	if err := counterpartyhandlers.LoadAddressPaths(context.TODO()); err != nil {
+		log.Printf("Unable to load address derivation paths: %s", err.Error())
+	}
+
+	// Keep track of the health and block height of each upstream node so that requests go to the node which is most caught up
+	go bitcoinapi.MonitorNodes(context.TODO())
+	go counterpartyapi.MonitorNodes(context.TODO())
+	go rippleapi.MonitorNodes(context.TODO())
+
+	// Record the credits and debits of Counterparty addresses from each new block
+	go counterpartyhandlers.IngestBlocks(context.TODO())
+
+	// Record the blocks of bitcoind and the ledgers of rippled so that how far each backend is behind is reported
+	go counterpartyhandlers.ProcessBitcoinBlocks(context.TODO())
+	go ripplehandlers.ProcessLedgers(context.TODO())
+
+	// Credit customers with the deposits to their deposit addresses and sweep them to the treasury address
+	go counterpartyhandlers.SweepDeposits(context.TODO())
+
+	// Record payments received by Ripple addresses
+	go ripplehandlers.ListenForPayments(context.TODO())
+
+	// Mirror Counterparty assets deposited to the gateway as Ripple IOUs and pay out the IOUs sent back as Counterparty assets
+	go ripplehandlers.IssueGatewayTransfers(context.TODO())
+	go counterpartyhandlers.PayGatewayTransfers(context.TODO())
+
+	// Take the scheduled snapshots of the holders of Counterparty assets
+	go counterpartyhandlers.SnapshotRegistry(context.TODO())
+
 	router := NewRouter()
 
 	log.Printf("Enu %s API server started on %s", env, hostname)
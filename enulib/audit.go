@@ -0,0 +1,84 @@
+// Append-only audit trail of state changes.
+// Each entry includes the hash of the entry before it in its own hash, so altering, removing or reordering any entry breaks every hash after it.
+
+package enulib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+const (
+	AuditRequest    = "request"    // a mutating API call. The status is the HTTP status code of the response
+	AuditPayment    = "payment"    // a payment, identified by paymentId
+	AuditAsset      = "asset"      // an asset operation, identified by assetId
+	AuditDividend   = "dividend"   // a dividend, identified by dividendId
+	AuditActivation = "activation" // an address activation, identified by activationId
+	AuditAccessKey  = "accessKey"  // an access key, identified by the access key
+	AuditOrder      = "order"      // an order, cancel or BTCpay, identified by orderId
+	AuditBroadcast  = "broadcast"  // a broadcast, identified by broadcastId
+	AuditTrustLine  = "trustLine"  // a trust line operation, identified by trustId
+	AuditGateway    = "gateway"    // a transfer through the gateway, identified by gatewayTransferId
+	AuditFee        = "fee"        // the fee of an operation, identified by the operationId
+	AuditDeposit    = "deposit"    // a deposit to a deposit address, identified by the rowId of the deposit
+)
+
+type AuditEntry struct {
+	AuditId      int64  `json:"auditId"`
+	AccessKey    string `json:"accessKey"` // the access key which made the change
+	RequestId    string `json:"requestId"` // empty for changes made by background processing
+	EntityType   string `json:"entityType"`
+	EntityId     string `json:"entityId"`
+	Action       string `json:"action"`
+	FromStatus   string `json:"fromStatus"`
+	ToStatus     string `json:"toStatus"`
+	Created      string `json:"created"` // UTC, formatted as 2006-01-02 15:04:05
+	PreviousHash string `json:"previousHash"`
+	Hash         string `json:"hash"`
+}
+
+type AuditEntries struct {
+	Entries   []AuditEntry `json:"entries"`
+	RequestId string       `json:"requestId"`
+	Nonce     int64        `json:"nonce"`
+}
+
+type AuditVerification struct {
+	Valid         bool   `json:"valid"`
+	Entries       int64  `json:"entries"`                 // number of entries checked
+	BrokenAuditId int64  `json:"brokenAuditId,omitempty"` // first entry which doesn't match the chain
+	LastHash      string `json:"lastHash"`                // hash of the last entry checked. Keep a copy to detect entries removed from the end
+}
+
+// Result of a verification of the audit trail returned by the API. The audit trail is shared by every access key so only the
+// outcome is returned, not the entry which broke the chain
+type AuditChainStatus struct {
+	Valid     bool   `json:"valid"`
+	Entries   int64  `json:"entries"`  // number of entries checked
+	LastHash  string `json:"lastHash"` // hash of the last entry checked
+	RequestId string `json:"requestId"`
+	Nonce     int64  `json:"nonce"`
+}
+
+// Returns the hex encoded SHA-256 hash of the entry, including the hash of the previous entry. The auditId and hash of the entry aren't hashed
+func HashAuditEntry(entry AuditEntry) string {
+	// The fields are hashed as a JSON array so that no two different entries have the same encoding
+	encoded, _ := json.Marshal([]string{entry.PreviousHash, entry.AccessKey, entry.RequestId, entry.EntityType, entry.EntityId, entry.Action, entry.FromStatus, entry.ToStatus, entry.Created})
+	hash := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Checks the entries, oldest first, continue the chain from previousHash. Returns the auditId of the first entry which doesn't, or 0 if they all do
+func VerifyAuditChain(previousHash string, entries []AuditEntry) int64 {
+	for _, entry := range entries {
+		if entry.PreviousHash != previousHash || entry.Hash != HashAuditEntry(entry) {
+			return entry.AuditId
+		}
+
+		previousHash = entry.Hash
+	}
+
+	return 0
+}
@@ -0,0 +1,93 @@
+package enulib
+
+import (
+	"testing"
+)
+
+// Builds a valid chain of the entries, starting from an empty hash
+func chainAuditEntries(entries []AuditEntry) []AuditEntry {
+	previousHash := ""
+
+	for i := range entries {
+		entries[i].AuditId = int64(i + 1)
+		entries[i].PreviousHash = previousHash
+		entries[i].Hash = HashAuditEntry(entries[i])
+		previousHash = entries[i].Hash
+	}
+
+	return entries
+}
+
+func testAuditEntries() []AuditEntry {
+	return chainAuditEntries([]AuditEntry{
+		{AccessKey: "key1", RequestId: "request1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", FromStatus: "", ToStatus: "authorized", Created: "2016-01-01 00:00:00"},
+		{AccessKey: "key1", RequestId: "", EntityType: AuditPayment, EntityId: "payment1", Action: "complete", FromStatus: "authorized", ToStatus: "complete", Created: "2016-01-01 00:00:10"},
+		{AccessKey: "key2", RequestId: "request2", EntityType: AuditAccessKey, EntityId: "key2", Action: "status", FromStatus: "valid", ToStatus: "disabled", Created: "2016-01-01 00:01:00"},
+	})
+}
+
+func TestHashAuditEntry(t *testing.T) {
+	entry := AuditEntry{AccessKey: "key1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", ToStatus: "authorized", Created: "2016-01-01 00:00:00"}
+	hash := HashAuditEntry(entry)
+
+	var testData = []struct {
+		Entry           AuditEntry
+		Same            bool
+		CaseDescription string
+	}{
+		{entry, true, "Same entry"},
+		{AuditEntry{AuditId: 5, AccessKey: "key1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", ToStatus: "authorized", Created: "2016-01-01 00:00:00", Hash: "x"}, true, "auditId and hash aren't hashed"},
+		{AuditEntry{AccessKey: "key1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", ToStatus: "complete", Created: "2016-01-01 00:00:00"}, false, "Different status"},
+		{AuditEntry{AccessKey: "key1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", ToStatus: "authorized", Created: "2016-01-01 00:00:00", PreviousHash: "00"}, false, "Different previous hash"},
+		{AuditEntry{AccessKey: "key1", EntityType: AuditPayment, EntityId: "payment1", Action: "payment", FromStatus: "authorized", Created: "2016-01-01 00:00:00"}, false, "Status moved between fields"},
+	}
+
+	for _, s := range testData {
+		result := HashAuditEntry(s.Entry) == hash
+
+		if result != s.Same {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Same, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	valid := testAuditEntries()
+
+	altered := testAuditEntries()
+	altered[1].ToStatus = "error"
+
+	rehashed := testAuditEntries()
+	rehashed[1].ToStatus = "error"
+	rehashed[1].Hash = HashAuditEntry(rehashed[1])
+
+	removed := testAuditEntries()
+	removed = append(removed[:1], removed[2:]...)
+
+	reordered := testAuditEntries()
+	reordered[0], reordered[1] = reordered[1], reordered[0]
+
+	var testData = []struct {
+		PreviousHash    string
+		Entries         []AuditEntry
+		Expected        int64
+		CaseDescription string
+	}{
+		{"", nil, 0, "No entries"},
+		{"", valid, 0, "Valid chain"},
+		{valid[0].Hash, valid[1:], 0, "Valid chain continued from an earlier entry"},
+		{"", altered, 2, "Altered entry"},
+		{"", rehashed, 3, "Altered entry with its hash recalculated breaks the next entry"},
+		{"", removed, 3, "Removed entry"},
+		{"", reordered, 2, "Reordered entries"},
+		{"00", valid, 1, "Wrong starting hash"},
+	}
+
+	for _, s := range testData {
+		result := VerifyAuditChain(s.PreviousHash, s.Entries)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
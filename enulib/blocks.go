@@ -0,0 +1,47 @@
+// Tracking of the blocks processed from each backend.
+// A block is processed once, in order of height. When the backend reorganises its chain the processed blocks which are no longer in
+// the chain are found by comparing the recorded hash of each height with the hash the backend now reports.
+
+package enulib
+
+// Returns the highest height at or below blockId which doesn't need processing again, searching at most maxDepth blocks down.
+// A height is kept when its recorded hash matches the hash in the chain, or when nothing was recorded for it so there is nothing to undo.
+// If every block in the search is different the height below the search is returned
+func FindForkBlock(blockId int64, maxDepth int64, recordedHash func(int64) (string, error), chainHash func(int64) (string, error)) (int64, error) {
+	for height := blockId; height > blockId-maxDepth && height >= 0; height-- {
+		recorded, err := recordedHash(height)
+		if err != nil {
+			return -1, err
+		}
+		if recorded == "" {
+			return height, nil
+		}
+
+		current, err := chainHash(height)
+		if err != nil {
+			return -1, err
+		}
+		if current == recorded {
+			return height, nil
+		}
+	}
+
+	if blockId-maxDepth < -1 {
+		return -1, nil
+	}
+
+	return blockId - maxDepth, nil
+}
+
+// Returns how many blocks the processing of a chain is behind the tip of its backend, or -1 if either isn't known.
+// The tip is polled separately so the last processed block can briefly be ahead of it, which counts as caught up
+func BlockLag(lastBlockId int64, tipBlockId int64) int64 {
+	if lastBlockId < 0 || tipBlockId < 0 {
+		return -1
+	}
+	if lastBlockId > tipBlockId {
+		return 0
+	}
+
+	return tipBlockId - lastBlockId
+}
@@ -0,0 +1,62 @@
+package enulib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindForkBlock(t *testing.T) {
+	recorded := map[int64]string{100: "a100", 101: "a101", 102: "a102", 103: "a103"}
+
+	var testData = []struct {
+		BlockId         int64
+		MaxDepth        int64
+		Chain           map[int64]string
+		Expected        int64
+		CaseDescription string
+	}{
+		{103, 10, map[int64]string{100: "a100", 101: "a101", 102: "a102", 103: "a103"}, 103, "No reorg"},
+		{103, 10, map[int64]string{100: "a100", 101: "a101", 102: "b102", 103: "b103"}, 101, "Two blocks reorged"},
+		{103, 10, map[int64]string{100: "b100", 101: "b101", 102: "b102", 103: "b103"}, 99, "Reorged below the recorded blocks"},
+		{103, 2, map[int64]string{100: "a100", 101: "b101", 102: "b102", 103: "b103"}, 101, "Deeper than the search"},
+		{1, 10, map[int64]string{0: "b0", 1: "b1"}, 1, "Nothing recorded"},
+	}
+
+	for _, s := range testData {
+		chain := s.Chain
+		result, err := FindForkBlock(s.BlockId, s.MaxDepth, func(height int64) (string, error) { return recorded[height], nil }, func(height int64) (string, error) { return chain[height], nil })
+
+		if err != nil || result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v %v\nCase: %s\n", s.Expected, result, err, s.CaseDescription)
+		}
+	}
+
+	// An error from the backend stops the search
+	backendError := errors.New("timeout")
+	if _, err := FindForkBlock(103, 10, func(height int64) (string, error) { return recorded[height], nil }, func(height int64) (string, error) { return "", backendError }); err != backendError {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", backendError, err, "Backend error")
+	}
+}
+
+func TestBlockLag(t *testing.T) {
+	var testData = []struct {
+		LastBlockId     int64
+		TipBlockId      int64
+		Expected        int64
+		CaseDescription string
+	}{
+		{400000, 400000, 0, "Caught up"},
+		{399990, 400000, 10, "Behind"},
+		{400001, 400000, 0, "Processed ahead of the polled tip"},
+		{-1, 400000, -1, "Nothing processed"},
+		{400000, -1, -1, "Tip not polled"},
+	}
+
+	for _, s := range testData {
+		result := BlockLag(s.LastBlockId, s.TipBlockId)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
@@ -41,3 +41,23 @@ func GenerateRequestId() string {
 func GenerateActivationId() string {
 	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
 }
+
+func GenerateWatchWalletId() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
+
+func GenerateOrderId() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
+
+func GenerateBroadcastId() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
+
+func GenerateTrustId() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
+
+func GenerateJournalId() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
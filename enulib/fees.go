@@ -0,0 +1,189 @@
+// Service fees charged to an access key for the operations it makes.
+// A fee schedule sets a flat fee or a percentage of the quantity for each operation on each blockchain. Fees are recorded when the
+// operation has been broadcast and are either billed in the monthly statement or collected on chain to the operator's fee address.
+
+package enulib
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+)
+
+const (
+	FeePayment    = "payment"
+	FeeIssuance   = "issuance"
+	FeeDividend   = "dividend"
+	FeeActivation = "activation"
+)
+
+const (
+	FeeFlat       = "flat"       // a fixed amount of the fee asset
+	FeePercentage = "percentage" // basis points of the quantity of the operation, charged in the asset of the operation
+)
+
+const (
+	FeeRecordedStatus   = "recorded"   // the fee is billed in the monthly statement
+	FeeCollectingStatus = "collecting" // the fee payment to the operator's fee address has been handed to the payment processor and isn't validated yet
+	FeeCollectedStatus  = "collected"  // the fee payment succeeded in a validated ledger
+	FeeErrorStatus      = "error"      // the fee payment failed. The fee is billed in the monthly statement instead
+)
+
+var ErrInvalidFeeOperation = errors.New("The fee operation must be one of payment, issuance, dividend or activation")
+var ErrInvalidFeeType = errors.New("The fee type must be flat or percentage")
+var ErrFlatFeeAsset = errors.New("A flat fee must specify the fee asset")
+var ErrPercentageFeeOperation = errors.New("Percentage fees can only be charged on payments and issuances")
+var ErrFeeBasisPoints = errors.New("A percentage fee must be between 0 and 10000 basis points")
+var ErrFeeCollectBlockchain = errors.New("Counterparty fees can't be collected on chain, they are billed in the monthly statement")
+
+// One drop, the smallest amount of XRP which can be paid, in the 1/100000000 XRP units of the API
+const XRPDrop = 100
+
+type FeeSchedule struct {
+	BlockchainId string `json:"blockchainId"`
+	Operation    string `json:"operation"`
+	FeeType      string `json:"feeType"`
+	FeeAsset     string `json:"feeAsset,omitempty"`  // asset of a flat fee
+	FeeIssuer    string `json:"feeIssuer,omitempty"` // issuer of a flat fee in a Ripple IOU
+	Amount       uint64 `json:"amount"`              // flat fee in the base unit of the fee asset
+	BasisPoints  uint64 `json:"basisPoints"`         // percentage fee in hundredths of a percent
+	Collect      bool   `json:"collect"`             // collect the fee on chain from the source address of the operation. Only Ripple fees can be collected
+}
+
+type FeeSchedules struct {
+	Schedules []FeeSchedule `json:"schedules"`
+	RequestId string        `json:"requestId"`
+	Nonce     int64         `json:"nonce"`
+}
+
+type Fee struct {
+	BlockchainId        string `json:"blockchainId"`
+	Operation           string `json:"operation"`
+	OperationId         string `json:"operationId"` // paymentId, assetId, dividendId or activationId
+	FeeType             string `json:"feeType"`
+	Asset               string `json:"asset"`
+	Quantity            uint64 `json:"quantity"`
+	FeeAsset            string `json:"feeAsset"`
+	FeeIssuer           string `json:"feeIssuer,omitempty"`
+	FeeAmount           uint64 `json:"feeAmount"`
+	Status              string `json:"status"`
+	CollectionPaymentId string `json:"collectionPaymentId,omitempty"`
+	Created             string `json:"created,omitempty"`
+}
+
+// Total fees of an operation on a blockchain in one fee asset
+type FeeStatementLine struct {
+	BlockchainId string `json:"blockchainId"`
+	Operation    string `json:"operation"`
+	FeeAsset     string `json:"feeAsset"`
+	FeeIssuer    string `json:"feeIssuer,omitempty"`
+	Count        int64  `json:"count"`
+	Total        uint64 `json:"total"`
+}
+
+type FeeStatement struct {
+	Month     string             `json:"month"` // 2006-01
+	Lines     []FeeStatementLine `json:"lines"`
+	Fees      []Fee              `json:"fees"`
+	RequestId string             `json:"requestId"`
+	Nonce     int64              `json:"nonce"`
+}
+
+// Checks the schedule can be used to calculate fees
+func ValidateFeeSchedule(schedule FeeSchedule) error {
+	switch schedule.Operation {
+	case FeePayment, FeeIssuance, FeeDividend, FeeActivation:
+	default:
+		return ErrInvalidFeeOperation
+	}
+
+	switch schedule.FeeType {
+	case FeeFlat:
+		if schedule.FeeAsset == "" {
+			return ErrFlatFeeAsset
+		}
+	case FeePercentage:
+		// The quantity of a dividend is per unit held and an activation has no asset, so a percentage of them isn't meaningful
+		if schedule.Operation != FeePayment && schedule.Operation != FeeIssuance {
+			return ErrPercentageFeeOperation
+		}
+		if schedule.BasisPoints > 10000 {
+			return ErrFeeBasisPoints
+		}
+	default:
+		return ErrInvalidFeeType
+	}
+
+	// On chain collection is only implemented for Ripple, so a Counterparty schedule asking for it is rejected rather than silently billed
+	if schedule.Collect && schedule.BlockchainId == consts.CounterpartyBlockchainId {
+		return ErrFeeCollectBlockchain
+	}
+
+	return nil
+}
+
+// Returns the fee the schedule charges on a quantity of the asset. Percentage fees are rounded down to the base unit of the asset.
+// XRP quantities are given in 1/100000000 XRP but can only be paid in drops, so Ripple fees in XRP are rounded down to whole drops
+func CalculateFee(schedule FeeSchedule, asset string, issuer string, quantity uint64) (string, string, uint64) {
+	feeAsset, feeIssuer, fee := schedule.FeeAsset, schedule.FeeIssuer, schedule.Amount
+
+	if schedule.FeeType == FeePercentage {
+		// Divide first so that quantity * basis points can't overflow
+		feeAsset, feeIssuer, fee = asset, issuer, quantity/10000*schedule.BasisPoints+quantity%10000*schedule.BasisPoints/10000
+	}
+
+	if schedule.BlockchainId == consts.RippleBlockchainId && strings.ToUpper(feeAsset) == "XRP" {
+		fee -= fee % XRPDrop
+	}
+
+	return feeAsset, feeIssuer, fee
+}
+
+// Totals the billable fees by blockchain, operation and fee asset. Fees collected on chain, or being collected, have already been paid
+func SummariseFees(fees []Fee) []FeeStatementLine {
+	result := []FeeStatementLine{}
+	lines := make(map[FeeStatementLine]*FeeStatementLine)
+
+	for _, fee := range fees {
+		if fee.Status != FeeRecordedStatus && fee.Status != FeeErrorStatus {
+			continue
+		}
+
+		key := FeeStatementLine{BlockchainId: fee.BlockchainId, Operation: fee.Operation, FeeAsset: fee.FeeAsset, FeeIssuer: fee.FeeIssuer}
+
+		if lines[key] == nil {
+			line := key
+			lines[key] = &line
+		}
+		lines[key].Count++
+		lines[key].Total += fee.FeeAmount
+	}
+
+	for _, line := range lines {
+		result = append(result, *line)
+	}
+
+	sort.Sort(byStatementLine(result))
+
+	return result
+}
+
+type byStatementLine []FeeStatementLine
+
+func (s byStatementLine) Len() int      { return len(s) }
+func (s byStatementLine) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byStatementLine) Less(i, j int) bool {
+	if s[i].BlockchainId != s[j].BlockchainId {
+		return s[i].BlockchainId < s[j].BlockchainId
+	}
+	if s[i].Operation != s[j].Operation {
+		return s[i].Operation < s[j].Operation
+	}
+	if s[i].FeeAsset != s[j].FeeAsset {
+		return s[i].FeeAsset < s[j].FeeAsset
+	}
+
+	return s[i].FeeIssuer < s[j].FeeIssuer
+}
@@ -0,0 +1,144 @@
+package enulib
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValidateFeeSchedule(t *testing.T) {
+	var testData = []struct {
+		Schedule        FeeSchedule
+		Expected        error
+		CaseDescription string
+	}{
+		{FeeSchedule{Operation: FeePayment, FeeType: FeeFlat, FeeAsset: "XCP", Amount: 1000}, nil, "Flat payment fee"},
+		{FeeSchedule{Operation: FeeActivation, FeeType: FeeFlat, FeeAsset: "XRP", Amount: 0}, nil, "Free activation"},
+		{FeeSchedule{Operation: FeeIssuance, FeeType: FeePercentage, BasisPoints: 25}, nil, "Percentage issuance fee"},
+		{FeeSchedule{Operation: FeePayment, FeeType: FeePercentage, BasisPoints: 10000}, nil, "100% payment fee"},
+		{FeeSchedule{Operation: "order", FeeType: FeeFlat, FeeAsset: "XCP"}, ErrInvalidFeeOperation, "Unknown operation"},
+		{FeeSchedule{Operation: FeePayment, FeeType: "tiered"}, ErrInvalidFeeType, "Unknown fee type"},
+		{FeeSchedule{Operation: FeePayment, FeeType: FeeFlat, Amount: 1000}, ErrFlatFeeAsset, "Flat fee without an asset"},
+		{FeeSchedule{Operation: FeeDividend, FeeType: FeePercentage, BasisPoints: 25}, ErrPercentageFeeOperation, "Percentage dividend fee"},
+		{FeeSchedule{Operation: FeeActivation, FeeType: FeePercentage, BasisPoints: 25}, ErrPercentageFeeOperation, "Percentage activation fee"},
+		{FeeSchedule{Operation: FeePayment, FeeType: FeePercentage, BasisPoints: 10001}, ErrFeeBasisPoints, "More than 100%"},
+		{FeeSchedule{BlockchainId: "ripple", Operation: FeePayment, FeeType: FeeFlat, FeeAsset: "XRP", Amount: 1000, Collect: true}, nil, "Collected Ripple fee"},
+		{FeeSchedule{BlockchainId: "counterparty", Operation: FeePayment, FeeType: FeeFlat, FeeAsset: "XCP", Amount: 1000, Collect: true}, ErrFeeCollectBlockchain, "Collected Counterparty fee"},
+	}
+
+	for _, s := range testData {
+		result := ValidateFeeSchedule(s.Schedule)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestCalculateFee(t *testing.T) {
+	var testData = []struct {
+		Schedule          FeeSchedule
+		Quantity          uint64
+		ExpectedFeeAsset  string
+		ExpectedFeeIssuer string
+		ExpectedFee       uint64
+		CaseDescription   string
+	}{
+		{FeeSchedule{FeeType: FeeFlat, FeeAsset: "XCP", Amount: 50000}, 100000000, "XCP", "", 50000, "Flat fee ignores the quantity"},
+		{FeeSchedule{FeeType: FeeFlat, FeeAsset: "USD", FeeIssuer: "rIssuer", Amount: 100}, 1, "USD", "rIssuer", 100, "Flat fee in a Ripple IOU"},
+		{FeeSchedule{FeeType: FeePercentage, BasisPoints: 25}, 100000000, "ASSET", "rAssetIssuer", 250000, "0.25% of the quantity in the asset of the operation"},
+		{FeeSchedule{FeeType: FeePercentage, BasisPoints: 25}, 399, "ASSET", "rAssetIssuer", 0, "Rounded down"},
+		{FeeSchedule{FeeType: FeePercentage, BasisPoints: 10000}, 12345, "ASSET", "rAssetIssuer", 12345, "100%"},
+		{FeeSchedule{FeeType: FeePercentage, BasisPoints: 10000}, math.MaxUint64, "ASSET", "rAssetIssuer", math.MaxUint64, "Largest quantity doesn't overflow"},
+		{FeeSchedule{FeeType: FeePercentage, BasisPoints: 5000}, math.MaxUint64, "ASSET", "rAssetIssuer", math.MaxUint64 / 2, "Half of the largest quantity"},
+	}
+
+	for _, s := range testData {
+		feeAsset, feeIssuer, fee := CalculateFee(s.Schedule, "ASSET", "rAssetIssuer", s.Quantity)
+
+		if feeAsset != s.ExpectedFeeAsset || feeIssuer != s.ExpectedFeeIssuer || fee != s.ExpectedFee {
+			t.Errorf("Expected: %s %s %d, Got: %s %s %d\nCase: %s\n", s.ExpectedFeeAsset, s.ExpectedFeeIssuer, s.ExpectedFee, feeAsset, feeIssuer, fee, s.CaseDescription)
+		}
+	}
+}
+
+func TestCalculateFeeXRP(t *testing.T) {
+	var testData = []struct {
+		Schedule        FeeSchedule
+		Quantity        uint64
+		ExpectedFee     uint64
+		CaseDescription string
+	}{
+		{FeeSchedule{BlockchainId: "ripple", FeeType: FeePercentage, BasisPoints: 50}, 1000, 0, "Fee under one drop"},
+		{FeeSchedule{BlockchainId: "ripple", FeeType: FeePercentage, BasisPoints: 50}, 10000, 0, "Fee of half a drop"},
+		{FeeSchedule{BlockchainId: "ripple", FeeType: FeePercentage, BasisPoints: 50}, 3000000, 15000, "Whole drops"},
+		{FeeSchedule{BlockchainId: "ripple", FeeType: FeePercentage, BasisPoints: 50}, 3012345, 15000, "Rounded down to whole drops"},
+		{FeeSchedule{BlockchainId: "ripple", FeeType: FeeFlat, FeeAsset: "XRP", Amount: 250}, 1, 200, "Flat fee rounded down to whole drops"},
+		{FeeSchedule{BlockchainId: "counterparty", FeeType: FeePercentage, BasisPoints: 50}, 3012345, 15061, "Not rounded outside Ripple"},
+	}
+
+	for _, s := range testData {
+		_, _, fee := CalculateFee(s.Schedule, "XRP", "", s.Quantity)
+
+		if fee != s.ExpectedFee {
+			t.Errorf("Expected: %d, Got: %d\nCase: %s\n", s.ExpectedFee, fee, s.CaseDescription)
+		}
+	}
+}
+
+func TestSummariseFees(t *testing.T) {
+	var testData = []struct {
+		Fees            []Fee
+		Expected        []FeeStatementLine
+		CaseDescription string
+	}{
+		{nil, []FeeStatementLine{}, "No fees"},
+		{
+			[]Fee{
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "XRP", Status: FeeRecordedStatus, FeeAmount: 10},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeRecordedStatus, FeeAmount: 100},
+				{BlockchainId: "counterparty", Operation: FeeIssuance, FeeAsset: "XCP", Status: FeeRecordedStatus, FeeAmount: 500},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeRecordedStatus, FeeAmount: 150},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "ASSET", Status: FeeRecordedStatus, FeeAmount: 7},
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "USD", FeeIssuer: "rB", Status: FeeRecordedStatus, FeeAmount: 1},
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "USD", FeeIssuer: "rA", Status: FeeRecordedStatus, FeeAmount: 2},
+			},
+			[]FeeStatementLine{
+				{BlockchainId: "counterparty", Operation: FeeIssuance, FeeAsset: "XCP", Count: 1, Total: 500},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "ASSET", Count: 1, Total: 7},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Count: 2, Total: 250},
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "USD", FeeIssuer: "rA", Count: 1, Total: 2},
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "USD", FeeIssuer: "rB", Count: 1, Total: 1},
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "XRP", Count: 1, Total: 10},
+			},
+			"Totals by blockchain, operation and fee asset",
+		},
+		{
+			[]Fee{
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeRecordedStatus, FeeAmount: 100},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeCollectedStatus, FeeAmount: 200},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeCollectingStatus, FeeAmount: 400},
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Status: FeeErrorStatus, FeeAmount: 800},
+			},
+			[]FeeStatementLine{
+				{BlockchainId: "counterparty", Operation: FeePayment, FeeAsset: "XCP", Count: 2, Total: 900},
+			},
+			"Fees collected on chain are not billed",
+		},
+		{
+			[]Fee{
+				{BlockchainId: "ripple", Operation: FeePayment, FeeAsset: "XRP", Status: FeeCollectedStatus, FeeAmount: 10},
+			},
+			[]FeeStatementLine{},
+			"Only collected fees",
+		},
+	}
+
+	for _, s := range testData {
+		result := SummariseFees(s.Fees)
+
+		if reflect.DeepEqual(result, s.Expected) == false {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Double-entry ledger of the internal accounts of an access key.
+// Every journal moves assets between accounts with entries which sum to zero for each asset. Only the external account, which stands
+// for the assets held on chain, may have a negative balance so customers can never spend more than they have been credited.
+
+package enulib
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+)
+
+// The account on the other side of deposits and withdrawals. Its balance is the negative of the assets held on chain for customers
+const LedgerExternalAccountId = "external"
+
+const (
+	JournalTransfer   = "transfer"   // off-chain transfer between internal accounts
+	JournalDeposit    = "deposit"    // assets received on chain, credited to an internal account
+	JournalWithdrawal = "withdrawal" // assets debited from an internal account and sent on chain
+	JournalReversal   = "reversal"   // undoes a journal, ie a withdrawal which couldn't be sent
+)
+
+var ErrInvalidLedgerEntry = errors.New("Ledger entries must have an account, a blockchain, an asset and a non-zero quantity")
+var ErrUnbalancedJournal = errors.New("The entries of the journal don't sum to zero for every asset")
+var ErrNegativeLedgerBalance = errors.New("The journal would leave an account with a negative balance")
+var ErrLedgerBalanceOverflow = errors.New("The journal would overflow the balance of an account")
+
+// An asset held in the ledger. The same asset code on another blockchain, or issued by another Ripple issuer, is a different asset
+type LedgerAsset struct {
+	BlockchainId string `json:"blockchainId"`
+	Asset        string `json:"asset"`
+	Issuer       string `json:"issuer,omitempty"` // only Ripple assets other than XRP have an issuer
+}
+
+// Returns the ledger asset of a currency on the blockchain. XRP has no issuer, so any issuer given with it is dropped
+func NewLedgerAsset(blockchainId string, asset string, issuer string) LedgerAsset {
+	if blockchainId == consts.RippleBlockchainId && strings.ToUpper(asset) == "XRP" {
+		return LedgerAsset{BlockchainId: blockchainId, Asset: "XRP"}
+	}
+
+	return LedgerAsset{BlockchainId: blockchainId, Asset: asset, Issuer: issuer}
+}
+
+// A credit (positive quantity) or debit (negative quantity) of an account
+type LedgerEntry struct {
+	JournalId string `json:"journalId,omitempty"`
+	AccountId string `json:"accountId"`
+	LedgerAsset
+	Quantity int64 `json:"quantity"`
+}
+
+type Journal struct {
+	JournalId    string        `json:"journalId"`
+	JournalType  string        `json:"journalType"`
+	Reference    string        `json:"reference,omitempty"` // txid of a deposit, paymentId of a withdrawal or journalId of a reversed journal
+	Entries      []LedgerEntry `json:"entries"`
+	RequestId    string        `json:"requestId,omitempty"`
+	Nonce        int64         `json:"nonce,omitempty"`
+	BlockchainId string        `json:"blockchainId,omitempty"`
+}
+
+type LedgerAccount struct {
+	AccountId    string        `json:"accountId"`
+	Balances     []Amount      `json:"balances"`
+	Entries      []LedgerEntry `json:"entries"`
+	RequestId    string        `json:"requestId"`
+	Nonce        int64         `json:"nonce"`
+	BlockchainId string        `json:"blockchainId"`
+}
+
+// Balances of ledger accounts keyed by account and then asset
+type LedgerBalances map[string]map[LedgerAsset]int64
+
+// Moves the quantity of the asset from one internal account to another
+func NewTransferJournal(sourceAccountId string, destinationAccountId string, asset LedgerAsset, quantity uint64, reference string) (Journal, error) {
+	return newJournal(JournalTransfer, sourceAccountId, destinationAccountId, asset, quantity, reference)
+}
+
+// Credits the account with the quantity of the asset received on chain in the transaction
+func NewDepositJournal(accountId string, asset LedgerAsset, quantity uint64, txId string) (Journal, error) {
+	return newJournal(JournalDeposit, LedgerExternalAccountId, accountId, asset, quantity, txId)
+}
+
+// Debits the account with the quantity of the asset sent on chain by the payment
+func NewWithdrawalJournal(accountId string, asset LedgerAsset, quantity uint64, paymentId string) (Journal, error) {
+	return newJournal(JournalWithdrawal, accountId, LedgerExternalAccountId, asset, quantity, paymentId)
+}
+
+// Returns the journal which undoes the given journal
+func NewReversalJournal(journal Journal) Journal {
+	result := Journal{JournalId: GenerateJournalId(), JournalType: JournalReversal, Reference: journal.JournalId}
+
+	for _, entry := range journal.Entries {
+		result.Entries = append(result.Entries, LedgerEntry{AccountId: entry.AccountId, LedgerAsset: entry.LedgerAsset, Quantity: -entry.Quantity})
+	}
+
+	return result
+}
+
+func newJournal(journalType string, sourceAccountId string, destinationAccountId string, asset LedgerAsset, quantity uint64, reference string) (Journal, error) {
+	if quantity == 0 || quantity > math.MaxInt64 || sourceAccountId == destinationAccountId {
+		return Journal{}, ErrInvalidLedgerEntry
+	}
+
+	journal := Journal{
+		JournalId:   GenerateJournalId(),
+		JournalType: journalType,
+		Reference:   reference,
+		Entries: []LedgerEntry{
+			{AccountId: sourceAccountId, LedgerAsset: asset, Quantity: -int64(quantity)},
+			{AccountId: destinationAccountId, LedgerAsset: asset, Quantity: int64(quantity)},
+		},
+	}
+
+	return journal, journal.Validate()
+}
+
+// Returns the accounts which the journal posts to
+func (j Journal) AccountIds() []string {
+	var result []string
+	seen := make(map[string]bool)
+
+	for _, entry := range j.Entries {
+		if seen[entry.AccountId] == false {
+			seen[entry.AccountId] = true
+			result = append(result, entry.AccountId)
+		}
+	}
+
+	return result
+}
+
+// Checks every entry is well formed and the entries of each asset sum to zero
+func (j Journal) Validate() error {
+	if len(j.Entries) < 2 {
+		return ErrUnbalancedJournal
+	}
+
+	totals := make(map[LedgerAsset]int64)
+	for _, entry := range j.Entries {
+		if entry.AccountId == "" || entry.BlockchainId == "" || entry.Asset == "" || entry.Quantity == 0 || entry.Quantity == math.MinInt64 {
+			return ErrInvalidLedgerEntry
+		}
+
+		total, ok := addBalance(totals[entry.LedgerAsset], entry.Quantity)
+		if ok == false {
+			return ErrUnbalancedJournal
+		}
+		totals[entry.LedgerAsset] = total
+	}
+
+	for _, total := range totals {
+		if total != 0 {
+			return ErrUnbalancedJournal
+		}
+	}
+
+	return nil
+}
+
+// Posts the journal to the balances. The balances are only changed if the journal is valid and leaves no internal account negative
+func (b LedgerBalances) Apply(j Journal) error {
+	if err := j.Validate(); err != nil {
+		return err
+	}
+
+	// Work out every new balance before changing any
+	updated := make(map[string]map[LedgerAsset]int64)
+	for _, entry := range j.Entries {
+		if updated[entry.AccountId] == nil {
+			updated[entry.AccountId] = make(map[LedgerAsset]int64)
+		}
+
+		current, ok := updated[entry.AccountId][entry.LedgerAsset]
+		if ok == false {
+			current = b[entry.AccountId][entry.LedgerAsset]
+		}
+
+		balance, ok := addBalance(current, entry.Quantity)
+		if ok == false {
+			return ErrLedgerBalanceOverflow
+		}
+		updated[entry.AccountId][entry.LedgerAsset] = balance
+	}
+
+	for accountId, assets := range updated {
+		for _, balance := range assets {
+			if balance < 0 && accountId != LedgerExternalAccountId {
+				return ErrNegativeLedgerBalance
+			}
+		}
+	}
+
+	for accountId, assets := range updated {
+		if b[accountId] == nil {
+			b[accountId] = make(map[LedgerAsset]int64)
+		}
+
+		for asset, balance := range assets {
+			b[accountId][asset] = balance
+		}
+	}
+
+	return nil
+}
+
+// Adds the quantity to the balance, returning false on overflow
+func addBalance(balance int64, quantity int64) (int64, bool) {
+	result := balance + quantity
+	if (quantity > 0 && result < balance) || (quantity < 0 && result > balance) {
+		return balance, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,275 @@
+package enulib
+
+import (
+	"math"
+	"testing"
+)
+
+var xcp = LedgerAsset{BlockchainId: "counterparty", Asset: "XCP"}
+var btc = LedgerAsset{BlockchainId: "counterparty", Asset: "BTC"}
+var usdBitstamp = LedgerAsset{BlockchainId: "ripple", Asset: "USD", Issuer: "rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B"}
+var usdGatehub = LedgerAsset{BlockchainId: "ripple", Asset: "USD", Issuer: "rhub8VRN55s94qWKDv6jmDy1pUykJzF3wq"}
+
+func TestNewLedgerAsset(t *testing.T) {
+	var testData = []struct {
+		BlockchainId    string
+		Asset           string
+		Issuer          string
+		Expected        LedgerAsset
+		CaseDescription string
+	}{
+		{"counterparty", "XCP", "", xcp, "Counterparty asset"},
+		{"ripple", "USD", usdBitstamp.Issuer, usdBitstamp, "Ripple currency keeps its issuer"},
+		{"ripple", "xrp", "", LedgerAsset{BlockchainId: "ripple", Asset: "XRP"}, "XRP is upper cased"},
+		{"ripple", "XRP", usdBitstamp.Issuer, LedgerAsset{BlockchainId: "ripple", Asset: "XRP"}, "XRP drops the issuer"},
+	}
+
+	for _, s := range testData {
+		result := NewLedgerAsset(s.BlockchainId, s.Asset, s.Issuer)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestJournalValidate(t *testing.T) {
+	var testData = []struct {
+		Entries         []LedgerEntry
+		ExpectedError   error
+		CaseDescription string
+	}{
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 100}}, nil, "Balanced transfer"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 60}, {AccountId: "carol", LedgerAsset: xcp, Quantity: 40}}, nil, "Balanced split"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 100}, {AccountId: "bob", LedgerAsset: btc, Quantity: -5}, {AccountId: "alice", LedgerAsset: btc, Quantity: 5}}, nil, "Balanced exchange of two assets"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 99}}, ErrUnbalancedJournal, "Unbalanced transfer"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: btc, Quantity: 100}}, ErrUnbalancedJournal, "Balanced total but different assets"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: 100}}, ErrUnbalancedJournal, "Single entry"},
+		{nil, ErrUnbalancedJournal, "No entries"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: 0}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 0}}, ErrInvalidLedgerEntry, "Zero quantities"},
+		{[]LedgerEntry{{AccountId: "", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 100}}, ErrInvalidLedgerEntry, "Missing account"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: LedgerAsset{BlockchainId: "counterparty"}, Quantity: -100}, {AccountId: "bob", LedgerAsset: LedgerAsset{BlockchainId: "counterparty"}, Quantity: 100}}, ErrInvalidLedgerEntry, "Missing asset"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: LedgerAsset{Asset: "XCP"}, Quantity: -100}, {AccountId: "bob", LedgerAsset: LedgerAsset{Asset: "XCP"}, Quantity: 100}}, ErrInvalidLedgerEntry, "Missing blockchain"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: usdBitstamp, Quantity: -100}, {AccountId: "bob", LedgerAsset: usdGatehub, Quantity: 100}}, ErrUnbalancedJournal, "Balanced total but different issuers"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: LedgerAsset{BlockchainId: "ripple", Asset: "XCP"}, Quantity: 100}}, ErrUnbalancedJournal, "Balanced total but different blockchains"},
+		{[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: math.MaxInt64}, {AccountId: "bob", LedgerAsset: xcp, Quantity: math.MaxInt64}, {AccountId: "carol", LedgerAsset: xcp, Quantity: 2}}, ErrUnbalancedJournal, "Entries which overflow to zero"},
+	}
+
+	for _, s := range testData {
+		err := Journal{JournalId: "test", JournalType: JournalTransfer, Entries: s.Entries}.Validate()
+
+		if err != s.ExpectedError {
+			t.Errorf("Expected: %v, Got: %v\nCase: %s\n", s.ExpectedError, err, s.CaseDescription)
+		}
+	}
+}
+
+func TestNewJournals(t *testing.T) {
+	var testData = []struct {
+		Journal         func() (Journal, error)
+		ExpectedType    string
+		ExpectedDebit   string
+		ExpectedCredit  string
+		ExpectedError   error
+		CaseDescription string
+	}{
+		{func() (Journal, error) { return NewTransferJournal("alice", "bob", xcp, 100, "") }, JournalTransfer, "alice", "bob", nil, "Transfer"},
+		{func() (Journal, error) { return NewDepositJournal("alice", xcp, 100, "txid") }, JournalDeposit, LedgerExternalAccountId, "alice", nil, "Deposit is credited from the external account"},
+		{func() (Journal, error) { return NewWithdrawalJournal("alice", xcp, 100, "paymentid") }, JournalWithdrawal, "alice", LedgerExternalAccountId, nil, "Withdrawal is debited to the external account"},
+		{func() (Journal, error) { return NewTransferJournal("alice", "alice", xcp, 100, "") }, "", "", "", ErrInvalidLedgerEntry, "Transfer to the same account"},
+		{func() (Journal, error) { return NewTransferJournal("alice", "bob", xcp, 0, "") }, "", "", "", ErrInvalidLedgerEntry, "Zero quantity"},
+		{func() (Journal, error) { return NewTransferJournal("alice", "bob", xcp, math.MaxInt64+1, "") }, "", "", "", ErrInvalidLedgerEntry, "Quantity too large for a balance"},
+	}
+
+	for _, s := range testData {
+		journal, err := s.Journal()
+
+		if err != s.ExpectedError {
+			t.Errorf("Expected: %v, Got: %v\nCase: %s\n", s.ExpectedError, err, s.CaseDescription)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if journal.JournalType != s.ExpectedType || journal.Entries[0].AccountId != s.ExpectedDebit || journal.Entries[0].Quantity >= 0 || journal.Entries[1].AccountId != s.ExpectedCredit || journal.Entries[1].Quantity <= 0 {
+			t.Errorf("Expected: %s debiting %s and crediting %s, Got: %+v\nCase: %s\n", s.ExpectedType, s.ExpectedDebit, s.ExpectedCredit, journal, s.CaseDescription)
+		}
+
+		// Reversals must also balance and undo every entry
+		reversal := NewReversalJournal(journal)
+		if err := reversal.Validate(); err != nil || reversal.Reference != journal.JournalId || reversal.Entries[0].Quantity != -journal.Entries[0].Quantity {
+			t.Errorf("Expected: reversal of %+v, Got: %+v\nCase: %s\n", journal, reversal, s.CaseDescription)
+		}
+	}
+}
+
+func TestLedgerBalancesApply(t *testing.T) {
+	var testData = []struct {
+		Balances        LedgerBalances
+		Entries         []LedgerEntry
+		ExpectedError   error
+		Expected        LedgerBalances
+		CaseDescription string
+	}{
+		{
+			LedgerBalances{"alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 100}},
+			nil,
+			LedgerBalances{"alice": {xcp: 0}, "bob": {xcp: 100}},
+			"Transfer of the whole balance",
+		},
+		{
+			LedgerBalances{"alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -101}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 101}},
+			ErrNegativeLedgerBalance,
+			LedgerBalances{"alice": {xcp: 100}},
+			"Transfer of more than the balance leaves the balances unchanged",
+		},
+		{
+			LedgerBalances{"alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: btc, Quantity: -1}, {AccountId: "bob", LedgerAsset: btc, Quantity: 1}},
+			ErrNegativeLedgerBalance,
+			LedgerBalances{"alice": {xcp: 100}},
+			"Transfer of an asset which isn't held",
+		},
+		{
+			LedgerBalances{"alice": {usdBitstamp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: usdGatehub, Quantity: -100}, {AccountId: "bob", LedgerAsset: usdGatehub, Quantity: 100}},
+			ErrNegativeLedgerBalance,
+			LedgerBalances{"alice": {usdBitstamp: 100}},
+			"Transfer of the same currency from another issuer",
+		},
+		{
+			LedgerBalances{"alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -80}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 80}, {AccountId: "alice", LedgerAsset: xcp, Quantity: -80}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 80}},
+			ErrNegativeLedgerBalance,
+			LedgerBalances{"alice": {xcp: 100}},
+			"Several entries against one account are checked in total",
+		},
+		{
+			LedgerBalances{},
+			[]LedgerEntry{{AccountId: LedgerExternalAccountId, LedgerAsset: xcp, Quantity: -100}, {AccountId: "alice", LedgerAsset: xcp, Quantity: 100}},
+			nil,
+			LedgerBalances{LedgerExternalAccountId: {xcp: -100}, "alice": {xcp: 100}},
+			"Deposit takes the external account negative",
+		},
+		{
+			LedgerBalances{LedgerExternalAccountId: {xcp: -100}, "alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: LedgerExternalAccountId, LedgerAsset: xcp, Quantity: 100}},
+			nil,
+			LedgerBalances{LedgerExternalAccountId: {xcp: 0}, "alice": {xcp: 0}},
+			"Withdrawal",
+		},
+		{
+			LedgerBalances{"alice": {xcp: 100}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 99}},
+			ErrUnbalancedJournal,
+			LedgerBalances{"alice": {xcp: 100}},
+			"Unbalanced journal isn't applied",
+		},
+		{
+			LedgerBalances{"alice": {xcp: 100}, "bob": {xcp: math.MaxInt64}},
+			[]LedgerEntry{{AccountId: "alice", LedgerAsset: xcp, Quantity: -100}, {AccountId: "bob", LedgerAsset: xcp, Quantity: 100}},
+			ErrLedgerBalanceOverflow,
+			LedgerBalances{"alice": {xcp: 100}, "bob": {xcp: math.MaxInt64}},
+			"Overflowing balance",
+		},
+	}
+
+	for _, s := range testData {
+		err := s.Balances.Apply(Journal{JournalId: "test", JournalType: JournalTransfer, Entries: s.Entries})
+
+		if err != s.ExpectedError {
+			t.Errorf("Expected: %v, Got: %v\nCase: %s\n", s.ExpectedError, err, s.CaseDescription)
+		}
+
+		if equalBalances(s.Balances, s.Expected) == false {
+			t.Errorf("Expected: %v, Got: %v\nCase: %s\n", s.Expected, s.Balances, s.CaseDescription)
+		}
+	}
+}
+
+// Applies a sequence of journals, some of which are rejected, and checks the invariants hold after each one
+func TestLedgerInvariants(t *testing.T) {
+	balances := make(LedgerBalances)
+
+	var journals []Journal
+	add := func(journal Journal, err error) {
+		if err != nil {
+			t.Fatalf("Unable to create journal: %s", err.Error())
+		}
+		journals = append(journals, journal)
+	}
+
+	add(NewDepositJournal("alice", xcp, 1000, "tx1"))
+	add(NewDepositJournal("bob", btc, 500, "tx2"))
+	add(NewTransferJournal("alice", "bob", xcp, 400, ""))
+	add(NewTransferJournal("bob", "carol", xcp, 500, ""))
+	add(NewTransferJournal("bob", "carol", xcp, 400, ""))
+	add(NewWithdrawalJournal("carol", xcp, 401, "payment1"))
+	add(NewWithdrawalJournal("carol", xcp, 400, "payment2"))
+	add(NewTransferJournal("alice", "carol", btc, 1, ""))
+
+	var applied []Journal
+	for _, journal := range journals {
+		if err := balances.Apply(journal); err == nil {
+			applied = append(applied, journal)
+		}
+
+		totals := make(map[LedgerAsset]int64)
+		for accountId, assets := range balances {
+			for asset, balance := range assets {
+				if balance < 0 && accountId != LedgerExternalAccountId {
+					t.Errorf("Expected: non-negative balance, Got: %d %v in %s\n", balance, asset, accountId)
+				}
+				totals[asset] += balance
+			}
+		}
+
+		for asset, total := range totals {
+			if total != 0 {
+				t.Errorf("Expected: balances of %v to sum to zero, Got: %d\n", asset, total)
+			}
+		}
+	}
+
+	if len(applied) != 5 {
+		t.Errorf("Expected: %d journals applied, Got: %d\n", 5, len(applied))
+	}
+
+	// Reversing every applied journal in reverse order returns every balance to zero
+	for i := len(applied) - 1; i >= 0; i-- {
+		if err := balances.Apply(NewReversalJournal(applied[i])); err != nil {
+			t.Errorf("Expected: reversal of %s, Got: %s\n", applied[i].JournalType, err.Error())
+		}
+	}
+
+	for accountId, assets := range balances {
+		for asset, balance := range assets {
+			if balance != 0 {
+				t.Errorf("Expected: 0 %v in %s, Got: %d\n", asset, accountId, balance)
+			}
+		}
+	}
+}
+
+func equalBalances(a LedgerBalances, b LedgerBalances) bool {
+	for accountId, assets := range b {
+		for asset, balance := range assets {
+			if a[accountId][asset] != balance {
+				return false
+			}
+		}
+	}
+
+	for accountId, assets := range a {
+		for asset, balance := range assets {
+			if b[accountId][asset] != balance {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -8,15 +8,55 @@ type AppError struct {
 }
 
 type Block struct {
-	BlockId  int64  `json:"blockId"`
-	Status   string `json:"status"`
-	Duration int64  `json:"duration"`
+	BlockchainId string `json:"chain"` // bitcoin, counterparty or ripple
+	BlockId      int64  `json:"blockId"`
+	BlockHash    string `json:"blockHash"`
+	Status       string `json:"status"`
+	Duration     int64  `json:"duration"` // milliseconds
+	Updated      string `json:"updated"`
+}
+
+// Heights which are missing below the last processed block of a chain
+type BlockGap struct {
+	FromBlockId int64 `json:"fromBlockId"`
+	ToBlockId   int64 `json:"toBlockId"`
+}
+
+// How far the processing of a chain is behind its backend. The tip is the height last reported by the backend, a tip of -1 means
+// the backend hasn't been polled since Enu was started and a last block of -1 means no blocks have been processed. The lag is -1 in either case
+type BlockchainProgress struct {
+	BlockchainId string     `json:"chain"`
+	LastBlockId  int64      `json:"lastBlockId"`
+	TipBlockId   int64      `json:"tipBlockId"`
+	TipUpdated   string     `json:"tipUpdated,omitempty"`
+	Lag          int64      `json:"lag"`
+	Gaps         []BlockGap `json:"gaps"`
 }
 
 type Blocks struct {
-	Allblocks []Block `json:"blocks"`
-	RequestId string  `json:"requestId"`
-	Nonce     int64   `json:"nonce"`
+	Allblocks []Block              `json:"blocks"`
+	Chains    []BlockchainProgress `json:"chains"`
+	RequestId string               `json:"requestId"`
+	Nonce     int64                `json:"nonce"`
+}
+
+// A credit or debit of an address owned by an access key. The other address is the sender of a credit or the recipient of a debit, if known
+type AddressTransaction struct {
+	BlockId      int64  `json:"blockId"`
+	TxId         string `json:"txId"`
+	Direction    string `json:"direction"` // in or out
+	Address      string `json:"address"`
+	OtherAddress string `json:"otherAddress"`
+	Asset        string `json:"asset"`
+	Quantity     uint64 `json:"quantity"`
+	Status       string `json:"status"`
+}
+
+type AddressTransactions struct {
+	Transactions []AddressTransaction `json:"transactions"`
+	RequestId    string               `json:"requestId"`
+	Nonce        int64                `json:"nonce"`
+	BlockchainId string               `json:"blockchainId"`
 }
 
 type Amount struct {
@@ -39,6 +79,7 @@ type AssetBalances struct {
 	Description  string          `json:"description"`
 	Supply       uint64          `json:"quantity"`
 	Balances     []AddressAmount `json:"balances"`
+	BlockId      uint64          `json:"blockId,omitempty"` // The block height of the holdings, if not the latest
 	RequestId    string          `json:"requestId"`
 	Nonce        int64           `json:"nonce"`
 }
@@ -63,23 +104,38 @@ type Payment struct {
 type Payments []Payment
 
 type SimplePayment struct {
-	BlockchainId            string `json:"blockchainId"`
-	SourceAddress           string `json:"sourceAddress"`
-	DestinationAddress      string `json:"destinationAddress"`
-	Asset                   string `json:"asset"`
-	Issuer                  string `json:"issuer"`
-	Amount                  uint64 `json:"amount"`
-	PaymentId               string `json:"paymentId"`
-	TxFee                   int64  `json:"txFee"`
-	BroadcastTxId           string `json:"broadcastTxId"`
-	BlockchainStatus        string `json:"blockchainStatus"`
-	BlockchainConfirmations uint64 `json:"blockchainConfirmations"`
-	PaymentTag              string `json:"paymentTag"`
-	Status                  string `json:"status"`
-	ErrorCode               int64  `json:"errorCode"`
-	ErrorMessage            string `json:"errorMessage"`
-	RequestId               string `json:"requestId"`
-	Nonce                   int64  `json:"nonce"`
+	BlockchainId            string        `json:"blockchainId"`
+	SourceAddress           string        `json:"sourceAddress"`
+	DestinationAddress      string        `json:"destinationAddress"`
+	Asset                   string        `json:"asset"`
+	Issuer                  string        `json:"issuer"`
+	Amount                  uint64        `json:"amount"`
+	SourceAsset             string        `json:"sourceAsset,omitempty"`
+	SourceIssuer            string        `json:"sourceIssuer,omitempty"`
+	SendMax                 uint64        `json:"sendMax,omitempty"`
+	DeliveredAmount         uint64        `json:"deliveredAmount,omitempty"`
+	DestinationTag          *uint32       `json:"destinationTag,omitempty"`
+	SourceTag               *uint32       `json:"sourceTag,omitempty"`
+	InvoiceId               string        `json:"invoiceId,omitempty"`
+	Memos                   []PaymentMemo `json:"memos,omitempty"`
+	PaymentId               string        `json:"paymentId"`
+	TxFee                   int64         `json:"txFee"`
+	BroadcastTxId           string        `json:"broadcastTxId"`
+	BlockchainStatus        string        `json:"blockchainStatus"`
+	BlockchainConfirmations uint64        `json:"blockchainConfirmations"`
+	PaymentTag              string        `json:"paymentTag"`
+	Status                  string        `json:"status"`
+	ErrorCode               int64         `json:"errorCode"`
+	ErrorMessage            string        `json:"errorMessage"`
+	RequestId               string        `json:"requestId"`
+	Nonce                   int64         `json:"nonce"`
+}
+
+// A memo attached to a Ripple payment, given in plain text
+type PaymentMemo struct {
+	Type   string `json:"type,omitempty"`
+	Data   string `json:"data"`
+	Format string `json:"format,omitempty"`
 }
 
 type Address struct {
@@ -105,6 +161,7 @@ type Asset struct {
 	DistributionPassphrase  string `json:"distributionPassphrase,omitempty"`
 	DistributionAddress     string `json:"distributionAddress,omitempty"`
 	AssetId                 string `json:"assetId"`
+	Operation               string `json:"operation,omitempty"`
 	Asset                   string `json:"asset"`
 	Issuer                  string `json:"issuer,omitempty"`
 	Description             string `json:"description"`
@@ -160,15 +217,22 @@ type AssetIssuances struct {
 }
 
 type WalletPayment struct {
-	Passphrase         string `json:"passphrase"`
-	SourceAddress      string `json:"sourceAddress"`
-	DestinationAddress string `json:"destinationAddress"`
-	Asset              string `json:"asset"`
-	Quantity           uint64 `json:"quantity"`
-	PaymentId          string `json:"paymentId"`
-	PaymentTag         string `json:"paymentTag"`
-	RequestId          string `json:"requestId"`
-	Nonce              int64  `json:"nonce"`
+	Passphrase         string        `json:"passphrase"`
+	SourceAddress      string        `json:"sourceAddress"`
+	DestinationAddress string        `json:"destinationAddress"`
+	Asset              string        `json:"asset"`
+	Quantity           uint64        `json:"quantity"`
+	SourceAsset        string        `json:"sourceAsset,omitempty"`
+	SourceIssuer       string        `json:"sourceIssuer,omitempty"`
+	SendMax            uint64        `json:"sendMax,omitempty"`
+	DestinationTag     *uint32       `json:"destinationTag,omitempty"`
+	SourceTag          *uint32       `json:"sourceTag,omitempty"`
+	InvoiceId          string        `json:"invoiceId,omitempty"`
+	Memos              []PaymentMemo `json:"memos,omitempty"`
+	PaymentId          string        `json:"paymentId"`
+	PaymentTag         string        `json:"paymentTag"`
+	RequestId          string        `json:"requestId"`
+	Nonce              int64         `json:"nonce"`
 }
 
 type Wallet struct {
@@ -184,3 +248,224 @@ type Wallet struct {
 	PublicKey     string   `json:"public_key,omitempty"`
 	PublicKeyHex  string   `json:"public_key_hex,omitempty"`
 }
+
+type WatchWallet struct {
+	WatchWalletId string   `json:"watchWalletId"`
+	Xpub          string   `json:"xpub"`
+	GapLimit      uint32   `json:"gapLimit"`
+	LastUsedIndex int64    `json:"lastUsedIndex"`
+	Addresses     []string `json:"addresses"`
+	Status        string   `json:"status"`
+	ErrorMessage  string   `json:"errorMessage"`
+	RequestId     string   `json:"requestId"`
+	Nonce         int64    `json:"nonce"`
+	BlockchainId  string   `json:"blockchainId"`
+}
+
+type WatchWalletBalances struct {
+	WatchWalletId string            `json:"watchWalletId"`
+	Balances      []Amount          `json:"balances"`
+	Addresses     []AddressBalances `json:"addresses"`
+	RequestId     string            `json:"requestId"`
+	Nonce         int64             `json:"nonce"`
+	BlockchainId  string            `json:"blockchainId"`
+}
+
+type Order struct {
+	OrderId       string `json:"orderId"`
+	Operation     string `json:"operation"`
+	SourceAddress string `json:"sourceAddress"`
+	GiveAsset     string `json:"giveAsset,omitempty"`
+	GiveIssuer    string `json:"giveIssuer,omitempty"`
+	GiveQuantity  uint64 `json:"giveQuantity,omitempty"`
+	GetAsset      string `json:"getAsset,omitempty"`
+	GetIssuer     string `json:"getIssuer,omitempty"`
+	GetQuantity   uint64 `json:"getQuantity,omitempty"`
+	Expiration    uint64 `json:"expiration,omitempty"`
+	OfferHash     string `json:"offerHash,omitempty"`
+	OfferSequence uint64 `json:"offerSequence,omitempty"`
+	BroadcastTxId string `json:"broadcastTxId"`
+	Status        string `json:"status"`
+	ErrorMessage  string `json:"errorMessage"`
+	RequestId     string `json:"requestId"`
+	Nonce         int64  `json:"nonce"`
+	BlockchainId  string `json:"blockchainId"`
+}
+
+type OpenOrder struct {
+	TxHash        string  `json:"txHash,omitempty"`
+	OfferSequence uint64  `json:"offerSequence,omitempty"`
+	SourceAddress string  `json:"sourceAddress"`
+	GiveAsset     string  `json:"giveAsset"`
+	GiveIssuer    string  `json:"giveIssuer,omitempty"`
+	GiveQuantity  uint64  `json:"giveQuantity"`
+	GiveRemaining int64   `json:"giveRemaining"`
+	GetAsset      string  `json:"getAsset"`
+	GetIssuer     string  `json:"getIssuer,omitempty"`
+	GetQuantity   uint64  `json:"getQuantity"`
+	GetRemaining  int64   `json:"getRemaining"`
+	Price         float64 `json:"price"`
+	ExpireIndex   uint64  `json:"expireIndex"`
+	Status        string  `json:"status"`
+}
+
+type OpenOrders struct {
+	Address      string      `json:"address"`
+	Orders       []OpenOrder `json:"orders"`
+	RequestId    string      `json:"requestId"`
+	Nonce        int64       `json:"nonce"`
+	BlockchainId string      `json:"blockchainId"`
+}
+
+// Bids give the quote asset for the base asset, asks give the base asset for the quote asset. Prices are in the quote asset per unit of the base asset
+type OrderBook struct {
+	BaseAsset    string      `json:"baseAsset"`
+	QuoteAsset   string      `json:"quoteAsset"`
+	Bids         []OpenOrder `json:"bids"`
+	Asks         []OpenOrder `json:"asks"`
+	RequestId    string      `json:"requestId"`
+	Nonce        int64       `json:"nonce"`
+	BlockchainId string      `json:"blockchainId"`
+}
+
+type Broadcast struct {
+	BroadcastId   string  `json:"broadcastId"`
+	SourceAddress string  `json:"sourceAddress"`
+	Text          string  `json:"text"`
+	Value         float64 `json:"value"`
+	FeeFraction   float64 `json:"feeFraction"`
+	Timestamp     uint64  `json:"timestamp"`
+	TxHash        string  `json:"txHash,omitempty"`
+	BlockIndex    uint64  `json:"blockIndex,omitempty"`
+	Locked        bool    `json:"locked,omitempty"`
+	BroadcastTxId string  `json:"broadcastTxId,omitempty"`
+	Status        string  `json:"status"`
+	ErrorMessage  string  `json:"errorMessage,omitempty"`
+	RequestId     string  `json:"requestId,omitempty"`
+	Nonce         int64   `json:"nonce,omitempty"`
+	BlockchainId  string  `json:"blockchainId,omitempty"`
+}
+
+type Broadcasts struct {
+	Address      string      `json:"address"`
+	Broadcasts   []Broadcast `json:"broadcasts"`
+	RequestId    string      `json:"requestId"`
+	Nonce        int64       `json:"nonce"`
+	BlockchainId string      `json:"blockchainId"`
+}
+
+// A trust line held by an address for an asset issued by the issuer. Operations on trust lines are tracked by the trustId
+type TrustLine struct {
+	TrustId       string `json:"trustId,omitempty"`
+	Operation     string `json:"operation,omitempty"`
+	Address       string `json:"address"`
+	Asset         string `json:"asset"`
+	Issuer        string `json:"issuer"`
+	Limit         uint64 `json:"limit"`
+	QualityIn     uint32 `json:"qualityIn,omitempty"`
+	QualityOut    uint32 `json:"qualityOut,omitempty"`
+	Balance       int64  `json:"balance"` // Negative if the address owes the issuer
+	Authorized    bool   `json:"authorized,omitempty"`
+	Frozen        bool   `json:"frozen,omitempty"`
+	NoRipple      bool   `json:"noRipple,omitempty"`
+	BroadcastTxId string `json:"broadcastTxId,omitempty"`
+	Status        string `json:"status,omitempty"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+	RequestId     string `json:"requestId,omitempty"`
+	Nonce         int64  `json:"nonce,omitempty"`
+	BlockchainId  string `json:"blockchainId,omitempty"`
+}
+
+type TrustLines struct {
+	Address      string      `json:"address"`
+	TrustLines   []TrustLine `json:"trustLines"`
+	RequestId    string      `json:"requestId"`
+	Nonce        int64       `json:"nonce"`
+	BlockchainId string      `json:"blockchainId"`
+}
+
+// An address derived from the deposit wallet for a customer of the access key. Assets received by the address are credited to the customer
+type DepositAddress struct {
+	CustomerReference string `json:"customerReference"`
+	Address           string `json:"address"`
+	RequestId         string `json:"requestId,omitempty"`
+	Nonce             int64  `json:"nonce,omitempty"`
+	BlockchainId      string `json:"blockchainId,omitempty"`
+}
+
+// An asset received by a deposit address. Once swept to the treasury address the sweep payment is recorded
+type Deposit struct {
+	CustomerReference string `json:"customerReference"`
+	Address           string `json:"address"`
+	BlockId           int64  `json:"blockId"`
+	TxId              string `json:"txId"`
+	SourceAddress     string `json:"sourceAddress"`
+	Asset             string `json:"asset"`
+	Quantity          uint64 `json:"quantity"`
+	Status            string `json:"status"`
+	SweepPaymentId    string `json:"sweepPaymentId,omitempty"`
+}
+
+type CustomerDeposits struct {
+	CustomerReference string    `json:"customerReference"`
+	Address           string    `json:"address"`
+	Balances          []Amount  `json:"balances"` // Total credited to the customer for each asset
+	Deposits          []Deposit `json:"deposits"`
+	RequestId         string    `json:"requestId"`
+	Nonce             int64     `json:"nonce"`
+	BlockchainId      string    `json:"blockchainId"`
+}
+
+// Mirrors a Counterparty asset as a Ripple IOU for one customer of the gateway. The Counterparty asset deposited to the Counterparty payment
+// address is issued as the IOU to the external Ripple account, and the IOU sent back from the external account to the native payment
+// address (the issuer) is paid out as the Counterparty asset to the Counterparty address
+type AddressMap struct {
+	AddressMapId               int64  `json:"addressMapId"`
+	CounterpartyPaymentAddress string `json:"counterpartyPaymentAddress"`
+	NativePaymentAddress       string `json:"nativePaymentAddress"`
+	ExternalAddress            string `json:"externalAddress"`
+	CounterpartyAddress        string `json:"counterpartyAddress"`
+	CounterpartyAssetName      string `json:"counterpartyAssetName"`
+	NativeAssetName            string `json:"nativeAssetName"`
+	CounterpartyDivisible      bool   `json:"counterpartyDivisible"`
+	RequestId                  string `json:"requestId,omitempty"`
+	Nonce                      int64  `json:"nonce,omitempty"`
+	BlockchainId               string `json:"blockchainId,omitempty"`
+}
+
+type AddressMaps struct {
+	AddressMaps  []AddressMap `json:"addressMaps"`
+	RequestId    string       `json:"requestId"`
+	Nonce        int64        `json:"nonce"`
+	BlockchainId string       `json:"blockchainId"`
+}
+
+// Both legs of a transfer through the gateway. The deposit leg was received on one blockchain and the payout leg is the payment made on the other
+type GatewayTransfer struct {
+	GatewayTransferId    int64  `json:"gatewayTransferId"`
+	AddressMapId         int64  `json:"addressMapId"`
+	DepositBlockchainId  string `json:"depositBlockchainId"`
+	DepositBlockId       int64  `json:"depositBlockId"`
+	DepositTxId          string `json:"depositTxId"`
+	DepositSourceAddress string `json:"depositSourceAddress"`
+	DepositAddress       string `json:"depositAddress"`
+	DepositAsset         string `json:"depositAsset"`
+	DepositQuantity      uint64 `json:"depositQuantity"`
+	PayoutBlockchainId   string `json:"payoutBlockchainId"`
+	PayoutSourceAddress  string `json:"payoutSourceAddress"`
+	PayoutAddress        string `json:"payoutAddress"`
+	PayoutAsset          string `json:"payoutAsset"`
+	PayoutQuantity       uint64 `json:"payoutQuantity"`
+	PayoutPaymentId      string `json:"payoutPaymentId,omitempty"`
+	PayoutTxId           string `json:"payoutTxId,omitempty"`
+	Status               string `json:"status"`
+	ErrorCode            int64  `json:"errorCode,omitempty"`
+	ErrorDescription     string `json:"errorDescription,omitempty"`
+}
+
+type GatewayTransfers struct {
+	GatewayTransfers []GatewayTransfer `json:"gatewayTransfers"`
+	RequestId        string            `json:"requestId"`
+	Nonce            int64             `json:"nonce"`
+	BlockchainId     string            `json:"blockchainId"`
+}
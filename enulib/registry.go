@@ -0,0 +1,94 @@
+// Registry of the holders of an asset at block heights.
+// Snapshots are taken at chosen heights or at the block of each issuance or dividend, and compared to report how the holdings changed.
+
+package enulib
+
+import (
+	"sort"
+)
+
+const (
+	SnapshotRequest  = "request"  // taken when the holders at the block were first requested
+	SnapshotBlock    = "block"    // taken at a scheduled block height
+	SnapshotIssuance = "issuance" // taken at the block of an issuance of the asset
+	SnapshotDividend = "dividend" // taken at the block of a dividend paid on the asset
+)
+
+type RegistrySnapshot struct {
+	Asset   string `json:"asset"`
+	BlockId uint64 `json:"blockId"`
+	Trigger string `json:"trigger"`
+	Holders int    `json:"holders"`
+	Total   uint64 `json:"total"` // Sum of the holdings at the block
+}
+
+type RegistrySnapshots struct {
+	Asset     string             `json:"asset"`
+	Snapshots []RegistrySnapshot `json:"snapshots"`
+	RequestId string             `json:"requestId"`
+	Nonce     int64              `json:"nonce"`
+}
+
+// Heights and events at which snapshots of the asset are taken
+type RegistrySchedule struct {
+	Asset      string   `json:"asset"`
+	BlockIds   []uint64 `json:"blockIds"`
+	OnIssuance bool     `json:"onIssuance"`
+	OnDividend bool     `json:"onDividend"`
+	RequestId  string   `json:"requestId"`
+	Nonce      int64    `json:"nonce"`
+}
+
+// The holding of an address at two block heights. Addresses which held nothing at a height have a quantity of 0
+type RegistryChange struct {
+	Address      string `json:"address"`
+	FromQuantity uint64 `json:"fromQuantity"`
+	ToQuantity   uint64 `json:"toQuantity"`
+	Change       int64  `json:"change"`
+}
+
+type RegistryDiff struct {
+	Asset       string           `json:"asset"`
+	FromBlockId uint64           `json:"fromBlockId"`
+	ToBlockId   uint64           `json:"toBlockId"`
+	Changes     []RegistryChange `json:"changes"`
+	RequestId   string           `json:"requestId"`
+	Nonce       int64            `json:"nonce"`
+}
+
+// Returns the holdings which differ between the two snapshots, ordered by address. New holders have a from quantity of 0
+// and holders who sold out have a to quantity of 0
+func DiffHoldings(from []AddressAmount, to []AddressAmount) []RegistryChange {
+	result := []RegistryChange{}
+	changes := make(map[string]*RegistryChange)
+
+	for _, holding := range from {
+		changes[holding.Address] = &RegistryChange{Address: holding.Address, FromQuantity: holding.Quantity}
+	}
+
+	for _, holding := range to {
+		if changes[holding.Address] == nil {
+			changes[holding.Address] = &RegistryChange{Address: holding.Address}
+		}
+		changes[holding.Address].ToQuantity = holding.Quantity
+	}
+
+	for _, change := range changes {
+		if change.FromQuantity == change.ToQuantity {
+			continue
+		}
+
+		change.Change = int64(change.ToQuantity) - int64(change.FromQuantity)
+		result = append(result, *change)
+	}
+
+	sort.Sort(byRegistryAddress(result))
+
+	return result
+}
+
+type byRegistryAddress []RegistryChange
+
+func (r byRegistryAddress) Len() int           { return len(r) }
+func (r byRegistryAddress) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byRegistryAddress) Less(i, j int) bool { return r[i].Address < r[j].Address }
@@ -0,0 +1,34 @@
+package enulib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffHoldings(t *testing.T) {
+	var testData = []struct {
+		From            []AddressAmount
+		To              []AddressAmount
+		Expected        []RegistryChange
+		CaseDescription string
+	}{
+		{nil, nil, []RegistryChange{}, "No holders at either block"},
+		{[]AddressAmount{{Address: "a", Quantity: 100}}, []AddressAmount{{Address: "a", Quantity: 100}}, []RegistryChange{}, "Unchanged holding is omitted"},
+		{nil, []AddressAmount{{Address: "a", Quantity: 100}}, []RegistryChange{{Address: "a", FromQuantity: 0, ToQuantity: 100, Change: 100}}, "New holder"},
+		{[]AddressAmount{{Address: "a", Quantity: 100}}, nil, []RegistryChange{{Address: "a", FromQuantity: 100, ToQuantity: 0, Change: -100}}, "Holder sold out"},
+		{
+			[]AddressAmount{{Address: "c", Quantity: 50}, {Address: "a", Quantity: 100}, {Address: "b", Quantity: 10}},
+			[]AddressAmount{{Address: "a", Quantity: 60}, {Address: "b", Quantity: 10}, {Address: "d", Quantity: 90}},
+			[]RegistryChange{{Address: "a", FromQuantity: 100, ToQuantity: 60, Change: -40}, {Address: "c", FromQuantity: 50, ToQuantity: 0, Change: -50}, {Address: "d", FromQuantity: 0, ToQuantity: 90, Change: 90}},
+			"Mixed changes are ordered by address",
+		},
+	}
+
+	for _, s := range testData {
+		result := DiffHoldings(s.From, s.To)
+
+		if reflect.DeepEqual(result, s.Expected) == false {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
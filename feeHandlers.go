@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Fee schedules of the access key
+func FeeSchedules(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "feeSchedules")
+
+	return handle(c, w, r)
+}
+
+// Monthly statement of the fees charged to the access key
+func FeeStatement(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "feeStatement")
+
+	return handle(c, w, r)
+}
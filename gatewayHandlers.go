@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Maps an external Ripple account to a Counterparty address through the gateway
+func AddressMapCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "addressMapCreate")
+
+	return handle(c, w, r)
+}
+
+// Address maps of the gateway of the access key
+func AddressMaps(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "addressMaps")
+
+	return handle(c, w, r)
+}
+
+// Both legs of the transfers through the gateway of the access key
+func GatewayTransfers(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "gatewayTransfers")
+
+	return handle(c, w, r)
+}
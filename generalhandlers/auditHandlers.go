@@ -0,0 +1,108 @@
+package generalhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+var audit_DefaultLimit int64 = 100
+var audit_MaxLimit int64 = 1000
+
+// Returns the audit entries of the access key, oldest first. The entityType and entityId query parameters filter the entries and
+// fromAuditId and limit page through them
+func AuditEntries(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.AuditEntries
+	var fromAuditId int64
+	var err error
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	query := r.URL.Query()
+	limit := audit_DefaultLimit
+
+	if query.Get("fromAuditId") != "" {
+		fromAuditId, err = strconv.ParseInt(query.Get("fromAuditId"), 10, 64)
+		if err != nil || fromAuditId < 0 {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid fromAuditId: %s", query.Get("fromAuditId"))
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAuditQuery.Code, consts.GenericErrors.InvalidAuditQuery.Description)
+
+			return nil
+		}
+	}
+
+	if query.Get("limit") != "" {
+		limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil || limit < 1 || limit > audit_MaxLimit {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid limit: %s", query.Get("limit"))
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAuditQuery.Code, consts.GenericErrors.InvalidAuditQuery.Description)
+
+			return nil
+		}
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "AuditEntries called for entityType: '%s', entityId: '%s', fromAuditId: %d, limit: %d by '%s'\n", query.Get("entityType"), query.Get("entityId"), fromAuditId, limit, accessKey)
+
+	entries, err := database.GetAuditEntries(c, accessKey, query.Get("entityType"), query.Get("entityId"), fromAuditId, limit)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	result.Entries = entries
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Checks the hash chain of the whole audit trail. The entry which broke the chain may belong to another access key so it is only
+// reported to the operator through utils/audit
+func AuditVerify(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.AuditChainStatus
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	log.FluentfContext(consts.LOGINFO, c, "AuditVerify called by '%s'\n", c.Value(consts.AccessKeyKey).(string))
+
+	verification, err := database.VerifyAudit(c)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	result.Valid = verification.Valid
+	result.Entries = verification.Entries
+	result.LastHash = verification.LastHash
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
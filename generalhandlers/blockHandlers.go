@@ -0,0 +1,124 @@
+package generalhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+var blocks_DefaultLimit int64 = 100
+var blocks_MaxLimit int64 = 1000
+
+// How many gaps are reported for each chain
+var blocks_MaxGaps int64 = 100
+
+// The backends whose blocks are processed
+var blockchains = []string{consts.BitcoinBlockchainId, consts.CounterpartyBlockchainId, consts.RippleBlockchainId}
+
+// Returns the blocks processed from each backend, highest first, along with how far each backend is behind and any gaps in the processed blocks.
+// The chain query parameter limits the result to bitcoin, counterparty or ripple and offset and limit page through the blocks
+func Blocks(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.Blocks
+	var offset int64
+	var err error
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	query := r.URL.Query()
+	chain := query.Get("chain")
+	limit := blocks_DefaultLimit
+
+	chains := blockchains
+	if chain != "" {
+		chains = nil
+		for _, b := range blockchains {
+			if b == chain {
+				chains = []string{chain}
+			}
+		}
+
+		if chains == nil {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid chain: %s", chain)
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBlockQuery.Code, consts.GenericErrors.InvalidBlockQuery.Description)
+
+			return nil
+		}
+	}
+
+	if query.Get("offset") != "" {
+		offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid offset: %s", query.Get("offset"))
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBlockQuery.Code, consts.GenericErrors.InvalidBlockQuery.Description)
+
+			return nil
+		}
+	}
+
+	if query.Get("limit") != "" {
+		limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil || limit < 1 || limit > blocks_MaxLimit {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid limit: %s", query.Get("limit"))
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidBlockQuery.Code, consts.GenericErrors.InvalidBlockQuery.Description)
+
+			return nil
+		}
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Blocks called for chain: '%s', offset: %d, limit: %d by '%s'\n", chain, offset, limit, accessKey)
+
+	result.Allblocks, err = database.GetBlocks(c, chain, offset, limit)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	for _, blockchainId := range chains {
+		progress := enulib.BlockchainProgress{BlockchainId: blockchainId}
+
+		progress.LastBlockId, _, err = database.GetLastBlock(c, blockchainId)
+		if err != nil {
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		progress.Gaps, err = database.GetBlockGaps(c, blockchainId, blocks_MaxGaps)
+		if err != nil {
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		tipBlockId, tipUpdated := handlers.GetBlockTip(blockchainId)
+		progress.TipBlockId = tipBlockId
+		if tipBlockId != -1 {
+			progress.TipUpdated = tipUpdated.Format("2006-01-02 15:04:05")
+		}
+		progress.Lag = enulib.BlockLag(progress.LastBlockId, progress.TipBlockId)
+
+		result.Chains = append(result.Chains, progress)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package generalhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Returns the fee schedules of the access key on the blockchain of the request
+func FeeSchedules(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.FeeSchedules
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	schedules, err := database.GetFeeSchedules(c, accessKey, c.Value(consts.BlockchainIdKey).(string))
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	result.Schedules = schedules
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the fees charged to the access key on every blockchain in a calendar month (UTC), with totals by blockchain, operation and fee asset.
+// The month query parameter is given as YYYY-MM and defaults to the current month
+func FeeStatement(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var result enulib.FeeStatement
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	result.RequestId = requestId
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format("2006-01")
+	}
+
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid month: %s", month)
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidStatementMonth.Code, consts.GenericErrors.InvalidStatementMonth.Description)
+
+		return nil
+	}
+	end := start.AddDate(0, 1, 0)
+
+	log.FluentfContext(consts.LOGINFO, c, "FeeStatement called for month: %s by '%s'\n", month, accessKey)
+
+	fees, err := database.GetFees(c, accessKey, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	result.Month = month
+	result.Fees = fees
+	result.Lines = enulib.SummariseFees(fees)
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package generalhandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Creates an internal account in the ledger of the access key
+func LedgerAccountCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var account enulib.LedgerAccount
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	accountId := m["accountId"].(string)
+
+	// The external account stands for the assets held on chain and can't be created or used directly
+	if accountId == enulib.LedgerExternalAccountId {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+
+	created, err := database.CreateLedgerAccount(c, accessKey, accountId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if created == false {
+		log.FluentfContext(consts.LOGERROR, c, "Ledger account %s already exists", accountId)
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Created ledger account %s for access key: %s", accountId, accessKey)
+
+	account.AccountId = accountId
+	account.RequestId = requestId
+	account.BlockchainId = c.Value(consts.BlockchainIdKey).(string)
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(account); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the balances of an internal account and the entries posted to it on the blockchain
+func GetLedgerAccount(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var account enulib.LedgerAccount
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	blockchainId := c.Value(consts.BlockchainIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	accountId := vars["accountId"]
+
+	exists, err := database.LedgerAccountExists(c, accessKey, accountId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if exists == false || accountId == enulib.LedgerExternalAccountId {
+		handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+
+	account.Balances, err = database.GetLedgerBalances(c, accessKey, blockchainId, accountId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	account.Entries, err = database.GetLedgerEntries(c, accessKey, blockchainId, accountId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	account.AccountId = accountId
+	account.RequestId = requestId
+	account.BlockchainId = blockchainId
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(account); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Moves assets between two internal accounts without a transaction on chain
+func LedgerTransfer(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var reference string
+
+	sourceAccountId := m["sourceAccountId"].(string)
+	destinationAccountId := m["destinationAccountId"].(string)
+	quantity := uint64(m["quantity"].(float64))
+
+	if m["reference"] != nil {
+		reference = m["reference"].(string)
+	}
+
+	if sourceAccountId == enulib.LedgerExternalAccountId || destinationAccountId == enulib.LedgerExternalAccountId {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+
+	asset, err := ledgerAssetFromRequest(c, m)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.IssuerMustBeGiven.Code, consts.RippleErrors.IssuerMustBeGiven.Description)
+
+		return nil
+	}
+
+	journal, err := enulib.NewTransferJournal(sourceAccountId, destinationAccountId, asset, quantity, reference)
+	postJournal(c, w, journal, err)
+
+	return nil
+}
+
+// Credits an internal account with assets received on chain. The transaction must have credited addresses of the access key with the asset
+// and quantity, and each transaction can only be deposited once
+func LedgerDeposit(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	accountId := m["accountId"].(string)
+	quantity := uint64(m["quantity"].(float64))
+	txId := m["txId"].(string)
+
+	if accountId == enulib.LedgerExternalAccountId {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+
+	asset, err := ledgerAssetFromRequest(c, m)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "%s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.IssuerMustBeGiven.Code, consts.RippleErrors.IssuerMustBeGiven.Description)
+
+		return nil
+	}
+
+	journal, err := enulib.NewDepositJournal(accountId, asset, quantity, txId)
+	postJournal(c, w, journal, err)
+
+	return nil
+}
+
+// Returns the asset of the request on the blockchain of the request. Ripple currencies other than XRP must be given with their issuer
+// since the same currency from another issuer is a different asset
+func ledgerAssetFromRequest(c context.Context, m map[string]interface{}) (enulib.LedgerAsset, error) {
+	var issuer string
+
+	blockchainId := c.Value(consts.BlockchainIdKey).(string)
+	if blockchainId == consts.RippleBlockchainId && m["issuer"] != nil {
+		issuer = m["issuer"].(string)
+	}
+
+	result := enulib.NewLedgerAsset(blockchainId, m["asset"].(string), issuer)
+	if result.BlockchainId == consts.RippleBlockchainId && result.Asset != "XRP" && result.Issuer == "" {
+		return result, errors.New(consts.RippleErrors.IssuerMustBeGiven.Description)
+	}
+
+	return result, nil
+}
+
+// Posts the journal and returns it to the client
+func postJournal(c context.Context, w http.ResponseWriter, journal enulib.Journal, err error) {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	blockchainId := c.Value(consts.BlockchainIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err == nil {
+		err = database.PostJournal(c, accessKey, blockchainId, requestId, journal)
+	}
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to post %s journal: %s", journal.JournalType, err.Error())
+		handlers.ReturnJournalError(c, w, err)
+
+		return
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Posted %s journal %s for access key: %s", journal.JournalType, journal.JournalId, accessKey)
+
+	journal.RequestId = requestId
+	journal.BlockchainId = blockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(journal); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+	}
+}
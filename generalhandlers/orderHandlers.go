@@ -0,0 +1,56 @@
+package generalhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Returns the status of an order, cancel or BTCpay placed through Enu
+func GetOrder(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	orderId := vars["orderId"]
+
+	if orderId == "" || len(orderId) < 16 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid orderId")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidOrderId.Code, consts.GenericErrors.InvalidOrderId.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "GetOrder called for '%s' by '%s'\n", orderId, c.Value(consts.AccessKeyKey).(string))
+
+	order, err := database.GetOrderByOrderId(c, c.Value(consts.AccessKeyKey).(string), orderId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if order.OrderId == "" {
+		handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidOrderId.Code, consts.GenericErrors.InvalidOrderId.Description)
+
+		return nil
+	}
+	order.RequestId = requestId
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
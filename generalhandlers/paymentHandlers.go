@@ -10,6 +10,7 @@ import (
 	"github.com/whoisjeremylam/enu/enulib"
 	"github.com/whoisjeremylam/enu/handlers"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/validation"
 
 	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
@@ -70,7 +71,7 @@ func GetPaymentsByAddress(c context.Context, w http.ResponseWriter, r *http.Requ
 	vars := mux.Vars(r)
 	address := vars["address"]
 
-	if address == "" {
+	if validation.IsValidAddress(c.Value(consts.BlockchainIdKey).(string), address) == false {
 		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
 		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
 
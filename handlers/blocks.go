@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// How many blocks below the last processed block are searched for the start of a reorg
+var Block_MaxReorgDepth int64 = 100
+
+// Processes the blocks of a backend. GetBlockHash returns the hash of the block at a height in the chain the backend currently follows.
+// Process and Rollback are optional: Process records whatever the chain needs from the block and Rollback undoes it when the block
+// is no longer in the chain. Processing a block again must replace what was recorded the first time
+type BlockProcessor struct {
+	BlockchainId string
+	GetBlockHash func(c context.Context, blockId int64) (string, error)
+	Process      func(c context.Context, blockId int64) error
+	Rollback     func(c context.Context, blockId int64) error
+}
+
+type blockTip struct {
+	blockId int64
+	updated time.Time
+}
+
+// The highest block reported by each backend when it was last polled
+var blockTips = make(map[string]blockTip)
+var blockTipsMutex sync.Mutex
+
+// Returns the highest block the backend of the chain reported when it was last polled, or -1 if it hasn't been polled since Enu was started
+func GetBlockTip(blockchainId string) (int64, time.Time) {
+	blockTipsMutex.Lock()
+	defer blockTipsMutex.Unlock()
+
+	tip, ok := blockTips[blockchainId]
+	if ok == false {
+		return -1, time.Time{}
+	}
+
+	return tip.blockId, tip.updated
+}
+
+func setBlockTip(blockchainId string, blockId int64) {
+	blockTipsMutex.Lock()
+	defer blockTipsMutex.Unlock()
+
+	blockTips[blockchainId] = blockTip{blockId: blockId, updated: time.Now().UTC()}
+}
+
+// Processes the blocks of the chain in order from the last processed block up to the tip the backend reported and records the outcome of each.
+// Processing starts from the tip the first time, history before then isn't processed. Before carrying on the last processed block is checked
+// against the backend, if the backend has reorganised its chain the blocks which are no longer in it are rolled back and marked as reorged
+// so they are processed again from the next call. Processing stops at a block which fails, it is retried on the next call
+func ProcessBlocks(c context.Context, processor BlockProcessor, tipBlockId int64) {
+	setBlockTip(processor.BlockchainId, tipBlockId)
+
+	lastBlockId, lastBlockHash, err := database.GetLastBlock(c, processor.BlockchainId)
+	if err != nil {
+		return
+	}
+
+	if lastBlockId == -1 {
+		lastBlockId = tipBlockId - 1
+	} else {
+		currentHash, err := processor.GetBlockHash(c, lastBlockId)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Unable to get the hash of %s block %d: %s", processor.BlockchainId, lastBlockId, err.Error())
+			return
+		}
+
+		if currentHash != lastBlockHash {
+			rollbackReorg(c, processor, lastBlockId)
+			return
+		}
+	}
+
+	for blockId := lastBlockId + 1; blockId <= tipBlockId; blockId++ {
+		start := time.Now()
+
+		blockHash, err := processor.GetBlockHash(c, blockId)
+		if err == nil && processor.Process != nil {
+			err = processor.Process(c, blockId)
+		}
+
+		duration := time.Since(start).Nanoseconds() / int64(time.Millisecond)
+
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Unable to process %s block %d: %s", processor.BlockchainId, blockId, err.Error())
+			database.InsertBlock(c, processor.BlockchainId, blockId, blockHash, consts.BlockErrorStatus, duration)
+
+			return
+		}
+
+		log.FluentfContext(consts.LOGINFO, c, "Processed %s block %d in %d milliseconds", processor.BlockchainId, blockId, duration)
+		database.InsertBlock(c, processor.BlockchainId, blockId, blockHash, consts.BlockProcessedStatus, duration)
+	}
+}
+
+// Finds where the chain of the backend diverged from the processed blocks and undoes the blocks above it
+func rollbackReorg(c context.Context, processor BlockProcessor, lastBlockId int64) {
+	recordedHash := func(blockId int64) (string, error) {
+		return database.GetBlockHash(c, processor.BlockchainId, blockId)
+	}
+	chainHash := func(blockId int64) (string, error) {
+		return processor.GetBlockHash(c, blockId)
+	}
+
+	forkBlockId, err := enulib.FindForkBlock(lastBlockId, Block_MaxReorgDepth, recordedHash, chainHash)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to find the start of the %s reorg below block %d: %s", processor.BlockchainId, lastBlockId, err.Error())
+		return
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "The %s chain was reorganised, blocks %d to %d are no longer in the chain", processor.BlockchainId, forkBlockId+1, lastBlockId)
+
+	if processor.Rollback != nil {
+		for blockId := lastBlockId; blockId > forkBlockId; blockId-- {
+			if err := processor.Rollback(c, blockId); err != nil {
+				log.FluentfContext(consts.LOGERROR, c, "Unable to roll back %s block %d: %s", processor.BlockchainId, blockId, err.Error())
+				return
+			}
+		}
+	}
+
+	database.UpdateBlocksReorged(c, processor.BlockchainId, forkBlockId)
+}
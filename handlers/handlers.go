@@ -20,8 +20,12 @@ import (
 	"github.com/whoisjeremylam/enu/internal/github.com/xeipuuv/gojsonschema"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/validation"
 )
 
+// Returned by ValidateParameters() when an address in the request fails validation
+var ErrInvalidAddress = errors.New(consts.GenericErrors.InvalidAddress.Description)
+
 var quotes = [...]string{"Here's to the crazy ones. The misfits. The rebels. The troublemakers. The round pegs in the square holes. The ones who see things differently. They're not fond of rules. And they have no respect for the status quo. You can quote them, disagree with them, glorify or vilify them. About the only thing you can't do is ignore them. Because they change things. They push the human race forward. And while some may see them as the crazy ones, we see genius. Because the people who are crazy enough to think they can change the world, are the ones who do. - Apple Inc.",
 	"You miss 100% of the shots you don’t take. –Wayne Gretzky",
 	"七転び八起き - Japanese proverb",
@@ -184,6 +188,35 @@ func ReturnServerErrorWithCustomError(c context.Context, w http.ResponseWriter,
 	}
 }
 
+// Returns the reason a journal couldn't be posted to the ledger
+func ReturnJournalError(c context.Context, w http.ResponseWriter, e error) {
+	switch e {
+	case enulib.ErrNegativeLedgerBalance:
+		ReturnBadRequest(c, w, consts.GenericErrors.InsufficientBalance.Code, consts.GenericErrors.InsufficientBalance.Description)
+	case enulib.ErrInvalidLedgerEntry, enulib.ErrUnbalancedJournal, enulib.ErrLedgerBalanceOverflow:
+		ReturnBadRequest(c, w, consts.GenericErrors.InvalidDocument.Code, consts.GenericErrors.InvalidDocument.Description)
+	case database.ErrUnknownLedgerAccount:
+		ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+	case database.ErrDuplicateJournal:
+		ReturnBadRequest(c, w, consts.GenericErrors.DuplicateJournal.Code, consts.GenericErrors.DuplicateJournal.Description)
+	case database.ErrUnverifiedDeposit:
+		ReturnBadRequest(c, w, consts.GenericErrors.UnverifiedDeposit.Code, consts.GenericErrors.UnverifiedDeposit.Description)
+	default:
+		ReturnServerError(c, w)
+	}
+}
+
+// Background loops have no API call to take their context from, so they are given the environment and blockchain which an API call would have
+func BackgroundContext(c context.Context, blockchainId string) context.Context {
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "dev"
+	}
+	c = context.WithValue(c, consts.EnvKey, env)
+
+	return context.WithValue(c, consts.BlockchainIdKey, blockchainId)
+}
+
 // Handles the '/' path and returns a random quote
 func Index(w http.ResponseWriter, r *http.Request) {
 	rand.Seed(time.Now().UnixNano())
@@ -327,7 +360,11 @@ func CheckAndParseJsonCTX(c context.Context, w http.ResponseWriter, r *http.Requ
 	}
 
 	err = ValidateParameters(c2, payload)
-	if err != nil {
+	if err == ErrInvalidAddress {
+		ReturnBadRequest(c2, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return c2, m, err
+	} else if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, err.Error())
 		ReturnUnprocessableEntity(c2, w, consts.GenericErrors.InvalidDocument.Code, err)
 
@@ -359,6 +396,15 @@ func ValidateParameters(c context.Context, parameters interface{}) error {
 		if result.Valid() {
 			return nil
 		} else {
+			// Addresses are reported with the same error regardless of which parameter they were given in
+			for _, desc := range result.Errors() {
+				if format, ok := desc.Details()["format"].(string); ok && validation.IsAddressFormat(format) {
+					log.FluentfContext(consts.LOGERROR, c, "Invalid address in parameter: %s", desc.Field())
+
+					return ErrInvalidAddress
+				}
+			}
+
 			var errorList string
 			for _, desc := range result.Errors() {
 				errorList = errorList + fmt.Sprintf("%s. ", desc)
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/whoisjeremylam/enu/consts"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func TestBackgroundContext(t *testing.T) {
+	var testData = []struct {
+		Env             string
+		BlockchainId    string
+		ExpectedEnv     string
+		CaseDescription string
+	}{
+		{"", consts.CounterpartyBlockchainId, "dev", "No environment set"},
+		{"prod", consts.CounterpartyBlockchainId, "prod", "Environment set"},
+		{"prod", consts.RippleBlockchainId, "prod", "Ripple"},
+	}
+
+	defer os.Setenv("ENV", os.Getenv("ENV"))
+
+	for _, s := range testData {
+		os.Setenv("ENV", s.Env)
+
+		c := BackgroundContext(context.Background(), s.BlockchainId)
+
+		// Payments assert both are strings, so a background loop without them would panic
+		env, _ := c.Value(consts.EnvKey).(string)
+		blockchainId, _ := c.Value(consts.BlockchainIdKey).(string)
+
+		if env != s.ExpectedEnv || blockchainId != s.BlockchainId {
+			t.Errorf("Expected: '%s' '%s', Got: '%s' '%s'\nCase: %s\n", s.ExpectedEnv, s.BlockchainId, env, blockchainId, s.CaseDescription)
+		}
+	}
+}
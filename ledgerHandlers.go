@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Creates an internal ledger account for the access key
+func LedgerAccountCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "ledgerAccountCreate")
+
+	return handle(c, w, r)
+}
+
+// Balances and entries of an internal ledger account
+func GetLedgerAccount(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "getLedgerAccount")
+
+	return handle(c, w, r)
+}
+
+// Off-chain transfer between two internal ledger accounts
+func LedgerTransfer(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "ledgerTransfer")
+
+	return handle(c, w, r)
+}
+
+// Credits an internal ledger account with an on-chain deposit
+func LedgerDeposit(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "ledgerDeposit")
+
+	return handle(c, w, r)
+}
+
+// Debits an internal ledger account and sends the assets on chain
+func LedgerWithdrawal(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "ledgerWithdrawal")
+
+	return handle(c, w, r)
+}
@@ -0,0 +1,321 @@
+// Health based routing of requests across the upstream nodes of a backend, eg several counterpartyd servers.
+// Reads go to the healthy node which has seen the most blocks. Writes are pinned to one node per account so that consecutive
+// transactions of an account see each other, and move only when that node becomes unhealthy or the account stops writing for a while.
+// Each node has a circuit breaker which opens after repeated failures so requests stop waiting on a node which is down.
+// Once the breaker has been open for a while a single request is let through to test the node again.
+
+package nodepool
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Consecutive failures after which the breaker of a node opens
+var Pool_FailureThreshold = 3
+
+// How long the breaker of a node stays open before a request is let through to test it
+var Pool_OpenDuration = 30000 // milliseconds
+
+// How often the health and block height of each node are checked
+var Pool_MonitorRate = 15000 // milliseconds
+
+// How long an account stays pinned to a node after its last write. Expired pins are forgotten so the pins don't grow without bound
+var Pool_PinDuration = 600000 // milliseconds
+
+const (
+	BreakerClosed   = "closed"   // the node is healthy
+	BreakerOpen     = "open"     // the node has failed repeatedly and isn't sent requests
+	BreakerHalfOpen = "halfopen" // a request has been let through to test the node
+)
+
+// The address and credentials of an upstream node
+type Node struct {
+	Host     string
+	User     string
+	Password string
+}
+
+type NodeStatus struct {
+	Host      string    `json:"host"`
+	Breaker   string    `json:"breaker"`
+	Height    int64     `json:"height"` // -1 until the height of the node is known
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError,omitempty"`
+	Checked   time.Time `json:"checked"`
+}
+
+// The node an account's writes go to and when the account last wrote
+type pin struct {
+	node    int
+	written time.Time
+}
+
+type Pool struct {
+	name     string
+	mutex    sync.Mutex
+	nodes    []Node
+	statuses []NodeStatus
+	opened   []time.Time
+	pinned   map[string]pin // account -> the node its writes go to
+	pruned   time.Time      // when expired pins were last removed
+	now      func() time.Time
+}
+
+// Returns a pool of the nodes. The order of the nodes is their priority when they are equally caught up
+func NewPool(name string, nodes []Node) *Pool {
+	p := Pool{name: name, nodes: nodes, pinned: make(map[string]pin), now: time.Now}
+
+	for _, node := range nodes {
+		p.statuses = append(p.statuses, NodeStatus{Host: node.Host, Breaker: BreakerClosed, Height: -1})
+		p.opened = append(p.opened, time.Time{})
+	}
+
+	return &p
+}
+
+// Returns the nodes listed in the configuration, a list of objects with a host and optionally a user and password
+func ConfigNodes(config interface{}) []Node {
+	var result []Node
+
+	list, ok := config.([]interface{})
+	if ok == false {
+		return result
+	}
+
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if ok == false || m["host"] == nil {
+			continue
+		}
+
+		node := Node{Host: m["host"].(string)}
+		if m["user"] != nil {
+			node.User = m["user"].(string)
+		}
+		if m["password"] != nil {
+			node.Password = m["password"].(string)
+		}
+
+		result = append(result, node)
+	}
+
+	return result
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+func (p *Pool) Len() int {
+	return len(p.nodes)
+}
+
+// Returns the node at the index
+func (p *Pool) Node(i int) Node {
+	return p.nodes[i]
+}
+
+// Returns the indexes of the nodes a read should be tried on in order: the healthy nodes which have seen the most blocks first.
+// Nodes whose breaker is open aren't returned, so an empty result means no node is available
+func (p *Pool) ReadNodes() []int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var result []int
+	for i := range p.nodes {
+		if p.available(i) {
+			result = append(result, i)
+		}
+	}
+
+	sort.Stable(byHeight{indexes: result, statuses: p.statuses})
+
+	return result
+}
+
+// Returns the indexes of the nodes a write by the account should be tried on in order. The node the account is pinned to comes first
+// while it is available and the pin hasn't expired, otherwise the account is pinned again to the best node for reads
+func (p *Pool) WriteNodes(account string) []int {
+	result := p.ReadNodes()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(result) == 0 {
+		return result
+	}
+
+	pinned, ok := p.pinned[account]
+	if ok && p.expired(pinned) == false {
+		for i, node := range result {
+			if node == pinned.node {
+				return append([]int{pinned.node}, append(append([]int{}, result[:i]...), result[i+1:]...)...)
+			}
+		}
+	}
+
+	p.pin(account, result[0])
+
+	return result
+}
+
+// Pins the writes of the account to the node which has just accepted one, eg when a write had to move to another node
+func (p *Pool) Pin(account string, i int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pin(account, i)
+}
+
+// Pins the account and, at most once per pin duration, forgets the pins which have expired. The mutex must be held
+func (p *Pool) pin(account string, i int) {
+	now := p.now()
+
+	if now.Sub(p.pruned) >= time.Duration(Pool_PinDuration)*time.Millisecond {
+		for a, pinned := range p.pinned {
+			if p.expired(pinned) {
+				delete(p.pinned, a)
+			}
+		}
+		p.pruned = now
+	}
+
+	p.pinned[account] = pin{node: i, written: now}
+}
+
+// Returns true if the account hasn't written for longer than the pin duration
+func (p *Pool) expired(pinned pin) bool {
+	return p.now().Sub(pinned.written) >= time.Duration(Pool_PinDuration)*time.Millisecond
+}
+
+// Records a request which the node answered, closing its breaker
+func (p *Pool) Success(i int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.statuses[i].Breaker = BreakerClosed
+	p.statuses[i].Failures = 0
+	p.statuses[i].LastError = ""
+	p.statuses[i].Checked = p.now()
+}
+
+// Records a request which failed because the node couldn't be reached or isn't ready. Returns true if the breaker of the node opened
+func (p *Pool) Failure(i int, err error) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	status := &p.statuses[i]
+	status.Failures++
+	status.Checked = p.now()
+	if err != nil {
+		status.LastError = err.Error()
+	}
+
+	// A failed test of the node opens the breaker again straight away
+	if status.Breaker == BreakerHalfOpen || (status.Breaker == BreakerClosed && status.Failures >= Pool_FailureThreshold) {
+		status.Breaker = BreakerOpen
+		p.opened[i] = p.now()
+
+		return true
+	}
+
+	return false
+}
+
+// Records the number of blocks the node has seen
+func (p *Pool) SetHeight(i int, height int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.statuses[i].Height = height
+}
+
+// Sends the request to each of the nodes in turn until one answers and returns the index of the node which answered, or -1 if none did.
+// The request returns false if the node couldn't be reached or isn't ready, an error which the node answered with counts as an answer
+func (p *Pool) Try(c context.Context, nodes []int, request func(node Node) (bool, error)) int {
+	for _, i := range nodes {
+		answered, err := request(p.nodes[i])
+		if answered {
+			p.Success(i)
+			return i
+		}
+
+		if p.Failure(i, err) {
+			log.FluentfContext(consts.LOGERROR, c, "The circuit breaker of %s node %s has opened after %d failures", p.name, p.nodes[i].Host, Pool_FailureThreshold)
+		}
+	}
+
+	return -1
+}
+
+// Checks the health and height of every node, including those whose breaker is open, until Enu is stopped.
+// The check returns the number of blocks the node has seen, or an error if the node couldn't be reached or isn't ready
+func (p *Pool) Monitor(c context.Context, check func(c context.Context, node Node) (int64, error)) {
+	for {
+		for i, node := range p.nodes {
+			height, err := check(c, node)
+			if err != nil {
+				if p.Failure(i, err) {
+					log.FluentfContext(consts.LOGERROR, c, "The circuit breaker of %s node %s has opened after %d failures", p.name, node.Host, Pool_FailureThreshold)
+				}
+				continue
+			}
+
+			p.SetHeight(i, height)
+			p.Success(i)
+		}
+
+		time.Sleep(time.Duration(Pool_MonitorRate) * time.Millisecond)
+	}
+}
+
+// Returns the status of each node
+func (p *Pool) Statuses() []NodeStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]NodeStatus{}, p.statuses...)
+}
+
+// Returns true if requests can be sent to the node. An open breaker lets a single request through once it has been open long enough
+func (p *Pool) available(i int) bool {
+	status := &p.statuses[i]
+
+	switch status.Breaker {
+	case BreakerOpen:
+		if p.now().Sub(p.opened[i]) < time.Duration(Pool_OpenDuration)*time.Millisecond {
+			return false
+		}
+		status.Breaker = BreakerHalfOpen
+		p.opened[i] = p.now()
+
+		return true
+	case BreakerHalfOpen:
+		// Only the request which is testing the node is let through, unless it was never sent
+		if p.now().Sub(p.opened[i]) < time.Duration(Pool_OpenDuration)*time.Millisecond {
+			return false
+		}
+		p.opened[i] = p.now()
+
+		return true
+	}
+
+	return true
+}
+
+type byHeight struct {
+	indexes  []int
+	statuses []NodeStatus
+}
+
+func (b byHeight) Len() int      { return len(b.indexes) }
+func (b byHeight) Swap(i, j int) { b.indexes[i], b.indexes[j] = b.indexes[j], b.indexes[i] }
+func (b byHeight) Less(i, j int) bool {
+	return b.statuses[b.indexes[i]].Height > b.statuses[b.indexes[j]].Height
+}
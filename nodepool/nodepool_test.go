@@ -0,0 +1,167 @@
+package nodepool
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func newTestPool(clock *time.Time) *Pool {
+	p := NewPool("test", []Node{{Host: "a"}, {Host: "b"}, {Host: "c"}})
+	p.now = func() time.Time { return *clock }
+
+	return p
+}
+
+func TestReadNodes(t *testing.T) {
+	var testData = []struct {
+		Heights         []int64
+		Failures        []int
+		Expected        []int
+		CaseDescription string
+	}{
+		{[]int64{-1, -1, -1}, []int{0, 0, 0}, []int{0, 1, 2}, "Heights unknown, configured order"},
+		{[]int64{100, 102, 101}, []int{0, 0, 0}, []int{1, 2, 0}, "Most caught up first"},
+		{[]int64{100, 100, 101}, []int{0, 0, 0}, []int{2, 0, 1}, "Equally caught up nodes keep the configured order"},
+		{[]int64{100, 102, 101}, []int{0, 3, 0}, []int{2, 0}, "Open breaker excluded"},
+		{[]int64{100, 102, 101}, []int{0, 2, 0}, []int{1, 2, 0}, "Failures below the threshold"},
+		{[]int64{100, 102, 101}, []int{3, 3, 3}, nil, "Every breaker open"},
+	}
+
+	for _, s := range testData {
+		clock := time.Now()
+		p := newTestPool(&clock)
+
+		for i := range s.Heights {
+			p.SetHeight(i, s.Heights[i])
+			for f := 0; f < s.Failures[i]; f++ {
+				p.Failure(i, errors.New("unreachable"))
+			}
+		}
+
+		result := p.ReadNodes()
+
+		if reflect.DeepEqual(result, s.Expected) == false {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestWriteNodes(t *testing.T) {
+	clock := time.Now()
+	p := newTestPool(&clock)
+	p.SetHeight(0, 100)
+	p.SetHeight(1, 100)
+	p.SetHeight(2, 100)
+
+	// The first write pins the account to the best node
+	if result := p.WriteNodes("alice"); reflect.DeepEqual(result, []int{0, 1, 2}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{0, 1, 2}, result, "First write")
+	}
+
+	// The account stays on its node even once another node is further ahead
+	p.SetHeight(2, 101)
+	if result := p.WriteNodes("alice"); reflect.DeepEqual(result, []int{0, 2, 1}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{0, 2, 1}, result, "Pinned")
+	}
+
+	// A different account is pinned to the best node
+	if result := p.WriteNodes("bob"); reflect.DeepEqual(result, []int{2, 0, 1}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{2, 0, 1}, result, "Another account")
+	}
+
+	// The account moves once its node's breaker opens
+	for f := 0; f < Pool_FailureThreshold; f++ {
+		p.Failure(0, errors.New("unreachable"))
+	}
+	if result := p.WriteNodes("alice"); reflect.DeepEqual(result, []int{2, 1}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{2, 1}, result, "Pinned node unavailable")
+	}
+
+	// ... and stays on the new node when the old one recovers
+	p.Success(0)
+	if result := p.WriteNodes("alice"); reflect.DeepEqual(result, []int{2, 0, 1}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{2, 0, 1}, result, "Pinned again")
+	}
+
+	// The pin expires once the account hasn't written for the pin duration
+	p.SetHeight(0, 102)
+	clock = clock.Add(time.Duration(Pool_PinDuration) * time.Millisecond)
+	if result := p.WriteNodes("alice"); reflect.DeepEqual(result, []int{0, 2, 1}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{0, 2, 1}, result, "Pin expired")
+	}
+
+	// ... and the pins of accounts which have stopped writing are forgotten
+	if len(p.pinned) != 1 {
+		t.Errorf("Expected: %d, Got: %d\nCase: %s\n", 1, len(p.pinned), "Expired pins removed")
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	clock := time.Now()
+	p := newTestPool(&clock)
+	openDuration := time.Duration(Pool_OpenDuration) * time.Millisecond
+
+	for f := 1; f < Pool_FailureThreshold; f++ {
+		if p.Failure(0, errors.New("unreachable")) {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", false, true, "Below the threshold")
+		}
+	}
+	if p.Failure(0, errors.New("unreachable")) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", true, false, "Threshold reached")
+	}
+	if status := p.Statuses()[0]; status.Breaker != BreakerOpen || status.LastError != "unreachable" {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", BreakerOpen, status, "Open")
+	}
+
+	// Once open long enough a single request tests the node
+	clock = clock.Add(openDuration)
+	if result := p.ReadNodes(); reflect.DeepEqual(result, []int{0, 1, 2}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{0, 1, 2}, result, "Half open")
+	}
+	if result := p.ReadNodes(); reflect.DeepEqual(result, []int{1, 2}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{1, 2}, result, "Only one test request")
+	}
+
+	// A failed test opens the breaker again straight away
+	if p.Failure(0, errors.New("unreachable")) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", true, false, "Failed test")
+	}
+	if result := p.ReadNodes(); reflect.DeepEqual(result, []int{1, 2}) == false {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", []int{1, 2}, result, "Open again")
+	}
+
+	// A successful test closes it
+	clock = clock.Add(openDuration)
+	p.ReadNodes()
+	p.Success(0)
+	if status := p.Statuses()[0]; status.Breaker != BreakerClosed || status.Failures != 0 {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", BreakerClosed, status, "Successful test")
+	}
+}
+
+func TestTry(t *testing.T) {
+	clock := time.Now()
+	p := newTestPool(&clock)
+
+	var tried []string
+	answered := p.Try(context.TODO(), []int{0, 1, 2}, func(node Node) (bool, error) {
+		tried = append(tried, node.Host)
+		return node.Host == "b", errors.New("unreachable")
+	})
+
+	if answered != 1 || reflect.DeepEqual(tried, []string{"a", "b"}) == false {
+		t.Errorf("Expected: %+v, Got: %+v %+v\nCase: %s\n", 1, answered, tried, "Second node answers")
+	}
+	if statuses := p.Statuses(); statuses[0].Failures != 1 || statuses[1].Failures != 0 || statuses[2].Failures != 0 {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", "a failure recorded for a", statuses, "Failures recorded")
+	}
+
+	answered = p.Try(context.TODO(), []int{0, 2}, func(node Node) (bool, error) { return false, nil })
+	if answered != -1 {
+		t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", -1, answered, "No node answers")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func OrderCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "orderCreate")
+
+	return handle(c, w, r)
+}
+
+func GetOrder(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "getOrder")
+
+	return handle(c, w, r)
+}
+
+func OrderCancel(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "orderCancel")
+
+	return handle(c, w, r)
+}
+
+// Pays the BTC owed for an order match
+func OrderBtcPay(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "orderBtcPay")
+
+	return handle(c, w, r)
+}
+
+// Open orders placed by an address
+func OpenOrdersByAddress(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "openOrders")
+
+	return handle(c, w, r)
+}
+
+// Open orders between two assets
+func OrderBook(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "orderBook")
+
+	return handle(c, w, r)
+}
@@ -0,0 +1,77 @@
+package rippleapi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// The header of a ledger as returned by the ledger method
+type Ledger struct {
+	LedgerIndex int64
+	LedgerHash  string
+	Validated   bool
+}
+
+// Returns the latest validated ledger
+func GetValidatedLedger(c context.Context) (Ledger, int64, error) {
+	return getLedger(c, "validated")
+}
+
+// Returns the ledger at the index, which is only final once it has been validated
+func GetLedger(c context.Context, ledgerIndex int64) (Ledger, int64, error) {
+	return getLedger(c, ledgerIndex)
+}
+
+// The ledger index is either a number or one of the shortcuts understood by rippled, such as validated
+func getLedger(c context.Context, ledgerIndex interface{}) (Ledger, int64, error) {
+	var result Ledger
+	var payload = make(map[string]interface{})
+	var params = make(map[string]interface{})
+
+	if isInit == false {
+		Init()
+	}
+
+	params["ledger_index"] = ledgerIndex
+	payload["method"] = "ledger"
+	payload["params"] = []map[string]interface{}{params}
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+		return result, errCode, err
+	}
+
+	r, _ := responseData["result"].(map[string]interface{})
+	if r == nil {
+		log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	if r["error"] != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error from ledger: %s", r["error"])
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	ledgerIndexFloat, _ := r["ledger_index"].(float64)
+	result.LedgerIndex = int64(ledgerIndexFloat)
+	result.LedgerHash, _ = r["ledger_hash"].(string)
+	result.Validated, _ = r["validated"].(bool)
+
+	if result.LedgerHash == "" {
+		log.FluentfContext(consts.LOGERROR, c, "Ledger %v was returned without a hash", ledgerIndex)
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	return result, 0, nil
+}
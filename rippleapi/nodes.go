@@ -0,0 +1,129 @@
+package rippleapi
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/nodepool"
+)
+
+// Errors rippled answers with while it isn't in sync with the network
+var notReadyErrors = map[string]bool{"noNetwork": true, "noCurrent": true, "noClosed": true, "tooBusy": true}
+
+// The account of each transaction which has been signed but not yet submitted, so it can be submitted to the node which signed it.
+// Transactions which are signed but never submitted are forgotten once there are this many
+var ripple_MaxSignedAccounts = 10000
+var signedAccounts = make(map[string]string)
+var signedAccountsMutex sync.Mutex
+
+type payloadWrite struct {
+	Method string `json:"method"`
+	Params []struct {
+		TxBlob string `json:"tx_blob"`
+		TxJson struct {
+			Account string
+		} `json:"tx_json"`
+	} `json:"params"`
+}
+
+// Posts the JSON RPC call to the rippled node which has seen the most validated ledgers, moving on to the next node if one times out,
+// can't be reached or isn't in sync. Transactions are signed and submitted on the node the account is pinned to so that the sequence
+// rippled fills in when signing follows the account's previous transaction
+func postRPCAPI(c context.Context, postData []byte) (map[string]interface{}, int64, error) {
+	var result map[string]interface{}
+	var errorCode int64 = consts.RippleErrors.Unavailable.Code
+	var err error = errors.New(consts.RippleErrors.Unavailable.Description)
+
+	if isInit == false {
+		Init()
+	}
+
+	method, account := writeAccount(postData)
+	nodes := rippleNodes.ReadNodes()
+	if account != "" {
+		nodes = rippleNodes.WriteNodes(account)
+	}
+
+	answered := rippleNodes.Try(c, nodes, func(node nodepool.Node) (bool, error) {
+		result, errorCode, err = postRPCAPINode(c, node, postData)
+
+		return errorCode != consts.RippleErrors.Unavailable.Code && errorCode != consts.RippleErrors.Timeout.Code, err
+	})
+
+	if answered == -1 {
+		log.FluentfContext(consts.LOGERROR, c, "No rippled node was available")
+		return result, errorCode, err
+	}
+
+	if account != "" {
+		rippleNodes.Pin(account, answered)
+	}
+
+	// Remember who signed the transaction so that submitting it goes to the same node
+	if method == "sign" && account != "" {
+		r, _ := result["result"].(map[string]interface{})
+		if txBlob, ok := r["tx_blob"].(string); ok {
+			signedAccountsMutex.Lock()
+			if len(signedAccounts) >= ripple_MaxSignedAccounts {
+				signedAccounts = make(map[string]string)
+			}
+			signedAccounts[txBlob] = account
+			signedAccountsMutex.Unlock()
+		}
+	}
+
+	return result, errorCode, err
+}
+
+// Returns the method of the call and the account whose transaction it signs or submits, or "" if the call is a read or the transaction
+// wasn't signed by Enu
+func writeAccount(postData []byte) (string, string) {
+	var payload payloadWrite
+
+	if err := json.Unmarshal(postData, &payload); err != nil || len(payload.Params) == 0 {
+		return payload.Method, ""
+	}
+
+	switch payload.Method {
+	case "sign":
+		return payload.Method, payload.Params[0].TxJson.Account
+	case "submit":
+		signedAccountsMutex.Lock()
+		defer signedAccountsMutex.Unlock()
+
+		account := signedAccounts[payload.Params[0].TxBlob]
+		delete(signedAccounts, payload.Params[0].TxBlob)
+
+		return payload.Method, account
+	}
+
+	return payload.Method, ""
+}
+
+// Checks the health and last validated ledger of each rippled node so that reads go to the node which is most caught up. Runs until Enu is stopped
+func MonitorNodes(c context.Context) {
+	if isInit == false {
+		Init()
+	}
+
+	payloadJsonBytes, _ := json.Marshal(map[string]interface{}{"method": "ledger", "params": []map[string]interface{}{{"ledger_index": "validated"}}})
+
+	rippleNodes.Monitor(c, func(c context.Context, node nodepool.Node) (int64, error) {
+		responseData, _, err := postRPCAPINode(c, node, payloadJsonBytes)
+		if err != nil {
+			return -1, err
+		}
+
+		r, _ := responseData["result"].(map[string]interface{})
+		ledgerIndex, ok := r["ledger_index"].(float64)
+		if ok == false {
+			return -1, errors.New(consts.RippleErrors.Unavailable.Description)
+		}
+
+		return int64(ledgerIndex), nil
+	})
+}
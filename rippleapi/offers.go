@@ -0,0 +1,361 @@
+package rippleapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Ripple times are the number of seconds since 2000-01-01 00:00:00 UTC
+const RippleEpoch = 946684800
+
+// Structure for offer create transactions.
+// TakerGets and TakerPays are either a string of drops for XRP or an Amount for other currencies
+type OfferCreateTx struct {
+	// Common fields
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          uint32      `json:",omitempty"`
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
+
+	// Offer specific fields
+	Expiration    uint32 `json:",omitempty"`
+	OfferSequence uint32 `json:",omitempty"`
+	TakerGets     interface{}
+	TakerPays     interface{}
+}
+
+// Structure for offer cancel transactions
+type OfferCancelTx struct {
+	// Common fields
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          uint32      `json:",omitempty"`
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
+
+	OfferSequence uint32
+}
+
+// An offer in the ledger. The sequence is that of the OfferCreate which placed the offer and identifies the offer to cancel
+type Offer struct {
+	Account    string
+	Sequence   uint32
+	Flags      uint32
+	TakerGets  Amount
+	TakerPays  Amount
+	Quality    string
+	Expiration uint32
+}
+
+// Converts a time into a Ripple time
+func ToRippleTime(t time.Time) uint32 {
+	return uint32(t.Unix() - RippleEpoch)
+}
+
+// Converts a quantity in the Enu API, which is denominated in satoshis, into a Ripple amount.
+// XRP amounts are converted to drops, otherwise the asset is converted to a Ripple currency issued by the issuer
+func ToAmount(asset string, issuer string, quantity uint64) (Amount, error) {
+	if strings.ToUpper(asset) == "XRP" {
+		return Amount{Value: strconv.FormatUint(quantity/100, 10), Currency: "XRP"}, nil
+	}
+
+	currency, err := ToCurrency(asset)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	value, err := Uint64ToAmount(quantity)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return Amount{Value: value, Currency: currency, Issuer: issuer}, nil
+}
+
+// Converts a Ripple amount into the asset name and quantity in satoshis used by the Enu API
+func FromAmount(amount Amount) (string, uint64, error) {
+	if amount.Currency == "XRP" {
+		drops, err := strconv.ParseUint(amount.Value, 10, 64)
+		if err != nil {
+			return "", 0, err
+		}
+
+		return "XRP", drops * 100, nil
+	}
+
+	asset, err := FromCurrency(amount.Currency)
+	if err != nil {
+		return "", 0, err
+	}
+
+	quantity, err := AmountToUint64(amount.Value)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return asset, quantity, nil
+}
+
+// XRP is given as a string of drops in transactions, other currencies as an object
+func txAmount(amount Amount) interface{} {
+	if amount.Currency == "XRP" {
+		return amount.Value
+	}
+
+	return amount
+}
+
+// The inverse of txAmount() for amounts returned by rippled
+func parseAmount(value interface{}) Amount {
+	switch v := value.(type) {
+	case string:
+		return Amount{Value: v, Currency: "XRP"}
+	case map[string]interface{}:
+		var amount Amount
+
+		if v["value"] != nil {
+			amount.Value = v["value"].(string)
+		}
+		if v["currency"] != nil {
+			amount.Currency = v["currency"].(string)
+		}
+		if v["issuer"] != nil {
+			amount.Issuer = v["issuer"].(string)
+		}
+
+		return amount
+	}
+
+	return Amount{}
+}
+
+// Creates and signs an offer to give takerGets in exchange for takerPays.
+// An expiration of 0 means the offer does not expire, otherwise it is a Ripple time.
+// Returns the tx string and the sequence of the offer if successful
+func CreateOffer(c context.Context, account string, takerGets Amount, takerPays Amount, expiration uint32, secret string) (string, uint32, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	tx := OfferCreateTx{
+		TransactionType: "OfferCreate",
+		Account:         account,
+		Flags:           2147483648, // require canonical signature
+		Fee:             DefaultFee,
+
+		Expiration: expiration,
+		TakerGets:  txAmount(takerGets),
+		TakerPays:  txAmount(takerPays),
+	}
+
+	signedTx, sequence, errCode, err := sign(c, tx, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in sign(): %s", err.Error())
+		return "", 0, errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "signed! sequence: %d, tx_blob: %s", sequence, signedTx)
+
+	return signedTx, sequence, 0, nil
+}
+
+// Creates and signs a cancel of the offer placed by the account with the given sequence
+func CancelOffer(c context.Context, account string, offerSequence uint32, secret string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	tx := OfferCancelTx{
+		TransactionType: "OfferCancel",
+		Account:         account,
+		Flags:           2147483648, // require canonical signature
+		Fee:             DefaultFee,
+
+		OfferSequence: offerSequence,
+	}
+
+	signedTx, errCode, err := Sign(c, tx, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Sign(): %s", err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "signed! tx_blob: %s", signedTx)
+
+	return signedTx, 0, nil
+}
+
+// Gets the open offers placed by the account
+func GetAccountOffers(c context.Context, account string) ([]Offer, int64, error) {
+	var payload = make(map[string]interface{})
+	var params = make(map[string]interface{})
+	var paramsArray []map[string]interface{}
+	var result []Offer
+
+	if isInit == false {
+		Init()
+	}
+
+	// Build parameters
+	params["account"] = account
+	params["ledger_index"] = "validated"
+	paramsArray = append(paramsArray, params)
+
+	// Build payload
+	payload["method"] = "account_offers"
+	payload["params"] = paramsArray
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+		return result, errCode, err
+	}
+
+	if responseData["result"] == nil {
+		log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	r := responseData["result"].(map[string]interface{})
+
+	// Result returned but with an error
+	if r["error"] != nil && r["error_code"] != nil && r["error_code"].(float64) == 18 {
+		// account not found, we won't raise an error but return an empty structure
+		return result, 0, nil
+	} else if r["error"] != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error from account_offers: %s", r["error"])
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	offers, _ := r["offers"].([]interface{})
+	for _, o := range offers {
+		offer := o.(map[string]interface{})
+		outputOffer := Offer{
+			Account:   account,
+			TakerGets: parseAmount(offer["taker_gets"]),
+			TakerPays: parseAmount(offer["taker_pays"]),
+		}
+
+		if offer["seq"] != nil {
+			outputOffer.Sequence = uint32(offer["seq"].(float64))
+		}
+		if offer["flags"] != nil {
+			outputOffer.Flags = uint32(offer["flags"].(float64))
+		}
+		if offer["quality"] != nil {
+			outputOffer.Quality = offer["quality"].(string)
+		}
+		if offer["expiration"] != nil {
+			outputOffer.Expiration = uint32(offer["expiration"].(float64))
+		}
+
+		result = append(result, outputOffer)
+	}
+
+	return result, 0, nil
+}
+
+// Gets the offers in the order book where the taker receives the takerGets currency and pays with the takerPays currency.
+// Only the currency and issuer of the amounts are used. The best offers are returned first
+func GetBookOffers(c context.Context, takerGets Amount, takerPays Amount, limit uint32) ([]Offer, int64, error) {
+	var payload = make(map[string]interface{})
+	var params = make(map[string]interface{})
+	var paramsArray []map[string]interface{}
+	var result []Offer
+
+	if isInit == false {
+		Init()
+	}
+
+	// Build parameters
+	params["taker_gets"] = Amount{Currency: takerGets.Currency, Issuer: takerGets.Issuer}
+	params["taker_pays"] = Amount{Currency: takerPays.Currency, Issuer: takerPays.Issuer}
+	params["ledger_index"] = "validated"
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	paramsArray = append(paramsArray, params)
+
+	// Build payload
+	payload["method"] = "book_offers"
+	payload["params"] = paramsArray
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+		return result, errCode, err
+	}
+
+	if responseData["result"] == nil {
+		log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	r := responseData["result"].(map[string]interface{})
+
+	if r["error"] != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error from book_offers: %s", r["error"])
+		return result, consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	offers, _ := r["offers"].([]interface{})
+	for _, o := range offers {
+		offer := o.(map[string]interface{})
+		outputOffer := Offer{
+			TakerGets: parseAmount(offer["TakerGets"]),
+			TakerPays: parseAmount(offer["TakerPays"]),
+		}
+
+		if offer["Account"] != nil {
+			outputOffer.Account = offer["Account"].(string)
+		}
+		if offer["Sequence"] != nil {
+			outputOffer.Sequence = uint32(offer["Sequence"].(float64))
+		}
+		if offer["Flags"] != nil {
+			outputOffer.Flags = uint32(offer["Flags"].(float64))
+		}
+		if offer["quality"] != nil {
+			outputOffer.Quality = offer["quality"].(string)
+		}
+		if offer["Expiration"] != nil {
+			outputOffer.Expiration = uint32(offer["Expiration"].(float64))
+		}
+
+		result = append(result, outputOffer)
+	}
+
+	return result, 0, nil
+}
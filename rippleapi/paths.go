@@ -0,0 +1,255 @@
+package rippleapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// A step in a payment path, either an account to ripple through or an order book to cross
+type PathStep struct {
+	Account  string `json:"account,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Issuer   string `json:"issuer,omitempty"`
+}
+
+// A way of paying the destination amount found by ripple_path_find. The source amount is the estimated cost in the source currency
+type PathAlternative struct {
+	SourceAmount Amount
+	Paths        [][]PathStep
+}
+
+// The outcome of a transaction. The result and delivered amount are only final once the transaction is validated
+type TransactionStatus struct {
+	Hash            string
+	Validated       bool
+	Result          string
+	DeliveredAmount Amount
+}
+
+// Finds the paths to deliver the amount to the destination by spending the source currency.
+// Only the currency and issuer of the source currency are used. Returns the first alternative which spends the source currency
+func FindPaths(c context.Context, account string, destination string, amount Amount, sourceCurrency Amount) (PathAlternative, int64, error) {
+	var payload = make(map[string]interface{})
+	var params = make(map[string]interface{})
+	var paramsArray []map[string]interface{}
+	var result PathAlternative
+
+	if isInit == false {
+		Init()
+	}
+
+	// Build parameters
+	params["source_account"] = account
+	params["destination_account"] = destination
+	params["destination_amount"] = txAmount(amount)
+	params["source_currencies"] = []Amount{Amount{Currency: sourceCurrency.Currency, Issuer: sourceCurrency.Issuer}}
+	paramsArray = append(paramsArray, params)
+
+	// Build payload
+	payload["method"] = "ripple_path_find"
+	payload["params"] = paramsArray
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+		return result, errCode, err
+	}
+
+	if responseData["result"] == nil {
+		log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	r := responseData["result"].(map[string]interface{})
+
+	if r["error"] != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error from ripple_path_find: %s", r["error"])
+		return result, consts.RippleErrors.NoPathFound.Code, errors.New(consts.RippleErrors.NoPathFound.Description)
+	}
+
+	alternatives, _ := r["alternatives"].([]interface{})
+	for _, a := range alternatives {
+		alternative := a.(map[string]interface{})
+		sourceAmount := parseAmount(alternative["source_amount"])
+
+		if strings.ToUpper(sourceAmount.Currency) != strings.ToUpper(sourceCurrency.Currency) {
+			continue
+		}
+
+		result.SourceAmount = sourceAmount
+
+		paths, _ := alternative["paths_computed"].([]interface{})
+		for _, p := range paths {
+			var path []PathStep
+
+			steps, _ := p.([]interface{})
+			for _, s := range steps {
+				step := s.(map[string]interface{})
+				var pathStep PathStep
+
+				if step["account"] != nil {
+					pathStep.Account = step["account"].(string)
+				}
+				if step["currency"] != nil {
+					pathStep.Currency = step["currency"].(string)
+				}
+				if step["issuer"] != nil {
+					pathStep.Issuer = step["issuer"].(string)
+				}
+
+				path = append(path, pathStep)
+			}
+
+			result.Paths = append(result.Paths, path)
+		}
+
+		return result, 0, nil
+	}
+
+	log.FluentfContext(consts.LOGERROR, c, "No path found from %s to deliver %s %s to %s", sourceCurrency.Currency, amount.Value, amount.Currency, destination)
+
+	return result, consts.RippleErrors.NoPathFound.Code, errors.New(consts.RippleErrors.NoPathFound.Description)
+}
+
+// Creates and signs a payment which delivers the amount to the destination spending at most sendMax of another currency via the given paths.
+// Returns the tx string if successful
+func CreatePathPayment(c context.Context, account string, destination string, amount Amount, sendMax Amount, paths [][]PathStep, options PaymentOptions, secret string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	var signedTx string
+	var errCode int64
+	var err error
+
+	if amount.Currency == "XRP" {
+		tx := PaymentXrpTx{
+			TransactionType: "Payment",
+			Account:         account,
+			Destination:     destination,
+			Amount:          amount.Value,
+			SendMax:         txAmount(sendMax),
+			Paths:           paths,
+			Flags:           2147483648, // require canonical signature
+			Fee:             DefaultFee,
+			DestinationTag:  options.DestinationTag,
+			SourceTag:       options.SourceTag,
+			InvoiceID:       options.InvoiceID,
+			Memos:           options.memoEntries(),
+		}
+
+		signedTx, errCode, err = Sign(c, tx, secret)
+	} else {
+		tx := PaymentAssetTx{
+			TransactionType: "Payment",
+			Account:         account,
+			Destination:     destination,
+			Amount:          amount,
+			SendMax:         txAmount(sendMax),
+			Paths:           paths,
+			Flags:           2147483648, // require canonical signature
+			Fee:             DefaultFee,
+			DestinationTag:  options.DestinationTag,
+			SourceTag:       options.SourceTag,
+			InvoiceID:       options.InvoiceID,
+			Memos:           options.memoEntries(),
+		}
+
+		signedTx, errCode, err = Sign(c, tx, secret)
+	}
+
+	if err != nil {
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "signed! tx_blob: %s", signedTx)
+
+	return signedTx, errCode, err
+}
+
+// Gets the outcome of a transaction, including the amount actually delivered by a payment
+func GetTransaction(c context.Context, txHash string) (TransactionStatus, int64, error) {
+	var payload = make(map[string]interface{})
+	var params = make(map[string]interface{})
+	var paramsArray []map[string]interface{}
+	var result TransactionStatus
+
+	if isInit == false {
+		Init()
+	}
+
+	// Build parameters
+	params["transaction"] = txHash
+	paramsArray = append(paramsArray, params)
+
+	// Build payload
+	payload["method"] = "tx"
+	payload["params"] = paramsArray
+
+	payloadJsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+		return result, errCode, err
+	}
+
+	if responseData["result"] == nil {
+		log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	r := responseData["result"].(map[string]interface{})
+
+	if r["error"] != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error from tx: %s", r["error"])
+		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+	}
+
+	result = parseTransactionStatus(r, r["meta"])
+
+	return result, 0, nil
+}
+
+// Maps a transaction and its metadata as returned by the tx and account_tx methods
+func parseTransactionStatus(tx map[string]interface{}, meta interface{}) TransactionStatus {
+	var result TransactionStatus
+
+	if tx["hash"] != nil {
+		result.Hash = tx["hash"].(string)
+	}
+
+	if tx["validated"] != nil {
+		result.Validated = tx["validated"].(bool)
+	}
+
+	if m, ok := meta.(map[string]interface{}); ok {
+		if m["TransactionResult"] != nil {
+			result.Result = m["TransactionResult"].(string)
+		}
+
+		// delivered_amount is "unavailable" for payments validated before it was recorded
+		if d, ok := m["delivered_amount"].(string); ok && d == "unavailable" {
+			result.DeliveredAmount = parseAmount(tx["Amount"])
+		} else if m["delivered_amount"] != nil {
+			result.DeliveredAmount = parseAmount(m["delivered_amount"])
+		}
+	}
+
+	return result
+}
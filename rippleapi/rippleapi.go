@@ -17,6 +17,7 @@ import (
 	"github.com/whoisjeremylam/enu/consts"
 	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
 	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/nodepool"
 )
 
 var DefaultFee = "10000"
@@ -37,8 +38,16 @@ const AsfGlobalFreeze = 7
 const AsfDefaultRipple = 8
 
 // AccountRoot Flags
+const LsfRequireDestTag = 131072
+const LsfRequireAuth = 262144
+const LsfNoFreeze = 2097152
+const LsfGlobalFreeze = 4194304
 const LsfDefaultRipple = 8388608
 
+// Transfer rates are in billionths of a unit. No fee is charged at the minimum and the fee doubles the amount sent at the maximum
+const MinTransferRate = 1000000000
+const MaxTransferRate = 2000000000
+
 // Trust set flags (on the transaction)
 const TfSetfAuth = 65536
 const TfSetNoRipple = 131072
@@ -49,50 +58,50 @@ const TfClearFreeze = 2097152
 // Structure for payment transactions for custom currencies
 type PaymentAssetTx struct {
 	// Common fields
-	Account            string `json:",omitempty"`
-	AccountTxnID       string `json:",omitempty"`
-	Fee                string `json:",omitempty"`
-	Flags              uint32 `json:",omitempty"`
-	LastLedgerSequence uint32 `json:",omitempty"`
-	Memos              []Memo
-	Sequence           uint32 `json:",omitempty"`
-	SigningPubKey      string `json:",omitempty"`
-	SourceTag          uint32 `json:",omitempty"`
-	TransactionType    string `json:",omitempty"`
-	TxnSignature       string `json:",omitempty"`
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          *uint32     `json:",omitempty"` // A tag of 0 is valid
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
 
 	// Payment specific fields
 	Amount         Amount
 	Destination    string
-	DestinationTag uint32
-	InvoiceID      string
-	//	Paths
-	//	SendMax Currency
+	DestinationTag *uint32      `json:",omitempty"` // A tag of 0 is valid
+	InvoiceID      string       `json:",omitempty"`
+	Paths          [][]PathStep `json:",omitempty"`
+	SendMax        interface{}  `json:",omitempty"`
 	//	DeliverMin Currency
 }
 
 // Structure for payment transactions for xrp
 type PaymentXrpTx struct {
 	// Common fields
-	Account            string `json:",omitempty"`
-	AccountTxnID       string `json:",omitempty"`
-	Fee                string `json:",omitempty"`
-	Flags              uint32 `json:",omitempty"`
-	LastLedgerSequence uint32 `json:",omitempty"`
-	Memos              []Memo
-	Sequence           uint32 `json:",omitempty"`
-	SigningPubKey      string `json:",omitempty"`
-	SourceTag          uint32 `json:",omitempty"`
-	TransactionType    string `json:",omitempty"`
-	TxnSignature       string `json:",omitempty"`
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          *uint32     `json:",omitempty"` // A tag of 0 is valid
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
 
 	// Payment specific fields
-	Amount         string `json:",omitempty"`
-	Destination    string `json:",omitempty"`
-	DestinationTag uint32 `json:",omitempty"`
-	InvoiceID      string `json:",omitempty"`
-	//	Paths
-	//	SendMax Currency
+	Amount         string       `json:",omitempty"`
+	Destination    string       `json:",omitempty"`
+	DestinationTag *uint32      `json:",omitempty"` // A tag of 0 is valid
+	InvoiceID      string       `json:",omitempty"`
+	Paths          [][]PathStep `json:",omitempty"`
+	SendMax        interface{}  `json:",omitempty"`
 	//	DeliverMin Currency
 }
 
@@ -102,6 +111,29 @@ type Memo struct {
 	MemoType   string `json:",omitempty"`
 }
 
+// Memos are wrapped in an object in transactions
+type MemoEntry struct {
+	Memo Memo
+}
+
+// Optional fields of a payment. Memos are given in plain text and are hex encoded when the payment is created
+type PaymentOptions struct {
+	DestinationTag *uint32
+	SourceTag      *uint32
+	InvoiceID      string
+	Memos          []Memo
+}
+
+func (o PaymentOptions) memoEntries() []MemoEntry {
+	var result []MemoEntry
+
+	for _, m := range o.Memos {
+		result = append(result, MemoEntry{Memo{MemoData: strings.ToUpper(hex.EncodeToString([]byte(m.MemoData))), MemoFormat: strings.ToUpper(hex.EncodeToString([]byte(m.MemoFormat))), MemoType: strings.ToUpper(hex.EncodeToString([]byte(m.MemoType)))}})
+	}
+
+	return result
+}
+
 type Wallet struct {
 	AccountId     string `json:"account_id"`
 	KeyType       string `json:"key_type"`
@@ -202,24 +234,24 @@ type Currency struct {
 
 type AccountSet struct {
 	// Common fields
-	Account            string `json:",omitempty"`
-	AccountTxnID       string `json:",omitempty"`
-	Fee                string `json:",omitempty"`
-	Flags              uint32 `json:",omitempty"`
-	LastLedgerSequence uint32 `json:",omitempty"`
-	Memos              []Memo
-	Sequence           uint32 `json:",omitempty"`
-	SigningPubKey      string `json:",omitempty"`
-	SourceTag          uint32 `json:",omitempty"`
-	TransactionType    string `json:",omitempty"`
-	TxnSignature       string `json:",omitempty"`
-
-	ClearFlag    uint32 `json:",omitempty"`
-	Domain       string `json:",omitempty"`
-	EmailHash    string `json:",omitempty"`
-	MessageKey   string `json:",omitempty"`
-	SetFlag      uint32 `json:",omitempty"`
-	TransferRate uint32 `json:",omitempty"`
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          uint32      `json:",omitempty"`
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
+
+	ClearFlag    uint32  `json:",omitempty"`
+	Domain       string  `json:",omitempty"`
+	EmailHash    string  `json:",omitempty"`
+	MessageKey   string  `json:",omitempty"`
+	SetFlag      uint32  `json:",omitempty"`
+	TransferRate *uint32 `json:",omitempty"` // A transfer rate of 0 removes the transfer fee
 }
 
 type LimitAmount struct {
@@ -230,21 +262,21 @@ type LimitAmount struct {
 
 type TrustSetStruct struct {
 	// Common fields
-	Account            string `json:",omitempty"`
-	AccountTxnID       string `json:",omitempty"`
-	Fee                string `json:",omitempty"`
-	Flags              uint32 `json:",omitempty"`
-	LastLedgerSequence uint32 `json:",omitempty"`
-	Memos              []Memo `json:",omitempty"`
-	Sequence           uint32 `json:",omitempty"`
-	SigningPubKey      string `json:",omitempty"`
-	SourceTag          uint32 `json:",omitempty"`
-	TransactionType    string `json:",omitempty"`
-	TxnSignature       string `json:",omitempty"`
+	Account            string      `json:",omitempty"`
+	AccountTxnID       string      `json:",omitempty"`
+	Fee                string      `json:",omitempty"`
+	Flags              uint32      `json:",omitempty"`
+	LastLedgerSequence uint32      `json:",omitempty"`
+	Memos              []MemoEntry `json:",omitempty"`
+	Sequence           uint32      `json:",omitempty"`
+	SigningPubKey      string      `json:",omitempty"`
+	SourceTag          uint32      `json:",omitempty"`
+	TransactionType    string      `json:",omitempty"`
+	TxnSignature       string      `json:",omitempty"`
 
 	LimitAmount LimitAmount `json:",omitempty"`
-	QualityIn   uint32      `json:",omitempty"`
-	QualityOut  uint32      `json:",omitempty"`
+	QualityIn   *uint32     `json:",omitempty"` // A quality of 0 resets the quality to the default
+	QualityOut  *uint32     `json:",omitempty"`
 }
 
 type Line struct {
@@ -257,6 +289,12 @@ type Line struct {
 	NoRipplePeer bool   `json:"no_ripple_peer,omitempty"`
 	QualityIn    uint   `json:"quality_in,omitempty"`
 	QualityOut   uint   `json:"quality_out,omitempty"`
+
+	// Set by the issuer on the holder's side of the line. The peer values are those set by the other account
+	Authorized     bool `json:"authorized,omitempty"`
+	PeerAuthorized bool `json:"peer_authorized,omitempty"`
+	Freeze         bool `json:"freeze,omitempty"`
+	FreezePeer     bool `json:"freeze_peer,omitempty"`
 }
 
 type Lines []Line
@@ -281,6 +319,17 @@ func (s Lines) Contains(account string, currency string) bool {
 	return result
 }
 
+// Returns the trust line with the account for the currency if it exists
+func (s Lines) Find(account string, currency string) (Line, bool) {
+	for _, line := range s {
+		if line.Account == account && strings.ToUpper(line.Currency) == strings.ToUpper(currency) {
+			return line, true
+		}
+	}
+
+	return Line{}, false
+}
+
 type AccountInfo struct {
 	Account         string `json:",omitempty"`
 	Balance         string `json:",omitempty"`
@@ -294,7 +343,15 @@ type AccountInfo struct {
 
 // Initialises global variables and database connection for all handlers
 var isInit bool = false // set to true only after the init sequence is complete
-var rippleHost string
+var rippleNodes *nodepool.Pool
+var gatewayIssuer GatewayIssuer
+var feeAddress string
+
+// The account which issues the Ripple IOUs mirroring Counterparty assets deposited to the gateway. IOUs sent back to it are redeemed
+type GatewayIssuer struct {
+	Address    string
+	Passphrase string
+}
 
 func Init() {
 	var configFilePath string
@@ -352,12 +409,49 @@ func InitWithConfigPath(configFilePath string) {
 	m := configuration.(map[string]interface{})
 
 	// Ripple API parameters
-	rippleHost = m["rippleHost"].(string) // End point for JSON RPC server
+	rippleHost := m["rippleHost"].(string) // End point for JSON RPC server
+
+	// Optional. Further rippled nodes which requests are balanced across, the node above is tried first when they are equally caught up
+	nodes := []nodepool.Node{{Host: rippleHost}}
+	if m["ripplenodes"] != nil {
+		nodes = append(nodes, nodepool.ConfigNodes(m["ripplenodes"])...)
+	}
+	rippleNodes = nodepool.NewPool("rippled", nodes)
+
+	// Optional. The gateway between Counterparty and Ripple is only available when the issuer is configured
+	if m["gatewayissuer"] != nil && m["gatewayissuerpassphrase"] != nil {
+		gatewayIssuer.Address = m["gatewayissuer"].(string)
+		gatewayIssuer.Passphrase = m["gatewayissuerpassphrase"].(string)
+	}
+
+	// Optional. Fees are only collected on chain when the operator's fee address is configured
+	if m["feeaddress"] != nil {
+		feeAddress = m["feeaddress"].(string)
+	}
 
 	isInit = true
 }
 
-func postRPCAPI(c context.Context, postData []byte) (map[string]interface{}, int64, error) {
+// Returns the gateway issuer and whether one is configured
+func GetGatewayIssuer() (GatewayIssuer, bool) {
+	if isInit == false {
+		Init()
+	}
+
+	return gatewayIssuer, gatewayIssuer.Passphrase != ""
+}
+
+// Returns the operator account which fees are collected to and whether one is configured
+func GetFeeAddress() (string, bool) {
+	if isInit == false {
+		Init()
+	}
+
+	return feeAddress, feeAddress != ""
+}
+
+// Posts the JSON RPC call to the rippled node
+func postRPCAPINode(c context.Context, node nodepool.Node, postData []byte) (map[string]interface{}, int64, error) {
 
 	var result map[string]interface{}
 	var apiResp ApiResult
@@ -366,7 +460,7 @@ func postRPCAPI(c context.Context, postData []byte) (map[string]interface{}, int
 	log.FluentfContext(consts.LOGDEBUG, c, "rippleapi postRPCAPI() posting: %s", postDataJson)
 
 	// Set headers
-	req, err := http.NewRequest("POST", rippleHost, bytes.NewBufferString(postDataJson))
+	req, err := http.NewRequest("POST", node.Host, bytes.NewBufferString(postDataJson))
 	req.Header.Set("Content-Type", "application/json")
 
 	clientPointer := &http.Client{}
@@ -389,9 +483,10 @@ func postRPCAPI(c context.Context, postData []byte) (map[string]interface{}, int
 		return result, consts.RippleErrors.Timeout.Code, errors.New(consts.RippleErrors.Timeout.Description)
 	}
 
+	// The node couldn't be reached
 	if apiResp.err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in Do(req): %s", apiResp.err.Error())
-		return result, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+		return result, consts.RippleErrors.Unavailable.Code, errors.New(consts.RippleErrors.Unavailable.Description)
 	}
 
 	// Success, read body and return
@@ -413,6 +508,14 @@ func postRPCAPI(c context.Context, postData []byte) (map[string]interface{}, int
 		return result, 0, nil
 	}
 
+	// The node is up but can't answer until it is in sync with the network
+	r, _ := result["result"].(map[string]interface{})
+	rippledError, _ := r["error"].(string)
+	if notReadyErrors[rippledError] {
+		log.FluentfContext(consts.LOGERROR, c, "rippled node %s isn't ready: %s", node.Host, rippledError)
+		return result, consts.RippleErrors.Unavailable.Code, errors.New(consts.RippleErrors.Unavailable.Description)
+	}
+
 	return result, 0, nil
 }
 
@@ -502,6 +605,13 @@ func Submit(c context.Context, txHexString string) (string, int64, error) {
 
 // Signs a tx with the given secret. The tx should be a struct containing the tx to be marshalled into JSON and then signed
 func Sign(c context.Context, tx interface{}, secret string) (string, int64, error) {
+	result, _, errorCode, err := sign(c, tx, secret)
+
+	return result, errorCode, err
+}
+
+// Signs a tx and also returns the sequence number which rippled filled in, eg so an offer can later be cancelled
+func sign(c context.Context, tx interface{}, secret string) (string, uint32, int64, error) {
 	if isInit == false {
 		Init()
 	}
@@ -510,6 +620,7 @@ func Sign(c context.Context, tx interface{}, secret string) (string, int64, erro
 	var params = make(map[string]interface{})
 	var paramsArray []map[string]interface{}
 	var result string
+	var sequence uint32
 
 	// Build parameters
 	params["offline"] = false
@@ -524,12 +635,12 @@ func Sign(c context.Context, tx interface{}, secret string) (string, int64, erro
 	payloadJsonBytes, err := json.Marshal(payload)
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
-		return "", consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+		return "", 0, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
 	}
 
 	responseData, errorCode, err := postRPCAPI(c, payloadJsonBytes)
 	if err != nil {
-		return "", errorCode, err
+		return "", 0, errorCode, err
 	}
 
 	log.Printf("%#v", responseData)
@@ -539,6 +650,10 @@ func Sign(c context.Context, tx interface{}, secret string) (string, int64, erro
 
 		if r["status"] != nil && r["status"] == "success" {
 			result = r["tx_blob"].(string)
+
+			if txJson, ok := r["tx_json"].(map[string]interface{}); ok && txJson["Sequence"] != nil {
+				sequence = uint32(txJson["Sequence"].(float64))
+			}
 		} else {
 			var errorMessage string
 			var errorCode int64
@@ -554,20 +669,20 @@ func Sign(c context.Context, tx interface{}, secret string) (string, int64, erro
 
 			// Invalid source
 			if errorCode == 55 {
-				return "", consts.RippleErrors.InvalidSource.Code, errors.New(consts.RippleErrors.InvalidSource.Description)
+				return "", 0, consts.RippleErrors.InvalidSource.Code, errors.New(consts.RippleErrors.InvalidSource.Description)
 			}
 
 			// Invalid destination
 			if errorCode == 29 {
-				return "", consts.RippleErrors.InvalidDestination.Code, errors.New(consts.RippleErrors.InvalidDestination.Description)
+				return "", 0, consts.RippleErrors.InvalidDestination.Code, errors.New(consts.RippleErrors.InvalidDestination.Description)
 			}
 
 			// do some errorhandling here
-			return "", consts.RippleErrors.SigningError.Code, errors.New(consts.RippleErrors.SigningError.Description)
+			return "", 0, consts.RippleErrors.SigningError.Code, errors.New(consts.RippleErrors.SigningError.Description)
 		}
 	}
 
-	return result, 0, nil
+	return result, sequence, 0, nil
 }
 
 // Creates a Ripple account offline. ie doesn't use the REST or RPC
@@ -759,7 +874,7 @@ func GetCurrenciesByAccount(c context.Context, account string) (CurrenciesByAcco
 // Creates and signs the payment for the custom currency that is specified.
 // If XRP is specified, then the amount MUST be specifed in droplets
 // Returns the tx string if successful
-func CreatePayment(c context.Context, account string, destination string, quantity string, currency string, issuer string, secret string) (string, int64, error) {
+func CreatePayment(c context.Context, account string, destination string, quantity string, currency string, issuer string, options PaymentOptions, secret string) (string, int64, error) {
 	if isInit == false {
 		Init()
 	}
@@ -776,6 +891,10 @@ func CreatePayment(c context.Context, account string, destination string, quanti
 			Amount:          quantity,
 			Flags:           2147483648, // require canonical signature
 			Fee:             DefaultFee,
+			DestinationTag:  options.DestinationTag,
+			SourceTag:       options.SourceTag,
+			InvoiceID:       options.InvoiceID,
+			Memos:           options.memoEntries(),
 		}
 
 		signedTx, errCode, err = Sign(c, tx, secret)
@@ -789,8 +908,12 @@ func CreatePayment(c context.Context, account string, destination string, quanti
 				Currency: currency,
 				Issuer:   issuer,
 			},
-			Flags: 2147483648, // require canonical signature
-			Fee:   DefaultFee,
+			Flags:          2147483648, // require canonical signature
+			Fee:            DefaultFee,
+			DestinationTag: options.DestinationTag,
+			SourceTag:      options.SourceTag,
+			InvoiceID:      options.InvoiceID,
+			Memos:          options.memoEntries(),
 		}
 
 		signedTx, errCode, err = Sign(c, tx, secret)
@@ -842,10 +965,81 @@ func AccountSetFlag(c context.Context, account string, flag uint32, secret strin
 	return txHash, errCode, err
 }
 
+// Clears an account flag. The flags are the same as those set by AccountSetFlag()
+func AccountClearFlag(c context.Context, account string, flag uint32, secret string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	tx := AccountSet{
+		// Common fields
+		TransactionType: "AccountSet",
+		Account:         account,
+		Flags:           2147483648, // require canonical signature
+		Fee:             DefaultFee,
+
+		ClearFlag: flag,
+	}
+
+	signedTx, errCode, err := Sign(c, tx, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Sign(): %s", err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "signed! tx_blob: %s", signedTx)
+
+	txHash, errCode, err := Submit(c, signedTx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Submit(): %s", err.Error())
+	}
+
+	return txHash, errCode, err
+}
+
+// Sets the fee charged by an issuer when its issued currencies are transferred between other accounts.
+// The rate is between MinTransferRate and MaxTransferRate, or 0 to remove the fee
+func SetTransferRate(c context.Context, account string, transferRate uint32, secret string) (string, int64, error) {
+	if isInit == false {
+		Init()
+	}
+
+	tx := AccountSet{
+		// Common fields
+		TransactionType: "AccountSet",
+		Account:         account,
+		Flags:           2147483648, // require canonical signature
+		Fee:             DefaultFee,
+
+		TransferRate: &transferRate,
+	}
+
+	signedTx, errCode, err := Sign(c, tx, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Sign(): %s", err.Error())
+		return "", errCode, err
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "signed! tx_blob: %s", signedTx)
+
+	txHash, errCode, err := Submit(c, signedTx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Submit(): %s", err.Error())
+	}
+
+	return txHash, errCode, err
+}
+
 // Modifies a trust line between two accounts
 // The trust line is directional - the given account trusts the issuer account for value amount of currency
 // A trust line occupies space in the Ripple ledger and therefore requires a fee to be paid and consequently the secret of the source account
 func TrustSet(c context.Context, account string, currency string, value string, issuerAccount string, flag uint32, secret string) (string, int64, error) {
+	return SetTrustLine(c, account, currency, value, issuerAccount, nil, nil, flag, secret)
+}
+
+// Modifies a trust line as TrustSet() does and also sets the rate at which balances on the line are valued.
+// Qualities are in billionths, nil leaves the quality unchanged
+func SetTrustLine(c context.Context, account string, currency string, value string, issuerAccount string, qualityIn *uint32, qualityOut *uint32, flag uint32, secret string) (string, int64, error) {
 	if isInit == false {
 		Init()
 	}
@@ -859,7 +1053,7 @@ func TrustSet(c context.Context, account string, currency string, value string,
 		// Common fields
 		TransactionType: "TrustSet",
 		Account:         account,
-		Flags:           2147483648 & flag, // require canonical signature
+		Flags:           2147483648 | flag, // require canonical signature
 		Fee:             DefaultFee,
 
 		// Set the limit
@@ -868,6 +1062,8 @@ func TrustSet(c context.Context, account string, currency string, value string,
 			Currency: currency,
 			Issuer:   issuerAccount,
 		},
+		QualityIn:  qualityIn,
+		QualityOut: qualityOut,
 	}
 
 	signedTx, errCode, err = Sign(c, tx, secret)
@@ -951,6 +1147,22 @@ func GetAccountLines(c context.Context, account string) (Lines, int64, error) {
 				outputLine.NoRipplePeer = line.(map[string]interface{})["no_ripple_peer"].(bool)
 			}
 
+			if line.(map[string]interface{})["authorized"] != nil {
+				outputLine.Authorized = line.(map[string]interface{})["authorized"].(bool)
+			}
+
+			if line.(map[string]interface{})["peer_authorized"] != nil {
+				outputLine.PeerAuthorized = line.(map[string]interface{})["peer_authorized"].(bool)
+			}
+
+			if line.(map[string]interface{})["freeze"] != nil {
+				outputLine.Freeze = line.(map[string]interface{})["freeze"].(bool)
+			}
+
+			if line.(map[string]interface{})["freeze_peer"] != nil {
+				outputLine.FreezePeer = line.(map[string]interface{})["freeze_peer"].(bool)
+			}
+
 			result = append(result, outputLine)
 		}
 	}
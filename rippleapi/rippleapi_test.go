@@ -385,3 +385,130 @@ func TestFromCustomCurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestToAmount(t *testing.T) {
+	var testData = []struct {
+		Asset           string
+		Issuer          string
+		Quantity        uint64
+		Expected        Amount
+		CaseDescription string
+	}{
+		{"XRP", "", 100000000, Amount{Value: "1000000", Currency: "XRP"}, "1 XRP in drops"},
+		{"USD", accountExisting, 150000000, Amount{Value: "1.5", Currency: "USD", Issuer: accountExisting}, "ISO currency"},
+		{"SHIMA", accountExisting, 1, Amount{Value: "0.00000001", Currency: "805348494d410000000000000000000000000000", Issuer: accountExisting}, "Custom currency"},
+	}
+
+	for _, s := range testData {
+		result, err := ToAmount(s.Asset, s.Issuer, s.Quantity)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+
+		asset, quantity, err := FromAmount(result)
+		if err != nil || asset != s.Asset || quantity != s.Quantity {
+			t.Errorf("Expected: %s %d, Got: %s %d\nCase: %s\n", s.Asset, s.Quantity, asset, quantity, s.CaseDescription)
+		}
+	}
+}
+
+func TestParseTransactionStatus(t *testing.T) {
+	var testData = []struct {
+		Tx              map[string]interface{}
+		Meta            interface{}
+		Expected        TransactionStatus
+		CaseDescription string
+	}{
+		{map[string]interface{}{"hash": "ABC", "validated": true, "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": map[string]interface{}{"value": "1.5", "currency": "USD", "issuer": accountExisting}}, TransactionStatus{"ABC", true, "tesSUCCESS", Amount{"1.5", "USD", accountExisting}}, "Issued currency delivered"},
+		{map[string]interface{}{"hash": "ABC", "validated": true, "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": "unavailable"}, TransactionStatus{"ABC", true, "tesSUCCESS", Amount{"1000", "XRP", ""}}, "Delivered amount unavailable"},
+		{map[string]interface{}{"hash": "ABC"}, nil, TransactionStatus{"ABC", false, "", Amount{}}, "Not validated"},
+	}
+
+	for _, s := range testData {
+		result := parseTransactionStatus(s.Tx, s.Meta)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestMemoEntries(t *testing.T) {
+	var testData = []struct {
+		Memos           []Memo
+		Expected        []MemoEntry
+		CaseDescription string
+	}{
+		{[]Memo{{MemoData: "Invoice 42"}}, []MemoEntry{{Memo{MemoData: "496E766F696365203432"}}}, "Data only"},
+		{[]Memo{{MemoData: "hi", MemoFormat: "text/plain", MemoType: "note"}}, []MemoEntry{{Memo{MemoData: "6869", MemoFormat: "746578742F706C61696E", MemoType: "6E6F7465"}}}, "Data, format and type"},
+		{nil, nil, "No memos"},
+	}
+
+	for _, s := range testData {
+		result := PaymentOptions{Memos: s.Memos}.memoEntries()
+
+		if len(result) != len(s.Expected) {
+			t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected, result, s.CaseDescription)
+			continue
+		}
+
+		for i := range result {
+			if result[i] != s.Expected[i] {
+				t.Errorf("Expected: %+v, Got: %+v\nCase: %s\n", s.Expected[i], result[i], s.CaseDescription)
+			}
+		}
+	}
+}
+
+func TestParseIncomingPayment(t *testing.T) {
+	var testData = []struct {
+		Tx                   map[string]interface{}
+		Meta                 interface{}
+		ExpectedOk           bool
+		ExpectedDelivered    Amount
+		ExpectedKnown        bool
+		ExpectedMemoData     string
+		ExpectedDestTagIsNil bool
+		CaseDescription      string
+	}{
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "Account": account2, "hash": "ABC", "validated": true, "Amount": "1000", "DestinationTag": float64(42), "Memos": []interface{}{map[string]interface{}{"Memo": map[string]interface{}{"MemoData": "6869"}}}}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": "1000"}, true, Amount{"1000", "XRP", ""}, true, "hi", false, "XRP payment with a tag and memo"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Flags": float64(TfPartialPayment), "Amount": map[string]interface{}{"value": "100", "currency": "USD", "issuer": accountExisting}}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": map[string]interface{}{"value": "0.01", "currency": "USD", "issuer": accountExisting}}, true, Amount{"0.01", "USD", accountExisting}, true, "", true, "Partial payment delivers less than the amount"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Flags": float64(TfPartialPayment), "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": "unavailable"}, true, Amount{}, false, "", true, "Partial payment with the delivered amount unavailable"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Flags": float64(TfPartialPayment), "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS"}, true, Amount{}, false, "", true, "Partial payment without a delivered amount"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Flags": float64(TfPartialPayment), "Amount": map[string]interface{}{"value": "100", "currency": "USD", "issuer": accountExisting}}, map[string]interface{}{"TransactionResult": "tesSUCCESS", "delivered_amount": map[string]interface{}{"currency": "USD", "issuer": accountExisting}}, true, Amount{}, false, "", true, "Partial payment with a delivered amount which has no value"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS"}, true, Amount{"1000", "XRP", ""}, true, "", true, "Payment which isn't partial delivers the amount"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account2, "Account": account, "hash": "ABC", "validated": true, "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tesSUCCESS"}, false, Amount{}, false, "", true, "Payment sent by the account"},
+		{map[string]interface{}{"TransactionType": "Payment", "Destination": account, "hash": "ABC", "validated": true, "Amount": "1000"}, map[string]interface{}{"TransactionResult": "tecPATH_DRY"}, false, Amount{}, false, "", true, "Failed payment"},
+		{map[string]interface{}{"TransactionType": "TrustSet", "Destination": account, "hash": "ABC", "validated": true}, map[string]interface{}{"TransactionResult": "tesSUCCESS"}, false, Amount{}, false, "", true, "Not a payment"},
+	}
+
+	for _, s := range testData {
+		result, ok := parseIncomingPayment(account, s.Tx, s.Meta)
+
+		if ok != s.ExpectedOk {
+			t.Errorf("Expected: %t, Got: %t\nCase: %s\n", s.ExpectedOk, ok, s.CaseDescription)
+			continue
+		}
+
+		if ok == false {
+			continue
+		}
+
+		if result.DeliveredAmount != s.ExpectedDelivered || result.DeliveredAmountKnown != s.ExpectedKnown {
+			t.Errorf("Expected: %+v %t, Got: %+v %t\nCase: %s\n", s.ExpectedDelivered, s.ExpectedKnown, result.DeliveredAmount, result.DeliveredAmountKnown, s.CaseDescription)
+		}
+
+		if (result.DestinationTag == nil) != s.ExpectedDestTagIsNil {
+			t.Errorf("Expected destination tag is nil: %t, Got: %v\nCase: %s\n", s.ExpectedDestTagIsNil, result.DestinationTag, s.CaseDescription)
+		}
+
+		if s.ExpectedMemoData != "" && (len(result.Memos) != 1 || result.Memos[0].MemoData != s.ExpectedMemoData) {
+			t.Errorf("Expected: %s, Got: %+v\nCase: %s\n", s.ExpectedMemoData, result.Memos, s.CaseDescription)
+		}
+	}
+}
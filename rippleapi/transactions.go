@@ -0,0 +1,213 @@
+package rippleapi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+	"github.com/whoisjeremylam/enu/log"
+)
+
+// Allows a payment to deliver less than its Amount. The delivered amount must be used to find out what was actually received
+const TfPartialPayment = 131072
+
+// How many transactions are requested from account_tx at a time
+const accountTxLimit = 200
+
+// A payment received by an account. The Amount is what the sender asked to deliver, which may be more than the DeliveredAmount of a partial payment
+type IncomingPayment struct {
+	TransactionStatus
+	LedgerIndex    int64
+	Account        string
+	Destination    string
+	Amount         Amount
+	DestinationTag *uint32
+	SourceTag      *uint32
+	InvoiceID      string
+	Memos          []Memo
+	PartialPayment bool
+
+	// False for partial payments which were validated before rippled recorded the delivered amount
+	DeliveredAmountKnown bool
+}
+
+// Gets the successful payments received by the account in validated ledgers from ledgerIndexMin onwards, oldest first.
+// A ledgerIndexMin of -1 searches the whole history of the account. Returns the last ledger which was searched
+func GetIncomingPayments(c context.Context, account string, ledgerIndexMin int64) ([]IncomingPayment, int64, int64, error) {
+	var result []IncomingPayment
+	var ledgerIndexMax int64
+	var marker interface{}
+
+	if isInit == false {
+		Init()
+	}
+
+	for {
+		var payload = make(map[string]interface{})
+		var params = make(map[string]interface{})
+		var paramsArray []map[string]interface{}
+
+		// Build parameters
+		params["account"] = account
+		params["ledger_index_min"] = ledgerIndexMin
+		params["ledger_index_max"] = -1
+		params["forward"] = true
+		params["limit"] = accountTxLimit
+		if marker != nil {
+			params["marker"] = marker
+		}
+		paramsArray = append(paramsArray, params)
+
+		// Build payload
+		payload["method"] = "account_tx"
+		payload["params"] = paramsArray
+
+		payloadJsonBytes, err := json.Marshal(payload)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in Marshal(): %s", err.Error())
+			return result, ledgerIndexMax, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+		}
+
+		responseData, errCode, err := postRPCAPI(c, payloadJsonBytes)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in postRPCAPI(): %s", err.Error())
+			return result, ledgerIndexMax, errCode, err
+		}
+
+		if responseData["result"] == nil {
+			log.FluentfContext(consts.LOGERROR, c, "Didn't receive a result from RPC server")
+			return result, ledgerIndexMax, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+		}
+
+		r := responseData["result"].(map[string]interface{})
+
+		// Result returned but with an error
+		if r["error"] != nil && r["error_code"] != nil && r["error_code"].(float64) == 18 {
+			// account not found, we won't raise an error but return an empty structure
+			return result, ledgerIndexMax, 0, nil
+		} else if r["error"] != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error from account_tx: %s", r["error"])
+			return result, ledgerIndexMax, consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
+		}
+
+		if r["ledger_index_max"] != nil {
+			ledgerIndexMax = int64(r["ledger_index_max"].(float64))
+		}
+
+		transactions, _ := r["transactions"].([]interface{})
+		for _, t := range transactions {
+			transaction, _ := t.(map[string]interface{})
+			tx, _ := transaction["tx"].(map[string]interface{})
+			if tx == nil {
+				continue
+			}
+
+			// The validated flag is given alongside the tx rather than inside it
+			if transaction["validated"] != nil {
+				tx["validated"] = transaction["validated"]
+			}
+
+			if payment, ok := parseIncomingPayment(account, tx, transaction["meta"]); ok {
+				result = append(result, payment)
+			}
+		}
+
+		// Further pages are requested with the marker until there are no more
+		marker = r["marker"]
+		if marker == nil {
+			break
+		}
+	}
+
+	return result, ledgerIndexMax, 0, nil
+}
+
+// Maps a transaction returned by account_tx to a payment received by the account.
+// Returns false if the transaction isn't a successful, validated payment to the account
+func parseIncomingPayment(account string, tx map[string]interface{}, meta interface{}) (IncomingPayment, bool) {
+	var result IncomingPayment
+
+	if tx["TransactionType"] != "Payment" || tx["Destination"] != account {
+		return result, false
+	}
+
+	result.TransactionStatus = parseTransactionStatus(tx, meta)
+	if result.Validated == false || result.Result != "tesSUCCESS" {
+		return result, false
+	}
+
+	result.Destination = account
+	result.Amount = parseAmount(tx["Amount"])
+	result.DeliveredAmountKnown = true
+
+	if tx["Account"] != nil {
+		result.Account = tx["Account"].(string)
+	}
+	if tx["ledger_index"] != nil {
+		result.LedgerIndex = int64(tx["ledger_index"].(float64))
+	}
+	if tx["DestinationTag"] != nil {
+		tag := uint32(tx["DestinationTag"].(float64))
+		result.DestinationTag = &tag
+	}
+	if tx["SourceTag"] != nil {
+		tag := uint32(tx["SourceTag"].(float64))
+		result.SourceTag = &tag
+	}
+	if tx["InvoiceID"] != nil {
+		result.InvoiceID = tx["InvoiceID"].(string)
+	}
+	if tx["Flags"] != nil {
+		result.PartialPayment = uint32(tx["Flags"].(float64))&TfPartialPayment != 0
+	}
+
+	// Without a numeric delivered amount only a payment which isn't partial is known to have delivered its Amount
+	if m, _ := meta.(map[string]interface{}); isNumericAmount(m["delivered_amount"]) == false {
+		if result.PartialPayment {
+			result.DeliveredAmount = Amount{}
+			result.DeliveredAmountKnown = false
+		} else {
+			result.DeliveredAmount = result.Amount
+		}
+	}
+
+	memos, _ := tx["Memos"].([]interface{})
+	for _, e := range memos {
+		entry, _ := e.(map[string]interface{})
+		memo, _ := entry["Memo"].(map[string]interface{})
+
+		result.Memos = append(result.Memos, Memo{MemoData: fromHex(memo["MemoData"]), MemoFormat: fromHex(memo["MemoFormat"]), MemoType: fromHex(memo["MemoType"])})
+	}
+
+	return result, true
+}
+
+// Returns true if the amount is XRP given as a number of drops or an issued currency amount with a numeric value
+func isNumericAmount(amount interface{}) bool {
+	switch a := amount.(type) {
+	case string:
+		_, err := strconv.ParseUint(a, 10, 64)
+		return err == nil
+	case map[string]interface{}:
+		value, _ := a["value"].(string)
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	}
+
+	return false
+}
+
+// Memo fields are hex encoded. Values which aren't valid hex are returned as is
+func fromHex(value interface{}) string {
+	s, _ := value.(string)
+
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+
+	return string(decoded)
+}
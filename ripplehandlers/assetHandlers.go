@@ -128,7 +128,7 @@ func delegatedAssetCreate(c context.Context, issuingAddress string, issuingPassp
 	}
 
 	// Write the asset with the generated asset id to the database
-	go database.InsertAsset(accessKey, blockchainId, assetId, issuingAddress, distributionAddress, rippleAsset, assetDescription, quantity, true, "valid")
+	go database.InsertAsset(c, accessKey, blockchainId, assetId, issuingAddress, distributionAddress, rippleAsset, assetDescription, quantity, true, "valid")
 
 	// Set issuer up as a gateway https://ripple.com/build/gateway-guide/
 	// set DefaultRipple on the issuer https://ripple.com/build/gateway-guide/#defaultripple
@@ -197,7 +197,7 @@ func delegatedAssetCreate(c context.Context, issuingAddress string, issuingPassp
 	}
 
 	// Pay from the issuer wallet to the distribution wallet the amount of custom currency specified
-	payTxId, _, err := delegatedSend(c, accessKey, issuingPassphrase, issuingAddress, distributionAddress, asset, issuingAddress, quantity, assetId, "Asset creation")
+	payTxId, _, err := delegatedSend(c, accessKey, issuingPassphrase, issuingAddress, distributionAddress, asset, issuingAddress, quantity, rippleapi.PaymentOptions{}, assetId, "Asset creation")
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in delegatedSend: %s", err.Error())
 
@@ -206,5 +206,9 @@ func delegatedAssetCreate(c context.Context, issuingAddress string, issuingPassp
 	}
 
 	database.UpdateAssetCompleteByAssetId(c, accessKey, assetId, payTxId)
+
+	// The issuer would pay a fee in its own asset by minting it, so the fee is billed
+	chargeFee(c, "", issuingAddress, enulib.FeeIssuance, assetId, asset, issuingAddress, quantity)
+
 	return 0, nil
 }
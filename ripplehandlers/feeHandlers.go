@@ -0,0 +1,77 @@
+package ripplehandlers
+
+import (
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+)
+
+// Sends a payment for the access key and charges the payment fee once the payment has been submitted
+func delegatedSendWithFee(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, issuer string, quantity uint64, options rippleapi.PaymentOptions, paymentId string, paymentTag string) {
+	if _, _, err := delegatedSend(c, accessKey, passphrase, sourceAddress, destinationAddress, asset, issuer, quantity, options, paymentId, paymentTag); err != nil {
+		return
+	}
+
+	chargeFee(c, passphrase, sourceAddress, enulib.FeePayment, paymentId, asset, issuer, quantity)
+}
+
+// Sends a cross currency payment for the access key and charges the payment fee on the amount delivered once the payment has been submitted.
+// The source account may not hold the delivered asset so the fee is only billed
+func delegatedPathSendWithFee(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, issuer string, quantity uint64, sourceAsset string, sourceIssuer string, sendMax uint64, options rippleapi.PaymentOptions, paymentId string, paymentTag string) {
+	if _, _, err := delegatedPathSend(c, accessKey, passphrase, sourceAddress, destinationAddress, asset, issuer, quantity, sourceAsset, sourceIssuer, sendMax, options, paymentId, paymentTag); err != nil {
+		return
+	}
+
+	chargeFee(c, "", sourceAddress, enulib.FeePayment, paymentId, asset, issuer, quantity)
+}
+
+// Records the fee of an operation which has been submitted. If the schedule asks for it and the operator's fee account is configured
+// the fee is sent from the source account of the operation, otherwise it is billed in the monthly statement.
+// Operations charged without a passphrase can only be billed: activations, which are paid for from internal wallets, and issuances,
+// whose issuer would pay a fee in its own asset by minting it
+func chargeFee(c context.Context, passphrase string, sourceAddress string, operation string, operationId string, asset string, issuer string, quantity uint64) {
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+
+	fee, schedule, recorded, err := database.RecordFee(c, accessKey, consts.RippleBlockchainId, operation, operationId, asset, issuer, quantity)
+	if err != nil || recorded == false {
+		return
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Charged %d %s for %s %s", fee.FeeAmount, fee.FeeAsset, operation, operationId)
+
+	// XRP fees are rounded down to whole drops, so a fee of less than one drop is zero and there's nothing to collect
+	feeAddress, configured := rippleapi.GetFeeAddress()
+	if schedule.Collect == false || configured == false || passphrase == "" || fee.FeeAmount == 0 {
+		return
+	}
+
+	paymentId := enulib.GeneratePaymentId()
+	database.UpdateFeeStatus(c, operation, operationId, database.FeeCollectingStatus, paymentId)
+
+	txHash, _, err := delegatedSend(c, accessKey, passphrase, sourceAddress, feeAddress, fee.FeeAsset, fee.FeeIssuer, fee.FeeAmount, rippleapi.PaymentOptions{}, paymentId, "fee")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to collect the fee for %s %s: %s", operation, operationId, err.Error())
+		database.UpdateFeeStatus(c, operation, operationId, database.FeeErrorStatus, paymentId)
+
+		return
+	}
+
+	// A submitted payment can still fail, so the fee stays collecting until the payment succeeds in a validated ledger
+	status, validated := waitForValidation(c, txHash)
+	if validated == false {
+		return
+	}
+
+	if status.Result != "tesSUCCESS" {
+		log.FluentfContext(consts.LOGERROR, c, "Fee payment %s for %s %s failed validation with: %s", txHash, operation, operationId, status.Result)
+		database.UpdateFeeStatus(c, operation, operationId, database.FeeErrorStatus, paymentId)
+
+		return
+	}
+
+	database.UpdateFeeStatus(c, operation, operationId, database.FeeCollectedStatus, paymentId)
+}
@@ -0,0 +1,75 @@
+package ripplehandlers
+
+import (
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+)
+
+// How often the Counterparty assets deposited to the gateway are issued as Ripple IOUs
+var ripple_GatewayPollRate = 60000 // milliseconds
+
+// How many ingested Counterparty blocks must confirm a deposit to the gateway before its IOU is issued
+var ripple_GatewayConfirmations int64 = 6
+
+// Records the Counterparty assets deposited to the Counterparty payment addresses of the gateway and issues the mirrored IOU from the gateway issuer
+// to the external account of the map. The external account must trust the issuer for the IOU. Runs until Enu is stopped
+func IssueGatewayTransfers(c context.Context) {
+	issuer, ok := rippleapi.GetGatewayIssuer()
+	if ok == false {
+		log.FluentfContext(consts.LOGINFO, c, "No gateway issuer is configured, Counterparty assets won't be issued as Ripple IOUs")
+		return
+	}
+
+	c = handlers.BackgroundContext(c, consts.RippleBlockchainId)
+
+	for {
+		time.Sleep(time.Duration(ripple_GatewayPollRate) * time.Millisecond)
+
+		received, err := database.InsertCounterpartyGatewayDeposits(c, ripple_GatewayConfirmations)
+		if err != nil {
+			continue
+		}
+		if received > 0 {
+			log.FluentfContext(consts.LOGINFO, c, "Received %d new gateway deposits on Counterparty", received)
+		}
+
+		transfers, err := database.GetPendingGatewayTransfers(c, consts.RippleBlockchainId)
+		if err != nil {
+			continue
+		}
+
+		for accessKey, accessKeyTransfers := range transfers {
+			for _, transfer := range accessKeyTransfers {
+				issueGatewayTransfer(context.WithValue(c, consts.AccessKeyKey, accessKey), issuer, transfer)
+			}
+		}
+	}
+}
+
+func issueGatewayTransfer(c context.Context, issuer rippleapi.GatewayIssuer, transfer enulib.GatewayTransfer) {
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+
+	paymentId := enulib.GeneratePaymentId()
+	if err := database.UpdateGatewayTransferPaying(c, transfer.GatewayTransferId, paymentId); err != nil {
+		return
+	}
+	log.FluentfContext(consts.LOGINFO, c, "Issuing gateway transfer %d of %d %s from %s to %s, paymentId: %s", transfer.GatewayTransferId, transfer.PayoutQuantity, transfer.PayoutAsset, issuer.Address, transfer.PayoutAddress, paymentId)
+
+	_, errCode, err := delegatedSend(c, accessKey, issuer.Passphrase, issuer.Address, transfer.PayoutAddress, transfer.PayoutAsset, issuer.Address, transfer.PayoutQuantity, rippleapi.PaymentOptions{}, paymentId, "gateway")
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Gateway transfer %d failed: %s", transfer.GatewayTransferId, err.Error())
+		database.UpdateGatewayTransferStatus(c, transfer.GatewayTransferId, database.GatewayErrorStatus, errCode, err.Error())
+
+		return
+	}
+
+	database.UpdateGatewayTransferStatus(c, transfer.GatewayTransferId, database.GatewayCompleteStatus, 0, "")
+}
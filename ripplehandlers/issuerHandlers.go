@@ -0,0 +1,351 @@
+package ripplehandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+	"github.com/whoisjeremylam/enu/ripplecrypto"
+)
+
+// Issuer settings, recorded in the operation column of the assets table
+const (
+	issuerOperationRequireAuth      = "requireauth"
+	issuerOperationClearRequireAuth = "clearrequireauth"
+	issuerOperationGlobalFreeze     = "globalfreeze"
+	issuerOperationGlobalUnfreeze   = "globalunfreeze"
+	issuerOperationNoFreeze         = "nofreeze"
+	issuerOperationTransferRate     = "transferrate"
+)
+
+// Operations by an issuer on a trust line held for its asset, recorded in the operation column of the trustassets table
+const (
+	trustOperationAuthorize = "authorize"
+	trustOperationFreeze    = "freeze"
+	trustOperationUnfreeze  = "unfreeze"
+)
+
+// Requires the issuer to authorize each trust line before the holder can receive its assets.
+// Ripple only allows this to be turned on while the issuer has no trust lines
+func AssetRequireAuth(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	operation := issuerOperationClearRequireAuth
+	if m["requireAuth"].(bool) == true {
+		operation = issuerOperationRequireAuth
+	}
+
+	return issuerSetting(c, w, m, operation, 0)
+}
+
+// Freezes or unfreezes all of the assets issued by the issuer
+func AssetGlobalFreeze(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	operation := issuerOperationGlobalUnfreeze
+	if m["freeze"].(bool) == true {
+		operation = issuerOperationGlobalFreeze
+	}
+
+	return issuerSetting(c, w, m, operation, 0)
+}
+
+// Permanently gives up the ability to freeze trust lines and to end a global freeze. This cannot be undone
+func AssetNoFreeze(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	return issuerSetting(c, w, m, issuerOperationNoFreeze, 0)
+}
+
+// Sets the fee charged when the assets of the issuer are transferred between other addresses
+func AssetTransferRate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	transferRate := uint64(m["transferRate"].(float64))
+
+	if transferRate != 0 && (transferRate < rippleapi.MinTransferRate || transferRate > rippleapi.MaxTransferRate) {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid transfer rate: %d", transferRate)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidTransferRate.Code, consts.RippleErrors.InvalidTransferRate.Description)
+
+		return nil
+	}
+
+	return issuerSetting(c, w, m, issuerOperationTransferRate, transferRate)
+}
+
+// Authorizes the trust line held by the holder address for an asset of the issuer
+func AssetAuthorize(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	return issuerTrustLine(c, w, m, trustOperationAuthorize)
+}
+
+// Freezes or unfreezes the trust line held by the holder address for an asset of the issuer
+func AssetFreeze(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	operation := trustOperationUnfreeze
+	if m["freeze"].(bool) == true {
+		operation = trustOperationFreeze
+	}
+
+	return issuerTrustLine(c, w, m, operation)
+}
+
+// Checks the issuer may make the change, returns the assetId to the client and changes the setting in async mode
+func issuerSetting(c context.Context, w http.ResponseWriter, m map[string]interface{}, operation string, transferRate uint64) *enulib.AppError {
+	var assetStruct enulib.Asset
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	assetStruct.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "IssuerSetting: received %s request sourceAddress: %s, transferRate: %d from accessKey: %s\n", operation, sourceAddress, transferRate, accessKey)
+
+	if operation == issuerOperationGlobalUnfreeze && checkFreezeAllowed(c, w, sourceAddress) == false {
+		return nil
+	}
+
+	// Generate an assetId
+	assetId := enulib.GenerateAssetId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated assetId: %s", assetId)
+	assetStruct.AssetId = assetId
+	assetStruct.Operation = operation
+	assetStruct.SourceAddress = sourceAddress
+	assetStruct.Issuer = sourceAddress
+	assetStruct.Quantity = transferRate
+	assetStruct.Status = "valid"
+	assetStruct.BlockchainId = consts.RippleBlockchainId
+
+	// Return to the client the assetId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(assetStruct); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedIssuerSetting(c, accessKey, passphrase, assetId, sourceAddress, operation, transferRate)
+
+	return nil
+}
+
+// Checks the issuer may make the change, returns the trustId to the client and changes the trust line in async mode
+func issuerTrustLine(c context.Context, w http.ResponseWriter, m map[string]interface{}, operation string) *enulib.AppError {
+	var trustLine enulib.TrustLine
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	trustLine.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	asset := m["asset"].(string)
+	holderAddress := m["holderAddress"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "IssuerTrustLine: received %s request sourceAddress: %s, asset: %s, holderAddress: %s from accessKey: %s\n", operation, sourceAddress, asset, holderAddress, accessKey)
+
+	if operation == trustOperationFreeze && checkFreezeAllowed(c, w, sourceAddress) == false {
+		return nil
+	}
+
+	// Authorizing a trust line is only meaningful, and only accepted by Ripple, if the issuer requires authorization
+	if operation == trustOperationAuthorize {
+		accountInfo, errorCode, err := rippleapi.GetAccountInfo(c, sourceAddress)
+		if err != nil {
+			handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+			return nil
+		}
+
+		if accountInfo.Flags&rippleapi.LsfRequireAuth == 0 {
+			log.FluentfContext(consts.LOGERROR, c, "%s does not require authorization", sourceAddress)
+			handlers.ReturnBadRequest(c, w, consts.RippleErrors.RequireAuthNotSet.Code, consts.RippleErrors.RequireAuthNotSet.Description)
+
+			return nil
+		}
+	}
+
+	// Generate a trustId
+	trustId := enulib.GenerateTrustId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated trustId: %s", trustId)
+	trustLine.TrustId = trustId
+	trustLine.Operation = operation
+	trustLine.Address = holderAddress
+	trustLine.Asset = asset
+	trustLine.Issuer = sourceAddress
+	trustLine.Status = "valid"
+	trustLine.BlockchainId = consts.RippleBlockchainId
+
+	// Return to the client the trustId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(trustLine); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedIssuerTrustLine(c, accessKey, passphrase, trustId, sourceAddress, operation, asset, holderAddress)
+
+	return nil
+}
+
+// Once NoFreeze is set the issuer can't freeze individual trust lines or end a global freeze
+func checkFreezeAllowed(c context.Context, w http.ResponseWriter, issuer string) bool {
+	accountInfo, errorCode, err := rippleapi.GetAccountInfo(c, issuer)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return false
+	}
+
+	if accountInfo.Flags&rippleapi.LsfNoFreeze != 0 {
+		log.FluentfContext(consts.LOGERROR, c, "%s has set NoFreeze", issuer)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.FreezeDisabled.Code, consts.RippleErrors.FreezeDisabled.Description)
+
+		return false
+	}
+
+	return true
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedIssuerSetting(c context.Context, accessKey string, passphrase string, assetId string, sourceAddress string, operation string, transferRate uint64) (string, int64, error) {
+	// Write the operation with the generated asset id to the database
+	err := database.InsertAssetOperation(c, accessKey, consts.RippleBlockchainId, assetId, operation, sourceAddress, "", "", "", transferRate, false, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	secret := ripplecrypto.PassphraseToSecret(c, passphrase)
+	if secret == "" {
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	var txHash string
+	var errCode int64
+
+	switch operation {
+	case issuerOperationRequireAuth:
+		txHash, errCode, err = rippleapi.AccountSetFlag(c, sourceAddress, rippleapi.AsfRequireAuth, secret)
+	case issuerOperationClearRequireAuth:
+		txHash, errCode, err = rippleapi.AccountClearFlag(c, sourceAddress, rippleapi.AsfRequireAuth, secret)
+	case issuerOperationGlobalFreeze:
+		txHash, errCode, err = rippleapi.AccountSetFlag(c, sourceAddress, rippleapi.AsfGlobalFreeze, secret)
+	case issuerOperationGlobalUnfreeze:
+		txHash, errCode, err = rippleapi.AccountClearFlag(c, sourceAddress, rippleapi.AsfGlobalFreeze, secret)
+	case issuerOperationNoFreeze:
+		txHash, errCode, err = rippleapi.AccountSetFlag(c, sourceAddress, rippleapi.AsfNoFreeze, secret)
+	case issuerOperationTransferRate:
+		txHash, errCode, err = rippleapi.SetTransferRate(c, sourceAddress, uint32(transferRate), secret)
+	default:
+		errCode, err = consts.GenericErrors.GeneralError.Code, errors.New(consts.GenericErrors.GeneralError.Description)
+	}
+
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in %s: %s", operation, err.Error())
+		database.UpdateAssetWithErrorByAssetId(c, accessKey, assetId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateAssetCompleteByAssetId(c, accessKey, assetId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+// The issuer sets the flag on its side of the trust line held by the holder, with a limit of 0 as issuers don't trust their holders
+func delegatedIssuerTrustLine(c context.Context, accessKey string, passphrase string, trustId string, sourceAddress string, operation string, asset string, holderAddress string) (string, int64, error) {
+	// Write the operation with the generated trust id to the database
+	err := database.InsertTrustLine(c, accessKey, trustId, consts.RippleBlockchainId, operation, holderAddress, asset, sourceAddress, 0, 0, 0, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	var flag uint32
+	switch operation {
+	case trustOperationAuthorize:
+		flag = rippleapi.TfSetfAuth
+	case trustOperationFreeze:
+		flag = rippleapi.TfSetFreeze
+	case trustOperationUnfreeze:
+		flag = rippleapi.TfClearFreeze
+	}
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	currency, err := rippleapi.ToCurrency(asset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToCurrency(): %s", err.Error())
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	secret := ripplecrypto.PassphraseToSecret(c, passphrase)
+	if secret == "" {
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	txHash, errCode, err := rippleapi.TrustSet(c, sourceAddress, currency, "0", holderAddress, flag, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.TrustSet(): %s", err.Error())
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateTrustLineCompleteByTrustId(c, accessKey, trustId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
@@ -0,0 +1,95 @@
+package ripplehandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Debits an internal account and sends the assets on chain from the given address. The account is debited before the payment is
+// sent so the balance can't be withdrawn twice. If the payment fails the withdrawal is reversed
+func LedgerWithdrawal(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var issuer string
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	blockchainId := c.Value(consts.BlockchainIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "ledgerWithdrawal")
+
+	accountId := m["accountId"].(string)
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	destinationAddress := m["destinationAddress"].(string)
+	asset := m["asset"].(string)
+	quantity := uint64(m["quantity"].(float64))
+
+	if m["issuer"] != nil {
+		issuer = m["issuer"].(string)
+	}
+
+	if accountId == enulib.LedgerExternalAccountId {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidLedgerAccount.Code, consts.GenericErrors.InvalidLedgerAccount.Description)
+
+		return nil
+	}
+
+	// If a custom asset is specified, then an issuer must be provided
+	if strings.ToUpper(asset) != "XRP" && issuer == "" {
+		log.FluentfContext(consts.LOGERROR, c, "%s", consts.RippleErrors.IssuerMustBeGiven.Description)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.IssuerMustBeGiven.Code, consts.RippleErrors.IssuerMustBeGiven.Description)
+
+		return nil
+	}
+
+	paymentId := enulib.GeneratePaymentId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated paymentId: %s", paymentId)
+
+	// The account is debited with the same asset and issuer which are sent
+	ledgerAsset := enulib.NewLedgerAsset(blockchainId, asset, issuer)
+	journal, err := enulib.NewWithdrawalJournal(accountId, ledgerAsset, quantity, paymentId)
+	if err == nil {
+		err = database.PostJournal(c, accessKey, blockchainId, requestId, journal)
+	}
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Unable to post withdrawal journal: %s", err.Error())
+		handlers.ReturnJournalError(c, w, err)
+
+		return nil
+	}
+
+	journal.RequestId = requestId
+	journal.BlockchainId = blockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(journal); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go func() {
+		if _, _, err := delegatedSend(c, accessKey, passphrase, sourceAddress, destinationAddress, ledgerAsset.Asset, ledgerAsset.Issuer, quantity, rippleapi.PaymentOptions{}, paymentId, "withdrawal"); err != nil {
+			reversal := enulib.NewReversalJournal(journal)
+
+			log.FluentfContext(consts.LOGERROR, c, "Withdrawal %s failed, reversing with journal %s. Error: %s", paymentId, reversal.JournalId, err.Error())
+			if err := database.PostJournal(c, accessKey, blockchainId, requestId, reversal); err != nil {
+				log.FluentfContext(consts.LOGERROR, c, "Unable to reverse withdrawal journal %s: %s", journal.JournalId, err.Error())
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package ripplehandlers
+
+import (
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+)
+
+// How often rippled is checked for newly validated ledgers
+var ripple_LedgerPollRate = 10000 // milliseconds
+
+// Records the hash of each ledger validated by rippled so that how far rippled is behind and changes to its ledger history can be reported.
+// Runs until Enu is stopped
+func ProcessLedgers(c context.Context) {
+	c = context.WithValue(c, consts.BlockchainIdKey, consts.RippleBlockchainId)
+
+	processor := handlers.BlockProcessor{
+		BlockchainId: consts.RippleBlockchainId,
+		GetBlockHash: func(c context.Context, ledgerIndex int64) (string, error) {
+			ledger, _, err := rippleapi.GetLedger(c, ledgerIndex)
+			return ledger.LedgerHash, err
+		},
+	}
+
+	for {
+		time.Sleep(time.Duration(ripple_LedgerPollRate) * time.Millisecond)
+
+		// Only validated ledgers are final
+		ledger, _, err := rippleapi.GetValidatedLedger(c)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.GetValidatedLedger(): %s", err.Error())
+			continue
+		}
+
+		handlers.ProcessBlocks(c, processor, ledger.LedgerIndex)
+	}
+}
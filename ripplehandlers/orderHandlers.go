@@ -0,0 +1,490 @@
+package ripplehandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/internal/github.com/vennd/mneumonic"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+	"github.com/whoisjeremylam/enu/ripplecrypto"
+	"github.com/whoisjeremylam/enu/validation"
+)
+
+// Places an offer on the Ripple DEX to give one currency in exchange for another.
+// Currencies other than XRP must be given with their issuer
+func OrderCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var order enulib.Order
+	var giveIssuer string
+	var getIssuer string
+	var expiration uint64
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	order.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	giveAsset := m["giveAsset"].(string)
+	giveQuantity := uint64(m["giveQuantity"].(float64))
+	getAsset := m["getAsset"].(string)
+	getQuantity := uint64(m["getQuantity"].(float64))
+
+	if m["giveIssuer"] != nil {
+		giveIssuer = m["giveIssuer"].(string)
+	}
+
+	if m["getIssuer"] != nil {
+		getIssuer = m["getIssuer"].(string)
+	}
+
+	// The number of seconds the offer remains open for, if not given the offer doesn't expire
+	if m["expiration"] != nil {
+		expiration = uint64(m["expiration"].(float64))
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderCreate: received request sourceAddress: %s, give: %d %s.%s, get: %d %s.%s, expiration: %d from accessKey: %s\n", sourceAddress, giveQuantity, giveIssuer, giveAsset, getQuantity, getIssuer, getAsset, expiration, accessKey)
+
+	if strings.ToUpper(giveAsset) == strings.ToUpper(getAsset) && giveIssuer == getIssuer {
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	if checkIssuer(c, w, giveAsset, giveIssuer) == false || checkIssuer(c, w, getAsset, getIssuer) == false {
+		return nil
+	}
+
+	// Generate an orderId
+	orderId := enulib.GenerateOrderId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated orderId: %s", orderId)
+	order.OrderId = orderId
+	order.Operation = "order"
+	order.SourceAddress = sourceAddress
+	order.GiveAsset = giveAsset
+	order.GiveIssuer = giveIssuer
+	order.GiveQuantity = giveQuantity
+	order.GetAsset = getAsset
+	order.GetIssuer = getIssuer
+	order.GetQuantity = getQuantity
+	order.Expiration = expiration
+	order.Status = "valid"
+	order.BlockchainId = consts.RippleBlockchainId
+
+	// Return to the client the orderId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedCreateOffer(c, accessKey, passphrase, orderId, sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration)
+
+	return nil
+}
+
+// Cancels an open offer. Offers are identified by the offerSequence returned when the order was placed
+func OrderCancel(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var order enulib.Order
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	order.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	offerSequence := uint64(m["offerSequence"].(float64))
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderCancel: received request sourceAddress: %s, offerSequence: %d from accessKey: %s\n", sourceAddress, offerSequence, accessKey)
+
+	// Only open offers placed by the source address can be cancelled
+	offers, errorCode, err := rippleapi.GetAccountOffers(c, sourceAddress)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	var found bool
+	for _, o := range offers {
+		if uint64(o.Sequence) == offerSequence {
+			found = true
+		}
+	}
+
+	if found == false {
+		log.FluentfContext(consts.LOGERROR, c, "Offer %d is not open for %s", offerSequence, sourceAddress)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidOffer.Code, consts.RippleErrors.InvalidOffer.Description)
+
+		return nil
+	}
+
+	orderId := enulib.GenerateOrderId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated orderId: %s", orderId)
+	order.OrderId = orderId
+	order.Operation = "cancel"
+	order.SourceAddress = sourceAddress
+	order.OfferSequence = offerSequence
+	order.Status = "valid"
+	order.BlockchainId = consts.RippleBlockchainId
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(order); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedCancelOffer(c, accessKey, passphrase, orderId, sourceAddress, offerSequence)
+
+	return nil
+}
+
+// Returns the open offers placed by the address
+func OpenOrdersByAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var openOrders enulib.OpenOrders
+	requestId := c.Value(consts.RequestIdKey).(string)
+	openOrders.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	address := vars["address"]
+
+	if validation.IsValidRippleAddress(address) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OpenOrdersByAddress: received request address: %s from accessKey: %s\n", address, c.Value(consts.AccessKeyKey).(string))
+
+	offers, errorCode, err := rippleapi.GetAccountOffers(c, address)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	openOrders.Address = address
+	openOrders.BlockchainId = consts.RippleBlockchainId
+	for _, o := range offers {
+		openOrder, err := toOpenOrder(o, false)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in toOpenOrder(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		openOrders.Orders = append(openOrders.Orders, openOrder)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(openOrders); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the offers between the base and quote currencies with the best prices first.
+// The issuers of currencies other than XRP are given in the baseIssuer and quoteIssuer query parameters
+func OrderBook(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var orderBook enulib.OrderBook
+	requestId := c.Value(consts.RequestIdKey).(string)
+	orderBook.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	baseAsset := vars["baseAsset"]
+	quoteAsset := vars["quoteAsset"]
+	baseIssuer := r.URL.Query().Get("baseIssuer")
+	quoteIssuer := r.URL.Query().Get("quoteIssuer")
+
+	if len(baseAsset) < 3 || len(quoteAsset) < 3 || (baseAsset == quoteAsset && baseIssuer == quoteIssuer) {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid asset")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAsset.Code, consts.GenericErrors.InvalidAsset.Description)
+
+		return nil
+	}
+
+	if checkIssuer(c, w, baseAsset, baseIssuer) == false || checkIssuer(c, w, quoteAsset, quoteIssuer) == false {
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "OrderBook: received request base: %s.%s, quote: %s.%s from accessKey: %s\n", baseIssuer, baseAsset, quoteIssuer, quoteAsset, c.Value(consts.AccessKeyKey).(string))
+
+	base, err := rippleapi.ToAmount(baseAsset, baseIssuer, 0)
+	if err != nil {
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return nil
+	}
+
+	quote, err := rippleapi.ToAmount(quoteAsset, quoteIssuer, 0)
+	if err != nil {
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return nil
+	}
+
+	// Asks give the base currency, bids get the base currency
+	asks, errorCode, err := rippleapi.GetBookOffers(c, base, quote, 0)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	bids, errorCode, err := rippleapi.GetBookOffers(c, quote, base, 0)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	orderBook.BaseAsset = baseAsset
+	orderBook.QuoteAsset = quoteAsset
+	orderBook.BlockchainId = consts.RippleBlockchainId
+
+	for _, o := range asks {
+		openOrder, err := toOpenOrder(o, false)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in toOpenOrder(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		orderBook.Asks = append(orderBook.Asks, openOrder)
+	}
+
+	for _, o := range bids {
+		openOrder, err := toOpenOrder(o, true)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in toOpenOrder(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		orderBook.Bids = append(orderBook.Bids, openOrder)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(orderBook); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Currencies other than XRP must be given with a valid issuer. Returns false if a bad request was returned to the client
+func checkIssuer(c context.Context, w http.ResponseWriter, asset string, issuer string) bool {
+	if strings.ToUpper(asset) != "XRP" && issuer == "" {
+		log.FluentfContext(consts.LOGERROR, c, "%s", consts.RippleErrors.IssuerMustBeGiven.Description)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.IssuerMustBeGiven.Code, consts.RippleErrors.IssuerMustBeGiven.Description)
+
+		return false
+	}
+
+	if issuer != "" && validation.IsValidRippleAddress(issuer) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid issuer: %s", issuer)
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return false
+	}
+
+	return true
+}
+
+// Prices are in the get currency per unit of the give currency, or the inverse for bids so that both sides of a book are priced in the quote currency
+func toOpenOrder(o rippleapi.Offer, bid bool) (enulib.OpenOrder, error) {
+	giveAsset, giveQuantity, err := rippleapi.FromAmount(o.TakerGets)
+	if err != nil {
+		return enulib.OpenOrder{}, err
+	}
+
+	getAsset, getQuantity, err := rippleapi.FromAmount(o.TakerPays)
+	if err != nil {
+		return enulib.OpenOrder{}, err
+	}
+
+	var price float64
+	if bid && getQuantity > 0 {
+		price = float64(giveQuantity) / float64(getQuantity)
+	} else if !bid && giveQuantity > 0 {
+		price = float64(getQuantity) / float64(giveQuantity)
+	}
+
+	return enulib.OpenOrder{OfferSequence: uint64(o.Sequence), SourceAddress: o.Account, GiveAsset: giveAsset, GiveIssuer: o.TakerGets.Issuer, GiveQuantity: giveQuantity, GiveRemaining: int64(giveQuantity), GetAsset: getAsset, GetIssuer: o.TakerPays.Issuer, GetQuantity: getQuantity, GetRemaining: int64(getQuantity), Price: price, ExpireIndex: uint64(o.Expiration), Status: "open"}, nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedCreateOffer(c context.Context, accessKey string, passphrase string, orderId string, sourceAddress string, giveAsset string, giveIssuer string, giveQuantity uint64, getAsset string, getIssuer string, getQuantity uint64, expiration uint64) (string, int64, error) {
+	// Write the order with the generated order id to the database
+	err := database.InsertOrder(c, accessKey, orderId, consts.RippleBlockchainId, "order", sourceAddress, giveAsset, giveIssuer, giveQuantity, getAsset, getIssuer, getQuantity, expiration, "", 0, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	// The taker gets what this address gives
+	takerGets, err := rippleapi.ToAmount(giveAsset, giveIssuer, giveQuantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToAmount(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	takerPays, err := rippleapi.ToAmount(getAsset, getIssuer, getQuantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToAmount(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	var rippleExpiration uint32
+	if expiration > 0 {
+		rippleExpiration = rippleapi.ToRippleTime(time.Now().Add(time.Duration(expiration) * time.Second))
+	}
+
+	// Convert passphrase to ripple secret
+	seed := mneumonic.FromWords(strings.Split(passphrase, " "))
+	secret, err := ripplecrypto.ToSecret(seed.ToHex())
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ripplecrypto.ToSecret(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	// Create and sign the transaction
+	signedTx, offerSequence, errCode, err := rippleapi.CreateOffer(c, sourceAddress, takerGets, takerPays, rippleExpiration, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.CreateOffer(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateOrderOfferSequenceByOrderId(c, accessKey, orderId, uint64(offerSequence))
+
+	// Submit the transaction
+	txHash, errCode, err := rippleapi.Submit(c, signedTx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Submit(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateOrderCompleteByOrderId(c, accessKey, orderId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedCancelOffer(c context.Context, accessKey string, passphrase string, orderId string, sourceAddress string, offerSequence uint64) (string, int64, error) {
+	err := database.InsertOrder(c, accessKey, orderId, consts.RippleBlockchainId, "cancel", sourceAddress, "", "", 0, "", "", 0, 0, "", offerSequence, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	// Convert passphrase to ripple secret
+	seed := mneumonic.FromWords(strings.Split(passphrase, " "))
+	secret, err := ripplecrypto.ToSecret(seed.ToHex())
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ripplecrypto.ToSecret(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	signedTx, errCode, err := rippleapi.CancelOffer(c, sourceAddress, uint32(offerSequence), secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.CancelOffer(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	txHash, errCode, err := rippleapi.Submit(c, signedTx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Submit(): %s", err.Error())
+		database.UpdateOrderWithErrorByOrderId(c, accessKey, orderId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateOrderCompleteByOrderId(c, accessKey, orderId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
@@ -0,0 +1,111 @@
+package ripplehandlers
+
+import (
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+)
+
+// How often the addresses owned by access keys are checked for incoming payments
+var ripple_IncomingPaymentPollRate = 10000 // milliseconds
+
+// Records the payments received by every Ripple address owned by an access key so they are returned by the payment history APIs.
+// Runs until Enu is stopped
+func ListenForPayments(c context.Context) {
+	c = context.WithValue(c, consts.BlockchainIdKey, consts.RippleBlockchainId)
+
+	// The ledger from which each address is next searched. Only used by this goroutine
+	ledgers := make(map[string]int64)
+
+	for {
+		time.Sleep(time.Duration(ripple_IncomingPaymentPollRate) * time.Millisecond)
+
+		addresses, err := database.GetAddressesByBlockchainId(c, consts.RippleBlockchainId)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in database.GetAddressesByBlockchainId(): %s", err.Error())
+			continue
+		}
+
+		for address, accessKeys := range addresses {
+			// Resume from the latest payment recorded before Enu was started
+			if _, ok := ledgers[address]; ok == false {
+				blockId, err := database.GetLastIncomingPaymentBlockId(c, consts.RippleBlockchainId, address)
+				if err != nil {
+					continue
+				}
+				ledgers[address] = blockId
+			}
+
+			lastLedger, err := recordIncomingPayments(c, address, accessKeys, ledgers[address])
+			if err != nil {
+				continue
+			}
+
+			// Payments are recorded once only, so the last ledger can be searched again safely in case it wasn't yet complete
+			if lastLedger > ledgers[address] {
+				ledgers[address] = lastLedger
+			}
+		}
+	}
+}
+
+// Records the payments received by the address from the ledger onwards for each access key which owns the address.
+// Returns the last ledger which was searched
+func recordIncomingPayments(c context.Context, address string, accessKeys []string, ledgerIndexMin int64) (int64, error) {
+	payments, lastLedger, _, err := rippleapi.GetIncomingPayments(c, address, ledgerIndexMin)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.GetIncomingPayments(): %s", err.Error())
+		return ledgerIndexMin, err
+	}
+
+	for _, payment := range payments {
+		for _, accessKey := range accessKeys {
+			if err := recordIncomingPayment(c, accessKey, payment); err != nil {
+				return ledgerIndexMin, err
+			}
+		}
+	}
+
+	return lastLedger, nil
+}
+
+func recordIncomingPayment(c context.Context, accessKey string, payment rippleapi.IncomingPayment) error {
+	exists, err := database.IncomingPaymentExists(c, accessKey, payment.Destination, payment.Hash)
+	if err != nil || exists {
+		return err
+	}
+
+	var asset string
+	var issuer string
+	var deliveredAmount uint64
+	var errorCode int64
+	var errorDescription string
+
+	// Only the delivered amount is credited. The amount given by the sender of a partial payment may be far more than was received
+	if payment.DeliveredAmountKnown {
+		asset, deliveredAmount, err = rippleapi.FromAmount(payment.DeliveredAmount)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.FromAmount(): %s", err.Error())
+			return err
+		}
+		issuer = payment.DeliveredAmount.Issuer
+	} else {
+		log.FluentfContext(consts.LOGERROR, c, "Delivered amount of partial payment %s to %s is unavailable", payment.Hash, payment.Destination)
+
+		asset, _ = rippleapi.FromCurrency(payment.Amount.Currency)
+		issuer = payment.Amount.Issuer
+		errorCode = consts.RippleErrors.DeliveredAmountUnavailable.Code
+		errorDescription = consts.RippleErrors.DeliveredAmountUnavailable.Description
+	}
+
+	paymentId := enulib.GeneratePaymentId()
+	log.FluentfContext(consts.LOGINFO, c, "Received payment %s of %d %s by %s for %s, recorded as paymentId: %s", payment.Hash, deliveredAmount, asset, payment.Destination, accessKey, paymentId)
+
+	return database.InsertIncomingPayment(c, accessKey, paymentId, payment.LedgerIndex, consts.RippleBlockchainId, payment.Hash, payment.Account, payment.Destination, asset, issuer, deliveredAmount, payment.DestinationTag, payment.SourceTag, payment.InvoiceID, toPaymentMemos(rippleapi.PaymentOptions{Memos: payment.Memos}), errorCode, errorDescription)
+}
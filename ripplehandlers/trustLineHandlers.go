@@ -0,0 +1,403 @@
+package ripplehandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/gorilla/mux"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/handlers"
+	"github.com/whoisjeremylam/enu/log"
+	"github.com/whoisjeremylam/enu/rippleapi"
+	"github.com/whoisjeremylam/enu/ripplecrypto"
+	"github.com/whoisjeremylam/enu/validation"
+)
+
+// Operations by a holder on its own trust lines, recorded in the operation column of the trustassets table
+const (
+	trustOperationTrust  = "trust"
+	trustOperationModify = "modify"
+	trustOperationRemove = "remove"
+)
+
+// Creates a trust line from the source address to the issuer for the asset, or modifies the limit and qualities of an existing line
+func TrustLineCreate(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var trustLine enulib.TrustLine
+	var qualityIn *uint32
+	var qualityOut *uint32
+
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	trustLine.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	asset := m["asset"].(string)
+	issuer := m["issuer"].(string)
+	limit := uint64(m["limit"].(float64))
+
+	// Qualities which aren't given are left unchanged
+	if m["qualityIn"] != nil {
+		q := uint32(m["qualityIn"].(float64))
+		qualityIn = &q
+		trustLine.QualityIn = q
+	}
+
+	if m["qualityOut"] != nil {
+		q := uint32(m["qualityOut"].(float64))
+		qualityOut = &q
+		trustLine.QualityOut = q
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "TrustLineCreate: received request sourceAddress: %s, asset: %s, issuer: %s, limit: %d from accessKey: %s\n", sourceAddress, asset, issuer, limit, accessKey)
+
+	currency, err := rippleapi.ToCurrency(asset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToCurrency(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return nil
+	}
+
+	lines, errorCode, err := rippleapi.GetAccountLines(c, sourceAddress)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	operation := trustOperationTrust
+	if lines.Contains(issuer, currency) {
+		operation = trustOperationModify
+	}
+
+	// Generate a trustId
+	trustId := enulib.GenerateTrustId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated trustId: %s", trustId)
+	trustLine.TrustId = trustId
+	trustLine.Operation = operation
+	trustLine.Address = sourceAddress
+	trustLine.Asset = asset
+	trustLine.Issuer = issuer
+	trustLine.Limit = limit
+	trustLine.Status = "valid"
+	trustLine.BlockchainId = consts.RippleBlockchainId
+
+	// Return to the client the trustId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(trustLine); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	go delegatedTrustSet(c, accessKey, passphrase, trustId, operation, sourceAddress, asset, issuer, limit, qualityIn, qualityOut)
+
+	return nil
+}
+
+// Removes the trust line from the source address to the issuer for the asset. The balance of the line must be zero
+func TrustLineRemove(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var trustLine enulib.TrustLine
+	requestId := c.Value(consts.RequestIdKey).(string)
+	accessKey := c.Value(consts.AccessKeyKey).(string)
+	trustLine.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	passphrase := m["passphrase"].(string)
+	sourceAddress := m["sourceAddress"].(string)
+	asset := m["asset"].(string)
+	issuer := m["issuer"].(string)
+
+	log.FluentfContext(consts.LOGINFO, c, "TrustLineRemove: received request sourceAddress: %s, asset: %s, issuer: %s from accessKey: %s\n", sourceAddress, asset, issuer, accessKey)
+
+	currency, err := rippleapi.ToCurrency(asset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToCurrency(): %s", err.Error())
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return nil
+	}
+
+	lines, errorCode, err := rippleapi.GetAccountLines(c, sourceAddress)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	line, found := lines.Find(issuer, currency)
+	if found == false {
+		log.FluentfContext(consts.LOGERROR, c, "%s has no trust line to %s for %s", sourceAddress, issuer, asset)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.TrustLineNotFound.Code, consts.RippleErrors.TrustLineNotFound.Description)
+
+		return nil
+	}
+
+	// Ripple only deletes a line once it is back in its default state, which can't happen while the line holds a balance
+	balance, err := toBalance(line.Balance)
+	if err != nil || balance != 0 {
+		log.FluentfContext(consts.LOGERROR, c, "Trust line from %s to %s for %s has a balance of %s", sourceAddress, issuer, asset, line.Balance)
+		handlers.ReturnBadRequest(c, w, consts.RippleErrors.TrustLineNotEmpty.Code, consts.RippleErrors.TrustLineNotEmpty.Description)
+
+		return nil
+	}
+
+	// Generate a trustId
+	trustId := enulib.GenerateTrustId()
+	log.FluentfContext(consts.LOGINFO, c, "Generated trustId: %s", trustId)
+	trustLine.TrustId = trustId
+	trustLine.Operation = trustOperationRemove
+	trustLine.Address = sourceAddress
+	trustLine.Asset = asset
+	trustLine.Issuer = issuer
+	trustLine.Status = "valid"
+	trustLine.BlockchainId = consts.RippleBlockchainId
+
+	// Return to the client the trustId and unblock the client
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(trustLine); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	// Qualities are reset to the default along with the limit
+	var defaultQuality uint32
+	go delegatedTrustSet(c, accessKey, passphrase, trustId, trustOperationRemove, sourceAddress, asset, issuer, 0, &defaultQuality, &defaultQuality)
+
+	return nil
+}
+
+// Returns the trust lines held by an address
+func TrustLinesByAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	var trustLines enulib.TrustLines
+	requestId := c.Value(consts.RequestIdKey).(string)
+	trustLines.RequestId = requestId
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	address := vars["address"]
+
+	if validation.IsValidRippleAddress(address) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "TrustLinesByAddress: received request address: %s from accessKey: %s\n", address, c.Value(consts.AccessKeyKey).(string))
+
+	lines, errorCode, err := rippleapi.GetAccountLines(c, address)
+	if err != nil {
+		handlers.ReturnServerErrorWithCustomError(c, w, errorCode, err.Error())
+
+		return nil
+	}
+
+	trustLines.Address = address
+	trustLines.BlockchainId = consts.RippleBlockchainId
+	trustLines.TrustLines = []enulib.TrustLine{}
+	for _, line := range lines {
+		trustLine, err := toTrustLine(address, line)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in toTrustLine(): %s", err.Error())
+			handlers.ReturnServerError(c, w)
+
+			return nil
+		}
+
+		trustLines.TrustLines = append(trustLines.TrustLines, trustLine)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(trustLines); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Returns the status of an operation on a trust line
+func GetTrustLine(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
+	requestId := c.Value(consts.RequestIdKey).(string)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(r)
+	trustId := vars["trustId"]
+
+	if trustId == "" || len(trustId) < 16 {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid trustId")
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidTrustId.Code, consts.GenericErrors.InvalidTrustId.Description)
+
+		return nil
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "GetTrustLine called for '%s' by '%s'\n", trustId, c.Value(consts.AccessKeyKey).(string))
+
+	trustLine, err := database.GetTrustLineByTrustId(c, c.Value(consts.AccessKeyKey).(string), trustId)
+	if err != nil {
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	if trustLine.TrustId == "" {
+		handlers.ReturnNotFoundWithCustomError(c, w, consts.GenericErrors.InvalidTrustId.Code, consts.GenericErrors.InvalidTrustId.Description)
+
+		return nil
+	}
+	trustLine.RequestId = requestId
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(trustLine); err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Encode(): %s", err.Error())
+		handlers.ReturnServerError(c, w)
+
+		return nil
+	}
+
+	return nil
+}
+
+// Maps a trust line returned by rippled to the Enu API, converting amounts into satoshis
+func toTrustLine(address string, line rippleapi.Line) (enulib.TrustLine, error) {
+	asset, err := rippleapi.FromCurrency(line.Currency)
+	if err != nil {
+		return enulib.TrustLine{}, err
+	}
+
+	limit, err := rippleapi.AmountToUint64(line.Limit)
+	if err != nil {
+		return enulib.TrustLine{}, err
+	}
+
+	balance, err := toBalance(line.Balance)
+	if err != nil {
+		return enulib.TrustLine{}, err
+	}
+
+	trustLine := enulib.TrustLine{
+		Address:    address,
+		Asset:      asset,
+		Issuer:     line.Account,
+		Limit:      limit,
+		QualityIn:  uint32(line.QualityIn),
+		QualityOut: uint32(line.QualityOut),
+		Balance:    balance,
+		Authorized: line.PeerAuthorized, // authorization and freezes are set by the issuer, which is the peer of the line
+		Frozen:     line.FreezePeer,
+		NoRipple:   line.NoRipple,
+	}
+
+	return trustLine, nil
+}
+
+// Balances are negative when the address owes the other account on the line
+func toBalance(value string) (int64, error) {
+	quantity, err := rippleapi.AmountToUint64(strings.TrimPrefix(value, "-"))
+	if err != nil {
+		return 0, err
+	}
+
+	if strings.HasPrefix(value, "-") {
+		return -int64(quantity), nil
+	}
+
+	return int64(quantity), nil
+}
+
+// Concurrency safe to create and send transactions from a single address.
+func delegatedTrustSet(c context.Context, accessKey string, passphrase string, trustId string, operation string, sourceAddress string, asset string, issuer string, limit uint64, qualityIn *uint32, qualityOut *uint32) (string, int64, error) {
+	var qualityInValue uint32
+	var qualityOutValue uint32
+
+	if qualityIn != nil {
+		qualityInValue = *qualityIn
+	}
+
+	if qualityOut != nil {
+		qualityOutValue = *qualityOut
+	}
+
+	// Write the operation with the generated trust id to the database
+	err := database.InsertTrustLine(c, accessKey, trustId, consts.RippleBlockchainId, operation, sourceAddress, asset, issuer, limit, qualityInValue, qualityOutValue, "valid")
+	if err != nil {
+		return "", consts.GenericErrors.GeneralError.Code, err
+	}
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	currency, err := rippleapi.ToCurrency(asset)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToCurrency(): %s", err.Error())
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	// Uint64ToAmount() trims all of the digits from a zero amount
+	value := "0"
+	if limit > 0 {
+		value, err = rippleapi.Uint64ToAmount(limit)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in Uint64ToAmount(): %s", err.Error())
+			database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, consts.GenericErrors.GeneralError.Code, consts.GenericErrors.GeneralError.Description)
+
+			return "", consts.GenericErrors.GeneralError.Code, errors.New(consts.GenericErrors.GeneralError.Description)
+		}
+	}
+
+	secret := ripplecrypto.PassphraseToSecret(c, passphrase)
+	if secret == "" {
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	txHash, errCode, err := rippleapi.SetTrustLine(c, sourceAddress, currency, value, issuer, qualityIn, qualityOut, 0, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.SetTrustLine(): %s", err.Error())
+		database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdateTrustLineCompleteByTrustId(c, accessKey, trustId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
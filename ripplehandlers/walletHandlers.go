@@ -20,10 +20,15 @@ import (
 	"github.com/whoisjeremylam/enu/log"
 	"github.com/whoisjeremylam/enu/rippleapi"
 	"github.com/whoisjeremylam/enu/ripplecrypto"
+	"github.com/whoisjeremylam/enu/validation"
 )
 
 var ripple_BackEndPollRate = 1000
 
+// How often and how many times a submitted transaction is checked for validation
+var ripple_ValidationPollRate = 4000 // milliseconds
+var ripple_ValidationRetries = 15
+
 var ripple_Mutexes = struct {
 	sync.RWMutex
 	m map[string]*sync.Mutex
@@ -73,6 +78,10 @@ func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map
 	var walletPayment enulib.WalletPayment
 	var paymentTag string
 	var issuer string
+	var sourceAsset string
+	var sourceIssuer string
+	var sendMax uint64
+	var options rippleapi.PaymentOptions
 
 	requestId := c.Value(consts.RequestIdKey).(string)
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -102,6 +111,89 @@ func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map
 		return nil
 	}
 
+	if issuer != "" && validation.IsValidRippleAddress(issuer) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid issuer: %s", issuer)
+		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+		return nil
+	}
+
+	// Paying in a different asset to the one the destination receives requires a path and the most the source is willing to spend
+	if m["sourceAsset"] != nil {
+		sourceAsset = m["sourceAsset"].(string)
+		sendMax = uint64(m["sendMax"].(float64))
+
+		if m["sourceIssuer"] != nil {
+			sourceIssuer = m["sourceIssuer"].(string)
+		}
+
+		if strings.ToUpper(sourceAsset) != "XRP" && sourceIssuer == "" {
+			log.FluentfContext(consts.LOGERROR, c, "%s", consts.RippleErrors.IssuerMustBeGiven.Description)
+			handlers.ReturnBadRequest(c, w, consts.RippleErrors.IssuerMustBeGiven.Code, consts.RippleErrors.IssuerMustBeGiven.Description)
+			return nil
+		}
+
+		if sourceIssuer != "" && validation.IsValidRippleAddress(sourceIssuer) == false {
+			log.FluentfContext(consts.LOGERROR, c, "Invalid source issuer: %s", sourceIssuer)
+			handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
+			return nil
+		}
+
+		// Same currency payments don't need a path
+		if strings.ToUpper(sourceAsset) == strings.ToUpper(asset) && sourceIssuer == issuer {
+			sourceAsset = ""
+			sourceIssuer = ""
+			sendMax = 0
+		}
+	}
+
+	// Tags, invoice id and memos are carried on chain for the recipient
+	if m["destinationTag"] != nil {
+		destinationTag := uint32(m["destinationTag"].(float64))
+		options.DestinationTag = &destinationTag
+	}
+
+	if m["sourceTag"] != nil {
+		sourceTag := uint32(m["sourceTag"].(float64))
+		options.SourceTag = &sourceTag
+	}
+
+	if m["invoiceId"] != nil {
+		options.InvoiceID = strings.ToUpper(m["invoiceId"].(string))
+	}
+
+	if m["memos"] != nil {
+		for _, i := range m["memos"].([]interface{}) {
+			memo := i.(map[string]interface{})
+			var rippleMemo rippleapi.Memo
+
+			rippleMemo.MemoData = memo["data"].(string)
+			if memo["type"] != nil {
+				rippleMemo.MemoType = memo["type"].(string)
+			}
+			if memo["format"] != nil {
+				rippleMemo.MemoFormat = memo["format"].(string)
+			}
+
+			options.Memos = append(options.Memos, rippleMemo)
+		}
+	}
+
+	// Payments to addresses which require a destination tag would otherwise fail after the client has been unblocked
+	if options.DestinationTag == nil {
+		destinationInfo, errCode, err := rippleapi.GetAccountInfo(c, destinationAddress)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.GetAccountInfo(): %s", err.Error())
+			handlers.ReturnServerErrorWithCustomError(c, w, errCode, err.Error())
+			return nil
+		}
+
+		if destinationInfo.Flags&rippleapi.LsfRequireDestTag != 0 {
+			log.FluentfContext(consts.LOGERROR, c, "Destination %s requires a destination tag", destinationAddress)
+			handlers.ReturnBadRequest(c, w, consts.RippleErrors.DestinationTagRequired.Code, consts.RippleErrors.DestinationTagRequired.Description)
+			return nil
+		}
+	}
+
 	log.FluentfContext(consts.LOGINFO, c, "WalletSend: received request sourceAddress: %s, destinationAddress: %s, asset: %s, issuer: %s, quantity: %d, paymentTag: %s from accessKey: %s\n", sourceAddress, destinationAddress, asset, issuer, quantity, c.Value(consts.AccessKeyKey).(string), paymentTag)
 	// Generate a paymentId
 	paymentId := enulib.GeneratePaymentId()
@@ -114,6 +206,13 @@ func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map
 	walletPayment.SourceAddress = sourceAddress
 	walletPayment.DestinationAddress = destinationAddress
 	walletPayment.Quantity = quantity
+	walletPayment.SourceAsset = sourceAsset
+	walletPayment.SourceIssuer = sourceIssuer
+	walletPayment.SendMax = sendMax
+	walletPayment.DestinationTag = options.DestinationTag
+	walletPayment.SourceTag = options.SourceTag
+	walletPayment.InvoiceId = options.InvoiceID
+	walletPayment.Memos = toPaymentMemos(options)
 	w.WriteHeader(http.StatusCreated)
 
 	if err := json.NewEncoder(w).Encode(walletPayment); err != nil {
@@ -123,21 +222,28 @@ func WalletSend(c context.Context, w http.ResponseWriter, r *http.Request, m map
 		return nil
 	}
 
+	if sourceAsset != "" {
+		go delegatedPathSendWithFee(c, c.Value(consts.AccessKeyKey).(string), passphrase, sourceAddress, destinationAddress, asset, issuer, quantity, sourceAsset, sourceIssuer, sendMax, options, paymentId, paymentTag)
+
+		return nil
+	}
+
 	//	txHash, errCode, err := rippleapi.SendPayment(c, sourceAddress, destinationAddress, amount, asset, issuer, secret)
-	go delegatedSend(c, c.Value(consts.AccessKeyKey).(string), passphrase, sourceAddress, destinationAddress, asset, issuer, quantity, paymentId, paymentTag)
+	go delegatedSendWithFee(c, c.Value(consts.AccessKeyKey).(string), passphrase, sourceAddress, destinationAddress, asset, issuer, quantity, options, paymentId, paymentTag)
 
 	return nil
 }
 
 // Concurrency safe to create and send transactions from a single address.
-func delegatedSend(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, issuer string, quantity uint64, paymentId string, paymentTag string) (string, int64, error) {
+func delegatedSend(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, issuer string, quantity uint64, options rippleapi.PaymentOptions, paymentId string, paymentTag string) (string, int64, error) {
 
 	// Write the payment with the generated payment id to the database
 	defaultFee, err := strconv.ParseUint(rippleapi.DefaultFee, 10, 64)
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in converting ripple fee: %s", err.Error())
 	}
-	go database.InsertPayment(c, accessKey, 0, c.Value(consts.BlockchainIdKey).(string), paymentId, sourceAddress, destinationAddress, asset, issuer, quantity, "valid", 0, defaultFee, paymentTag)
+	database.InsertPayment(c, accessKey, 0, c.Value(consts.BlockchainIdKey).(string), paymentId, sourceAddress, destinationAddress, asset, issuer, quantity, "valid", 0, defaultFee, paymentTag)
+	recordPaymentOptions(c, accessKey, paymentId, options)
 
 	// Mutex lock this address
 	ripple_Mutexes.Lock()
@@ -164,6 +270,14 @@ func delegatedSend(c context.Context, accessKey string, passphrase string, sourc
 	// Convert int to the ripple amount
 	var amount string
 	if strings.ToUpper(asset) == "XRP" {
+		// Less than one drop can't be paid
+		if quantity < enulib.XRPDrop {
+			log.FluentfContext(consts.LOGERROR, c, "Unable to send %d XRP, less than one drop", quantity)
+			database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.RippleErrors.InvalidAmount.Code, consts.RippleErrors.InvalidAmount.Description)
+
+			return "", consts.RippleErrors.InvalidAmount.Code, errors.New(consts.RippleErrors.InvalidAmount.Description)
+		}
+
 		// Amounts are specified in satoshis in the Enu API
 		// Convert to a string and truncate the last two characters
 		a := strconv.FormatUint(quantity, 10)
@@ -199,7 +313,7 @@ func delegatedSend(c context.Context, accessKey string, passphrase string, sourc
 	}
 
 	// Create and sign the transaction
-	signedTx, errCode, err := rippleapi.CreatePayment(c, sourceAddress, destinationAddress, amount, currency, issuer, secret)
+	signedTx, errCode, err := rippleapi.CreatePayment(c, sourceAddress, destinationAddress, amount, currency, issuer, options, secret)
 	if err != nil {
 		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.CreatePayment(): %s", err.Error())
 		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errCode, err.Error())
@@ -223,6 +337,180 @@ func delegatedSend(c context.Context, accessKey string, passphrase string, sourc
 	return txHash, 0, nil
 }
 
+// Concurrency safe to create and send cross currency payments from a single address.
+// The source asset is converted to the asset received by the destination through the path found by rippled, spending at most sendMax
+func delegatedPathSend(c context.Context, accessKey string, passphrase string, sourceAddress string, destinationAddress string, asset string, issuer string, quantity uint64, sourceAsset string, sourceIssuer string, sendMax uint64, options rippleapi.PaymentOptions, paymentId string, paymentTag string) (string, int64, error) {
+
+	// Write the payment with the generated payment id to the database
+	defaultFee, err := strconv.ParseUint(rippleapi.DefaultFee, 10, 64)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in converting ripple fee: %s", err.Error())
+	}
+	database.InsertPayment(c, accessKey, 0, c.Value(consts.BlockchainIdKey).(string), paymentId, sourceAddress, destinationAddress, asset, issuer, quantity, "valid", 0, defaultFee, paymentTag)
+	database.UpdatePaymentSourceByPaymentId(c, accessKey, paymentId, sourceAsset, sourceIssuer, sendMax)
+	recordPaymentOptions(c, accessKey, paymentId, options)
+
+	// Mutex lock this address
+	ripple_Mutexes.Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked the map") // The map of mutexes must be locked before we modify the mutexes stored in the map
+
+	// If an entry doesn't currently exist in the map for that address
+	if ripple_Mutexes.m[sourceAddress] == nil {
+		log.FluentfContext(consts.LOGINFO, c, "Created new entry in map for %s", sourceAddress)
+		ripple_Mutexes.m[sourceAddress] = new(sync.Mutex)
+	}
+
+	ripple_Mutexes.m[sourceAddress].Lock()
+	log.FluentfContext(consts.LOGINFO, c, "Locked: %s\n", sourceAddress)
+
+	defer ripple_Mutexes.Unlock()
+	defer ripple_Mutexes.m[sourceAddress].Unlock()
+
+	// We must sleep for at least the time it takes for most transactions to enter a ledger
+	log.FluentfContext(consts.LOGINFO, c, "Sleeping %d milliseconds", ripple_BackEndPollRate+1000)
+	time.Sleep(time.Duration(ripple_BackEndPollRate+1000) * time.Millisecond)
+
+	// Convert the amount to deliver and the most to spend into ripple amounts
+	amount, err := rippleapi.ToAmount(asset, issuer, quantity)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToAmount(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	maxAmount, err := rippleapi.ToAmount(sourceAsset, sourceIssuer, sendMax)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.ToAmount(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.RippleErrors.InvalidCurrency.Code, consts.RippleErrors.InvalidCurrency.Description)
+
+		return "", consts.RippleErrors.InvalidCurrency.Code, errors.New(consts.RippleErrors.InvalidCurrency.Description)
+	}
+
+	// Find a path which converts the source asset into the destination asset
+	alternative, errCode, err := rippleapi.FindPaths(c, sourceAddress, destinationAddress, amount, maxAmount)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.FindPaths(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	// Don't submit a payment which is expected to cost more than the client is willing to spend
+	_, estimate, err := rippleapi.FromAmount(alternative.SourceAmount)
+	if err != nil || estimate > sendMax {
+		log.FluentfContext(consts.LOGERROR, c, "Path costs %s %s which exceeds sendMax: %d", alternative.SourceAmount.Value, sourceAsset, sendMax)
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.RippleErrors.NoPathFound.Code, consts.RippleErrors.NoPathFound.Description)
+
+		return "", consts.RippleErrors.NoPathFound.Code, errors.New(consts.RippleErrors.NoPathFound.Description)
+	}
+
+	// Convert passphrase to ripple secret
+	seed := mneumonic.FromWords(strings.Split(passphrase, " "))
+	secret, err := ripplecrypto.ToSecret(seed.ToHex())
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in ripplecrypto.ToSecret(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.GenericErrors.InvalidPassphrase.Code, consts.GenericErrors.InvalidPassphrase.Description)
+
+		return "", consts.GenericErrors.InvalidPassphrase.Code, errors.New(consts.GenericErrors.InvalidPassphrase.Description)
+	}
+
+	// Create and sign the transaction
+	signedTx, errCode, err := rippleapi.CreatePathPayment(c, sourceAddress, destinationAddress, amount, maxAmount, alternative.Paths, options, secret)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.CreatePathPayment(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	//	 Submit the transaction
+	txHash, errCode, err := rippleapi.Submit(c, signedTx)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in Submit(): %s", err.Error())
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, errCode, err.Error())
+
+		return "", errCode, err
+	}
+
+	database.UpdatePaymentCompleteByPaymentId(c, accessKey, paymentId, txHash)
+
+	// The amount delivered is only known once the payment is in a validated ledger
+	go recordDeliveredAmount(c, accessKey, paymentId, txHash)
+
+	log.FluentfContext(consts.LOGINFO, c, "Complete.")
+
+	return txHash, 0, nil
+}
+
+// Stores the optional fields sent with the payment so they are returned with the payment
+func recordPaymentOptions(c context.Context, accessKey string, paymentId string, options rippleapi.PaymentOptions) {
+	if options.DestinationTag == nil && options.SourceTag == nil && options.InvoiceID == "" && len(options.Memos) == 0 {
+		return
+	}
+
+	err := database.UpdatePaymentTagsByPaymentId(c, accessKey, paymentId, options.DestinationTag, options.SourceTag, options.InvoiceID, toPaymentMemos(options))
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in database.UpdatePaymentTagsByPaymentId(): %s", err.Error())
+	}
+}
+
+func toPaymentMemos(options rippleapi.PaymentOptions) []enulib.PaymentMemo {
+	var result []enulib.PaymentMemo
+
+	for _, m := range options.Memos {
+		result = append(result, enulib.PaymentMemo{Type: m.MemoType, Data: m.MemoData, Format: m.MemoFormat})
+	}
+
+	return result
+}
+
+// Waits for the payment to be validated and records the amount which was delivered to the destination
+func recordDeliveredAmount(c context.Context, accessKey string, paymentId string, txHash string) {
+	status, validated := waitForValidation(c, txHash)
+	if validated == false {
+		return
+	}
+
+	if status.Result != "tesSUCCESS" {
+		log.FluentfContext(consts.LOGERROR, c, "Payment %s failed validation with: %s", txHash, status.Result)
+		database.UpdatePaymentWithErrorByPaymentId(c, accessKey, paymentId, consts.RippleErrors.SubmitErrorFeeLost.Code, consts.RippleErrors.SubmitErrorFeeLost.Description)
+
+		return
+	}
+
+	_, delivered, err := rippleapi.FromAmount(status.DeliveredAmount)
+	if err != nil {
+		log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.FromAmount(): %s", err.Error())
+
+		return
+	}
+
+	log.FluentfContext(consts.LOGINFO, c, "Payment %s delivered %d", txHash, delivered)
+	database.UpdatePaymentDeliveredAmountByPaymentId(c, accessKey, paymentId, delivered)
+}
+
+// Polls for the outcome of a transaction until it is in a validated ledger. Returns false if it still isn't validated after the retries
+func waitForValidation(c context.Context, txHash string) (rippleapi.TransactionStatus, bool) {
+	for i := 0; i < ripple_ValidationRetries; i++ {
+		time.Sleep(time.Duration(ripple_ValidationPollRate) * time.Millisecond)
+
+		status, _, err := rippleapi.GetTransaction(c, txHash)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.GetTransaction(): %s", err.Error())
+			continue
+		}
+
+		if status.Validated {
+			return status, true
+		}
+	}
+
+	log.FluentfContext(consts.LOGERROR, c, "Transaction %s was not validated after %d attempts", txHash, ripple_ValidationRetries)
+
+	return rippleapi.TransactionStatus{}, false
+}
+
 func ActivateAddress(c context.Context, w http.ResponseWriter, r *http.Request, m map[string]interface{}) *enulib.AppError {
 	requestId := c.Value(consts.RequestIdKey).(string)
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -233,7 +521,7 @@ func ActivateAddress(c context.Context, w http.ResponseWriter, r *http.Request,
 	vars := mux.Vars(r)
 	address := vars["address"]
 
-	if address == "" {
+	if validation.IsValidRippleAddress(address) == false {
 		w.WriteHeader(http.StatusBadRequest)
 		returnCode := enulib.ReturnCode{RequestId: c.Value(consts.RequestIdKey).(string), Code: consts.GenericErrors.InvalidAddress.Code, Description: consts.GenericErrors.InvalidAddress.Description}
 		if err := json.NewEncoder(w).Encode(returnCode); err != nil {
@@ -420,7 +708,7 @@ func delegatedActivateAddress(c context.Context, addressToActivate string, passp
 		database.InsertActivation(c, accessKey, activationId, blockchainId, sourceAddress, amountXRPToSend)
 
 		// Send the xrp - note that XRP must be specified in satoshis so we multiply by 100
-		_, _, err = delegatedSend(c, accessKey, wallets[randomNumber].Passphrase, wallets[randomNumber].Address, addressToActivate, "XRP", "", amountXRPToSend*100, activationId, "")
+		_, _, err = delegatedSend(c, accessKey, wallets[randomNumber].Passphrase, wallets[randomNumber].Address, addressToActivate, "XRP", "", amountXRPToSend*100, rippleapi.PaymentOptions{}, activationId, "")
 		if err != nil {
 			log.FluentfContext(consts.LOGERROR, c, "Error in delegatedSend(): %s", err.Error())
 			database.UpdatePaymentWithErrorByPaymentId(c, accessKey, activationId, consts.RippleErrors.MiscError.Code, consts.RippleErrors.MiscError.Description)
@@ -428,6 +716,7 @@ func delegatedActivateAddress(c context.Context, addressToActivate string, passp
 		}
 
 		complete = true
+		chargeFee(c, "", "", enulib.FeeActivation, activationId, "", "", amount)
 
 		// Throttle
 		if complete == false {
@@ -448,7 +737,8 @@ func delegatedActivateAddress(c context.Context, addressToActivate string, passp
 
 	// For each trustline which doesn't already exist, create it
 	for _, line := range linesRequired {
-		database.InsertTrustAsset(c, accessKey, activationId, blockchainId, line.Currency, line.Issuer, rippleapi.DefaultAmountToTrust)
+		trustId := enulib.GenerateTrustId()
+		database.InsertTrustAsset(c, accessKey, activationId, trustId, blockchainId, addressToActivate, line.Currency, line.Issuer, rippleapi.DefaultAmountToTrust)
 
 		// Convert int to the ripple amount
 		rippleAmount, err := rippleapi.Uint64ToAmount(rippleapi.DefaultAmountToTrust)
@@ -474,7 +764,15 @@ func delegatedActivateAddress(c context.Context, addressToActivate string, passp
 			return consts.RippleErrors.MiscError.Code, errors.New(consts.RippleErrors.MiscError.Description)
 		}
 
-		_, _, err = rippleapi.TrustSet(c, addressToActivate, currency, rippleAmount, line.Issuer, 0, secret)
+		txHash, errCode, err := rippleapi.TrustSet(c, addressToActivate, currency, rippleAmount, line.Issuer, 0, secret)
+		if err != nil {
+			log.FluentfContext(consts.LOGERROR, c, "Error in rippleapi.TrustSet(): %s", err.Error())
+			database.UpdateTrustLineWithErrorByTrustId(c, accessKey, trustId, errCode, err.Error())
+
+			continue
+		}
+
+		database.UpdateTrustLineCompleteByTrustId(c, accessKey, trustId, txHash)
 	}
 
 	log.FluentfContext(consts.LOGINFO, c, "delegatedActivateAddress() complete")
@@ -494,7 +792,8 @@ func WalletBalance(c context.Context, w http.ResponseWriter, r *http.Request, m
 	vars := mux.Vars(r)
 	address := vars["address"]
 
-	if address == "" || len(address) != 34 {
+	if validation.IsValidRippleAddress(address) == false {
+		log.FluentfContext(consts.LOGERROR, c, "Invalid address")
 		handlers.ReturnBadRequest(c, w, consts.GenericErrors.InvalidAddress.Code, consts.GenericErrors.InvalidAddress.Description)
 		return nil
 	}
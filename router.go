@@ -24,13 +24,71 @@ func NewRouter() *mux.Router {
 	router.Handle("/asset/dividend", ctxHandler(DividendCreate)).Methods("POST")
 	router.Handle("/asset/dividend/{dividendId}", ctxHandler(GetDividend)).Methods("GET")
 	router.Handle("/asset/issuances/{asset}", ctxHandler(AssetIssuances)).Methods("GET")
+	router.Handle("/asset/ledger/schedule", ctxHandler(AssetRegistrySchedule)).Methods("POST")
+	router.Handle("/asset/ledger/{asset}/diff", ctxHandler(AssetRegistryDiff)).Methods("GET")
+	router.Handle("/asset/ledger/{asset}/snapshots", ctxHandler(AssetRegistrySnapshots)).Methods("GET")
 	router.Handle("/asset/ledger/{asset}", ctxHandler(AssetLedger)).Methods("GET")
+	router.Handle("/asset/reissue", ctxHandler(AssetReissue)).Methods("POST")
+	router.Handle("/asset/lock", ctxHandler(AssetLock)).Methods("POST")
+	router.Handle("/asset/transfer", ctxHandler(AssetTransfer)).Methods("POST")
+	router.Handle("/asset/description", ctxHandler(AssetDescription)).Methods("POST")
+	router.Handle("/asset/requireauth", ctxHandler(AssetRequireAuth)).Methods("POST")
+	router.Handle("/asset/authorize", ctxHandler(AssetAuthorize)).Methods("POST")
+	router.Handle("/asset/freeze", ctxHandler(AssetFreeze)).Methods("POST")
+	router.Handle("/asset/globalfreeze", ctxHandler(AssetGlobalFreeze)).Methods("POST")
+	router.Handle("/asset/nofreeze", ctxHandler(AssetNoFreeze)).Methods("POST")
+	router.Handle("/asset/transferrate", ctxHandler(AssetTransferRate)).Methods("POST")
+
+	router.Handle("/trustline", ctxHandler(TrustLineCreate)).Methods("POST")
+	router.Handle("/trustline/remove", ctxHandler(TrustLineRemove)).Methods("POST")
+	router.Handle("/trustline/address/{address}", ctxHandler(TrustLinesByAddress)).Methods("GET")
+	router.Handle("/trustline/{trustId}", ctxHandler(GetTrustLine)).Methods("GET")
+
+	router.Handle("/order", ctxHandler(OrderCreate)).Methods("POST")
+	router.Handle("/order/cancel", ctxHandler(OrderCancel)).Methods("POST")
+	router.Handle("/order/btcpay", ctxHandler(OrderBtcPay)).Methods("POST")
+	router.Handle("/order/address/{address}", ctxHandler(OpenOrdersByAddress)).Methods("GET")
+	router.Handle("/order/book/{baseAsset}/{quoteAsset}", ctxHandler(OrderBook)).Methods("GET")
+	router.Handle("/order/{orderId}", ctxHandler(GetOrder)).Methods("GET")
+
+	router.Handle("/broadcast", ctxHandler(BroadcastCreate)).Methods("POST")
+	router.Handle("/broadcast/address/{address}", ctxHandler(BroadcastsByAddress)).Methods("GET")
+	router.Handle("/broadcast/{broadcastId}", ctxHandler(GetBroadcast)).Methods("GET")
+
+	router.Handle("/transactions", ctxHandler(AddressTransactions)).Methods("GET")
+	router.Handle("/transactions/address/{address}", ctxHandler(AddressTransactions)).Methods("GET")
+
+	router.Handle("/deposit/address", ctxHandler(DepositAddressCreate)).Methods("POST")
+	router.Handle("/deposit/{customerReference}", ctxHandler(CustomerDeposits)).Methods("GET")
+
+	router.Handle("/ledger/account", ctxHandler(LedgerAccountCreate)).Methods("POST")
+	router.Handle("/ledger/account/{accountId}", ctxHandler(GetLedgerAccount)).Methods("GET")
+	router.Handle("/ledger/transfer", ctxHandler(LedgerTransfer)).Methods("POST")
+	router.Handle("/ledger/deposit", ctxHandler(LedgerDeposit)).Methods("POST")
+	router.Handle("/ledger/withdrawal", ctxHandler(LedgerWithdrawal)).Methods("POST")
+
+	router.Handle("/gateway/map", ctxHandler(AddressMapCreate)).Methods("POST")
+	router.Handle("/gateway/maps", ctxHandler(AddressMaps)).Methods("GET")
+	router.Handle("/gateway/transfers", ctxHandler(GatewayTransfers)).Methods("GET")
+	router.Handle("/audit", ctxHandler(AuditEntries)).Methods("GET")
+	router.Handle("/audit/verify", ctxHandler(AuditVerify)).Methods("GET")
+	router.Handle("/fees/schedules", ctxHandler(FeeSchedules)).Methods("GET")
+	router.Handle("/fees/statement", ctxHandler(FeeStatement)).Methods("GET")
 
 	router.Handle("/wallet", ctxHandler(WalletCreate)).Methods("POST")
 	router.Handle("/wallet/balances/{address}", ctxHandler(WalletBalance)).Methods("GET")
 	router.Handle("/wallet/payment", ctxHandler(WalletSend)).Methods("POST")
 	router.Handle("/wallet/payment/{paymentId}", ctxHandler(GetPayment)).Methods("GET")
 	router.Handle("/wallet/activate/address/{address}", ctxHandler(ActivateAddress)).Methods("POST")
+	router.Handle("/wallet/addresses", ctxHandler(WalletAddresses)).Methods("POST")
+	router.Handle("/wallet/xpub", ctxHandler(WalletXpub)).Methods("POST")
+	router.Handle("/wallet/multisig", ctxHandler(MultisigAddressCreate)).Methods("POST")
+	router.Handle("/wallet/multisig/sign", ctxHandler(MultisigSign)).Methods("POST")
+	router.Handle("/wallet/multisig/combine", ctxHandler(MultisigCombine)).Methods("POST")
+	router.Handle("/wallet/watch", ctxHandler(WatchWalletCreate)).Methods("POST")
+	router.Handle("/wallet/watch/{watchWalletId}", ctxHandler(GetWatchWallet)).Methods("GET")
+	router.Handle("/wallet/watch/{watchWalletId}/scan", ctxHandler(WatchWalletScan)).Methods("POST")
+	router.Handle("/wallet/watch/{watchWalletId}/balances", ctxHandler(WatchWalletBalance)).Methods("GET")
 
 	// Direct access to Counterparty resources
 	router.Handle("/counterparty/asset", ctxHandler(AssetCreate)).Methods("POST")
@@ -38,13 +96,47 @@ func NewRouter() *mux.Router {
 	router.Handle("/counterparty/asset/dividend", ctxHandler(DividendCreate)).Methods("POST")
 	router.Handle("/counterparty/asset/dividend/{dividendId}", ctxHandler(GetDividend)).Methods("GET")
 	router.Handle("/counterparty/asset/issuances/{asset}", ctxHandler(AssetIssuances)).Methods("GET")
+	router.Handle("/counterparty/asset/ledger/schedule", ctxHandler(AssetRegistrySchedule)).Methods("POST")
+	router.Handle("/counterparty/asset/ledger/{asset}/diff", ctxHandler(AssetRegistryDiff)).Methods("GET")
+	router.Handle("/counterparty/asset/ledger/{asset}/snapshots", ctxHandler(AssetRegistrySnapshots)).Methods("GET")
 	router.Handle("/counterparty/asset/ledger/{asset}", ctxHandler(AssetLedger)).Methods("GET")
+	router.Handle("/counterparty/asset/reissue", ctxHandler(AssetReissue)).Methods("POST")
+	router.Handle("/counterparty/asset/lock", ctxHandler(AssetLock)).Methods("POST")
+	router.Handle("/counterparty/asset/transfer", ctxHandler(AssetTransfer)).Methods("POST")
+	router.Handle("/counterparty/asset/description", ctxHandler(AssetDescription)).Methods("POST")
+	router.Handle("/counterparty/order", ctxHandler(OrderCreate)).Methods("POST")
+	router.Handle("/counterparty/order/cancel", ctxHandler(OrderCancel)).Methods("POST")
+	router.Handle("/counterparty/order/btcpay", ctxHandler(OrderBtcPay)).Methods("POST")
+	router.Handle("/counterparty/order/address/{address}", ctxHandler(OpenOrdersByAddress)).Methods("GET")
+	router.Handle("/counterparty/order/book/{baseAsset}/{quoteAsset}", ctxHandler(OrderBook)).Methods("GET")
+	router.Handle("/counterparty/order/{orderId}", ctxHandler(GetOrder)).Methods("GET")
+	router.Handle("/counterparty/broadcast", ctxHandler(BroadcastCreate)).Methods("POST")
+	router.Handle("/counterparty/broadcast/address/{address}", ctxHandler(BroadcastsByAddress)).Methods("GET")
+	router.Handle("/counterparty/broadcast/{broadcastId}", ctxHandler(GetBroadcast)).Methods("GET")
 	router.Handle("/counterparty/wallet", ctxHandler(WalletCreate)).Methods("POST")
 	router.Handle("/counterparty/wallet/balances/{address}", ctxHandler(WalletBalance)).Methods("GET")
 	router.Handle("/counterparty/wallet/payment", ctxHandler(WalletSend)).Methods("POST")
 	router.Handle("/counterparty/wallet/payment/{paymentId}", ctxHandler(GetPayment)).Methods("GET")
 	router.Handle("/counterparty/wallet/activate/address/{address}", ctxHandler(ActivateAddress)).Methods("POST")
+	router.Handle("/counterparty/wallet/addresses", ctxHandler(WalletAddresses)).Methods("POST")
+	router.Handle("/counterparty/wallet/xpub", ctxHandler(WalletXpub)).Methods("POST")
+	router.Handle("/counterparty/wallet/multisig", ctxHandler(MultisigAddressCreate)).Methods("POST")
+	router.Handle("/counterparty/wallet/multisig/sign", ctxHandler(MultisigSign)).Methods("POST")
+	router.Handle("/counterparty/wallet/multisig/combine", ctxHandler(MultisigCombine)).Methods("POST")
+	router.Handle("/counterparty/wallet/watch", ctxHandler(WatchWalletCreate)).Methods("POST")
+	router.Handle("/counterparty/wallet/watch/{watchWalletId}", ctxHandler(GetWatchWallet)).Methods("GET")
+	router.Handle("/counterparty/wallet/watch/{watchWalletId}/scan", ctxHandler(WatchWalletScan)).Methods("POST")
+	router.Handle("/counterparty/wallet/watch/{watchWalletId}/balances", ctxHandler(WatchWalletBalance)).Methods("GET")
 	router.Handle("/counterparty/payment/address/{address}", ctxHandler(GetPaymentsByAddress)).Methods("GET")
+	router.Handle("/counterparty/transactions", ctxHandler(AddressTransactions)).Methods("GET")
+	router.Handle("/counterparty/transactions/address/{address}", ctxHandler(AddressTransactions)).Methods("GET")
+	router.Handle("/counterparty/deposit/address", ctxHandler(DepositAddressCreate)).Methods("POST")
+	router.Handle("/counterparty/deposit/{customerReference}", ctxHandler(CustomerDeposits)).Methods("GET")
+	router.Handle("/counterparty/ledger/account", ctxHandler(LedgerAccountCreate)).Methods("POST")
+	router.Handle("/counterparty/ledger/account/{accountId}", ctxHandler(GetLedgerAccount)).Methods("GET")
+	router.Handle("/counterparty/ledger/transfer", ctxHandler(LedgerTransfer)).Methods("POST")
+	router.Handle("/counterparty/ledger/deposit", ctxHandler(LedgerDeposit)).Methods("POST")
+	router.Handle("/counterparty/ledger/withdrawal", ctxHandler(LedgerWithdrawal)).Methods("POST")
 
 	router.Handle("/blocks", ctxHandler(GetBlocks)).Methods("GET")
 
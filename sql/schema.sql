@@ -44,7 +44,13 @@ CREATE TABLE `addresses` (
   `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
   `accessKey` varchar(64) DEFAULT NULL,
   `sourceAddress` varchar(100) DEFAULT NULL,
-  PRIMARY KEY (`rowId`)
+  `accountXpub` varchar(120) DEFAULT NULL,
+  `derivationPath` varchar(100) DEFAULT NULL,
+  `addressIndex` int(11) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  KEY `addresses1` (`sourceAddress`),
+  KEY `addresses2` (`accessKey`,`accountXpub`),
+  UNIQUE KEY `addresses3` (`accessKey`,`accountXpub`,`addressIndex`)
 ) ENGINE=InnoDB AUTO_INCREMENT=357 DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -57,21 +63,26 @@ DROP TABLE IF EXISTS `addressmaps`;
 /*!40101 SET character_set_client = utf8 */;
 CREATE TABLE `addressmaps` (
   `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
   `counterpartyPaymentAddress` varchar(200) DEFAULT NULL,
   `nativePaymentAddress` varchar(200) DEFAULT NULL,
   `externalAddress` varchar(200) DEFAULT NULL,
   `counterpartyAddress` varchar(200) DEFAULT NULL,
   `counterpartyAssetName` varchar(200) DEFAULT NULL,
   `nativeAssetName` varchar(200) DEFAULT NULL,
+  `counterpartyDivisible` tinyint(1) DEFAULT 1,
   `UDF1` varchar(200) DEFAULT NULL,
   `UDF2` varchar(200) DEFAULT NULL,
   `UDF3` varchar(200) DEFAULT NULL,
   `UDF4` varchar(200) DEFAULT NULL,
   `UDF5` varchar(200) DEFAULT NULL,
+  `addressIndex` bigint(20) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   PRIMARY KEY (`rowid`),
   UNIQUE KEY `addressMaps1` (`counterpartyPaymentAddress`),
-  UNIQUE KEY `addressMaps2` (`nativePaymentAddress`),
-  UNIQUE KEY `addressMaps3` (`externalAddress`)
+  KEY `addressMaps2` (`nativePaymentAddress`),
+  UNIQUE KEY `addressMaps3` (`externalAddress`),
+  KEY `addressMaps4` (`accessKey`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -86,6 +97,7 @@ CREATE TABLE `assets` (
   `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
   `accessKey` varchar(64) DEFAULT NULL,
   `assetId` varchar(200) DEFAULT NULL,
+  `operation` varchar(20) DEFAULT 'issuance',
   `sourceAddress` varchar(200) DEFAULT NULL,
   `distributionAddress` varchar(200) DEFAULT NULL,
   `asset` varchar(200) DEFAULT NULL,
@@ -115,13 +127,27 @@ DROP TABLE IF EXISTS `audit`;
 /*!40101 SET @saved_cs_client     = @@character_set_client */;
 /*!40101 SET character_set_client = utf8 */;
 CREATE TABLE `audit` (
-  `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
-  `blockId` varchar(200) DEFAULT NULL,
-  `txid` varchar(200) DEFAULT NULL,
-  `description` varchar(200) DEFAULT NULL,
-  PRIMARY KEY (`rowid`)
+  `auditId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) NOT NULL,
+  `requestId` varchar(64) NOT NULL DEFAULT '',
+  `entityType` varchar(20) NOT NULL,
+  `entityId` varchar(200) NOT NULL,
+  `action` varchar(200) NOT NULL,
+  `fromStatus` varchar(45) NOT NULL DEFAULT '',
+  `toStatus` varchar(45) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  `previousHash` char(64) NOT NULL,
+  `hash` char(64) NOT NULL,
+  PRIMARY KEY (`auditId`),
+  UNIQUE KEY `audit1` (`previousHash`),
+  KEY `audit2` (`accessKey`),
+  KEY `audit3` (`entityType`,`entityId`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
+DELIMITER ;;
+/*!50003 CREATE*/ /*!50003 TRIGGER `audit_noupdate` BEFORE UPDATE ON `audit` FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The audit table is append-only' */;;
+/*!50003 CREATE*/ /*!50003 TRIGGER `audit_nodelete` BEFORE DELETE ON `audit` FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The audit table is append-only' */;;
+DELIMITER ;
 
 --
 -- Table structure for table `blockchains`
@@ -146,14 +172,44 @@ DROP TABLE IF EXISTS `blocks`;
 /*!40101 SET character_set_client = utf8 */;
 CREATE TABLE `blocks` (
   `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
-  `blockId` bigint(20) DEFAULT NULL,
+  `blockchainId` varchar(50) NOT NULL,
+  `blockId` bigint(20) NOT NULL,
+  `blockHash` varchar(100) DEFAULT NULL,
   `status` varchar(100) DEFAULT NULL,
   `duration` bigint(20) DEFAULT NULL,
+  `updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`rowid`),
-  UNIQUE KEY `blocks1` (`blockId`)
+  UNIQUE KEY `blocks1` (`blockchainId`,`blockId`),
+  KEY `blocks2` (`blockchainId`,`status`,`blockId`)
 ) ENGINE=InnoDB AUTO_INCREMENT=331 DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `broadcasts`
+--
+
+DROP TABLE IF EXISTS `broadcasts`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `broadcasts` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `broadcastId` varchar(64) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `sourceAddress` varchar(200) DEFAULT NULL,
+  `text` varchar(512) DEFAULT NULL,
+  `value` double DEFAULT NULL,
+  `feeFraction` double DEFAULT NULL,
+  `timestamp` bigint(20) DEFAULT NULL,
+  `status` varchar(200) DEFAULT NULL,
+  `broadcastTxId` varchar(200) DEFAULT NULL,
+  `errorCode` bigint(20) DEFAULT NULL,
+  `errorDescription` varchar(512) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `broadcasts1` (`broadcastId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `credits`
 --
@@ -201,6 +257,53 @@ CREATE TABLE `debits` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `depositaddresses`
+--
+
+DROP TABLE IF EXISTS `depositaddresses`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `depositaddresses` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `customerReference` varchar(100) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `address` varchar(200) DEFAULT NULL,
+  `derivationPath` varchar(100) DEFAULT NULL,
+  `addressIndex` bigint(20) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `depositaddresses1` (`accessKey`,`customerReference`),
+  UNIQUE KEY `depositaddresses2` (`address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `deposits`
+--
+
+DROP TABLE IF EXISTS `deposits`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `deposits` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `customerReference` varchar(100) DEFAULT NULL,
+  `address` varchar(200) DEFAULT NULL,
+  `blockId` bigint(20) DEFAULT NULL,
+  `txid` varchar(200) DEFAULT NULL,
+  `sourceAddress` varchar(200) DEFAULT NULL,
+  `asset` varchar(200) DEFAULT NULL,
+  `quantity` bigint(20) DEFAULT NULL,
+  `status` varchar(45) DEFAULT NULL,
+  `sweepPaymentId` varchar(64) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `deposits1` (`address`,`txid`,`asset`),
+  KEY `deposits2` (`accessKey`,`customerReference`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `dividends`
 --
@@ -236,13 +339,83 @@ DROP TABLE IF EXISTS `fees`;
 /*!40101 SET @saved_cs_client     = @@character_set_client */;
 /*!40101 SET character_set_client = utf8 */;
 CREATE TABLE `fees` (
-  `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
-  `blockId` varchar(200) DEFAULT NULL,
-  `txid` varchar(200) DEFAULT NULL,
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) NOT NULL,
+  `blockchainId` varchar(50) NOT NULL,
+  `operation` varchar(20) NOT NULL,
+  `operationId` varchar(64) NOT NULL,
+  `feeType` varchar(20) NOT NULL,
+  `asset` varchar(200) DEFAULT NULL,
+  `quantity` bigint(20) unsigned DEFAULT NULL,
   `feeAsset` varchar(200) DEFAULT NULL,
-  `feeAmount` bigint(20) DEFAULT NULL,
-  PRIMARY KEY (`rowid`),
-  KEY `fees1` (`blockId`,`txid`)
+  `feeIssuer` varchar(200) DEFAULT NULL,
+  `feeAmount` bigint(20) unsigned DEFAULT NULL,
+  `status` varchar(20) DEFAULT NULL,
+  `collectionPaymentId` varchar(64) DEFAULT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `fees1` (`operation`,`operationId`),
+  KEY `fees2` (`accessKey`,`created`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `feeschedules`
+--
+
+DROP TABLE IF EXISTS `feeschedules`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `feeschedules` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) NOT NULL,
+  `blockchainId` varchar(50) NOT NULL,
+  `operation` varchar(20) NOT NULL,
+  `feeType` varchar(20) NOT NULL,
+  `feeAsset` varchar(200) DEFAULT NULL,
+  `feeIssuer` varchar(200) DEFAULT NULL,
+  `amount` bigint(20) unsigned NOT NULL DEFAULT '0',
+  `basisPoints` int(11) unsigned NOT NULL DEFAULT '0',
+  `collect` tinyint(1) NOT NULL DEFAULT '0',
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `feeschedules1` (`accessKey`,`blockchainId`,`operation`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `gatewaytransfers`
+--
+
+DROP TABLE IF EXISTS `gatewaytransfers`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `gatewaytransfers` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `addressMapId` bigint(20) DEFAULT NULL,
+  `depositBlockchainId` varchar(50) DEFAULT NULL,
+  `depositBlockId` bigint(20) DEFAULT NULL,
+  `depositTxId` varchar(200) DEFAULT NULL,
+  `depositSourceAddress` varchar(200) DEFAULT NULL,
+  `depositAddress` varchar(200) DEFAULT NULL,
+  `depositAsset` varchar(200) DEFAULT NULL,
+  `depositQuantity` bigint(20) DEFAULT NULL,
+  `payoutBlockchainId` varchar(50) DEFAULT NULL,
+  `payoutSourceAddress` varchar(200) DEFAULT NULL,
+  `payoutAddress` varchar(200) DEFAULT NULL,
+  `payoutAsset` varchar(200) DEFAULT NULL,
+  `payoutQuantity` bigint(20) DEFAULT NULL,
+  `payoutPaymentId` varchar(64) DEFAULT NULL,
+  `status` varchar(45) DEFAULT NULL,
+  `errorCode` bigint(20) DEFAULT NULL,
+  `errorDescription` varchar(512) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated` timestamp NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `gatewaytransfers1` (`depositBlockchainId`,`depositTxId`,`depositAddress`,`depositAsset`),
+  KEY `gatewaytransfers2` (`accessKey`),
+  KEY `gatewaytransfers3` (`payoutBlockchainId`,`status`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -263,6 +436,100 @@ CREATE TABLE `inputaddresses` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `journalentries`
+--
+
+DROP TABLE IF EXISTS `journalentries`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `journalentries` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `journalId` varchar(64) DEFAULT NULL,
+  `accountId` varchar(100) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `asset` varchar(200) DEFAULT NULL,
+  `issuer` varchar(200) DEFAULT NULL,
+  `quantity` bigint(20) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  KEY `journalentries1` (`accessKey`,`accountId`),
+  KEY `journalentries2` (`journalId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `journals`
+--
+
+DROP TABLE IF EXISTS `journals`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `journals` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `journalId` varchar(64) DEFAULT NULL,
+  `journalType` varchar(45) DEFAULT NULL,
+  `reference` varchar(200) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `requestId` varchar(64) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `journals1` (`journalId`),
+  UNIQUE KEY `journals2` (`accessKey`,`journalType`,`reference`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `ledgeraccounts`
+--
+
+DROP TABLE IF EXISTS `ledgeraccounts`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `ledgeraccounts` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `accountId` varchar(100) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `ledgeraccounts1` (`accessKey`,`accountId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `orders`
+--
+
+DROP TABLE IF EXISTS `orders`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `orders` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `orderId` varchar(64) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `operation` varchar(20) DEFAULT NULL,
+  `sourceAddress` varchar(200) DEFAULT NULL,
+  `giveAsset` varchar(200) DEFAULT NULL,
+  `giveIssuer` varchar(200) DEFAULT NULL,
+  `giveQuantity` bigint(20) DEFAULT NULL,
+  `getAsset` varchar(200) DEFAULT NULL,
+  `getIssuer` varchar(200) DEFAULT NULL,
+  `getQuantity` bigint(20) DEFAULT NULL,
+  `expiration` bigint(20) DEFAULT NULL,
+  `offerHash` varchar(200) DEFAULT NULL,
+  `offerSequence` bigint(20) DEFAULT NULL,
+  `status` varchar(45) DEFAULT NULL,
+  `broadcastTxId` varchar(200) DEFAULT NULL,
+  `errorCode` bigint(20) DEFAULT NULL,
+  `errorDescription` varchar(512) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `orders1` (`orderId`),
+  KEY `orders2` (`offerHash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `outputaddresses`
 --
@@ -298,6 +565,14 @@ CREATE TABLE `payments` (
   `outAsset` varchar(200) DEFAULT NULL,
   `issuer` varchar(200) DEFAULT NULL,
   `outAmount` bigint(20) DEFAULT NULL,
+  `sourceAsset` varchar(200) DEFAULT NULL,
+  `sourceIssuer` varchar(200) DEFAULT NULL,
+  `sendMax` bigint(20) DEFAULT NULL,
+  `deliveredAmount` bigint(20) DEFAULT NULL,
+  `destinationTag` bigint(20) DEFAULT NULL,
+  `sourceTag` bigint(20) DEFAULT NULL,
+  `invoiceId` varchar(64) DEFAULT NULL,
+  `memos` text,
   `status` varchar(200) DEFAULT NULL,
   `lastUpdatedBlockId` bigint(20) DEFAULT NULL,
   `txFee` bigint(20) DEFAULT NULL,
@@ -322,12 +597,52 @@ DROP TABLE IF EXISTS `registry`;
 /*!40101 SET character_set_client = utf8 */;
 CREATE TABLE `registry` (
   `rowid` bigint(20) NOT NULL AUTO_INCREMENT,
-  `blockId` varchar(200) DEFAULT NULL,
+  `blockId` bigint(20) DEFAULT NULL,
   `ownerAddress` varchar(200) DEFAULT NULL,
   `asset` varchar(200) DEFAULT NULL,
   `quantity` bigint(20) DEFAULT NULL,
   `status` varchar(200) DEFAULT NULL,
-  PRIMARY KEY (`rowid`)
+  PRIMARY KEY (`rowid`),
+  KEY `registry1` (`asset`,`blockId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `registryschedules`
+--
+
+DROP TABLE IF EXISTS `registryschedules`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `registryschedules` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `asset` varchar(200) DEFAULT NULL,
+  `triggerType` varchar(20) DEFAULT NULL,
+  `blockId` bigint(20) NOT NULL DEFAULT '0',
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `registryschedules1` (`accessKey`,`asset`,`triggerType`,`blockId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `registrysnapshots`
+--
+
+DROP TABLE IF EXISTS `registrysnapshots`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `registrysnapshots` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `asset` varchar(200) DEFAULT NULL,
+  `blockId` bigint(20) DEFAULT NULL,
+  `triggerType` varchar(20) DEFAULT NULL,
+  `holders` bigint(20) DEFAULT NULL,
+  `total` bigint(20) DEFAULT NULL,
+  `created` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `registrysnapshots1` (`asset`,`blockId`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -363,7 +678,17 @@ CREATE TABLE `trustassets` (
   `asset` varchar(200) DEFAULT NULL,
   `issuer` varchar(200) DEFAULT NULL,
   `trustAmount` bigint(20) DEFAULT NULL,
-  PRIMARY KEY (`rowid`)
+  `qualityIn` bigint(20) DEFAULT NULL,
+  `qualityOut` bigint(20) DEFAULT NULL,
+  `trustId` varchar(45) DEFAULT NULL,
+  `operation` varchar(20) DEFAULT 'trust',
+  `address` varchar(200) DEFAULT NULL,
+  `status` varchar(200) DEFAULT NULL,
+  `broadcastTxId` varchar(200) DEFAULT NULL,
+  `errorCode` bigint(20) DEFAULT NULL,
+  `errorDescription` varchar(512) DEFAULT NULL,
+  PRIMARY KEY (`rowid`),
+  KEY `trustassets1` (`trustId`)
 ) ENGINE=InnoDB AUTO_INCREMENT=74 DEFAULT CHARSET=latin1;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -387,6 +712,29 @@ CREATE TABLE `userkeys` (
   PRIMARY KEY (`rowId`)
 ) ENGINE=InnoDB AUTO_INCREMENT=337 DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `watchwallets`
+--
+
+DROP TABLE IF EXISTS `watchwallets`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `watchwallets` (
+  `rowId` bigint(20) NOT NULL AUTO_INCREMENT,
+  `accessKey` varchar(64) DEFAULT NULL,
+  `watchWalletId` varchar(64) DEFAULT NULL,
+  `blockchainId` varchar(50) DEFAULT NULL,
+  `xpub` varchar(120) DEFAULT NULL,
+  `gapLimit` int(11) DEFAULT NULL,
+  `lastUsedIndex` bigint(20) DEFAULT '-1',
+  `status` varchar(45) DEFAULT NULL,
+  `errorCode` bigint(20) DEFAULT NULL,
+  `errorDescription` varchar(512) DEFAULT NULL,
+  PRIMARY KEY (`rowId`),
+  UNIQUE KEY `watchwallets1` (`watchWalletId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
 /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
 
 /*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
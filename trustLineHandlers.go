@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+// Status of an operation on a trust line
+func GetTrustLine(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "getTrustLine")
+
+	return handle(c, w, r)
+}
+
+// Create a trust line or modify its limit and qualities
+func TrustLineCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "trustLineCreate")
+
+	return handle(c, w, r)
+}
+
+// Remove a trust line with a zero balance
+func TrustLineRemove(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "trustLineRemove")
+
+	return handle(c, w, r)
+}
+
+// Trust lines held by an address
+func TrustLinesByAddress(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "trustLinesByAddress")
+
+	return handle(c, w, r)
+}
@@ -0,0 +1,60 @@
+// Verifies the hash chain of the audit trail with -verify, otherwise prints the audit entries which match the flags as JSON, one per line.
+// Exits with 1 if the chain is broken and 2 if the database can't be read
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func main() {
+	verify := flag.Bool("verify", false, "verify the hash chain of every audit entry")
+	accessKey := flag.String("accessKey", "", "only print entries made by the access key")
+	entityType := flag.String("entityType", "", "only print entries of the entity type, ie payment, asset, dividend, activation, accessKey or request")
+	entityId := flag.String("entityId", "", "only print entries of the entity")
+	fromAuditId := flag.Int64("fromAuditId", 0, "only print entries after the auditId")
+	limit := flag.Int64("limit", 100, "maximum number of entries to print")
+	flag.Parse()
+
+	c := context.TODO()
+
+	if *verify {
+		result, err := database.VerifyAudit(c)
+		if err != nil {
+			log.Fluentf(consts.LOGERROR, "%s", err.Error())
+			os.Exit(2)
+		}
+
+		if result.Valid == false {
+			fmt.Printf("Audit chain is broken at auditId %d after %d valid entries. Last valid hash: %s\n", result.BrokenAuditId, result.Entries, result.LastHash)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Audit chain is valid. Entries: %d, last hash: %s\n", result.Entries, result.LastHash)
+		return
+	}
+
+	entries, err := database.GetAuditEntries(c, *accessKey, *entityType, *entityId, *fromAuditId, *limit)
+	if err != nil {
+		log.Fluentf(consts.LOGERROR, "%s", err.Error())
+		os.Exit(2)
+	}
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Fluentf(consts.LOGERROR, "%s", err.Error())
+			os.Exit(2)
+		}
+
+		fmt.Println(string(line))
+	}
+}
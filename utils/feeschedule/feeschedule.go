@@ -0,0 +1,75 @@
+// Sets, removes and lists the fee schedules of an access key. Without -set or -delete the schedules of the access key are printed as JSON, one per line.
+// Exits with 1 if the schedule is invalid and 2 if the database can't be updated
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/whoisjeremylam/enu/consts"
+	"github.com/whoisjeremylam/enu/database"
+	"github.com/whoisjeremylam/enu/enulib"
+	"github.com/whoisjeremylam/enu/log"
+
+	"github.com/whoisjeremylam/enu/internal/golang.org/x/net/context"
+)
+
+func main() {
+	accessKey := flag.String("accessKey", "", "access key the fees are charged to")
+	set := flag.Bool("set", false, "set the fee schedule of the operation, replacing any existing schedule")
+	remove := flag.Bool("delete", false, "remove the fee schedule of the operation")
+
+	var schedule enulib.FeeSchedule
+	flag.StringVar(&schedule.BlockchainId, "blockchainId", consts.CounterpartyBlockchainId, "blockchain the operation is made on")
+	flag.StringVar(&schedule.Operation, "operation", enulib.FeePayment, "payment, issuance, dividend or activation")
+	flag.StringVar(&schedule.FeeType, "feeType", enulib.FeeFlat, "flat or percentage")
+	flag.StringVar(&schedule.FeeAsset, "feeAsset", "", "asset of a flat fee")
+	flag.StringVar(&schedule.FeeIssuer, "feeIssuer", "", "issuer of a flat fee in a Ripple IOU")
+	flag.Uint64Var(&schedule.Amount, "amount", 0, "flat fee in the base unit of the fee asset")
+	flag.Uint64Var(&schedule.BasisPoints, "basisPoints", 0, "percentage fee in hundredths of a percent")
+	flag.BoolVar(&schedule.Collect, "collect", false, "collect the fee on chain to the operator's fee address. Only Ripple fees can be collected")
+	flag.Parse()
+
+	if *accessKey == "" || database.UserKeyExists(*accessKey) == false {
+		fmt.Println("A valid -accessKey must be given")
+		os.Exit(1)
+	}
+
+	c := context.TODO()
+
+	switch {
+	case *set:
+		if err := enulib.ValidateFeeSchedule(schedule); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		if err := database.SetFeeSchedule(c, *accessKey, schedule); err != nil {
+			log.Fluentf(consts.LOGERROR, "%s", err.Error())
+			os.Exit(2)
+		}
+	case *remove:
+		if err := database.DeleteFeeSchedule(c, *accessKey, schedule.BlockchainId, schedule.Operation); err != nil {
+			log.Fluentf(consts.LOGERROR, "%s", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	schedules, err := database.GetFeeSchedules(c, *accessKey, "")
+	if err != nil {
+		log.Fluentf(consts.LOGERROR, "%s", err.Error())
+		os.Exit(2)
+	}
+
+	for _, s := range schedules {
+		line, err := json.Marshal(s)
+		if err != nil {
+			log.Fluentf(consts.LOGERROR, "%s", err.Error())
+			os.Exit(2)
+		}
+
+		fmt.Println(string(line))
+	}
+}
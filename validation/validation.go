@@ -0,0 +1,99 @@
+// Validation of blockchain addresses. Addresses are base58check decoded and their version byte and checksum verified
+// rather than relying on the length of the address.
+package validation
+
+import (
+	"strings"
+
+	"github.com/whoisjeremylam/enu/consts"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcutil/base58"
+	"github.com/whoisjeremylam/enu/internal/github.com/xeipuuv/gojsonschema"
+)
+
+// Names of the formats which may be used in the JSON schemas in consts.ParameterValidations
+const BitcoinAddressFormat = "bitcoinAddress"
+const RippleAddressFormat = "rippleAddress"
+
+// The network Counterparty addresses are validated against
+var BitcoinNetwork = &chaincfg.MainNetParams
+
+// Base58 alphabets. rippled uses the same scheme as bitcoin with a different ordering of the characters
+const bitcoinAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+const rippleAlphabet = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+
+// Version byte of ripple account ids
+const rippleAccountVersion = 0
+
+// Length of the hash160 encoded in bitcoin and ripple addresses
+const addressHashLength = 20
+
+type bitcoinAddressFormatChecker struct{}
+type rippleAddressFormatChecker struct{}
+
+func (f bitcoinAddressFormatChecker) IsFormat(input string) bool {
+	return IsValidBitcoinAddress(input, BitcoinNetwork)
+}
+
+func (f rippleAddressFormatChecker) IsFormat(input string) bool {
+	return IsValidRippleAddress(input)
+}
+
+func init() {
+	gojsonschema.FormatCheckers.Add(BitcoinAddressFormat, bitcoinAddressFormatChecker{})
+	gojsonschema.FormatCheckers.Add(RippleAddressFormat, rippleAddressFormatChecker{})
+}
+
+// IsAddressFormat returns true if the JSON schema format is one of the address formats
+func IsAddressFormat(format string) bool {
+	return format == BitcoinAddressFormat || format == RippleAddressFormat
+}
+
+// IsValidAddress validates the address against the rules of the given blockchain
+func IsValidAddress(blockchainId string, address string) bool {
+	switch blockchainId {
+	case consts.CounterpartyBlockchainId:
+		return IsValidBitcoinAddress(address, BitcoinNetwork)
+	case consts.RippleBlockchainId:
+		return IsValidRippleAddress(address)
+	}
+
+	return false
+}
+
+// IsValidBitcoinAddress returns true if the address is a pay to pubkey hash or pay to script hash address for the network
+// and the checksum is correct
+func IsValidBitcoinAddress(address string, params *chaincfg.Params) bool {
+	hash, version, err := base58.CheckDecode(address)
+	if err != nil || len(hash) != addressHashLength {
+		return false
+	}
+
+	return version == params.PubKeyHashAddrID || version == params.ScriptHashAddrID
+}
+
+// IsValidRippleAddress returns true if the address is a ripple account id encoded with the rippled alphabet and the checksum is correct
+func IsValidRippleAddress(address string) bool {
+	if address == "" || address[0] != 'r' {
+		return false
+	}
+
+	// Translate to the bitcoin alphabet so the base58check decoder can be reused
+	translated := make([]byte, len(address))
+	for i := 0; i < len(address); i++ {
+		position := strings.IndexByte(rippleAlphabet, address[i])
+		if position < 0 {
+			return false
+		}
+
+		translated[i] = bitcoinAlphabet[position]
+	}
+
+	hash, version, err := base58.CheckDecode(string(translated))
+	if err != nil || len(hash) != addressHashLength {
+		return false
+	}
+
+	return version == rippleAccountVersion
+}
@@ -0,0 +1,90 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/whoisjeremylam/enu/consts"
+
+	"github.com/whoisjeremylam/enu/internal/github.com/btcsuite/btcd/chaincfg"
+	"github.com/whoisjeremylam/enu/internal/github.com/xeipuuv/gojsonschema"
+)
+
+func TestIsValidBitcoinAddress(t *testing.T) {
+	var testData = []struct {
+		Address         string
+		Params          *chaincfg.Params
+		Expected        bool
+		CaseDescription string
+	}{
+		{"1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg1", &chaincfg.MainNetParams, true, "P2PKH address"},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", &chaincfg.MainNetParams, true, "P2SH address"},
+		{"1111111111111111111114oLvT2", &chaincfg.MainNetParams, true, "Address shorter than 34 characters"},
+		{"1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg2", &chaincfg.MainNetParams, false, "Incorrect checksum"},
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", &chaincfg.MainNetParams, false, "Testnet address on mainnet"},
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", &chaincfg.TestNet3Params, true, "Testnet address on testnet"},
+		{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", &chaincfg.MainNetParams, false, "Ripple address"},
+		{"1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg0", &chaincfg.MainNetParams, false, "Character not in the base58 alphabet"},
+		{"", &chaincfg.MainNetParams, false, "Empty address"},
+	}
+
+	for _, s := range testData {
+		result := IsValidBitcoinAddress(s.Address, s.Params)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %t, Got: %t\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestIsValidRippleAddress(t *testing.T) {
+	var testData = []struct {
+		Address         string
+		Expected        bool
+		CaseDescription string
+	}{
+		{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", true, "Genesis account"},
+		{"rrrrrrrrrrrrrrrrrrrrrhoLvTp", true, "Account zero"},
+		{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTj", false, "Incorrect checksum"},
+		{"1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg1", false, "Bitcoin address"},
+		{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyT0", false, "Character not in the rippled alphabet"},
+		{"", false, "Empty address"},
+	}
+
+	for _, s := range testData {
+		result := IsValidRippleAddress(s.Address)
+
+		if result != s.Expected {
+			t.Errorf("Expected: %t, Got: %t\nCase: %s\n", s.Expected, result, s.CaseDescription)
+		}
+	}
+}
+
+func TestAddressFormats(t *testing.T) {
+	var testData = []struct {
+		BlockchainId    string
+		Document        map[string]interface{}
+		Expected        bool
+		CaseDescription string
+	}{
+		{consts.CounterpartyBlockchainId, map[string]interface{}{"sourceAddress": "1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg1"}, true, "Valid bitcoin address"},
+		{consts.CounterpartyBlockchainId, map[string]interface{}{"sourceAddress": "1HpkZBjNFRFagyj6Q2adRSagkfNDERZhg2"}, false, "Invalid bitcoin address"},
+		{consts.RippleBlockchainId, map[string]interface{}{"sourceAddress": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"}, true, "Valid ripple address"},
+		{consts.RippleBlockchainId, map[string]interface{}{"sourceAddress": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTj"}, false, "Invalid ripple address"},
+	}
+
+	formats := map[string]string{consts.CounterpartyBlockchainId: BitcoinAddressFormat, consts.RippleBlockchainId: RippleAddressFormat}
+
+	for _, s := range testData {
+		schema := `{"properties":{"sourceAddress":{"type":"string","format":"` + formats[s.BlockchainId] + `"}}}`
+
+		result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(schema), gojsonschema.NewGoLoader(s.Document))
+		if err != nil {
+			t.Errorf("Error in Validate(): %s\nCase: %s\n", err.Error(), s.CaseDescription)
+			continue
+		}
+
+		if result.Valid() != s.Expected || IsValidAddress(s.BlockchainId, s.Document["sourceAddress"].(string)) != s.Expected {
+			t.Errorf("Expected: %t, Got: %t\nCase: %s\n", s.Expected, result.Valid(), s.CaseDescription)
+		}
+	}
+}
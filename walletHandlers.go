@@ -36,3 +36,69 @@ func ActivateAddress(c context.Context, w http.ResponseWriter, r *http.Request)
 
 	return handle(c, w, r)
 }
+
+func WalletAddresses(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "walletAddresses")
+
+	return handle(c, w, r)
+}
+
+func WalletXpub(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "walletXpub")
+
+	return handle(c, w, r)
+}
+
+func WatchWalletCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "watchWalletCreate")
+
+	return handle(c, w, r)
+}
+
+func GetWatchWallet(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "getWatchWallet")
+
+	return handle(c, w, r)
+}
+
+func WatchWalletScan(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "watchWalletScan")
+
+	return handle(c, w, r)
+}
+
+func WatchWalletBalance(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "watchWalletBalance")
+
+	return handle(c, w, r)
+}
+
+// Creates an m-of-n multisig address
+func MultisigAddressCreate(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "multisigAddressCreate")
+
+	return handle(c, w, r)
+}
+
+// Adds the signatures of a co-signer to a multisig transaction
+func MultisigSign(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "multisigSign")
+
+	return handle(c, w, r)
+}
+
+// Combines the signatures of the co-signers of a multisig transaction
+func MultisigCombine(c context.Context, w http.ResponseWriter, r *http.Request) *enulib.AppError {
+	// Add to the context the RequestType
+	c = context.WithValue(c, consts.RequestTypeKey, "multisigCombine")
+
+	return handle(c, w, r)
+}